@@ -0,0 +1,121 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+)
+
+func TestAbort(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "plan9":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := tcp.NewConn(p)
+	if err != nil {
+		p.Close()
+		t.Fatal(err)
+	}
+
+	if err := tc.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	var b [1]byte
+	_, err = c.Read(b[:])
+	if err == nil || !strings.Contains(err.Error(), "reset") {
+		t.Fatalf("got %v; want connection reset", err)
+	}
+}
+
+func TestAbortOnWriteTimeout(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "plan9":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, p := tcptest.Pair(t)
+	c.SetAbortOnWriteTimeout(true)
+	if err := c.SetWriteDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The peer reads nothing, and the write blocks on the full
+	// send queue until the deadline.
+	b := make([]byte, 1<<16)
+	var err error
+	for err == nil {
+		_, err = c.Write(b)
+	}
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("got %v; want timeout error", err)
+	}
+	if _, err := c.Write(b); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("got %v; want %v", err, net.ErrClosed)
+	}
+	p.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = io.Copy(ioutil.Discard, p); err == nil || !strings.Contains(err.Error(), "reset") {
+		t.Fatalf("got %v; want connection reset", err)
+	}
+}
+
+func TestAbortOnWriteTimeoutRawIO(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		write func(*tcp.Conn, []byte) (int, error)
+	}{
+		{"WriteMore", (*tcp.Conn).WriteMore},
+		{"TryWrite", (*tcp.Conn).TryWrite},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, p := tcptest.Pair(t)
+			c.SetAbortOnWriteTimeout(true)
+			if err := c.SetWriteDeadline(time.Now().Add(-time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			_, err := tt.write(c, []byte("HELLO"))
+			if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+				t.Fatalf("got %v; want timeout error", err)
+			}
+			if _, err := c.Write([]byte("HELLO")); !errors.Is(err, net.ErrClosed) {
+				t.Fatalf("got %v; want %v", err, net.ErrClosed)
+			}
+			p.SetReadDeadline(time.Now().Add(time.Second))
+			if _, err = io.Copy(ioutil.Discard, p); err == nil || !strings.Contains(err.Error(), "reset") {
+				t.Fatalf("got %v; want connection reset", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by the service
+// manager; see SD_LISTEN_FDS_START.
+const listenFDsStart = 3
+
+// ListenersFromActivation returns the TCP listeners passed by the
+// service manager such as systemd with the socket activation
+// protocol, in the order of file descriptors.
+// It returns no listeners when the process is not socket-activated.
+//
+// It consumes the LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES
+// environment variables. The passed file descriptors that are not TCP
+// listeners are left open.
+//
+// Windows doesn't support this feature.
+func ListenersFromActivation() ([]*Listener, error) {
+	lns, _, err := listenersFromActivation()
+	return lns, err
+}
+
+// NamedListenersFromActivation is like ListenersFromActivation but
+// returns the listeners keyed by the names in LISTEN_FDNAMES.
+// The listeners without names are keyed by "unknown".
+func NamedListenersFromActivation() (map[string][]*Listener, error) {
+	lns, names, err := listenersFromActivation()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string][]*Listener)
+	for i, ln := range lns {
+		m[names[i]] = append(m[names[i]], ln)
+	}
+	return m, nil
+}
+
+func listenersFromActivation() ([]*Listener, []string, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil, nil
+	}
+	fdnames := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	var lns []*Listener
+	var names []string
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(fdnames) && fdnames[i] != "" {
+			name = fdnames[i]
+		}
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		if err != nil {
+			continue
+		}
+		if _, ok := ln.(*net.TCPListener); !ok {
+			ln.Close()
+			continue
+		}
+		f.Close()
+		tl, err := NewListener(ln)
+		if err != nil {
+			ln.Close()
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, nil, err
+		}
+		lns = append(lns, tl)
+		names = append(names, name)
+	}
+	return lns, names, nil
+}
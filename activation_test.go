@@ -0,0 +1,62 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestListenersFromActivation(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "nacl", "plan9", "windows":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	if os.Getenv("LISTEN_FDS") != "" {
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		m, err := tcp.NamedListenersFromActivation()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m["http"]) != 1 {
+			t.Fatalf("got %v; want a listener named http", m)
+		}
+		if _, err := m["http"][0].Stats(); err != nil && runtime.GOOS == "linux" {
+			t.Fatal(err)
+		}
+		if os.Getenv("LISTEN_FDS") != "" {
+			t.Fatal("environment not consumed")
+		}
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestListenersFromActivation$")
+	cmd.Env = append(os.Environ(), "LISTEN_FDS=1", "LISTEN_FDNAMES=http")
+	cmd.ExtraFiles = []*os.File{f}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	lns, err := tcp.ListenersFromActivation()
+	if err != nil || len(lns) != 0 {
+		t.Fatalf("got %v, %v; want no listeners", lns, err)
+	}
+}
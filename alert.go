@@ -0,0 +1,230 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// A RetransmitThreshold represents the thresholds of retransmissions
+// evaluated by RetransmitMonitor.
+// Zero values are replaced with the defaults noted in comments.
+type RetransmitThreshold struct {
+	Window    time.Duration // window of evaluation; default 10s
+	Interval  time.Duration // interval of sampling; default a tenth of Window
+	MaxRatio  float64       // maximum ratio of retransmitted to sent segments in Window; zero means unlimited
+	MinSegs   uint64        // minimum # of sent segments in Window for evaluating MaxRatio; default 1
+	MaxRTOs   int           // maximum # of consecutive retransmission timeouts; zero means unlimited
+	Aggregate bool          // whether to evaluate the sum of all the connections as a group too
+}
+
+func (th *RetransmitThreshold) withDefaults() RetransmitThreshold {
+	tth := *th
+	if tth.Window <= 0 {
+		tth.Window = 10 * time.Second
+	}
+	if tth.Interval <= 0 {
+		tth.Interval = tth.Window / 10
+	}
+	if tth.MinSegs == 0 {
+		tth.MinSegs = 1
+	}
+	return tth
+}
+
+// A RetransmitAlert represents the retransmissions that breached
+// the threshold.
+type RetransmitAlert struct {
+	Conn        *Conn         // connection; nil for the group
+	Window      time.Duration // span of the samples
+	SegsOut     uint64        // # of sent segments in Window
+	Retransmits uint64        // # of retransmitted segments in Window
+	Ratio       float64       // ratio of Retransmits to SegsOut
+	RTOs        int           // # of consecutive retransmission timeouts; the maximum for the group
+}
+
+// A RetransmitAlertFunc is called by a RetransmitMonitor when the
+// retransmissions breach the threshold.
+type RetransmitAlertFunc func(a *RetransmitAlert)
+
+// A RetransmitMonitor tracks connections and calls the alert
+// function when the retransmissions on a connection, or on all the
+// connections as a group, breach the threshold.
+//
+// The alert function is called once per breach; a connection or
+// group staying within the threshold becomes a candidate again.
+// The TCP information of the connections is fetched by a
+// StatsCollector.
+//
+// Only Linux supports the retransmission counters.
+type RetransmitMonitor struct {
+	th   RetransmitThreshold
+	fn   RetransmitAlertFunc
+	sc   *StatsCollector
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mu      sync.Mutex
+	samples map[*Conn][]retransmitSample
+	alerted map[*Conn]bool // nil key for the group
+	err     error
+}
+
+type retransmitSample struct {
+	at          time.Time
+	segsOut     uint64
+	retransmits uint64
+}
+
+// NewRetransmitMonitor returns a new monitor that samples the
+// connections every interval of threshold th and calls fn when the
+// retransmissions breach th.
+func NewRetransmitMonitor(th *RetransmitThreshold, fn RetransmitAlertFunc) (*RetransmitMonitor, error) {
+	tth := th.withDefaults()
+	if fn == nil || tth.MaxRatio < 0 || tth.MaxRTOs < 0 || tth.Interval > tth.Window {
+		return nil, errors.New("invalid threshold or alert function")
+	}
+	m := &RetransmitMonitor{
+		th:      tth,
+		fn:      fn,
+		sc:      NewStatsCollector(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		samples: make(map[*Conn][]retransmitSample),
+		alerted: make(map[*Conn]bool),
+	}
+	go m.run()
+	return m, nil
+}
+
+// Add registers the connection c with the monitor.
+func (m *RetransmitMonitor) Add(c *Conn) {
+	m.mu.Lock()
+	if _, ok := m.samples[c]; !ok {
+		m.samples[c] = nil
+		m.sc.Add(c)
+	}
+	m.mu.Unlock()
+}
+
+// Remove unregisters the connection c from the monitor.
+func (m *RetransmitMonitor) Remove(c *Conn) {
+	m.mu.Lock()
+	m.remove(c)
+	m.mu.Unlock()
+}
+
+func (m *RetransmitMonitor) remove(c *Conn) {
+	delete(m.samples, c)
+	delete(m.alerted, c)
+	m.sc.Remove(c)
+}
+
+// Len returns the number of registered connections.
+func (m *RetransmitMonitor) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.samples)
+}
+
+// Err returns the last error in sampling.
+func (m *RetransmitMonitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Stop stops monitoring.
+func (m *RetransmitMonitor) Stop() {
+	m.once.Do(func() { close(m.stop) })
+	<-m.done
+}
+
+func (m *RetransmitMonitor) run() {
+	defer close(m.done)
+	t := time.NewTicker(m.th.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			m.evaluate()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *RetransmitMonitor) evaluate() {
+	infos, err := m.sc.Collect()
+	now := time.Now()
+	m.mu.Lock()
+	if err != nil {
+		m.err = err
+		m.mu.Unlock()
+		return
+	}
+	var alerts []*RetransmitAlert
+	group := RetransmitAlert{Window: m.th.Window}
+	for c, ss := range m.samples {
+		info, ok := infos[c]
+		if !ok {
+			if ss == nil { // may be added during collection
+				m.samples[c] = []retransmitSample{}
+				continue
+			}
+			m.remove(c) // closed
+			continue
+		}
+		d := counters(info)
+		ss = append(ss, retransmitSample{at: now, segsOut: d.SegsOut, retransmits: d.Retransmits})
+		for len(ss) > 1 && now.Sub(ss[1].at) >= m.th.Window {
+			ss = ss[1:]
+		}
+		m.samples[c] = ss
+		a := RetransmitAlert{
+			Conn:        c,
+			Window:      now.Sub(ss[0].at),
+			SegsOut:     d.SegsOut - ss[0].segsOut,
+			Retransmits: d.Retransmits - ss[0].retransmits,
+			RTOs:        consecutiveRTOs(info),
+		}
+		group.SegsOut += a.SegsOut
+		group.Retransmits += a.Retransmits
+		if a.RTOs > group.RTOs {
+			group.RTOs = a.RTOs
+		}
+		if m.breached(&a) {
+			alerts = append(alerts, &a)
+		}
+	}
+	if m.th.Aggregate && m.breached(&group) {
+		alerts = append(alerts, &group)
+	}
+	m.mu.Unlock()
+	for _, a := range alerts {
+		m.fn(a)
+	}
+}
+
+// breached reports whether a newly breaches the threshold and
+// records the state of a.
+// It must be called with m.mu held.
+func (m *RetransmitMonitor) breached(a *RetransmitAlert) bool {
+	if a.SegsOut > 0 {
+		a.Ratio = float64(a.Retransmits) / float64(a.SegsOut)
+	}
+	b := m.th.MaxRatio > 0 && a.SegsOut >= m.th.MinSegs && a.Ratio > m.th.MaxRatio ||
+		m.th.MaxRTOs > 0 && a.RTOs > m.th.MaxRTOs
+	alerted := m.alerted[a.Conn]
+	if b {
+		m.alerted[a.Conn] = true
+	} else {
+		delete(m.alerted, a.Conn)
+	}
+	return b && !alerted
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+)
+
+func TestRetransmitMonitor(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c0, p0 := tcptest.Pair(t)
+	c1, _ := tcptest.Pair(t)
+
+	alerts := make(chan *tcp.RetransmitAlert, 8)
+	m, err := tcp.NewRetransmitMonitor(&tcp.RetransmitThreshold{Window: time.Second, Interval: 50 * time.Millisecond, MaxRatio: 0.01, Aggregate: true}, func(a *tcp.RetransmitAlert) {
+		alerts <- a
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+	m.Add(c0)
+	m.Add(c1)
+	if n := m.Len(); n != 2 {
+		t.Fatalf("got %d; want 2", n)
+	}
+
+	// The second connection stays clean, and the peer of the
+	// first connection drops all the incoming segments.
+	if _, err := c1.Write([]byte("HELLO-R-U-THERE")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p0.AttachFilter([]tcp.RawInstruction{{Op: 0x06, K: 0}}); err != nil { // ret #0
+		t.Fatal(err)
+	}
+	if _, err := c0.Write([]byte("HELLO-R-U-THERE")); err != nil {
+		t.Fatal(err)
+	}
+
+	var conn, group bool
+	timeout := time.After(5 * time.Second)
+	for !conn || !group {
+		select {
+		case a := <-alerts:
+			switch a.Conn {
+			case nil:
+				group = true
+			case c0:
+				conn = true
+			default:
+				t.Fatalf("unexpected alert on %v", a.Conn)
+			}
+			if a.Retransmits == 0 || a.Ratio <= 0.01 {
+				t.Errorf("got %+v; want breaching retransmissions", a)
+			}
+		case <-timeout:
+			t.Fatalf("timed out; connection=%v group=%v err=%v", conn, group, m.Err())
+		}
+	}
+
+	m.Remove(c1)
+	if n := m.Len(); n != 1 {
+		t.Fatalf("got %d; want 1", n)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "errors"
+
+// A BlackBoxMode represents a mode of the black box logging, which
+// records the internal events of TCP stack for the connection.
+type BlackBoxMode int
+
+const (
+	BlackBoxOff       BlackBoxMode = iota // logging disabled
+	BlackBoxTail                          // keep the latest events
+	BlackBoxHead                          // keep the earliest events
+	BlackBoxHeadAuto                      // keep the earliest events and dump them automatically
+	BlackBoxContinual                     // dump the events continually
+	BlackBoxTailAuto                      // keep the latest events and dump them automatically
+)
+
+var blackBoxModeNames = [...]string{
+	BlackBoxOff:       "off",
+	BlackBoxTail:      "tail",
+	BlackBoxHead:      "head",
+	BlackBoxHeadAuto:  "head-auto",
+	BlackBoxContinual: "continual",
+	BlackBoxTailAuto:  "tail-auto",
+}
+
+func (m BlackBoxMode) String() string {
+	if m < 0 || int(m) >= len(blackBoxModeNames) {
+		return "<nil>"
+	}
+	return blackBoxModeNames[m]
+}
+
+// SetBlackBox sets the mode of the black box logging.
+// The dumped events are delivered to the tcp_log device, and tools
+// such as tcplog_dumper write them to files.
+//
+// Only FreeBSD supports this feature. It requires the kernel built
+// with TCP_BLACKBOX option.
+// See TCP_LOG for further information.
+func (c *Conn) SetBlackBox(m BlackBoxMode) error {
+	if m < 0 || int(m) >= len(blackBoxModeNames) {
+		return c.opError("set", errors.New("invalid black box mode"))
+	}
+	if err := c.control(func(s uintptr) error { return setBlackBox(s, m) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// BlackBox returns the mode of the black box logging.
+//
+// Only FreeBSD supports this feature.
+func (c *Conn) BlackBox() (BlackBoxMode, error) {
+	var m BlackBoxMode
+	err := c.control(func(s uintptr) (err error) {
+		m, err = blackBox(s)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return m, nil
+}
+
+// SetBlackBoxID sets the identifier of the black box log, which
+// correlates the logs of multiple connections such as the ones of a
+// user session.
+//
+// Only FreeBSD supports this feature.
+// See TCP_LOGID for further information.
+func (c *Conn) SetBlackBoxID(id string) error {
+	if err := c.control(func(s uintptr) error { return setBlackBoxID(s, id) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// DumpBlackBox dumps the events kept by the black box logging to the
+// tcp_log device with the reason.
+//
+// Only FreeBSD supports this feature.
+// See TCP_LOGDUMP for further information.
+func (c *Conn) DumpBlackBox(reason string) error {
+	if err := c.control(func(s uintptr) error { return dumpBlackBox(s, reason) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// ReadBlackBox reads the events kept by the black box logging into
+// b. It returns the number of bytes read.
+// The events are in the binary form of struct tcp_log_buffer of the
+// running kernel.
+//
+// Only FreeBSD supports this feature.
+// See TCP_LOGBUF for further information.
+func (c *Conn) ReadBlackBox(b []byte) (int, error) {
+	var n int
+	err := c.control(func(s uintptr) (err error) {
+		n, err = readBlackBox(s, b)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"os"
+	"unsafe"
+)
+
+func setBlackBox(s uintptr, m BlackBoxMode) error {
+	v := int32(m)
+	if err := setsockopt(s, ianaProtocolTCP, sysTCP_LOG, (*[4]byte)(unsafe.Pointer(&v))[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func blackBox(s uintptr) (BlackBoxMode, error) {
+	var b [4]byte
+	if _, err := getsockopt(s, ianaProtocolTCP, sysTCP_LOG, b[:]); err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+	return BlackBoxMode(int32(nativeEndian.Uint32(b[:]))), nil
+}
+
+func setBlackBoxID(s uintptr, id string) error {
+	if len(id) >= sysTCP_LOG_ID_LEN {
+		return errors.New("black box log id too long")
+	}
+	if err := setsockopt(s, ianaProtocolTCP, sysTCP_LOGID, append([]byte(id), 0)); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func dumpBlackBox(s uintptr, reason string) error {
+	if len(reason) >= sysTCP_LOG_REASON_LEN {
+		return errors.New("black box dump reason too long")
+	}
+	if err := setsockopt(s, ianaProtocolTCP, sysTCP_LOGDUMP, append([]byte(reason), 0)); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func readBlackBox(s uintptr, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := getsockopt(s, ianaProtocolTCP, sysTCP_LOGBUF, b)
+	if err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+	return n, nil
+}
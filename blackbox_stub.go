@@ -0,0 +1,17 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !freebsd
+
+package tcp
+
+func setBlackBox(s uintptr, m BlackBoxMode) error { return ErrNotSupported }
+
+func blackBox(s uintptr) (BlackBoxMode, error) { return 0, ErrNotSupported }
+
+func setBlackBoxID(s uintptr, id string) error { return ErrNotSupported }
+
+func dumpBlackBox(s uintptr, reason string) error { return ErrNotSupported }
+
+func readBlackBox(s uintptr, b []byte) (int, error) { return 0, ErrNotSupported }
@@ -5,6 +5,7 @@
 package tcp_test
 
 import (
+	"io"
 	"net"
 	"reflect"
 	"runtime"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
 	"github.com/mikioh/tcpopt"
 )
 
@@ -77,7 +79,7 @@ func TestBuffered(t *testing.T) {
 
 func TestAvailable(t *testing.T) {
 	switch runtime.GOOS {
-	case "darwin", "freebsd", "linux", "netbsd":
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
 	default:
 		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
 	}
@@ -299,3 +301,141 @@ func TestBufferTrim(t *testing.T) {
 		t.Fatalf("got %#v; want %#v", oo, o)
 	}
 }
+
+func TestSegmentWriter(t *testing.T) {
+	c, s := tcptest.Pair(t)
+	w, err := tcp.NewSegmentWriter(c, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	mss := w.MSS()
+	if mss <= 0 {
+		t.Fatalf("got %d; want positive mss", mss)
+	}
+
+	small := make([]byte, 10)
+	if _, err := w.Write(small); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.Buffered(); n != len(small) {
+		t.Fatalf("got %d buffered bytes; want %d", n, len(small))
+	}
+	if _, err := w.Write(make([]byte, mss)); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.Buffered(); n != len(small) {
+		t.Fatalf("got %d buffered bytes; want %d after writing a segment", n, len(small))
+	}
+	b := make([]byte, mss+len(small))
+	if _, err := io.ReadFull(s, b[:mss]); err != nil {
+		t.Fatal(err)
+	}
+	// The partial segment is written by the idle timer.
+	s.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(s, b[mss:]); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.Buffered(); n != 0 {
+		t.Fatalf("got %d buffered bytes; want 0", n)
+	}
+
+	w.Write(small)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(s, b[:len(small)]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPooled(t *testing.T) {
+	p, err := tcp.NewReadPool(100, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		n, cap int
+	}{
+		{0, 128}, {1, 128}, {128, 128}, {129, 256}, {4000, 4096}, {4097, 8192}, {1 << 20, 8192},
+	} {
+		b := p.Get(tt.n)
+		if cap(b) != tt.cap || len(b) != tt.cap {
+			t.Errorf("Get(%d): got len=%d, cap=%d; want %d", tt.n, len(b), cap(b), tt.cap)
+		}
+		p.Put(b)
+	}
+
+	c, s := tcptest.Pair(t)
+	msg := make([]byte, 3000)
+	if _, err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+		for c.Buffered() < len(msg) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	var got int
+	for got < len(msg) {
+		b, err := c.ReadPooled(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch runtime.GOOS {
+		case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+			if len(b) != len(msg) || cap(b) != 4096 {
+				t.Fatalf("got len=%d, cap=%d; want %d, 4096", len(b), cap(b), len(msg))
+			}
+		}
+		got += len(b)
+		p.Put(b)
+	}
+}
+
+func TestQueues(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, p := tcptest.Pair(t)
+	m := []byte("HELLO-R-U-THERE")
+	if _, err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	var q tcp.Queues
+	var err error
+	for i := 0; i < 50; i++ {
+		if q, err = p.Queues(); err != nil {
+			t.Fatal(err)
+		}
+		if q.Buffered == len(m) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if q.Buffered != len(m) {
+		t.Fatalf("got %+v; want %d bytes buffered", q, len(m))
+	}
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	// The peer drops all the incoming segments, and the written
+	// data remains unacknowledged.
+	if err := p.AttachFilter([]tcp.RawInstruction{{Op: 0x06, K: 0}}); err != nil { // ret #0
+		t.Fatal(err)
+	}
+	if _, err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if q, err = c.Queues(); err != nil {
+		t.Fatal(err)
+	}
+	if q.Queued != len(m) || q.Unsent+q.Unacked != q.Queued || q.Unacked == 0 {
+		t.Fatalf("got %+v; want %d bytes in send queue", q, len(m))
+	}
+}
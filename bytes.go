@@ -0,0 +1,50 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+// A ByteCounters represents the cumulative byte counters of the
+// connection maintained by the kernel.
+type ByteCounters struct {
+	Time          time.Time // time of sampling
+	Acked         uint64    // # of bytes acknowledged by peer
+	Received      uint64    // # of bytes received from peer
+	Sent          uint64    // # of bytes sent including retransmissions
+	Retransmitted uint64    // # of bytes retransmitted
+}
+
+// ByteCounters returns the cumulative byte counters of the
+// connection.
+// The counters not provided by the running kernel are zero.
+// Unlike Sampler, it takes a single sample on demand.
+//
+// Only Linux supports this feature.
+func (c *Conn) ByteCounters() (*ByteCounters, error) {
+	var bc *ByteCounters
+	err := c.control(func(s uintptr) (err error) {
+		bc, err = byteCounters(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	bc.Time = time.Now()
+	return bc, nil
+}
+
+// Goodput returns the rates in bytes per second of the acknowledged
+// bytes and the received bytes over the interval between the samples
+// prev and bc, which must be taken from the same connection in this
+// order.
+// It returns zero rates when the interval is not positive.
+func (bc *ByteCounters) Goodput(prev *ByteCounters) (send, receive float64) {
+	d := SampleDelta{
+		Interval:      bc.Time.Sub(prev.Time),
+		BytesAcked:    bc.Acked - prev.Acked,
+		BytesReceived: bc.Received - prev.Received,
+	}
+	return d.SendRate(), d.ReceiveRate()
+}
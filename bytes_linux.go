@@ -0,0 +1,18 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+func byteCounters(s uintptr) (*ByteCounters, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	return &ByteCounters{
+		Acked:         ti.Bytes_acked,
+		Received:      ti.Bytes_received,
+		Sent:          ti.Bytes_sent,
+		Retransmitted: ti.Bytes_retrans,
+	}, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// A Capture represents a packet capture restricted to the flow of a
+// connection. It captures the packets of both directions from the IP
+// header onward.
+type Capture struct {
+	f        *os.File
+	rc       syscall.RawConn
+	snaplen  int
+	loopback map[int]bool // indices of loopback interfaces
+}
+
+// Capture starts capturing the packets of the connection.
+// The snaplen is the maximum number of bytes captured from each
+// packet; zero means 65535.
+//
+// It requires the CAP_NET_RAW capability.
+// Only Linux supports this feature.
+func (c *Conn) Capture(snaplen int) (*Capture, error) {
+	la, ok1 := c.LocalAddr().(*net.TCPAddr)
+	ra, ok2 := c.RemoteAddr().(*net.TCPAddr)
+	if !ok1 || !ok2 {
+		return nil, c.opError("capture", errors.New("unknown address type"))
+	}
+	if snaplen <= 0 {
+		snaplen = 65535
+	}
+	cp, err := openCapture(FlowFilter(la, ra, snaplen), snaplen)
+	if err != nil {
+		return nil, c.opError("capture", err)
+	}
+	return cp, nil
+}
+
+// ReadPacket reads the next packet into b. It returns the number of
+// bytes read, the original length of the packet and the capture
+// time. It returns os.ErrClosed after the capture is closed.
+func (cp *Capture) ReadPacket(b []byte) (n, length int, t time.Time, err error) {
+	return cp.readPacket(b)
+}
+
+// WritePcap writes the captured packets to w in the pcap format with
+// the raw IP link type until the capture is closed or an error
+// occurs. It returns nil when the capture is closed.
+func (cp *Capture) WritePcap(w io.Writer) error {
+	var h [24]byte
+	binary.LittleEndian.PutUint32(h[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(h[4:6], 2)
+	binary.LittleEndian.PutUint16(h[6:8], 4)
+	binary.LittleEndian.PutUint32(h[16:20], uint32(cp.snaplen))
+	binary.LittleEndian.PutUint32(h[20:24], linkTypeRaw)
+	if _, err := w.Write(h[:]); err != nil {
+		return err
+	}
+	b := make([]byte, 16+cp.snaplen)
+	for {
+		n, length, t, err := cp.readPacket(b[16:])
+		if err != nil {
+			if err == os.ErrClosed {
+				return nil
+			}
+			return err
+		}
+		binary.LittleEndian.PutUint32(b[0:4], uint32(t.Unix()))
+		binary.LittleEndian.PutUint32(b[4:8], uint32(t.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(b[8:12], uint32(n))
+		binary.LittleEndian.PutUint32(b[12:16], uint32(length))
+		if _, err := w.Write(b[:16+n]); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops the capture.
+func (cp *Capture) Close() error { return cp.f.Close() }
+
+const linkTypeRaw = 101 // LINKTYPE_RAW
+
+// Classic BPF opcodes.
+const (
+	bpfLdAbsW = 0x20
+	bpfLdAbsH = 0x28
+	bpfLdAbsB = 0x30
+	bpfLdIndH = 0x48
+	bpfLdxMSH = 0xb1
+	bpfRSHK   = 0x74
+	bpfJEqK   = 0x15
+	bpfRetK   = 0x06
+)
+
+// FlowFilter returns the classic BPF program that accepts up to
+// snaplen bytes of the TCP segments between the end points la and ra
+// in both directions. The program assumes that the packet starts
+// with the IP header.
+func FlowFilter(la, ra *net.TCPAddr, snaplen int) []RawInstruction {
+	var a bpfAssembler
+	const reject, reverse = 1, 2
+	v4 := la.IP.To4() != nil && ra.IP.To4() != nil
+	a.op(bpfLdAbsB, 0)
+	a.op(bpfRSHK, 4)
+	if v4 {
+		a.jeq(4, reject)
+		a.op(bpfLdAbsB, 9)
+		a.jeq(ianaProtocolTCP, reject)
+		a.op(bpfLdxMSH, 0)
+	} else {
+		a.jeq(6, reject)
+		a.op(bpfLdAbsB, 6)
+		a.jeq(ianaProtocolTCP, reject)
+	}
+	a.flow(la, ra, v4, reverse)
+	a.op(bpfRetK, uint32(snaplen))
+	a.label(reverse)
+	a.flow(ra, la, v4, reject)
+	a.op(bpfRetK, uint32(snaplen))
+	a.label(reject)
+	a.op(bpfRetK, 0)
+	return a.assemble()
+}
+
+// A bpfAssembler assembles classic BPF programs with forward jumps
+// to labels on mismatch.
+type bpfAssembler struct {
+	prog   []RawInstruction
+	jumps  map[int]int // instruction index to label
+	labels map[int]int // label to instruction index
+}
+
+func (a *bpfAssembler) op(op uint16, k uint32) {
+	a.prog = append(a.prog, RawInstruction{Op: op, K: k})
+}
+
+// jeq emits the instruction that jumps to label l when the
+// accumulator isn't equal to k.
+func (a *bpfAssembler) jeq(k uint32, l int) {
+	if a.jumps == nil {
+		a.jumps = make(map[int]int)
+	}
+	a.jumps[len(a.prog)] = l
+	a.op(bpfJEqK, k)
+}
+
+func (a *bpfAssembler) label(l int) {
+	if a.labels == nil {
+		a.labels = make(map[int]int)
+	}
+	a.labels[l] = len(a.prog)
+}
+
+// flow emits the instructions that match the segments from src to
+// dst, jumping to label l on mismatch.
+func (a *bpfAssembler) flow(src, dst *net.TCPAddr, v4 bool, l int) {
+	if v4 {
+		a.op(bpfLdAbsW, 12)
+		a.jeq(binary.BigEndian.Uint32(src.IP.To4()), l)
+		a.op(bpfLdAbsW, 16)
+		a.jeq(binary.BigEndian.Uint32(dst.IP.To4()), l)
+		a.op(bpfLdIndH, 0)
+		a.jeq(uint32(src.Port), l)
+		a.op(bpfLdIndH, 2)
+		a.jeq(uint32(dst.Port), l)
+		return
+	}
+	for i, ip := range []net.IP{src.IP.To16(), dst.IP.To16()} {
+		for j := 0; j < net.IPv6len; j += 4 {
+			a.op(bpfLdAbsW, uint32(8+16*i+j))
+			a.jeq(binary.BigEndian.Uint32(ip[j:j+4]), l)
+		}
+	}
+	a.op(bpfLdAbsH, 40)
+	a.jeq(uint32(src.Port), l)
+	a.op(bpfLdAbsH, 42)
+	a.jeq(uint32(dst.Port), l)
+}
+
+func (a *bpfAssembler) assemble() []RawInstruction {
+	for i, l := range a.jumps {
+		a.prog[i].Jf = uint8(a.labels[l] - i - 1)
+	}
+	return a.prog
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+func openCapture(prog []RawInstruction, snaplen int) (*Capture, error) {
+	// The socket receives nothing until it is bound, so that no
+	// packet passes through before the filter is attached.
+	s, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := attachFilter(uintptr(s), prog, false); err != nil {
+		syscall.Close(s)
+		return nil, err
+	}
+	sa := syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ALL)}
+	if err := syscall.Bind(s, &sa); err != nil {
+		syscall.Close(s)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	f := os.NewFile(uintptr(s), "packet")
+	rc, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cp := &Capture{f: f, rc: rc, snaplen: snaplen, loopback: make(map[int]bool)}
+	if ifs, err := net.Interfaces(); err == nil {
+		for _, ifi := range ifs {
+			if ifi.Flags&net.FlagLoopback != 0 {
+				cp.loopback[ifi.Index] = true
+			}
+		}
+	}
+	return cp, nil
+}
+
+func (cp *Capture) readPacket(b []byte) (n, length int, t time.Time, err error) {
+	for {
+		var from syscall.Sockaddr
+		var operr error
+		err = cp.rc.Read(func(s uintptr) bool {
+			length, from, operr = syscall.Recvfrom(int(s), b, syscall.MSG_TRUNC)
+			return operr != syscall.EAGAIN
+		})
+		if err != nil {
+			return 0, 0, time.Time{}, os.ErrClosed
+		}
+		if operr != nil {
+			if operr == syscall.EINTR {
+				continue
+			}
+			return 0, 0, time.Time{}, os.NewSyscallError("recvfrom", operr)
+		}
+		// A packet on a loopback interface is seen twice, as an
+		// outgoing and an incoming one.
+		if sa, ok := from.(*syscall.SockaddrLinklayer); ok && sa.Pkttype == syscall.PACKET_OUTGOING && cp.loopback[sa.Ifindex] {
+			continue
+		}
+		n = length
+		if n > len(b) {
+			n = len(b)
+		}
+		return n, length, time.Now(), nil
+	}
+}
+
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return nativeEndian.Uint16(b[:])
+}
@@ -0,0 +1,17 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import "time"
+
+func openCapture(prog []RawInstruction, snaplen int) (*Capture, error) {
+	return nil, ErrNotSupported
+}
+
+func (cp *Capture) readPacket(b []byte) (n, length int, t time.Time, err error) {
+	return 0, 0, time.Time{}, ErrNotSupported
+}
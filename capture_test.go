@@ -0,0 +1,112 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestCapture(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := tc.Capture(0)
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	pc, err := tc.Capture(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pcap bytes.Buffer
+	done := make(chan error)
+	go func() { done <- pc.WritePcap(&pcap) }()
+
+	if _, err := c.Write([]byte("HELLO")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 5)
+	if _, err := p.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Write([]byte("WORLD")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	la := c.LocalAddr().(*net.TCPAddr)
+	ra := c.RemoteAddr().(*net.TCPAddr)
+	var out, in bool
+	b = make([]byte, 1<<16)
+	for !out || !in {
+		n, _, _, err := cp.ReadPacket(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n < 20+20 || b[0]>>4 != 4 {
+			t.Fatalf("got %d bytes of version %d", n, b[0]>>4)
+		}
+		ihl := int(b[0]&0xf) * 4
+		src := binary.BigEndian.Uint16(b[ihl : ihl+2])
+		dst := binary.BigEndian.Uint16(b[ihl+2 : ihl+4])
+		switch {
+		case src == uint16(la.Port) && dst == uint16(ra.Port):
+			out = out || bytes.HasSuffix(b[:n], []byte("HELLO"))
+		case src == uint16(ra.Port) && dst == uint16(la.Port):
+			in = in || bytes.HasSuffix(b[:n], []byte("WORLD"))
+		default:
+			t.Fatalf("got segment from %d to %d; want between %v and %v", src, dst, la, ra)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := pc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if pcap.Len() <= 24 || binary.LittleEndian.Uint32(pcap.Bytes()) != 0xa1b2c3d4 {
+		t.Fatalf("got %d bytes of pcap", pcap.Len())
+	}
+}
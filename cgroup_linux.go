@@ -0,0 +1,31 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// joinCgroup moves the calling thread to the cgroup at path. It uses
+// cgroup.threads of cgroup v2 when available, and tasks of cgroup v1
+// otherwise.
+func joinCgroup(path string) error {
+	name := filepath.Join(path, "cgroup.threads")
+	if _, err := os.Stat(name); err != nil {
+		name = filepath.Join(path, "tasks")
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strconv.Itoa(syscall.Gettid()))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
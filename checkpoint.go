@@ -0,0 +1,304 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// CheckpointVersion is the version of checkpoint encoding produced by
+// this package.
+const CheckpointVersion = 1
+
+var checkpointMagic = [4]byte{'T', 'C', 'P', 'C'}
+
+// A Checkpoint represents the state of a connection captured in the
+// TCP_REPAIR mode, which is enough to restore the connection
+// deterministically in another process or on another host.
+//
+// A Checkpoint provides a portable, versioned encoding in both binary
+// and JSON forms. Multi-byte integers in the binary form are encoded
+// in big-endian byte order.
+// Capturing and restoring the state require the CAP_NET_ADMIN
+// capability and are outside the scope of Checkpoint.
+type Checkpoint struct {
+	Local  *net.TCPAddr `json:"-"` // local address
+	Remote *net.TCPAddr `json:"-"` // remote address
+
+	SendSeq    uint32 `json:"snd_seq"` // sequence number of the next byte to send
+	ReceiveSeq uint32 `json:"rcv_seq"` // sequence number of the next byte to receive
+
+	Window CheckpointWindow `json:"window"` // window state
+
+	MSS           int    `json:"mss"`            // maximum segment size; zero means unknown
+	SendWScale    int    `json:"snd_wscale"`     // window scale of sender; negative means not negotiated
+	ReceiveWScale int    `json:"rcv_wscale"`     // window scale of receiver; negative means not negotiated
+	SACKPermitted bool   `json:"sack_permitted"` // use of selective acknowledgment
+	Timestamp     uint32 `json:"timestamp"`      // current timestamp value; zero means not negotiated
+
+	SendQueue    []byte `json:"snd_queue"` // data in send queue, including unacknowledged data
+	ReceiveQueue []byte `json:"rcv_queue"` // data in receive queue
+
+	Options []CheckpointOption `json:"options,omitempty"` // socket options to restore
+}
+
+// A CheckpointWindow represents the window state of a checkpointed
+// connection. See TCP_REPAIR_WINDOW for further information.
+type CheckpointWindow struct {
+	SendWL1       uint32 `json:"snd_wl1"` // segment sequence number used for the last window update
+	SendWindow    uint32 `json:"snd_wnd"` // send window
+	MaxWindow     uint32 `json:"max_window"`
+	ReceiveWindow uint32 `json:"rcv_wnd"` // receive window
+	ReceiveWUP    uint32 `json:"rcv_wup"` // receive sequence number at the last window update
+}
+
+// A CheckpointOption represents a socket option of a checkpointed
+// connection in the raw form.
+type CheckpointOption struct {
+	Level int    `json:"level"`
+	Name  int    `json:"name"`
+	Value []byte `json:"value"`
+}
+
+type checkpointJSON struct {
+	Version int    `json:"version"`
+	Local   string `json:"local"`
+	Remote  string `json:"remote"`
+	*checkpointAlias
+}
+
+type checkpointAlias Checkpoint
+
+// MarshalJSON implements the MarshalJSON method of json.Marshaler
+// interface.
+func (cp Checkpoint) MarshalJSON() ([]byte, error) {
+	if cp.Local == nil || cp.Remote == nil {
+		return nil, errors.New("missing address")
+	}
+	return json.Marshal(checkpointJSON{
+		Version:         CheckpointVersion,
+		Local:           cp.Local.String(),
+		Remote:          cp.Remote.String(),
+		checkpointAlias: (*checkpointAlias)(&cp),
+	})
+}
+
+// UnmarshalJSON implements the UnmarshalJSON method of
+// json.Unmarshaler interface.
+// It leaves cp unchanged on error.
+func (cp *Checkpoint) UnmarshalJSON(b []byte) error {
+	var tmp Checkpoint
+	v := checkpointJSON{checkpointAlias: (*checkpointAlias)(&tmp)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Version != CheckpointVersion {
+		return fmt.Errorf("unsupported checkpoint version: %d", v.Version)
+	}
+	for _, a := range []struct {
+		s  string
+		ap **net.TCPAddr
+	}{{v.Local, &tmp.Local}, {v.Remote, &tmp.Remote}} {
+		ta, err := parseCheckpointAddr(a.s)
+		if err != nil {
+			return err
+		}
+		*a.ap = ta
+	}
+	*cp = tmp
+	return nil
+}
+
+func parseCheckpointAddr(s string) (*net.TCPAddr, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, err
+	}
+	var zone string
+	if i := strings.LastIndexByte(host, '%'); i > 0 {
+		host, zone = host[:i], host[i+1:]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address: %s", s)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+	return &net.TCPAddr{IP: canonicalIP(ip), Port: p, Zone: zone}, nil
+}
+
+func canonicalIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// MarshalBinary implements the MarshalBinary method of
+// encoding.BinaryMarshaler interface.
+func (cp *Checkpoint) MarshalBinary() ([]byte, error) {
+	var e checkpointEncoder
+	e.b = append(e.b, checkpointMagic[:]...)
+	e.uint16(CheckpointVersion)
+	for _, a := range []*net.TCPAddr{cp.Local, cp.Remote} {
+		if a == nil {
+			return nil, errors.New("missing address")
+		}
+		e.bytes(a.IP)
+		e.uint16(uint16(a.Port))
+		e.bytes([]byte(a.Zone))
+	}
+	e.uint32(cp.SendSeq)
+	e.uint32(cp.ReceiveSeq)
+	w := &cp.Window
+	for _, v := range []uint32{w.SendWL1, w.SendWindow, w.MaxWindow, w.ReceiveWindow, w.ReceiveWUP} {
+		e.uint32(v)
+	}
+	e.uint32(uint32(cp.MSS))
+	e.uint32(uint32(int32(cp.SendWScale)))
+	e.uint32(uint32(int32(cp.ReceiveWScale)))
+	if cp.SACKPermitted {
+		e.b = append(e.b, 1)
+	} else {
+		e.b = append(e.b, 0)
+	}
+	e.uint32(cp.Timestamp)
+	e.bytes(cp.SendQueue)
+	e.bytes(cp.ReceiveQueue)
+	e.uint32(uint32(len(cp.Options)))
+	for _, o := range cp.Options {
+		e.uint32(uint32(int32(o.Level)))
+		e.uint32(uint32(int32(o.Name)))
+		e.bytes(o.Value)
+	}
+	return e.b, nil
+}
+
+// UnmarshalBinary implements the UnmarshalBinary method of
+// encoding.BinaryUnmarshaler interface.
+func (cp *Checkpoint) UnmarshalBinary(b []byte) error {
+	d := checkpointDecoder{b: b}
+	if magic := d.next(4); d.err == nil && string(magic) != string(checkpointMagic[:]) {
+		return errors.New("invalid checkpoint")
+	}
+	if v := d.uint16(); d.err == nil && v != CheckpointVersion {
+		return fmt.Errorf("unsupported checkpoint version: %d", v)
+	}
+	var c Checkpoint
+	for _, a := range []**net.TCPAddr{&c.Local, &c.Remote} {
+		ip := canonicalIP(d.bytes())
+		port := d.uint16()
+		zone := string(d.bytes())
+		*a = &net.TCPAddr{IP: ip, Port: int(port), Zone: zone}
+	}
+	c.SendSeq = d.uint32()
+	c.ReceiveSeq = d.uint32()
+	w := &c.Window
+	for _, v := range []*uint32{&w.SendWL1, &w.SendWindow, &w.MaxWindow, &w.ReceiveWindow, &w.ReceiveWUP} {
+		*v = d.uint32()
+	}
+	c.MSS = int(d.uint32())
+	c.SendWScale = int(int32(d.uint32()))
+	c.ReceiveWScale = int(int32(d.uint32()))
+	if sack := d.next(1); d.err == nil {
+		c.SACKPermitted = sack[0] != 0
+	}
+	c.Timestamp = d.uint32()
+	c.SendQueue = d.bytes()
+	c.ReceiveQueue = d.bytes()
+	n := d.uint32()
+	for i := uint32(0); i < n && d.err == nil; i++ {
+		var o CheckpointOption
+		o.Level = int(int32(d.uint32()))
+		o.Name = int(int32(d.uint32()))
+		o.Value = d.bytes()
+		c.Options = append(c.Options, o)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.b) > 0 {
+		return errors.New("trailing data in checkpoint")
+	}
+	*cp = c
+	return nil
+}
+
+type checkpointEncoder struct {
+	b []byte
+}
+
+func (e *checkpointEncoder) uint16(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	e.b = append(e.b, b[:]...)
+}
+
+func (e *checkpointEncoder) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.b = append(e.b, b[:]...)
+}
+
+func (e *checkpointEncoder) bytes(p []byte) {
+	e.uint32(uint32(len(p)))
+	e.b = append(e.b, p...)
+}
+
+type checkpointDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *checkpointDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errors.New("short checkpoint")
+		return nil
+	}
+	p := d.b[:n:n]
+	d.b = d.b[n:]
+	return p
+}
+
+func (d *checkpointDecoder) uint16() uint16 {
+	if p := d.next(2); p != nil {
+		return binary.BigEndian.Uint16(p)
+	}
+	return 0
+}
+
+func (d *checkpointDecoder) uint32() uint32 {
+	if p := d.next(4); p != nil {
+		return binary.BigEndian.Uint32(p)
+	}
+	return 0
+}
+
+func (d *checkpointDecoder) bytes() []byte {
+	n := d.uint32()
+	if d.err != nil {
+		return nil
+	}
+	if uint64(n) > uint64(len(d.b)) {
+		d.err = errors.New("short checkpoint")
+		return nil
+	}
+	p := d.next(int(n))
+	if len(p) == 0 {
+		return nil
+	}
+	return append([]byte(nil), p...)
+}
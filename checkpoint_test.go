@@ -0,0 +1,81 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/mikioh/tcp"
+)
+
+var testCheckpoint = tcp.Checkpoint{
+	Local:         &net.TCPAddr{IP: net.ParseIP("192.0.2.1").To4(), Port: 49152},
+	Remote:        &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443, Zone: "eth0"},
+	SendSeq:       0x01020304,
+	ReceiveSeq:    0xfffffff0,
+	Window:        tcp.CheckpointWindow{SendWL1: 1, SendWindow: 65535, MaxWindow: 65535, ReceiveWindow: 43690, ReceiveWUP: 2},
+	MSS:           1460,
+	SendWScale:    7,
+	ReceiveWScale: -1,
+	SACKPermitted: true,
+	Timestamp:     123456,
+	SendQueue:     []byte("HELLO"),
+	ReceiveQueue:  []byte("R-U-THERE"),
+	Options:       []tcp.CheckpointOption{{Level: 6, Name: 1, Value: []byte{1, 0, 0, 0}}},
+}
+
+func TestCheckpointBinary(t *testing.T) {
+	b, err := testCheckpoint.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp tcp.Checkpoint
+	if err := cp.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cp, testCheckpoint) {
+		t.Fatalf("got %+v; want %+v", cp, testCheckpoint)
+	}
+	for i := range b {
+		if err := cp.UnmarshalBinary(b[:i]); err == nil {
+			t.Fatalf("truncated checkpoint of %d bytes accepted", i)
+		}
+	}
+	b[5]++ // version
+	if err := cp.UnmarshalBinary(b); err == nil {
+		t.Fatal("unknown version accepted")
+	}
+}
+
+func TestCheckpointJSON(t *testing.T) {
+	b, err := json.Marshal(&testCheckpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp tcp.Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cp, testCheckpoint) {
+		t.Fatalf("got %+v; want %+v", cp, testCheckpoint)
+	}
+	if err := json.Unmarshal([]byte(`{"version":2,"snd_seq":1}`), &cp); err == nil {
+		t.Fatal("unknown version accepted")
+	}
+	if !reflect.DeepEqual(cp, testCheckpoint) {
+		t.Fatalf("got %+v after error; want %+v", cp, testCheckpoint)
+	}
+	vb, err := json.Marshal(testCheckpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(vb, b) {
+		t.Fatalf("got %s for value; want %s", vb, b)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tcpstat lists or watches the TCP sockets on the host with their
+// statistics, and dumps the socket options of a single socket.
+//
+// Usage:
+//
+//	tcpstat [flags]
+//
+// The sockets are listed by the inet_diag interface of the kernel,
+// and the options of a socket are read from a duplicate of the
+// socket descriptor taken from the owning process by pidfd_getfd,
+// which requires the permission to ptrace the process.
+//
+// Only Linux supports this command.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+var (
+	stateFlag   = flag.String("state", "", "comma-separated list of states such as established,time-wait")
+	lportFlag   = flag.String("lport", "", "local port or range of ports such as 1024-65535")
+	rportFlag   = flag.String("rport", "", "remote port or range of ports")
+	lprefixFlag = flag.String("lprefix", "", "prefix of local addresses such as 192.0.2.0/24")
+	rprefixFlag = flag.String("rprefix", "", "prefix of remote addresses")
+	watchFlag   = flag.Duration("watch", 0, "interval of listing repeatedly; zero means once")
+	jsonFlag    = flag.Bool("json", false, "print sockets as JSON objects")
+	optionsFlag = flag.String("options", "", "socket cookie of the socket to dump the options of")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("tcpstat: ")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: tcpstat [flags]\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+	}
+	f, err := parseFilter()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *optionsFlag != "" {
+		cookie, err := strconv.ParseUint(*optionsFlag, 0, 64)
+		if err != nil {
+			log.Fatalf("invalid socket cookie: %s", *optionsFlag)
+		}
+		if err := dumpOptions(f, cookie); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	for {
+		if err := list(f); err != nil {
+			log.Fatal(err)
+		}
+		if *watchFlag <= 0 {
+			return
+		}
+		time.Sleep(*watchFlag)
+		fmt.Println()
+	}
+}
+
+// parseFilter returns the socket filter specified by the flags.
+func parseFilter() (*tcp.SocketFilter, error) {
+	var f tcp.SocketFilter
+	var err error
+	if f.States, err = parseStates(*stateFlag); err != nil {
+		return nil, err
+	}
+	if f.LocalPorts, err = parsePorts(*lportFlag); err != nil {
+		return nil, err
+	}
+	if f.RemotePorts, err = parsePorts(*rportFlag); err != nil {
+		return nil, err
+	}
+	if f.LocalPrefix, err = parsePrefix(*lprefixFlag); err != nil {
+		return nil, err
+	}
+	if f.RemotePrefix, err = parsePrefix(*rprefixFlag); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// parseStates parses the comma-separated list of state names.
+func parseStates(s string) ([]tcpinfo.State, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var sts []tcpinfo.State
+	for _, name := range strings.Split(s, ",") {
+		st := tcpinfo.Unknown
+		for i := tcpinfo.Closed; i <= tcpinfo.TimeWait; i++ {
+			if i.String() == name {
+				st = i
+				break
+			}
+		}
+		if st == tcpinfo.Unknown {
+			return nil, fmt.Errorf("unknown state: %s", name)
+		}
+		sts = append(sts, st)
+	}
+	return sts, nil
+}
+
+// parsePorts parses the port or the range of ports such as
+// "1024-65535".
+func parsePorts(s string) (*tcp.PortRange, error) {
+	if s == "" {
+		return nil, nil
+	}
+	min, max := s, s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		min, max = s[:i], s[i+1:]
+	}
+	var pr tcp.PortRange
+	var err error
+	if pr.Min, err = strconv.Atoi(min); err != nil || pr.Min < 0 || pr.Min > 0xffff {
+		return nil, fmt.Errorf("invalid port: %s", s)
+	}
+	if pr.Max, err = strconv.Atoi(max); err != nil || pr.Max < pr.Min || pr.Max > 0xffff {
+		return nil, fmt.Errorf("invalid port: %s", s)
+	}
+	return &pr, nil
+}
+
+func parsePrefix(s string) (*net.IPNet, error) {
+	if s == "" {
+		return nil, nil
+	}
+	_, p, err := net.ParseCIDR(s)
+	return p, err
+}
+
+// list prints the sockets that match the filter f.
+func list(f *tcp.SocketFilter) error {
+	sis, err := tcp.Sockets(f)
+	if err != nil {
+		return err
+	}
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		for i := range sis {
+			if err := enc.Encode(newRecord(&sis[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "STATE\tRECV-Q\tSEND-Q\tLOCAL\tREMOTE\tRTT\tRTO\tMSS\tCWND\tCOOKIE")
+	for _, si := range sis {
+		var rtt, rto time.Duration
+		var mss, cwnd uint
+		if si.Info != nil {
+			rtt, rto, mss = si.Info.RTT, si.Info.RTO, uint(si.Info.SenderMSS)
+			if si.Info.CongestionControl != nil {
+				cwnd = si.Info.CongestionControl.SenderWindowSegs
+			}
+		}
+		fmt.Fprintf(w, "%v\t%d\t%d\t%v\t%v\t%v\t%v\t%d\t%d\t%#x\n", si.State, si.ReceiveQueue, si.SendQueue, si.Local, si.Remote, rtt, rto, mss, cwnd, si.Cookie)
+	}
+	return w.Flush()
+}
+
+// A record represents a socket in the JSON form.
+type record struct {
+	Local        string        `json:"local"`
+	Remote       string        `json:"remote"`
+	State        string        `json:"state"`
+	ReceiveQueue int           `json:"recv_q"`
+	SendQueue    int           `json:"send_q"`
+	UID          int           `json:"uid"`
+	Inode        int           `json:"inode"`
+	Cookie       uint64        `json:"cookie"`
+	Info         *tcpinfo.Info `json:"info,omitempty"`
+}
+
+func newRecord(si *tcp.SocketInfo) *record {
+	return &record{
+		Local:        si.Local.String(),
+		Remote:       si.Remote.String(),
+		State:        si.State.String(),
+		ReceiveQueue: si.ReceiveQueue,
+		SendQueue:    si.SendQueue,
+		UID:          si.UID,
+		Inode:        si.Inode,
+		Cookie:       si.Cookie,
+		Info:         si.Info,
+	}
+}
+
+// dumpOptions prints the socket options known to the package of the
+// socket identified by the cookie among the sockets that match the
+// filter f.
+func dumpOptions(f *tcp.SocketFilter, cookie uint64) error {
+	sis, err := tcp.Sockets(f)
+	if err != nil {
+		return err
+	}
+	var si *tcp.SocketInfo
+	for i := range sis {
+		if sis[i].Cookie == cookie {
+			si = &sis[i]
+			break
+		}
+	}
+	if si == nil {
+		return fmt.Errorf("socket %#x not found", cookie)
+	}
+	s, err := socketFD(si.Inode)
+	if err != nil {
+		return err
+	}
+	defer closeFD(s)
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v->%v\n", si.State, si.Local, si.Remote)
+	b := make([]byte, 256)
+	for _, spec := range tcp.OptionSpecs() {
+		n, err := getsockopt(s, spec.Level, spec.Number, b)
+		if err != nil {
+			for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+				err = e
+			}
+			fmt.Fprintf(w, "%s\t<%v>\n", spec.Name, err)
+			continue
+		}
+		o, err := tcpopt.Parse(spec.Level, spec.Number, b[:n])
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%#x\n", spec.Name, b[:n])
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%v\n", spec.Name, o)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpinfo"
+)
+
+func TestParsePorts(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want *tcp.PortRange
+		ok   bool
+	}{
+		{"", nil, true},
+		{"80", &tcp.PortRange{Min: 80, Max: 80}, true},
+		{"1024-65535", &tcp.PortRange{Min: 1024, Max: 65535}, true},
+		{"443-80", nil, false},
+		{"65536", nil, false},
+		{"http", nil, false},
+	} {
+		pr, err := parsePorts(tt.in)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(pr, tt.want) {
+			t.Errorf("%q: got %v, %v; want %v", tt.in, pr, err, tt.want)
+		}
+	}
+}
+
+func TestParseStates(t *testing.T) {
+	sts, err := parseStates("established,time-wait")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []tcpinfo.State{tcpinfo.Established, tcpinfo.TimeWait}; !reflect.DeepEqual(sts, want) {
+		t.Fatalf("got %v; want %v", sts, want)
+	}
+	if _, err := parseStates("established,bogus"); err == nil {
+		t.Fatal("got nil; want an error")
+	}
+}
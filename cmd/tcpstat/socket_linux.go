@@ -0,0 +1,72 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// socketFD returns the duplicate of the socket descriptor identified
+// by the inode number, which is taken from the process owning the
+// socket. The duplicate shares the open file description with the
+// owning process, and the caller must not change its file status
+// flags.
+func socketFD(inode int) (int, error) {
+	pid, fd, err := findSocket(inode)
+	if err != nil {
+		return -1, err
+	}
+	pidfd, _, errno := unix.Syscall(unix.SYS_PIDFD_OPEN, uintptr(pid), 0, 0)
+	if errno != 0 {
+		return -1, os.NewSyscallError("pidfd_open", errno)
+	}
+	defer unix.Close(int(pidfd))
+	s, _, errno := unix.Syscall(unix.SYS_PIDFD_GETFD, pidfd, uintptr(fd), 0)
+	if errno != 0 {
+		return -1, os.NewSyscallError("pidfd_getfd", errno)
+	}
+	return int(s), nil
+}
+
+// getsockopt reads the socket option specified by level and name of
+// the descriptor s into b, and returns the length of the option
+// value.
+func getsockopt(s, level, name int, b []byte) (int, error) {
+	l := uint32(len(b))
+	if _, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(s), uintptr(level), uintptr(name), uintptr(unsafe.Pointer(&b[0])), uintptr(unsafe.Pointer(&l)), 0); errno != 0 {
+		return 0, os.NewSyscallError("getsockopt", errno)
+	}
+	return int(l), nil
+}
+
+// closeFD closes the descriptor s.
+func closeFD(s int) error { return unix.Close(s) }
+
+// findSocket returns the process ID and the descriptor of the socket
+// identified by the inode number.
+func findSocket(inode int) (pid, fd int, err error) {
+	link := fmt.Sprintf("socket:[%d]", inode)
+	names, err := filepath.Glob("/proc/[0-9]*/fd/[0-9]*")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, name := range names {
+		if l, err := os.Readlink(name); err != nil || l != link {
+			continue
+		}
+		elems := strings.Split(name, "/") // "", "proc", pid, "fd", fd
+		pid, _ = strconv.Atoi(elems[2])
+		fd, _ = strconv.Atoi(elems[4])
+		return pid, fd, nil
+	}
+	return 0, 0, fmt.Errorf("owner of %s not found", link)
+}
@@ -0,0 +1,15 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package main
+
+import "github.com/mikioh/tcp"
+
+func socketFD(inode int) (int, error) { return -1, tcp.ErrNotSupported }
+
+func getsockopt(s, level, name int, b []byte) (int, error) { return 0, tcp.ErrNotSupported }
+
+func closeFD(s int) error { return tcp.ErrNotSupported }
@@ -0,0 +1,90 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A StatsCollector represents a collector of the TCP information of
+// many connections.
+//
+// On Linux, Collect fetches the information of all the tracked
+// connections by a single inet_diag dump per address family, which
+// is much cheaper than reading the TCP_INFO option on each connection
+// when tracking thousands of connections. The results are matched to
+// the tracked connections by the socket cookie, or by the local and
+// remote addresses on the kernels that don't support socket cookies.
+// On the other platforms, Collect reads the option on each connection.
+type StatsCollector struct {
+	mu    sync.Mutex
+	conns map[*Conn]statsKey
+}
+
+type statsKey struct {
+	cookie uint64 // socket cookie, or zero
+	tuple  string // local and remote addresses
+}
+
+// NewStatsCollector returns a new collector.
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{conns: make(map[*Conn]statsKey)}
+}
+
+// Add starts tracking the connection c.
+func (sc *StatsCollector) Add(c *Conn) {
+	k := statsKey{tuple: tupleKey(c.LocalAddr().(*net.TCPAddr), c.RemoteAddr().(*net.TCPAddr))}
+	if cookie, err := c.Cookie(); err == nil {
+		k.cookie = cookie
+	}
+	sc.mu.Lock()
+	sc.conns[c] = k
+	sc.mu.Unlock()
+}
+
+// Remove stops tracking the connection c.
+func (sc *StatsCollector) Remove(c *Conn) {
+	sc.mu.Lock()
+	delete(sc.conns, c)
+	sc.mu.Unlock()
+}
+
+// Len returns the number of tracked connections.
+func (sc *StatsCollector) Len() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return len(sc.conns)
+}
+
+// Collect returns the TCP information of the tracked connections.
+// The connections of which the information is not found, such as
+// the closed ones, are omitted from the result.
+func (sc *StatsCollector) Collect() (map[*Conn]*tcpinfo.Info, error) {
+	sc.mu.Lock()
+	conns := make(map[*Conn]statsKey, len(sc.conns))
+	for c, k := range sc.conns {
+		conns[c] = k
+	}
+	sc.mu.Unlock()
+	if len(conns) == 0 {
+		return map[*Conn]*tcpinfo.Info{}, nil
+	}
+	m, err := collectStats(conns)
+	if err != nil {
+		return nil, &net.OpError{Op: "get", Net: "tcp", Err: err}
+	}
+	return m, nil
+}
+
+// tupleKey returns the key of the connection from the local address
+// la to the remote address ra.
+// IPv4-mapped IPv6 addresses are formatted as IPv4 addresses.
+func tupleKey(la, ra *net.TCPAddr) string {
+	return la.IP.String() + " " + strconv.Itoa(la.Port) + " " + ra.IP.String() + " " + strconv.Itoa(ra.Port)
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"syscall"
+
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+func collectStats(conns map[*Conn]statsKey) (map[*Conn]*tcpinfo.Info, error) {
+	byCookie := make(map[uint64]*Conn)
+	byTuple := make(map[string]*Conn)
+	for c, k := range conns {
+		if k.cookie != 0 {
+			byCookie[k.cookie] = c
+		} else {
+			byTuple[k.tuple] = c
+		}
+	}
+	states := uint32(1<<uint(len(linuxStates))-1) &^ (1 << sysTCP_LISTEN)
+	var o tcpinfo.Info
+	m := make(map[*Conn]*tcpinfo.Info, len(conns))
+	for _, family := range []int{syscall.AF_INET, syscall.AF_INET6} {
+		err := inetDiag(family, states, 1<<(sysINET_DIAG_INFO-1), nil, func(dm *inetDiagMsg, attrs []byte) bool {
+			c, ok := byCookie[uint64(dm.Id.Cookie[1])<<32|uint64(dm.Id.Cookie[0])]
+			if !ok && len(byTuple) > 0 {
+				c, ok = byTuple[tupleKey(dm.Id.srcAddr(family), dm.Id.dstAddr(family))]
+			}
+			if !ok {
+				return true
+			}
+			b := diagAttr(attrs, sysINET_DIAG_INFO)
+			if b == nil {
+				return true
+			}
+			io, err := tcpopt.Parse(o.Level(), o.Name(), b)
+			if err != nil {
+				return true
+			}
+			m[c] = io.(*tcpinfo.Info)
+			return len(m) < len(conns)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(m) == len(conns) {
+			break
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,19 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import "github.com/mikioh/tcpinfo"
+
+func collectStats(conns map[*Conn]statsKey) (map[*Conn]*tcpinfo.Info, error) {
+	m := make(map[*Conn]*tcpinfo.Info, len(conns))
+	for c := range conns {
+		if info, err := connInfo(c); err == nil {
+			m[c] = info
+		}
+	}
+	return m, nil
+}
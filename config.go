@@ -0,0 +1,148 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// The order of applying socket options.
+// Options that enable a facility must precede the options that
+// tune it.
+const (
+	rankBuffer = iota
+	rankToggle
+	rankTuning
+)
+
+type configItem struct {
+	rank int
+	o    tcpopt.Option
+}
+
+// A Configurator accumulates socket options and applies them to the
+// connection at once.
+type Configurator struct {
+	c     *Conn
+	items []configItem
+	errs  []error
+}
+
+// Configure returns a new Configurator for the connection.
+func (c *Conn) Configure() *Configurator {
+	return &Configurator{c: c}
+}
+
+// NoDelay specifies the use of Nagle's algorithm.
+func (cfg *Configurator) NoDelay(on bool) *Configurator {
+	return cfg.add(rankToggle, tcpopt.NoDelay(on))
+}
+
+// Cork specifies the use of TCP_CORK or TCP_NOPUSH option.
+func (cfg *Configurator) Cork(on bool) *Configurator {
+	return cfg.add(rankToggle, tcpopt.Cork(on))
+}
+
+// SendBuffer specifies the size of send buffer.
+func (cfg *Configurator) SendBuffer(n int) *Configurator {
+	if n <= 0 {
+		return cfg.fail("invalid send buffer size")
+	}
+	return cfg.add(rankBuffer, tcpopt.SendBuffer(n))
+}
+
+// ReceiveBuffer specifies the size of receive buffer.
+func (cfg *Configurator) ReceiveBuffer(n int) *Configurator {
+	if n <= 0 {
+		return cfg.fail("invalid receive buffer size")
+	}
+	return cfg.add(rankBuffer, tcpopt.ReceiveBuffer(n))
+}
+
+// KeepAlive enables keepalive with the idle interval d.
+// A zero d disables keepalive.
+func (cfg *Configurator) KeepAlive(d time.Duration) *Configurator {
+	if d < 0 {
+		return cfg.fail("invalid keepalive idle interval")
+	}
+	if d == 0 {
+		return cfg.add(rankToggle, tcpopt.KeepAlive(false))
+	}
+	cfg.add(rankToggle, tcpopt.KeepAlive(true))
+	return cfg.add(rankTuning, tcpopt.KeepAliveIdleInterval(d))
+}
+
+// UserTimeout specifies the maximum amount of time that transmitted
+// data may remain unacknowledged.
+//
+// Only Linux and Windows support this option.
+func (cfg *Configurator) UserTimeout(d time.Duration) *Configurator {
+	if d < 0 || d > math.MaxInt32*userTimeoutUnit {
+		return cfg.fail("invalid user timeout")
+	}
+	return cfg.add(rankTuning, UserTimeout(d))
+}
+
+// Option specifies an arbitrary socket option.
+// It is applied after the other options.
+func (cfg *Configurator) Option(o tcpopt.Option) *Configurator {
+	return cfg.add(rankTuning, o)
+}
+
+// Apply applies the accumulated socket options to the connection.
+// It returns a ConfigError when any validation or option setting
+// fails. No option is applied when validation fails.
+func (cfg *Configurator) Apply() error {
+	if len(cfg.errs) > 0 {
+		return ConfigError(cfg.errs)
+	}
+	sort.Stable(byRank(cfg.items))
+	var errs []error
+	for _, it := range cfg.items {
+		if err := cfg.c.SetOption(it.o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return ConfigError(errs)
+	}
+	return nil
+}
+
+func (cfg *Configurator) add(rank int, o tcpopt.Option) *Configurator {
+	cfg.items = append(cfg.items, configItem{rank: rank, o: o})
+	return cfg
+}
+
+func (cfg *Configurator) fail(s string) *Configurator {
+	cfg.errs = append(cfg.errs, errors.New(s))
+	return cfg
+}
+
+type byRank []configItem
+
+func (items byRank) Len() int           { return len(items) }
+func (items byRank) Less(i, j int) bool { return items[i].rank < items[j].rank }
+func (items byRank) Swap(i, j int)      { items[i], items[j] = items[j], items[i] }
+
+// A ConfigError represents a list of errors returned from Apply.
+type ConfigError []error
+
+func (e ConfigError) Error() string {
+	ss := make([]string, len(e))
+	for i, err := range e {
+		ss[i] = err.Error()
+	}
+	return strings.Join(ss, "; ")
+}
+
+// Unwrap returns the list of errors.
+func (e ConfigError) Unwrap() []error { return e }
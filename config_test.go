@@ -0,0 +1,118 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"math"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestConfigure(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tc.Configure().NoDelay(true).KeepAlive(30 * time.Second).UserTimeout(10 * time.Second).Apply(); err != nil {
+		t.Fatal(err)
+	}
+	ka, err := tc.KeepAlive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ka.Enable || ka.IdleInterval != 30*time.Second {
+		t.Fatalf("got %#v; want enabled with 30s idle interval", ka)
+	}
+	var o tcp.UserTimeout
+	var b [4]byte
+	oo, err := tc.Option(o.Level(), o.Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oo != tcp.UserTimeout(10*time.Second) {
+		t.Fatalf("got %#v; want %#v", oo, tcp.UserTimeout(10*time.Second))
+	}
+
+	err = tc.Configure().SendBuffer(-1).KeepAlive(-time.Second).UserTimeout(math.MaxInt32*time.Millisecond + time.Millisecond).Apply()
+	if cerr, ok := err.(tcp.ConfigError); !ok || len(cerr) != 3 {
+		t.Fatalf("got %v; want ConfigError with 3 errors", err)
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ps, err := tcp.LoadProfiles(strings.NewReader(`{"profiles": [
+		{"name": "bulk", "options": [
+			{"name": "SO_SNDBUF", "value": 65536, "apply_on": "dial"},
+			{"name": "TCP_NODELAY", "value": true},
+			{"name": "TCP_NONEXISTENT", "value": 1, "optional": true}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := ps["bulk"]
+	if p == nil {
+		t.Fatal("profile not found")
+	}
+	if len(p.DialOptions()) != 2 || len(p.AcceptOptions()) != 1 {
+		t.Fatalf("got %d dial and %d accept options; want 2 and 1", len(p.DialOptions()), len(p.AcceptOptions()))
+	}
+	c, _ := tcptest.Pair(t)
+	for _, o := range p.DialOptions() {
+		if err := c.SetOption(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var b [4]byte
+	o, err := c.Option(tcpopt.NoDelay(false).Level(), tcpopt.NoDelay(false).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcpopt.NoDelay(true) {
+		t.Fatalf("got %v; want %v", o, tcpopt.NoDelay(true))
+	}
+
+	_, err = tcp.LoadProfiles(strings.NewReader(`{"profiles": [
+		{"name": "broken", "options": [
+			{"name": "TCP_NONEXISTENT", "value": 1},
+			{"name": "TCP_NODELAY", "value": "yes"},
+			{"name": "TCP_CONGESTION", "value": 1},
+			{"name": "SO_SNDBUF", "value": 65536, "apply_on": "connect"}
+		]}
+	]}`))
+	if cerr, ok := err.(tcp.ConfigError); !ok || len(cerr) != 4 {
+		t.Fatalf("got %v; want ConfigError with 4 errors", err)
+	}
+}
@@ -6,79 +6,613 @@ package tcp
 
 import (
 	"errors"
+	"io"
 	"net"
-	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/mikioh/netreflect"
 	"github.com/mikioh/tcpopt"
 )
 
 var _ net.Conn = &Conn{}
 
+// ErrNotSupported is returned when the platform doesn't support the
+// requested feature.
+var ErrNotSupported = errors.New("operation not supported")
+
+// ErrWouldBlock is returned when a non-blocking operation cannot be
+// completed immediately.
+// It implements net.Error and reports both a timeout and a temporary
+// failure.
+var ErrWouldBlock error = &wouldBlockError{}
+
 // A Conn represents an end point that uses TCP connection.
 // It allows to set non-portable, platform-dependent TCP-level socket
 // options.
+//
+// Multiple goroutines may invoke methods on a Conn simultaneously.
+// The socket options are accessed while holding a reference to the
+// socket descriptor, so that the methods called concurrently with
+// Close return an error wrapping net.ErrClosed instead of operating
+// on a released, possibly reused, descriptor.
 type Conn struct {
 	net.Conn
-	s uintptr // socket descriptor for configuring options
+	s       uintptr         // socket descriptor for configuring options
+	rc      syscall.RawConn // nil when the connection doesn't implement syscall.Conn
+	wrapped bool            // true when the socket is found by unwrapping Conn
+	id      uint64          // identifier of the connection
+
+	created time.Time
+
+	odMu sync.Mutex
+	od   net.Addr // cached original destination address
+
+	leak *connLeak // non-nil when tracked by a leak detector
+
+	traceMu sync.Mutex
+	trace   *tracer // non-nil in the trace mode
+
+	release func() // releases the slot of connection limit, if any
+
+	hs *Handshake // measurement of the opening handshake, if dialed by Dialer or accepted by Listener
+
+	ioHookMu sync.Mutex   // serializes changes of ioHooks
+	ioHooks  atomic.Value // []*IOHook
+	ioStats  *ioCounters  // non-nil when the counting is enabled
+	abortFn  *IOHook      // non-nil when aborting on write timeouts
+
+	labelMu sync.Mutex
+	labels  Labels // labels set by SetLabels
+
+	regMu  sync.Mutex
+	regs   []*ConnRegistry // registries the connection is registered to
+	closed bool
+}
+
+// An OptionSetter represents a connection that allows to set and get
+// socket options. Conn and the types embedding Conn implement it.
+type OptionSetter interface {
+	SetOption(o tcpopt.Option) error
+	Option(level, name int, b []byte) (tcpopt.Option, error)
+}
+
+// FindOptionSetter returns the OptionSetter found by unwrapping the
+// connection c, using the Unwrap or NetConn method, or the embedded
+// net.Conn, of wrapped connections.
+// It reports false when no OptionSetter is found.
+func FindOptionSetter(c net.Conn) (OptionSetter, bool) {
+	for c != nil {
+		if setter, ok := c.(OptionSetter); ok {
+			return setter, true
+		}
+		c = unwrap(c)
+	}
+	return nil, false
+}
+
+// Unwrap returns the underlying connection.
+func (c *Conn) Unwrap() net.Conn { return c.Conn }
+
+// ReadFrom implements the ReadFrom method of io.ReaderFrom interface.
+// It uses the ReadFrom method of the underlying connection when
+// available, which allows the standard library to use sendfile and
+// splice.
+func (c *Conn) ReadFrom(r io.Reader) (int64, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.readFrom(r)
+	}
+	start := time.Now()
+	n, err := c.readFrom(r)
+	c.observe(fns, "write", n, start, err)
+	return n, err
+}
+
+func (c *Conn) readFrom(r io.Reader) (int64, error) {
+	if rf, ok := c.Conn.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(writerOnly{c.Conn}, r)
+}
+
+// WriteTo implements the WriteTo method of io.WriterTo interface.
+// It uses the WriteTo method of the underlying connection when
+// available.
+func (c *Conn) WriteTo(w io.Writer) (int64, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.writeTo(w)
+	}
+	start := time.Now()
+	n, err := c.writeTo(w)
+	c.observe(fns, "read", n, start, err)
+	return n, err
+}
+
+func (c *Conn) writeTo(w io.Writer) (int64, error) {
+	if wt, ok := c.Conn.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, readerOnly{c.Conn})
+}
+
+// writerOnly and readerOnly hide the methods other than Write and
+// Read to prevent io.Copy from recursion.
+type writerOnly struct{ io.Writer }
+type readerOnly struct{ io.Reader }
+
+// unwrap returns the connection wrapped by c, or nil.
+// It falls back to the net.Conn embedded in the struct of c, such as
+// the connections returned by netutil.LimitListener, when c has
+// neither the Unwrap nor the NetConn method.
+func unwrap(c net.Conn) net.Conn {
+	switch c := c.(type) {
+	case interface{ Unwrap() net.Conn }:
+		return c.Unwrap()
+	case interface{ NetConn() net.Conn }:
+		return c.NetConn()
+	}
+	return embeddedConn(c)
+}
+
+var connType = reflect.TypeOf((*net.Conn)(nil)).Elem()
+
+// embeddedConn returns the net.Conn embedded in the struct, or the
+// struct pointed to, of c, or nil.
+func embeddedConn(c net.Conn) net.Conn {
+	v := reflect.ValueOf(c)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == connType && f.PkgPath == "" {
+			if ec, ok := v.Field(i).Interface().(net.Conn); ok && ec != c {
+				return ec
+			}
+		}
+	}
+	return nil
 }
 
 // SetOption sets a socket option.
+// The hook installed by SetOptionHook is called when it exists.
 func (c *Conn) SetOption(o tcpopt.Option) error {
-	b, err := o.Marshal()
-	if err != nil {
-		return &net.OpError{Op: "set", Net: c.LocalAddr().Network(), Source: nil, Addr: c.LocalAddr(), Err: err}
+	return c.setOptionWithHook(o)
+}
+
+func (c *Conn) setOption(o tcpopt.Option) error {
+	var bb [4]byte
+	b := bb[:]
+	if !marshalInt32(o, &bb) {
+		var err error
+		if b, err = o.Marshal(); err != nil {
+			return c.optionError("set", o.Level(), o.Name(), err)
+		}
 	}
-	if err := setsockopt(c.s, o.Level(), o.Name(), b); err != nil {
-		return &net.OpError{Op: "set", Net: c.LocalAddr().Network(), Source: nil, Addr: c.LocalAddr(), Err: os.NewSyscallError("setsockopt", err)}
+	if err := c.setsockopt(o.Level(), o.Name(), b); err != nil {
+		return c.optionError("set", o.Level(), o.Name(), privilegeError(o, err))
 	}
 	return nil
 }
 
 // Option returns a socket option.
+// It returns a RawOption carrying the option value as is when no
+// parser is registered for the option.
 func (c *Conn) Option(level, name int, b []byte) (tcpopt.Option, error) {
 	if len(b) == 0 {
 		return nil, errors.New("short buffer")
 	}
-	if err := getsockopt(c.s, level, name, b); err != nil {
-		return nil, &net.OpError{Op: "get", Net: c.LocalAddr().Network(), Source: nil, Addr: c.LocalAddr(), Err: os.NewSyscallError("getsockopt", err)}
+	if len(b) <= sockoptBufLen {
+		return c.pooledOption(level, name, b)
+	}
+	n, err := c.getsockopt(level, name, b)
+	if err != nil {
+		return nil, c.optionError("get", level, name, err)
 	}
-	o, err := tcpopt.Parse(level, name, b)
+	o, err := parseOrRaw(level, name, b, n)
 	if err != nil {
-		return nil, &net.OpError{Op: "get", Net: c.LocalAddr().Network(), Source: nil, Addr: c.LocalAddr(), Err: err}
+		return nil, c.optionError("get", level, name, err)
 	}
 	return o, nil
 }
 
+// OptionValue returns a socket option specified by level and name.
+// Unlike Option, it discovers a large enough buffer for the option
+// value by growing the buffer until the kernel returns less than the
+// buffer size.
+func (c *Conn) OptionValue(level, name int) (tcpopt.Option, error) {
+	sc := sockoptCalls.Get().(*sockoptCall)
+	defer sc.release()
+	sc.b = sc.buf[:]
+	for {
+		n, err := c.runSockopt(sc, false, level, name)
+		if err != nil {
+			return nil, c.optionError("get", level, name, err)
+		}
+		if n < len(sc.b) || len(sc.b) >= maxOptionLen {
+			sc.b = sc.b[:n]
+			break
+		}
+		sc.b = make([]byte, 2*len(sc.b))
+	}
+	o, err := parseOrRaw(level, name, sc.b, len(sc.b))
+	if err != nil {
+		return nil, c.optionError("get", level, name, err)
+	}
+	return o, nil
+}
+
+// SetRawOption sets a socket option specified by level and name.
+// Unlike SetOption, the value b is passed to the kernel as is.
+func (c *Conn) SetRawOption(level, name int, b []byte) error {
+	if len(b) == 0 {
+		return errors.New("short buffer")
+	}
+	if err := c.setsockopt(level, name, b); err != nil {
+		return c.optionError("set", level, name, err)
+	}
+	return nil
+}
+
+// RawOption reads a socket option specified by level and name into
+// b and returns the number of bytes of the option value.
+// Unlike Option, it doesn't parse the option value.
+func (c *Conn) RawOption(level, name int, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, errors.New("short buffer")
+	}
+	n, err := c.getsockopt(level, name, b)
+	if err != nil {
+		return 0, c.optionError("get", level, name, err)
+	}
+	return n, nil
+}
+
+// Cookie returns the socket cookie, an identifier of the socket that
+// is unique within the kernel and used by eBPF programs and
+// sock_diag.
+//
+// Only Linux supports this feature.
+func (c *Conn) Cookie() (uint64, error) {
+	so := options[soCookie]
+	if so.name < 1 {
+		return 0, c.opError("get", ErrNotSupported)
+	}
+	var b [8]byte
+	if _, err := c.getsockopt(so.level, so.name, b[:]); err != nil {
+		return 0, c.optionError("get", so.level, so.name, err)
+	}
+	return nativeEndian.Uint64(b[:]), nil
+}
+
+// Abort closes the connection and sends a RST segment to the peer
+// immediately, discarding any unsent data, instead of performing the
+// orderly release with FIN segments.
+func (c *Conn) Abort() error {
+	lc, ok := c.Conn.(interface {
+		SetLinger(int) error
+	})
+	if !ok {
+		return c.opError("close", ErrNotSupported)
+	}
+	if err := lc.SetLinger(0); err != nil {
+		c.Close()
+		return err
+	}
+	return c.Close()
+}
+
+// SetAbortOnWriteTimeout enables or disables the abortive close of
+// the connection on write timeouts.
+// When enabled, a Write, WriteContext, WriteMore or TryWrite call
+// failing with a timeout, either by the write deadline or by the user
+// timeout, closes the connection by Abort before returning the
+// error. It discards the
+// unsent data and releases the resources of the connection right
+// away, instead of leaving the connection lingering with a full send
+// queue behind a stuck peer.
+// A write interrupted by the cancellation of the context of
+// WriteContext also aborts the connection.
+func (c *Conn) SetAbortOnWriteTimeout(enable bool) {
+	c.ioHookMu.Lock()
+	defer c.ioHookMu.Unlock()
+	old := c.hooks()
+	switch {
+	case enable && c.abortFn == nil:
+		fn := IOHook(abortOnWriteTimeout)
+		c.abortFn = &fn
+		c.ioHooks.Store(append(old[:len(old):len(old)], c.abortFn))
+	case !enable && c.abortFn != nil:
+		fns := make([]*IOHook, 0, len(old))
+		for _, p := range old {
+			if p != c.abortFn {
+				fns = append(fns, p)
+			}
+		}
+		c.ioHooks.Store(fns)
+		c.abortFn = nil
+	}
+}
+
+func abortOnWriteTimeout(c *Conn, ev *IOEvent) {
+	var operr *net.OpError
+	if ev.Op == "write" && errors.As(ev.Err, &operr) && operr.Op == "write" && operr.Timeout() {
+		c.Abort()
+	}
+}
+
 // Buffered returns the number of bytes that can be read from the
 // underlying socket read buffer.
 // It returns -1 when the platform doesn't support this feature.
-func (c *Conn) Buffered() int { return buffered(c.s) }
+func (c *Conn) Buffered() int {
+	n := -1
+	c.control(func(s uintptr) error { n = buffered(s); return nil })
+	return n
+}
 
 // Available returns how many bytes are unused in the underlying
 // socket write buffer.
 // It returns -1 when the platform doesn't support this feature.
-func (c *Conn) Available() int { return available(c.s) }
+func (c *Conn) Available() int {
+	n := -1
+	c.control(func(s uintptr) error { n = available(s); return nil })
+	return n
+}
+
+// CloseRead shuts down the reading side of the connection.
+// It returns an error when the underlying connection doesn't support
+// half-close.
+func (c *Conn) CloseRead() error {
+	cr, ok := c.Conn.(interface {
+		CloseRead() error
+	})
+	if !ok {
+		return c.opError("close", ErrNotSupported)
+	}
+	return cr.CloseRead()
+}
+
+// CloseWrite shuts down the writing side of the connection.
+// It returns an error when the underlying connection doesn't support
+// half-close.
+func (c *Conn) CloseWrite() error {
+	cw, ok := c.Conn.(interface {
+		CloseWrite() error
+	})
+	if !ok {
+		return c.opError("close", ErrNotSupported)
+	}
+	return cw.CloseWrite()
+}
 
 // OriginalDst returns an original destination address, which is an
 // address not modified by intermediate entities such as network
 // address and port translators inside the kernel, on the connection.
 //
-// Only Linux and BSD variants using PF support this feature.
+// The result is cached on the first successful call because it never
+// changes on an established connection. The returned address must
+// not be modified.
+//
+// Only Linux, BSD variants using PF and Windows support this feature.
+// On Windows, it requires the WFP callout that redirects the
+// connection to store the original destination as SOCKADDR_STORAGE in
+// the redirect context; see RedirectRecords for the redirection.
 func (c *Conn) OriginalDst() (net.Addr, error) {
+	c.odMu.Lock()
+	od := c.od
+	c.odMu.Unlock()
+	if od != nil {
+		return od, nil
+	}
+	return c.RefreshOriginalDst()
+}
+
+// RefreshOriginalDst is like OriginalDst but always queries the
+// kernel and updates the cached address.
+func (c *Conn) RefreshOriginalDst() (net.Addr, error) {
 	la := c.LocalAddr().(*net.TCPAddr)
-	od, err := originalDst(c.s, la, c.RemoteAddr().(*net.TCPAddr))
+	var od net.Addr
+	err := c.control(func(s uintptr) (err error) {
+		od, err = originalDst(s, la, c.RemoteAddr().(*net.TCPAddr))
+		return
+	})
 	if err != nil {
-		return nil, &net.OpError{Op: "get", Net: c.LocalAddr().Network(), Source: nil, Addr: la, Err: err}
+		return nil, c.opError("get", err)
 	}
+	c.odMu.Lock()
+	c.od = od
+	c.odMu.Unlock()
 	return od, nil
 }
 
+// control calls fn with the socket descriptor of the connection.
+//
+// It holds a reference to the descriptor using the Control method of
+// syscall.RawConn during the call, which prevents a concurrent Close
+// from releasing the descriptor, and another socket from reusing it,
+// in the middle of the call. It returns net.ErrClosed when the
+// connection is closed.
+// The cached descriptor is used as is when the underlying connection
+// doesn't implement syscall.Conn.
+func (c *Conn) control(fn func(s uintptr) error) error {
+	if c.rc == nil {
+		return fn(c.s)
+	}
+	var ferr error
+	if err := c.rc.Control(func(s uintptr) { ferr = fn(s) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+// setsockopt and getsockopt are like setsockopt and getsockopt but
+// take the socket descriptor of the connection using control.
+func (c *Conn) setsockopt(level, name int, b []byte) error {
+	_, err := c.sockopt(true, level, name, b)
+	return err
+}
+
+func (c *Conn) getsockopt(level, name int, b []byte) (int, error) {
+	return c.sockopt(false, level, name, b)
+}
+
+// A sockoptCall represents a call of setsockopt or getsockopt inside
+// the Control method of syscall.RawConn.
+// The calls are pooled with the function bound to the call, and the
+// option value is copied through the buffer of the call, to avoid
+// allocations on the frequent option access.
+type sockoptCall struct {
+	set         bool
+	level, name int
+	b           []byte
+	n           int
+	err         error
+	buf         [sockoptBufLen]byte
+	fn          func(uintptr) // bound to run
+}
+
+// sockoptBufLen is the length of the buffer of a pooled call.
+const sockoptBufLen = 256
+
+var sockoptCalls = sync.Pool{
+	New: func() interface{} {
+		sc := new(sockoptCall)
+		sc.fn = sc.run
+		return sc
+	},
+}
+
+// release returns sc to the pool.
+func (sc *sockoptCall) release() {
+	sc.b, sc.err = nil, nil
+	sockoptCalls.Put(sc)
+}
+
+func (sc *sockoptCall) run(s uintptr) {
+	if sc.set {
+		sc.err = setsockopt(s, sc.level, sc.name, sc.b)
+		return
+	}
+	sc.n, sc.err = getsockopt(s, sc.level, sc.name, sc.b)
+}
+
+func (c *Conn) sockopt(set bool, level, name int, b []byte) (int, error) {
+	if c.rc == nil {
+		if set {
+			return 0, setsockopt(c.s, level, name, b)
+		}
+		return getsockopt(c.s, level, name, b)
+	}
+	sc := sockoptCalls.Get().(*sockoptCall)
+	if len(b) <= len(sc.buf) {
+		sc.b = sc.buf[:len(b)]
+	} else {
+		sc.b = make([]byte, len(b))
+	}
+	copy(sc.b, b)
+	n, err := c.runSockopt(sc, set, level, name)
+	if !set && err == nil {
+		copy(b, sc.b[:n])
+	}
+	sc.release()
+	return n, err
+}
+
+// runSockopt runs the call sc on the socket descriptor of the
+// connection.
+func (c *Conn) runSockopt(sc *sockoptCall, set bool, level, name int) (int, error) {
+	sc.set, sc.level, sc.name = set, level, name
+	sc.n, sc.err = 0, nil
+	if c.rc == nil {
+		sc.run(c.s)
+	} else if err := c.rc.Control(sc.fn); err != nil {
+		return 0, err
+	}
+	return sc.n, sc.err
+}
+
+// pooledOption implements Option for the buffer b of up to
+// sockoptBufLen bytes. It reads and parses the option value in the
+// buffer of a pooled call, and copies the value out to b before
+// returning the buffer to the pool, which keeps b from escaping to
+// the parsers.
+func (c *Conn) pooledOption(level, name int, b []byte) (tcpopt.Option, error) {
+	sc := sockoptCalls.Get().(*sockoptCall)
+	defer sc.release()
+	sc.b = sc.buf[:len(b)]
+	n, err := c.runSockopt(sc, false, level, name)
+	if err != nil {
+		return nil, c.optionError("get", level, name, err)
+	}
+	copy(b, sc.b[:n])
+	o, err := parseOrRaw(level, name, sc.b, n)
+	if err != nil {
+		return nil, c.optionError("get", level, name, err)
+	}
+	return o, nil
+}
+
+// opError returns the error for the operation op.
+func (c *Conn) opError(op string, err error) error {
+	la := c.LocalAddr()
+	return &net.OpError{Op: op, Net: la.Network(), Source: nil, Addr: la, Err: err}
+}
+
+// newConn returns a new end point of the connection c using the
+// socket descriptor s and the raw connection rc, which may be nil.
+func newConn(c net.Conn, s uintptr, rc syscall.RawConn) *Conn {
+	tc := &Conn{Conn: c, s: s, rc: rc, created: time.Now()}
+	tc.id = newID(tc)
+	return tc
+}
+
 // NewConn returns a new end point.
+// The connection c may be a wrapper of the connection backed by the
+// socket, such as a connection returned by netutil.LimitListener;
+// NewConn walks the chain of wrappers using the Unwrap, NetConn and
+// SyscallConn methods, or the embedded net.Conn, of each layer to
+// find the socket. The end point reads and writes through c, and
+// uses the socket only for socket options; the methods reading or
+// writing the socket directly, such as ReadFull, TryRead, TryWrite,
+// WriteMore, Sendfile and TimeToFirstByte, return an error on the end
+// point of a wrapper, which may transform the data, and Relay falls
+// back to io.Copy.
+// On the platforms that support SocketKind, it returns an error when
+// the connection c is not backed by a TCP socket.
 func NewConn(c net.Conn) (*Conn, error) {
-	s, err := netreflect.SocketOf(c)
+	s, rc, wrapped, err := wrappedSocketOf(c)
 	if err != nil {
 		return nil, err
 	}
-	return &Conn{Conn: c, s: s}, nil
+	if err := checkSocketKind(s); err != nil {
+		return nil, err
+	}
+	tc := newConn(c, s, rc)
+	tc.wrapped = wrapped
+	tc.track()
+	tc.register()
+	return tc, nil
+}
+
+// Close closes the connection.
+// It turns off the trace mode of the connection and removes the
+// connection from the registries.
+func (c *Conn) Close() error {
+	c.untrack()
+	c.unregister()
+	c.SetTrace(nil, 0)
+	if c.release != nil {
+		c.release()
+	}
+	return c.Conn.Close()
 }
@@ -24,7 +24,7 @@ func originalDst(s uintptr, la, _ *net.TCPAddr) (net.Addr, error) {
 		name = sysIP6T_SO_ORIGINAL_DST
 		b = make([]byte, sizeofSockaddrInet6)
 	}
-	if err := getsockopt(s, level, name, b); err != nil {
+	if _, err := getsockopt(s, level, name, b); err != nil {
 		return nil, os.NewSyscallError("getsockopt", err)
 	}
 	od := new(net.TCPAddr)
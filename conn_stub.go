@@ -2,15 +2,12 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build !darwin,!dragonfly,!freebsd,!linux,!openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!openbsd,!windows
 
 package tcp
 
-import (
-	"errors"
-	"net"
-)
+import "net"
 
 func originalDst(s uintptr, la, ra *net.TCPAddr) (net.Addr, error) {
-	return nil, errors.New("operation not supported")
+	return nil, ErrNotSupported
 }
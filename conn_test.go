@@ -5,11 +5,21 @@
 package tcp_test
 
 import (
+	"errors"
+	"io"
 	"net"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
 	"golang.org/x/net/nettest"
+	"golang.org/x/net/netutil"
 )
 
 func TestConn(t *testing.T) {
@@ -46,3 +56,510 @@ func TestConn(t *testing.T) {
 		return tc, p.Conn, func() { tc.Close(); p.Conn.Close() }, nil
 	})
 }
+
+type opaqueConn struct {
+	net.Conn
+}
+
+func (c *opaqueConn) Unwrap() net.Conn { return c.Conn }
+
+func TestFindOptionSetter(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.Unwrap() != c {
+		t.Fatalf("got %v; want %v", tc.Unwrap(), c)
+	}
+
+	if _, ok := tcp.FindOptionSetter(c); ok {
+		t.Fatalf("got OptionSetter from %T", c)
+	}
+	setter, ok := tcp.FindOptionSetter(&opaqueConn{Conn: tc})
+	if !ok {
+		t.Fatal("no OptionSetter")
+	}
+	if err := setter.SetOption(tcpopt.NoDelay(true)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewConnWrapped(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln = netutil.LimitListener(ln, 1)
+	defer ln.Close()
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		ac, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wc := &opaqueConn{Conn: ac}
+		tc, err := tcp.NewConn(wc)
+		if err != nil {
+			ac.Close()
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if tc.Unwrap() != wc {
+			t.Fatalf("#%d: got %v; want %v", i, tc.Unwrap(), wc)
+		}
+		if err := tc.SetOption(tcpopt.NoDelay(true)); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if _, err := tc.TryWrite([]byte("HELLO")); err == nil {
+			t.Fatalf("#%d: got nil; want an error for raw I/O on wrapped connection", i)
+		}
+		// Closing through the wrappers releases the slot of the
+		// listener for the next connection.
+		if err := tc.Close(); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+	}
+}
+
+func TestSocketKind(t *testing.T) {
+	switch runtime.GOOS {
+	case "freebsd", "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	sk, err := c.SocketKind()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk.Type != 1 || sk.Protocol != 6 { // SOCK_STREAM and TCP
+		t.Fatalf("got %v; want tcp socket", sk)
+	}
+
+	uc, err := net.Dial("udp4", "127.0.0.1:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uc.Close()
+	if _, err := tcp.NewConn(uc); err == nil {
+		t.Fatal("got nil; want an error for udp socket")
+	}
+}
+
+func TestConnString(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "plan9":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	tc, _ := tcptest.Pair(t)
+
+	di := tc.DebugInfo()
+	if di.LocalAddr.String() != tc.LocalAddr().String() || di.RemoteAddr.String() != tc.RemoteAddr().String() {
+		t.Fatalf("got %v->%v; want %v->%v", di.LocalAddr, di.RemoteAddr, tc.LocalAddr(), tc.RemoteAddr())
+	}
+	if runtime.GOOS == "linux" && di.State != tcpinfo.Established {
+		t.Errorf("got %v; want %v", di.State, tcpinfo.Established)
+	}
+	s := tc.String()
+	if !strings.HasPrefix(s, "tcp "+tc.LocalAddr().String()+"->"+tc.RemoteAddr().String()+" ") {
+		t.Errorf("unexpected description: %s", s)
+	}
+	t.Log(s)
+}
+
+func TestULP(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := tc.ULP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "" {
+		t.Fatalf("got %q; want the empty string", name)
+	}
+	if err := tc.SetULP(""); err == nil {
+		t.Fatal("got nil; want empty name error")
+	}
+	if err := tc.SetULP("tls"); err != nil {
+		t.Skip(err) // requires the tls module
+	}
+	name, err = tc.ULP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "tls" {
+		t.Fatalf("got %q; want %q", name, "tls")
+	}
+}
+
+func TestStack(t *testing.T) {
+	switch runtime.GOOS {
+	case "freebsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := tc.Stack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name == "" {
+		t.Fatal("got the empty string; want a stack name")
+	}
+	if err := tc.SetStack(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SetStack(""); err == nil {
+		t.Fatal("got nil; want empty name error")
+	}
+}
+
+func TestBlackBox(t *testing.T) {
+	switch runtime.GOOS {
+	case "freebsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tc.SetBlackBox(tcp.BlackBoxTail); err != nil {
+		t.Skip(err) // requires the kernel with TCP_BLACKBOX option
+	}
+	m, err := tc.BlackBox()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != tcp.BlackBoxTail {
+		t.Fatalf("got %v; want %v", m, tcp.BlackBoxTail)
+	}
+	if err := tc.SetBlackBoxID("tcp-test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.Write([]byte("HELLO-R-U-THERE")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1<<16)
+	if _, err := tc.ReadBlackBox(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SetBlackBox(tcp.BlackBoxMode(-1)); err == nil {
+		t.Fatal("got nil; want invalid mode error")
+	}
+}
+
+func TestConnRegistry(t *testing.T) {
+	r := tcp.NewConnRegistry()
+	tcp.SetConnRegistry(r)
+	c, s := tcptest.Pair(t)
+	tcp.SetConnRegistry(nil)
+	if n := r.Len(); n != 2 {
+		t.Fatalf("got %d; want 2", n)
+	}
+
+	r.Register(c, tcp.Labels{"peer": "db", "tenant": "blue"})
+	if got, ok := r.Lookup(tcp.Labels{"peer": "db"}); !ok || got != c {
+		t.Fatalf("got %v, %v; want %v, true", got, ok, c)
+	}
+	if rcs := r.Conns(tcp.Labels{"tenant": "red"}); len(rcs) != 0 {
+		t.Fatalf("got %d connections; want none", len(rcs))
+	}
+	if rcs := r.Conns(nil); len(rcs) != 2 {
+		t.Fatalf("got %d connections; want 2", len(rcs))
+	}
+	if l, ok := r.Labels(c); !ok || l["tenant"] != "blue" {
+		t.Fatalf("got %v, %v; want tenant=blue", l, ok)
+	}
+	if !strings.Contains(r.String(), `"peer":"db"`) {
+		t.Fatalf("got %s; want peer label", r)
+	}
+
+	r.Unregister(s)
+	c.Close()
+	if n := r.Len(); n != 0 {
+		t.Fatalf("got %d; want 0", n)
+	}
+	r.Register(c, nil)
+	if n := r.Len(); n != 0 {
+		t.Fatalf("got %d after registering closed connection; want 0", n)
+	}
+}
+
+func TestIOHook(t *testing.T) {
+	c, s := tcptest.Pair(t)
+	var evs []tcp.IOEvent
+	var order []int
+	remove := c.AddIOHook(func(cc *tcp.Conn, ev *tcp.IOEvent) {
+		if cc != c {
+			t.Errorf("got %v; want %v", cc, c)
+		}
+		evs = append(evs, *ev)
+		order = append(order, 1)
+	})
+	remove2 := c.AddIOHook(func(_ *tcp.Conn, ev *tcp.IOEvent) { order = append(order, 2) })
+
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 5)
+	if _, err := s.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	s.Write(b)
+	if _, err := io.ReadFull(c, b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadFrom(strings.NewReader("world")); err != nil {
+		t.Fatal(err)
+	}
+	remove2()
+	c.SetReadDeadline(time.Now())
+	if _, err := c.Read(b); err == nil {
+		t.Fatal("got nil; want timeout error")
+	}
+	remove()
+	c.Write(b)
+
+	if len(evs) != 4 {
+		t.Fatalf("got %d events; want 4: %+v", len(evs), evs)
+	}
+	for i, want := range []struct {
+		op  string
+		n   int64
+		err bool
+	}{
+		{"write", 5, false}, {"read", 5, false}, {"write", 5, false}, {"read", 0, true},
+	} {
+		if ev := evs[i]; ev.Op != want.op || ev.N != want.n || (ev.Err != nil) != want.err || ev.Duration < 0 {
+			t.Errorf("#%d: got %+v; want %+v", i, ev, want)
+		}
+	}
+	if len(order) != 7 || order[0] != 1 || order[1] != 2 {
+		t.Fatalf("got %v; want hooks in installation order", order)
+	}
+}
+
+func TestConnCloseOption(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := make([]byte, 4)
+			for j := 0; j < 100; j++ {
+				c.SetOption(tcpopt.NoDelay(j%2 == 0))
+				c.Option(tcpopt.NoDelay(false).Level(), tcpopt.NoDelay(false).Name(), b)
+				c.Buffered()
+			}
+		}()
+	}
+	c.Close()
+	wg.Wait()
+
+	if err := c.SetOption(tcpopt.NoDelay(true)); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("got %v; want %v", err, net.ErrClosed)
+	}
+	b := make([]byte, 4)
+	if _, err := c.Option(tcpopt.NoDelay(false).Level(), tcpopt.NoDelay(false).Name(), b); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("got %v; want %v", err, net.ErrClosed)
+	}
+	if n := c.Buffered(); n != -1 {
+		t.Errorf("got %d; want -1", n)
+	}
+}
+
+func TestConnID(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "plan9":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, s := tcptest.Pair(t)
+	id := c.ID()
+	if id == 0 || id == s.ID() {
+		t.Fatalf("got %#x and %#x; want distinct non-zero IDs", id, s.ID())
+	}
+	if cookie, err := c.Cookie(); err == nil && cookie != id {
+		t.Errorf("got %#x; want %#x", id, cookie)
+	}
+	c.Close()
+	if c.ID() != id {
+		t.Errorf("got %#x after close; want %#x", c.ID(), id)
+	}
+}
+
+func TestConnStats(t *testing.T) {
+	c, s := tcptest.Pair(t)
+	if _, ok := c.Stats(); ok {
+		t.Fatal("got true; want false before enabling")
+	}
+	c.EnableStats()
+	c.EnableStats()
+
+	start := time.Now()
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadFrom(strings.NewReader("world")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 10)
+	if _, err := io.ReadFull(s, b); err != nil {
+		t.Fatal(err)
+	}
+	s.Write(b[:4])
+	if _, err := io.ReadFull(c, b[:4]); err != nil {
+		t.Fatal(err)
+	}
+	st, ok := c.Stats()
+	if !ok {
+		t.Fatal("got false; want true")
+	}
+	if st.BytesWritten != 10 || st.Writes != 2 || st.BytesRead != 4 || st.Reads < 1 {
+		t.Fatalf("got %+v; want 10 bytes in 2 writes and 4 bytes read", st)
+	}
+	if st.LastWrite.Before(start) || st.LastRead.Before(st.LastWrite) {
+		t.Fatalf("got %+v; want last activities after %v", st, start)
+	}
+}
+
+func TestConnStatsRawIO(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, s := tcptest.Pair(t)
+	c.EnableStats()
+	if _, err := c.WriteMore([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TryWrite([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 10)
+	if _, err := io.ReadFull(s, b); err != nil {
+		t.Fatal(err)
+	}
+	s.Write(b)
+	if _, err := c.ReadFull(b[:4]); err != nil {
+		t.Fatal(err)
+	}
+	for c.Buffered() < 6 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := c.TryRead(b); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := c.Stats()
+	if st.BytesWritten != 10 || st.Writes != 2 || st.BytesRead != 10 || st.Reads != 2 {
+		t.Fatalf("got %+v; want 10 bytes in 2 writes and 10 bytes in 2 reads", st)
+	}
+}
+
+func TestConnLabels(t *testing.T) {
+	c, _ := tcptest.Pair(t)
+	c.SetLabels(tcp.Labels{"tenant": "blue", "route": "default"})
+	if l := c.Labels(); len(l) != 2 || l["tenant"] != "blue" {
+		t.Fatalf("got %v; want tenant and route labels", l)
+	}
+
+	r := tcp.NewConnRegistry()
+	r.Register(c, tcp.Labels{"route": "backup"})
+	l, ok := r.Labels(c)
+	if !ok || len(l) != 2 || l["tenant"] != "blue" || l["route"] != "backup" {
+		t.Fatalf("got %v, %v; want merged labels", l, ok)
+	}
+	if cc, ok := r.Lookup(tcp.Labels{"tenant": "blue", "route": "backup"}); !ok || cc != c {
+		t.Fatalf("got %v, %v; want %v", cc, ok, c)
+	}
+	c.SetLabels(tcp.Labels{"tenant": "red"})
+	if rcs := r.Conns(tcp.Labels{"tenant": "red"}); len(rcs) != 1 || rcs[0].Labels["route"] != "backup" {
+		t.Fatalf("got %v; want connection relabeled", rcs)
+	}
+	if rcs := r.Conns(tcp.Labels{"tenant": "blue"}); len(rcs) != 0 {
+		t.Fatalf("got %v; want none", rcs)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// sizeofSockaddrStorage is the size of SOCKADDR_STORAGE.
+const sizeofSockaddrStorage = 0x80
+
+// originalDst returns the original destination stored in the
+// redirect context by the WFP callout.
+func originalDst(s uintptr, _, _ *net.TCPAddr) (net.Addr, error) {
+	var b [sizeofSockaddrStorage]byte
+	var n uint32
+	if err := syscall.WSAIoctl(syscall.Handle(s), sysSIO_QUERY_WFP_CONNECTION_REDIRECT_CONTEXT, nil, 0, &b[0], uint32(len(b)), &n, nil, 0); err != nil {
+		return nil, os.NewSyscallError("wsaioctl", err)
+	}
+	od := &net.TCPAddr{Port: int(binary.BigEndian.Uint16(b[2:4]))}
+	switch nativeEndian.Uint16(b[0:2]) {
+	case syscall.AF_INET:
+		od.IP = net.IPv4(b[4], b[5], b[6], b[7])
+	case syscall.AF_INET6:
+		od.IP = make(net.IP, net.IPv6len)
+		copy(od.IP, b[8:24])
+		od.Zone = zoneCache.name(int(nativeEndian.Uint32(b[24:28])))
+	default:
+		return nil, errors.New("unknown redirect context")
+	}
+	return od, nil
+}
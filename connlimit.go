@@ -0,0 +1,164 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// SetMaxConns limits the number of concurrent connections accepted
+// from the listener to n. A non-positive n removes the limit.
+//
+// When the limit is reached, Accept waits for a connection to be
+// closed if wait is true, which leaves new connections in the accept
+// queue of the kernel. Otherwise Accept closes new connections
+// immediately.
+//
+// The closure of the Conn returned from Accept frees up the slot for
+// a new connection. Connections accepted before the call are not
+// counted.
+func (ln *Listener) SetMaxConns(n int, wait bool) {
+	var l *connLimit
+	if n > 0 {
+		l = &connLimit{sem: make(chan struct{}, n), wait: wait, done: make(chan struct{})}
+	}
+	ln.limitMu.Lock()
+	old := ln.limit
+	ln.limit = l
+	ln.limitMu.Unlock()
+	if old != nil {
+		old.close() // unblocks the pending Accept
+	}
+}
+
+// Accept waits for and returns the next connection to the listener.
+// While the connection limit, the rate limit, the verdict or the
+// tracking is in effect, it returns a Conn, which carries the
+// handshake RTT and the time of accept; see Conn.Handshake.
+// Otherwise it returns the connection of the underlying listener.
+// See SetMaxConns for the connection limit, SetAcceptRateLimit for
+// the rate limit, SetPreAccept for the verdict on connections,
+// SetTrackConns for the tracking and SetDeadline for the deadline.
+func (ln *Listener) Accept() (net.Conn, error) {
+	return ln.accept(nil)
+}
+
+func (ln *Listener) accept(ctx context.Context) (net.Conn, error) {
+	for {
+		ln.limitMu.Lock()
+		l, rl, pa, tracking := ln.limit, ln.rate, ln.preAccept, ln.tracking
+		ln.limitMu.Unlock()
+		if l == nil && rl == nil && pa == nil && !tracking {
+			c, retry, err := ln.acceptContext(ctx)
+			if err != nil && retry {
+				continue
+			}
+			return c, err
+		}
+		if l != nil && l.wait {
+			ok, err := ln.acquire(ctx, l)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				if ln.closed() {
+					return nil, ln.opError("accept", net.ErrClosed)
+				}
+				continue // limit changed
+			}
+		}
+		c, retry, err := ln.acceptContext(ctx)
+		if err != nil {
+			if l != nil && l.wait {
+				l.release()
+			}
+			if retry {
+				continue
+			}
+			return nil, err
+		}
+		now := time.Now()
+		if rl != nil && !rl.allow(c.RemoteAddr(), now) {
+			c.Close()
+			if l != nil && l.wait {
+				l.release()
+			}
+			continue
+		}
+		if l != nil && !l.wait && !l.tryAcquire() {
+			c.Close()
+			continue
+		}
+		var tc *Conn
+		if pa != nil {
+			tc, err = preAccept(c, pa)
+		} else {
+			tc, err = NewConn(c)
+		}
+		if err != nil {
+			c.Close()
+		}
+		if tc == nil {
+			if l != nil {
+				l.release()
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue // rejected
+		}
+		accepted(tc, now)
+		if err := ln.track(tc, l, tracking); err != nil {
+			return nil, err
+		}
+		return tc, nil
+	}
+}
+
+// Close closes the listener.
+// Pending Accept calls waiting for a connection slot are unblocked.
+func (ln *Listener) Close() error {
+	ln.limitMu.Lock()
+	l := ln.limit
+	ln.limitMu.Unlock()
+	err := ln.Listener.Close()
+	ln.limitMu.Lock()
+	ln.isClosed = true
+	ln.limitMu.Unlock()
+	if l != nil {
+		l.close()
+	}
+	return err
+}
+
+func (ln *Listener) closed() bool {
+	ln.limitMu.Lock()
+	defer ln.limitMu.Unlock()
+	return ln.isClosed
+}
+
+// A connLimit represents a limit of concurrent connections.
+type connLimit struct {
+	sem  chan struct{}
+	wait bool
+	done chan struct{}
+	once sync.Once
+}
+
+func (l *connLimit) tryAcquire() bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *connLimit) release() { <-l.sem }
+
+func (l *connLimit) close() { l.once.Do(func() { close(l.done) }) }
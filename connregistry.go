@@ -0,0 +1,237 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Labels represents the metadata tags of a connection, such as
+// "peer", "tenant" and "purpose".
+type Labels map[string]string
+
+// matches reports whether l contains all the labels of sel.
+func (l Labels) matches(sel Labels) bool {
+	for k, v := range sel {
+		if lv, ok := l[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (l Labels) clone() Labels {
+	ll := make(Labels, len(l))
+	for k, v := range l {
+		ll[k] = v
+	}
+	return ll
+}
+
+// SetLabels sets the labels of the connection, such as "tenant",
+// "route" and "upstream", replacing the labels already set.
+// The labels are propagated to the registries, the samplers and the
+// exporters observing the connection, which allows the metrics to be
+// sliced by their dimensions. A nil labels removes the labels.
+func (c *Conn) SetLabels(labels Labels) {
+	c.labelMu.Lock()
+	c.labels = labels.clone()
+	c.labelMu.Unlock()
+}
+
+// Labels returns the labels of the connection set by SetLabels.
+func (c *Conn) Labels() Labels {
+	c.labelMu.Lock()
+	defer c.labelMu.Unlock()
+	return c.labels.clone()
+}
+
+// labelsWith returns the labels of the connection merged with l,
+// which takes precedence.
+func (c *Conn) labelsWith(l Labels) Labels {
+	c.labelMu.Lock()
+	ll := c.labels.clone()
+	c.labelMu.Unlock()
+	for k, v := range l {
+		ll[k] = v
+	}
+	return ll
+}
+
+// A RegisteredConn represents a connection in the registry.
+type RegisteredConn struct {
+	Conn       *Conn
+	Labels     Labels // labels of the connection merged with the labels of registration
+	Registered time.Time
+}
+
+// A ConnRegistry represents a set of live connections with labels,
+// which allows debugging tools and exporters to enumerate and look
+// up the current connections.
+// Connections leave the registry when they are closed.
+//
+// It implements expvar.Var; String returns the list of the
+// registered connections in JSON.
+type ConnRegistry struct {
+	mu    sync.RWMutex
+	conns map[*Conn]*RegisteredConn
+}
+
+// NewConnRegistry returns a new empty registry.
+func NewConnRegistry() *ConnRegistry {
+	return &ConnRegistry{conns: make(map[*Conn]*RegisteredConn)}
+}
+
+var connRegistry struct {
+	sync.RWMutex
+	r *ConnRegistry
+}
+
+// SetConnRegistry installs the package-wide registry r, to which
+// every connection returned from NewConn is registered without
+// labels. A nil r removes the installed registry.
+// Connections created before the installation are not registered.
+func SetConnRegistry(r *ConnRegistry) {
+	connRegistry.Lock()
+	connRegistry.r = r
+	connRegistry.Unlock()
+}
+
+// Register registers the connection c with the labels. It replaces
+// the labels of c already registered.
+// The labels of registration take precedence over the labels set by
+// Conn.SetLabels.
+// It does nothing when c is closed.
+func (r *ConnRegistry) Register(c *Conn, labels Labels) {
+	c.regMu.Lock()
+	defer c.regMu.Unlock()
+	if c.closed {
+		return
+	}
+	r.mu.Lock()
+	if rc, ok := r.conns[c]; ok {
+		rc.Labels = labels.clone()
+		r.mu.Unlock()
+		return
+	}
+	r.conns[c] = &RegisteredConn{Conn: c, Labels: labels.clone(), Registered: time.Now()}
+	r.mu.Unlock()
+	c.regs = append(c.regs, r)
+}
+
+// Unregister removes the connection c from the registry.
+func (r *ConnRegistry) Unregister(c *Conn) {
+	c.regMu.Lock()
+	for i, rr := range c.regs {
+		if rr == r {
+			c.regs = append(c.regs[:i], c.regs[i+1:]...)
+			break
+		}
+	}
+	c.regMu.Unlock()
+	r.remove(c)
+}
+
+func (r *ConnRegistry) remove(c *Conn) {
+	r.mu.Lock()
+	delete(r.conns, c)
+	r.mu.Unlock()
+}
+
+// Labels returns the labels of the connection c, which are the
+// labels set by Conn.SetLabels merged with the labels of
+// registration.
+// It reports false when c is not registered.
+func (r *ConnRegistry) Labels(c *Conn) (Labels, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rc, ok := r.conns[c]
+	if !ok {
+		return nil, false
+	}
+	return c.labelsWith(rc.Labels), true
+}
+
+// Len returns the number of registered connections.
+func (r *ConnRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.conns)
+}
+
+// Conns returns the snapshot of the registered connections that
+// have all the labels of sel. A nil sel matches every connection.
+// The connections are in no particular order.
+func (r *ConnRegistry) Conns(sel Labels) []RegisteredConn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var rcs []RegisteredConn
+	for _, rc := range r.conns {
+		if labels := rc.Conn.labelsWith(rc.Labels); labels.matches(sel) {
+			rcs = append(rcs, RegisteredConn{Conn: rc.Conn, Labels: labels, Registered: rc.Registered})
+		}
+	}
+	return rcs
+}
+
+// Lookup returns the first registered connection found that has
+// all the labels of sel. It reports false when no connection
+// matches.
+func (r *ConnRegistry) Lookup(sel Labels) (*Conn, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for c, rc := range r.conns {
+		if c.labelsWith(rc.Labels).matches(sel) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// String implements the String method of expvar.Var interface.
+func (r *ConnRegistry) String() string {
+	type entry struct {
+		Local      string    `json:"local"`
+		Remote     string    `json:"remote"`
+		Labels     Labels    `json:"labels"`
+		Registered time.Time `json:"registered"`
+	}
+	rcs := r.Conns(nil)
+	es := make([]entry, 0, len(rcs))
+	for _, rc := range rcs {
+		es = append(es, entry{Local: rc.Conn.LocalAddr().String(), Remote: rc.Conn.RemoteAddr().String(), Labels: rc.Labels, Registered: rc.Registered})
+	}
+	b, err := json.Marshal(es)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
+
+// register registers the connection c to the package-wide registry
+// when installed.
+func (c *Conn) register() {
+	connRegistry.RLock()
+	r := connRegistry.r
+	connRegistry.RUnlock()
+	if r != nil {
+		r.Register(c, nil)
+	}
+}
+
+// unregister removes the connection c from all the registries on
+// closing.
+func (c *Conn) unregister() {
+	c.regMu.Lock()
+	regs := c.regs
+	c.regs = nil
+	c.closed = true
+	c.regMu.Unlock()
+	for _, r := range regs {
+		r.remove(c)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin,!dragonfly,!freebsd
+
+package tcp
+
+import "time"
+
+// connectionTimeoutUnit is the unit of ConnectionTimeout value on
+// the platforms not supporting the option.
+const connectionTimeoutUnit = time.Second
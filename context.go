@@ -0,0 +1,228 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// aLongTimeAgo is a deadline in the past that interrupts the blocked
+// I/O operations immediately.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// ReadContext reads data from the connection like Read, but returns
+// when ctx is done.
+//
+// The blocked read is interrupted by setting the read deadline in
+// the past, and the read deadline is cleared before returning from
+// the interrupted read. The returned error wraps ctx.Err() when the
+// read is interrupted.
+func (c *Conn) ReadContext(ctx context.Context, b []byte) (int, error) {
+	return c.ioContext(ctx, "read", b, c.Read, c.Conn.SetReadDeadline)
+}
+
+// WriteContext writes data to the connection like Write, but returns
+// when ctx is done.
+//
+// The blocked write is interrupted by setting the write deadline in
+// the past, and the write deadline is cleared before returning from
+// the interrupted write. The returned error wraps ctx.Err() when the
+// write is interrupted; the number of bytes written before the
+// interruption is returned as well.
+func (c *Conn) WriteContext(ctx context.Context, b []byte) (int, error) {
+	return c.ioContext(ctx, "write", b, c.Write, c.Conn.SetWriteDeadline)
+}
+
+func (c *Conn) ioContext(ctx context.Context, op string, b []byte, fn func([]byte) (int, error), setDeadline func(time.Time) error) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, c.ioError(op, err)
+	}
+	if ctx.Done() == nil {
+		return fn(b)
+	}
+	stop := make(chan struct{})
+	interrupted := make(chan bool)
+	go func() {
+		select {
+		case <-ctx.Done():
+			setDeadline(aLongTimeAgo)
+			interrupted <- true
+		case <-stop:
+			interrupted <- false
+		}
+	}()
+	n, err := fn(b)
+	close(stop)
+	if <-interrupted {
+		setDeadline(time.Time{})
+		if err != nil {
+			err = c.ioError(op, ctx.Err())
+		}
+	}
+	return n, err
+}
+
+// A deadliner represents a listener that supports the deadline of
+// accept, such as *net.TCPListener.
+type deadliner interface {
+	SetDeadline(time.Time) error
+}
+
+// SetDeadline sets the deadline of Accept and AcceptContext calls,
+// including the wait for a connection slot limited by SetMaxConns.
+// A zero value for t means Accept will not time out.
+// The slot wait already in progress keeps the previous deadline.
+func (ln *Listener) SetDeadline(t time.Time) error {
+	dl, ok := ln.Listener.(deadliner)
+	if !ok {
+		return ln.opError("set", ErrNotSupported)
+	}
+	ln.limitMu.Lock()
+	defer ln.limitMu.Unlock()
+	ln.deadline = t
+	if ln.interrupts > 0 {
+		return nil // restored when the interruption ends
+	}
+	return dl.SetDeadline(t)
+}
+
+// AcceptContext waits for and returns the next connection to the
+// listener like Accept, but returns when ctx is done.
+//
+// The blocked accept is interrupted by setting the deadline of the
+// listener in the past, and the deadline set by SetDeadline is
+// restored before returning. The Accept and AcceptContext calls
+// blocked in other goroutines resume waiting without noticing the
+// interruption. The returned error wraps ctx.Err() when the accept
+// is interrupted.
+func (ln *Listener) AcceptContext(ctx context.Context) (net.Conn, error) {
+	return ln.accept(ctx)
+}
+
+// acquire waits for a slot of the connection limit l until ctx is
+// done or the deadline, and reports whether it's acquired.
+func (ln *Listener) acquire(ctx context.Context, l *connLimit) (bool, error) {
+	ln.limitMu.Lock()
+	deadline := ln.deadline
+	ln.limitMu.Unlock()
+	var done <-chan struct{}
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return false, ln.opError("accept", err)
+		}
+		done = ctx.Done()
+	}
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return false, ln.opError("accept", os.ErrDeadlineExceeded)
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return true, nil
+	case <-l.done:
+		return false, nil
+	case <-timeout:
+		return false, ln.opError("accept", os.ErrDeadlineExceeded)
+	case <-done:
+		return false, ln.opError("accept", ctx.Err())
+	}
+}
+
+// acceptContext accepts a connection from the underlying listener
+// until ctx is done. It reports whether the accept failed only
+// because of the interruption by another AcceptContext call, and
+// should be retried.
+func (ln *Listener) acceptContext(ctx context.Context) (net.Conn, bool, error) {
+	gen := ln.resume()
+	if ctx == nil || ctx.Done() == nil {
+		c, err := ln.Listener.Accept()
+		return c, err != nil && ln.interrupted(err, gen), err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, ln.opError("accept", err)
+	}
+	dl, ok := ln.Listener.(deadliner)
+	if !ok {
+		return nil, false, ln.opError("accept", ErrNotSupported)
+	}
+	stop := make(chan struct{})
+	interrupted := make(chan bool)
+	go func() {
+		select {
+		case <-ctx.Done():
+			ln.limitMu.Lock()
+			if ln.interrupts == 0 {
+				ln.resumed = make(chan struct{})
+			}
+			ln.interrupts++
+			ln.interruptGen++
+			dl.SetDeadline(aLongTimeAgo)
+			ln.limitMu.Unlock()
+			interrupted <- true
+		case <-stop:
+			interrupted <- false
+		}
+	}()
+	c, err := ln.Listener.Accept()
+	close(stop)
+	if <-interrupted {
+		ln.limitMu.Lock()
+		ln.interrupts--
+		if ln.interrupts == 0 {
+			dl.SetDeadline(ln.deadline)
+			close(ln.resumed)
+		}
+		ln.limitMu.Unlock()
+		if err != nil {
+			return nil, false, ln.opError("accept", ctx.Err())
+		}
+	}
+	return c, err != nil && ln.interrupted(err, gen), err
+}
+
+// resume waits for the interruptions in progress, during which the
+// accept fails immediately, to end, and returns the generation of
+// the interruptions.
+func (ln *Listener) resume() uint64 {
+	ln.limitMu.Lock()
+	defer ln.limitMu.Unlock()
+	for ln.interrupts > 0 {
+		resumed := ln.resumed
+		ln.limitMu.Unlock()
+		<-resumed
+		ln.limitMu.Lock()
+	}
+	return ln.interruptGen
+}
+
+// interrupted reports whether the accept error err is caused by the
+// interruption of another AcceptContext call that started after the
+// generation gen. It waits for the interruption to end.
+func (ln *Listener) interrupted(err error, gen uint64) bool {
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+	ln.limitMu.Lock()
+	ok := ln.interruptGen != gen
+	var resumed chan struct{}
+	if ln.interrupts > 0 {
+		resumed = ln.resumed
+	}
+	ln.limitMu.Unlock()
+	if ok && resumed != nil {
+		<-resumed
+	}
+	return ok
+}
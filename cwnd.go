@@ -0,0 +1,59 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// CongestionWindow returns the sending congestion window in # of
+// segments.
+//
+// Only Linux supports this feature.
+func (c *Conn) CongestionWindow() (int, error) {
+	var w *window
+	err := c.control(func(s uintptr) (err error) {
+		w, err = windows(s)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return w.cwnd, nil
+}
+
+// SlowStartThreshold returns the slow start threshold in # of
+// segments. A large value such as 0x7fffffff means that the
+// connection is still in the initial slow start.
+//
+// Only Linux supports this feature.
+func (c *Conn) SlowStartThreshold() (int, error) {
+	var w *window
+	err := c.control(func(s uintptr) (err error) {
+		w, err = windows(s)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return w.ssthresh, nil
+}
+
+// SendWindow returns the receive window advertised by peer in bytes.
+//
+// Only Linux supports this feature. It requires Linux 5.4 or above.
+func (c *Conn) SendWindow() (int, error) {
+	var w *window
+	err := c.control(func(s uintptr) (err error) {
+		w, err = windows(s)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return w.snd, nil
+}
+
+type window struct {
+	cwnd     int // congestion window in # of segments
+	ssthresh int // slow start threshold in # of segments
+	snd      int // send window in bytes
+}
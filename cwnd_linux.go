@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+func windows(s uintptr) (*window, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	return &window{cwnd: int(ti.Snd_cwnd), ssthresh: int(ti.Snd_ssthresh), snd: int(ti.Snd_wnd)}, nil
+}
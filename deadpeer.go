@@ -0,0 +1,173 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A DeadPeerFunc is called when the kernel aborts the connection c
+// because keepalive probes or the user timeout expired.
+// The info is the last known connection information before the
+// abort, or nil when it is not available.
+type DeadPeerFunc func(c *Conn, info *tcpinfo.Info)
+
+// A DeadPeerDetector represents a connection watched for aborts
+// caused by an unresponsive peer.
+//
+// An abort is detected either by an ETIMEDOUT error returned from
+// Read or Write of the detector, or by polling the connection state
+// at a regular interval. Once an abort is detected, Read and Write
+// return the error that caused it.
+//
+// Polling requires the TCP information option; on the platforms
+// that don't support it, only Read and Write detect aborts.
+type DeadPeerDetector struct {
+	*Conn
+	fn       DeadPeerFunc
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	fireOnce sync.Once
+
+	mu   sync.Mutex
+	info *tcpinfo.Info // last known information
+	err  error         // cause of abort
+}
+
+// NewDeadPeerDetector returns a new detector that polls connection c
+// every interval and calls fn at most once when an abort is detected.
+func NewDeadPeerDetector(c *Conn, interval time.Duration, fn DeadPeerFunc) (*DeadPeerDetector, error) {
+	if interval <= 0 || fn == nil {
+		return nil, errors.New("invalid interval or function")
+	}
+	d := &DeadPeerDetector{
+		Conn: c,
+		fn:   fn,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go d.run(interval)
+	return d, nil
+}
+
+// Read reads data from the connection.
+func (d *DeadPeerDetector) Read(b []byte) (int, error) {
+	n, err := d.Conn.Read(b)
+	if err != nil {
+		err = d.check(err)
+	}
+	return n, err
+}
+
+// Write writes data to the connection.
+func (d *DeadPeerDetector) Write(b []byte) (int, error) {
+	n, err := d.Conn.Write(b)
+	if err != nil {
+		err = d.check(err)
+	}
+	return n, err
+}
+
+// ReadFrom implements the ReadFrom method of io.ReaderFrom interface.
+func (d *DeadPeerDetector) ReadFrom(r io.Reader) (int64, error) {
+	n, err := d.Conn.ReadFrom(r)
+	if err != nil {
+		err = d.check(err)
+	}
+	return n, err
+}
+
+// WriteTo implements the WriteTo method of io.WriterTo interface.
+func (d *DeadPeerDetector) WriteTo(w io.Writer) (int64, error) {
+	n, err := d.Conn.WriteTo(w)
+	if err != nil {
+		err = d.check(err)
+	}
+	return n, err
+}
+
+// Err returns the error that caused the abort, or nil when no abort
+// has been detected.
+func (d *DeadPeerDetector) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Stop stops polling.
+// It doesn't close the connection.
+func (d *DeadPeerDetector) Stop() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	<-d.done
+}
+
+func (d *DeadPeerDetector) run(interval time.Duration) {
+	defer close(d.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if !d.poll() {
+			return
+		}
+		select {
+		case <-t.C:
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// poll records the connection information and reports whether the
+// connection is still worth polling.
+func (d *DeadPeerDetector) poll() bool {
+	var o tcpinfo.Info
+	var b [256]byte
+	io, err := d.Conn.Option(o.Level(), o.Name(), b[:])
+	if err != nil {
+		return false
+	}
+	i := io.(*tcpinfo.Info)
+	if i.State != tcpinfo.Closed {
+		d.mu.Lock()
+		d.info = i
+		d.mu.Unlock()
+		return true
+	}
+	var serr error
+	d.control(func(s uintptr) error { serr = sockError(s); return nil })
+	if errors.Is(serr, syscall.ETIMEDOUT) {
+		d.fire(d.ioError("read", serr))
+	}
+	return false
+}
+
+// check returns the error to be returned from the failed operation
+// with err.
+func (d *DeadPeerDetector) check(err error) error {
+	if errors.Is(err, syscall.ETIMEDOUT) {
+		d.fire(err)
+	}
+	if cause := d.Err(); cause != nil {
+		return cause
+	}
+	return err
+}
+
+func (d *DeadPeerDetector) fire(err error) {
+	d.fireOnce.Do(func() {
+		d.mu.Lock()
+		d.err = err
+		info := d.info
+		d.mu.Unlock()
+		d.fn(d.Conn, info)
+	})
+}
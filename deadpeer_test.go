@@ -0,0 +1,132 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpinfo"
+)
+
+func TestDeadPeerDetector(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp, err := tcp.NewConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fired := make(chan struct{}, 1)
+	d, err := tcp.NewDeadPeerDetector(tc, 10*time.Millisecond, func(*tcp.Conn, *tcpinfo.Info) {
+		fired <- struct{}{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	// A reset by peer is not a dead peer.
+	if err := tp.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	var b [1]byte
+	if _, err := d.Read(b[:]); err == nil {
+		t.Fatal("got nil; want an error")
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-fired:
+		t.Fatal("got a dead peer notification for reset connection")
+	default:
+	}
+	if err := d.Err(); err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+}
+
+func TestVerifyPeer(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	t.Run("Alive", func(t *testing.T) {
+		c, _ := tcptest.Pair(t)
+		want, err := c.KeepAlive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.VerifyPeer(ctx); err != nil {
+			t.Fatal(err)
+		}
+		ka, err := c.KeepAlive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *ka != *want {
+			t.Fatalf("got %+v; want %+v", ka, want)
+		}
+	})
+	t.Run("Closed", func(t *testing.T) {
+		c, p := tcptest.Pair(t)
+		p.Close()
+		time.Sleep(50 * time.Millisecond)
+		if err := c.VerifyPeer(context.Background()); err != io.EOF {
+			t.Fatalf("got %v; want %v", err, io.EOF)
+		}
+	})
+	t.Run("Reset", func(t *testing.T) {
+		c, p := tcptest.Pair(t)
+		p.Abort()
+		time.Sleep(50 * time.Millisecond)
+		if err := c.VerifyPeer(context.Background()); err == nil || err == io.EOF {
+			t.Fatalf("got %v; want reset error", err)
+		}
+	})
+	t.Run("Unresponsive", func(t *testing.T) {
+		c, p := tcptest.Pair(t)
+		if err := p.AttachFilter([]tcp.RawInstruction{{Op: 0x06, K: 0}}); err != nil { // ret #0
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		defer cancel()
+		if err := c.VerifyPeer(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v; want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A DebugInfo represents a snapshot of the connection for logging
+// and error messages.
+type DebugInfo struct {
+	ID         uint64        // identifier of the connection
+	LocalAddr  net.Addr      // local address
+	RemoteAddr net.Addr      // remote address
+	State      tcpinfo.State // kernel state; tcpinfo.Unknown when unavailable
+	Buffered   int           // bytes in the socket read buffer, or -1
+	Available  int           // unused bytes in the socket write buffer, or -1
+}
+
+func (di *DebugInfo) String() string {
+	return fmt.Sprintf("tcp %v->%v %v rbuf=%d wfree=%d id=%#x", di.LocalAddr, di.RemoteAddr, di.State, di.Buffered, di.Available, di.ID)
+}
+
+// DebugInfo returns the snapshot of the connection.
+// The kernel state is read from the TCP information of the
+// connection on the platforms that support it.
+func (c *Conn) DebugInfo() *DebugInfo {
+	di := &DebugInfo{
+		ID:         c.ID(),
+		LocalAddr:  c.LocalAddr(),
+		RemoteAddr: c.RemoteAddr(),
+		Buffered:   c.Buffered(),
+		Available:  c.Available(),
+	}
+	if info, err := connInfo(c); err == nil {
+		di.State = info.State
+	}
+	return di
+}
+
+// String returns the description of the connection, which consists
+// of the 4-tuple, the kernel state, the depths of the socket buffers
+// and the identifier, such as
+//
+//	tcp 192.0.2.1:49152->192.0.2.2:80 established rbuf=0 wfree=2626560 id=0x1001
+func (c *Conn) String() string { return c.DebugInfo().String() }
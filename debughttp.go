@@ -0,0 +1,144 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// debugEntry is a connection rendered by the handler returned from
+// DebugHandler.
+type debugEntry struct {
+	ID          uint64            `json:"id"`
+	Local       string            `json:"local"`
+	Remote      string            `json:"remote"`
+	State       string            `json:"state"`
+	Labels      Labels            `json:"labels,omitempty"`
+	Registered  time.Time         `json:"registered"`
+	Buffered    int               `json:"buffered"`  // bytes in the socket read buffer, or -1
+	Available   int               `json:"available"` // unused bytes in the socket write buffer, or -1
+	RTT         time.Duration     `json:"rtt"`
+	RTTVar      time.Duration     `json:"rttvar"`
+	Retransmits uint64            `json:"retransmits"`
+	Options     map[string]string `json:"options,omitempty"`
+}
+
+// DebugHandler returns an HTTP handler that renders the connections
+// in the registry r, such as their 4-tuples, kernel states, depths of
+// socket buffers, RTTs, retransmitted segments and current values of
+// socket options, similar to the handlers of net/http/pprof package.
+//
+// The handler renders plain text by default, and JSON when the query
+// parameter "format" is "json". The other query parameters select the
+// connections by labels; for example, "/debug/tcp?tenant=blue"
+// renders the connections labeled tenant=blue only.
+//
+// The handler is usually registered at "/debug/tcp", such as
+//
+//	http.Handle("/debug/tcp", tcp.DebugHandler(r))
+func DebugHandler(r *ConnRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		var sel Labels
+		for k, vs := range q {
+			if k == "format" || len(vs) == 0 {
+				continue
+			}
+			if sel == nil {
+				sel = make(Labels)
+			}
+			sel[k] = vs[0]
+		}
+		es := debugEntries(r.Conns(sel))
+		if q.Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(es)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		fmt.Fprintf(bw, "%d connections\n", len(es))
+		for i := range es {
+			es[i].writeText(bw)
+		}
+		bw.Flush()
+	})
+}
+
+// debugEntries returns the entries of rcs sorted by the registration
+// time.
+func debugEntries(rcs []RegisteredConn) []debugEntry {
+	sort.Slice(rcs, func(i, j int) bool { return rcs[i].Registered.Before(rcs[j].Registered) })
+	es := make([]debugEntry, 0, len(rcs))
+	for _, rc := range rcs {
+		di := rc.Conn.DebugInfo()
+		e := debugEntry{
+			ID:         di.ID,
+			Local:      di.LocalAddr.String(),
+			Remote:     di.RemoteAddr.String(),
+			State:      di.State.String(),
+			Labels:     rc.Labels,
+			Registered: rc.Registered,
+			Buffered:   di.Buffered,
+			Available:  di.Available,
+		}
+		if info, err := connInfo(rc.Conn); err == nil {
+			e.RTT, e.RTTVar = info.RTT, info.RTTVar
+			e.Retransmits = counters(info).Retransmits
+		}
+		if opts, err := rc.Conn.Options(); err == nil {
+			e.Options = make(map[string]string, len(opts))
+			for _, o := range opts {
+				e.Options[reflect.TypeOf(o).Name()] = optionString(o)
+			}
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+// optionString returns the value of the option o in text.
+// The options defined as time.Duration are formatted as durations.
+func optionString(o tcpopt.Option) string {
+	if v := reflect.ValueOf(o); v.Kind() == reflect.Int64 {
+		return time.Duration(v.Int()).String()
+	}
+	return fmt.Sprint(o)
+}
+
+// writeText writes the entry in the form of
+//
+//	tcp 192.0.2.1:49152->192.0.2.2:80 established rbuf=0 wfree=2626560 rtt=1ms rttvar=500µs retrans=0 id=0x1001 peer=db
+//		KeepAlive=true NoDelay=true ...
+func (e *debugEntry) writeText(w *bufio.Writer) {
+	fmt.Fprintf(w, "tcp %s->%s %s rbuf=%d wfree=%d rtt=%v rttvar=%v retrans=%d id=%#x", e.Local, e.Remote, e.State, e.Buffered, e.Available, e.RTT, e.RTTVar, e.Retransmits, e.ID)
+	keys := make([]string, 0, len(e.Labels))
+	for k := range e.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, " %s=%s", k, e.Labels[k])
+	}
+	w.WriteByte('\n')
+	if len(e.Options) == 0 {
+		return
+	}
+	opts := make([]string, 0, len(e.Options))
+	for name, v := range e.Options {
+		opts = append(opts, name+"="+v)
+	}
+	sort.Strings(opts)
+	fmt.Fprintf(w, "\t%s\n", strings.Join(opts, " "))
+}
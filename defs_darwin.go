@@ -13,8 +13,10 @@ package tcp
 /*
 #include <sys/ioctl.h>
 #include <sys/socket.h>
+#include <sys/syscall.h>
 
 #include <netinet/in.h>
+#include <netinet/tcp.h>
 
 union pf_state_xport {
 	u_int16_t port;
@@ -48,15 +50,36 @@ import "C"
 const (
 	sysSOL_SOCKET = C.SOL_SOCKET
 
+	sysSO_REUSEADDR = C.SO_REUSEADDR
+	sysSO_REUSEPORT = C.SO_REUSEPORT
+	sysSO_SNDLOWAT  = C.SO_SNDLOWAT
+	sysSO_RCVLOWAT  = C.SO_RCVLOWAT
+	sysSO_OOBINLINE = C.SO_OOBINLINE
+
 	sysFIONREAD = C.FIONREAD
 
 	sysSO_NREAD     = C.SO_NREAD
 	sysSO_NWRITE    = C.SO_NWRITE
 	sysSO_NUMRCVPKT = C.SO_NUMRCVPKT
 
+	sysTCP_NOOPT             = C.TCP_NOOPT
+	sysTCP_CONNECTIONTIMEOUT = C.TCP_CONNECTIONTIMEOUT
+	sysTCP_RXT_CONNDROPTIME  = C.TCP_RXT_CONNDROPTIME
+	sysTCP_ENABLE_ECN        = C.TCP_ENABLE_ECN
+	sysTCP_CONNECTION_INFO   = C.TCP_CONNECTION_INFO
+
+	sysTCPCI_OPT_ECN = C.TCPCI_OPT_ECN
+
 	sysAF_INET  = C.AF_INET
 	sysAF_INET6 = C.AF_INET6
 
+	sysSYS_CONNECTX = C.SYS_connectx
+
+	sysSAE_ASSOCID_ANY = C.SAE_ASSOCID_ANY
+
+	sysCONNECT_RESUME_ON_READ_WRITE = C.CONNECT_RESUME_ON_READ_WRITE
+	sysCONNECT_DATA_IDEMPOTENT      = C.CONNECT_DATA_IDEMPOTENT
+
 	sysPF_INOUT = 0
 	sysPF_IN    = 1
 	sysPF_OUT   = 2
@@ -74,10 +97,13 @@ type sockaddrInet6 C.struct_sockaddr_in6
 
 type pfiocNatlook C.struct_pfioc_natlook
 
+type saEndpoints C.sa_endpoints_t
+
 const (
 	sizeofSockaddrStorage = C.sizeof_struct_sockaddr_storage
 	sizeofSockaddr        = C.sizeof_struct_sockaddr
 	sizeofSockaddrInet    = C.sizeof_struct_sockaddr_in
 	sizeofSockaddrInet6   = C.sizeof_struct_sockaddr_in6
 	sizeofPfiocNatlook    = C.sizeof_struct_pfioc_natlook
+	sizeofSaEndpoints     = C.sizeof_sa_endpoints_t
 )
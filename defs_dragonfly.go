@@ -12,16 +12,28 @@ package tcp
 
 /*
 #include <sys/ioctl.h>
+#include <sys/socket.h>
 
 #include <net/if.h>
 
 #include <netinet/in.h>
+#include <netinet/tcp.h>
 
 #include <net/pf/pfvar.h>
 */
 import "C"
 
 const (
+	sysSOL_SOCKET = C.SOL_SOCKET
+
+	sysSO_REUSEADDR = C.SO_REUSEADDR
+	sysSO_REUSEPORT = C.SO_REUSEPORT
+	sysSO_SNDLOWAT  = C.SO_SNDLOWAT
+	sysSO_RCVLOWAT  = C.SO_RCVLOWAT
+	sysSO_OOBINLINE = C.SO_OOBINLINE
+
+	sysTCP_KEEPINIT = C.TCP_KEEPINIT
+
 	sysFIONREAD = C.FIONREAD
 
 	sysAF_INET  = C.AF_INET
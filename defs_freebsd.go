@@ -18,12 +18,39 @@ package tcp
 #include <net/pfvar.h>
 
 #include <netinet/in.h>
+#include <netinet/tcp.h>
+#include <netinet/tcp_log_buf.h>
 
 #include <netpfil/pf/pf.h>
 */
 import "C"
 
 const (
+	sysSOL_SOCKET = C.SOL_SOCKET
+
+	sysSO_REUSEADDR    = C.SO_REUSEADDR
+	sysSO_REUSEPORT    = C.SO_REUSEPORT
+	sysSO_REUSEPORT_LB = C.SO_REUSEPORT_LB
+	sysSO_SNDLOWAT     = C.SO_SNDLOWAT
+	sysSO_RCVLOWAT     = C.SO_RCVLOWAT
+	sysSO_OOBINLINE    = C.SO_OOBINLINE
+	sysSO_TYPE         = C.SO_TYPE
+	sysSO_PROTOCOL     = C.SO_PROTOCOL
+	sysSO_DOMAIN       = C.SO_DOMAIN
+	sysSO_USER_COOKIE  = C.SO_USER_COOKIE
+
+	sysTCP_KEEPINIT = C.TCP_KEEPINIT
+
+	sysTCP_FUNCTION_BLK          = C.TCP_FUNCTION_BLK
+	sysTCP_FUNCTION_NAME_LEN_MAX = C.TCP_FUNCTION_NAME_LEN_MAX
+
+	sysTCP_LOG            = C.TCP_LOG
+	sysTCP_LOGBUF         = C.TCP_LOGBUF
+	sysTCP_LOGID          = C.TCP_LOGID
+	sysTCP_LOGDUMP        = C.TCP_LOGDUMP
+	sysTCP_LOG_ID_LEN     = C.TCP_LOG_ID_LEN
+	sysTCP_LOG_REASON_LEN = C.TCP_LOG_REASON_LEN
+
 	sysFIONREAD  = C.FIONREAD
 	sysFIONWRITE = C.FIONWRITE
 	sysFIONSPACE = C.FIONSPACE
@@ -55,4 +82,5 @@ const (
 	sizeofSockaddrInet    = C.sizeof_struct_sockaddr_in
 	sizeofSockaddrInet6   = C.sizeof_struct_sockaddr_in6
 	sizeofPfiocNatlook    = C.sizeof_struct_pfioc_natlook
+	sizeofTCPFunctionSet  = C.sizeof_struct_tcp_function_set
 )
@@ -10,24 +10,168 @@ package tcp
 // +godefs map struct_in6_addr [16]byte /* in6_addr */
 
 /*
+#define _GNU_SOURCE
+#include <fcntl.h>
+#include <sys/epoll.h>
 #include <sys/ioctl.h>
 #include <sys/socket.h>
 
+#include <linux/bpf.h>
+#include <linux/errqueue.h>
 #include <linux/if.h>
+#include <linux/inet_diag.h>
 #include <linux/in.h>
 #include <linux/in6.h>
 #include <linux/netfilter_ipv4.h>
 #include <linux/netfilter_ipv6/ip6_tables.h>
+#include <linux/smc_diag.h>
+#include <linux/sock_diag.h>
 #include <linux/sockios.h>
+#include <linux/tcp.h>
 */
 import "C"
 
 const (
-	sysSIOCINQ  = C.SIOCINQ
-	sysSIOCOUTQ = C.SIOCOUTQ
+	sysSIOCINQ     = C.SIOCINQ
+	sysSIOCOUTQ    = C.SIOCOUTQ
+	sysSIOCOUTQNSD = C.SIOCOUTQNSD
+
+	sysSOL_SOCKET = C.SOL_SOCKET
+
+	sysSO_ATTACH_FILTER = C.SO_ATTACH_FILTER
+	sysSO_DETACH_FILTER = C.SO_DETACH_FILTER
+	sysSO_ATTACH_BPF    = C.SO_ATTACH_BPF
+	sysSO_COOKIE        = C.SO_COOKIE
+	sysSO_MEMINFO       = C.SO_MEMINFO
+	sysSO_REUSEADDR     = C.SO_REUSEADDR
+	sysSO_REUSEPORT     = C.SO_REUSEPORT
+	sysSO_RCVLOWAT      = C.SO_RCVLOWAT
+	sysSO_OOBINLINE     = C.SO_OOBINLINE
+	sysSO_TIMESTAMPNS   = C.SO_TIMESTAMPNS
+	sysSO_ZEROCOPY      = C.SO_ZEROCOPY
+	sysSO_SNDBUFFORCE   = C.SO_SNDBUFFORCE
+	sysSO_RCVBUFFORCE   = C.SO_RCVBUFFORCE
+	sysSO_PRIORITY      = C.SO_PRIORITY
+	sysSO_DOMAIN        = C.SO_DOMAIN
+	sysSO_TYPE          = C.SO_TYPE
+	sysSO_PROTOCOL      = C.SO_PROTOCOL
+	sysSO_BINDTODEVICE  = C.SO_BINDTODEVICE
+
+	sysSO_MAX_PACING_RATE = C.SO_MAX_PACING_RATE
+
+	sysSO_ATTACH_REUSEPORT_CBPF = C.SO_ATTACH_REUSEPORT_CBPF
+	sysSO_ATTACH_REUSEPORT_EBPF = C.SO_ATTACH_REUSEPORT_EBPF
+	sysSO_DETACH_REUSEPORT_BPF  = C.SO_DETACH_REUSEPORT_BPF
+
+	sysBPF_OBJ_GET            = C.BPF_OBJ_GET
+	sysBPF_MAP_UPDATE_ELEM    = C.BPF_MAP_UPDATE_ELEM
+	sysBPF_MAP_DELETE_ELEM    = C.BPF_MAP_DELETE_ELEM
+	sysBPF_PROG_ATTACH        = C.BPF_PROG_ATTACH
+	sysBPF_PROG_DETACH        = C.BPF_PROG_DETACH
+	sysBPF_OBJ_GET_INFO_BY_FD = C.BPF_OBJ_GET_INFO_BY_FD
+
+	sysBPF_MAP_TYPE_SOCKMAP  = C.BPF_MAP_TYPE_SOCKMAP
+	sysBPF_MAP_TYPE_SOCKHASH = C.BPF_MAP_TYPE_SOCKHASH
+
+	sysBPF_SK_SKB_STREAM_PARSER  = C.BPF_SK_SKB_STREAM_PARSER
+	sysBPF_SK_SKB_STREAM_VERDICT = C.BPF_SK_SKB_STREAM_VERDICT
+	sysBPF_SK_MSG_VERDICT        = C.BPF_SK_MSG_VERDICT
+	sysBPF_SK_SKB_VERDICT        = C.BPF_SK_SKB_VERDICT
+
+	sysEPOLLET = C.EPOLLET
+
+	sysSPLICE_F_MOVE     = C.SPLICE_F_MOVE
+	sysSPLICE_F_NONBLOCK = C.SPLICE_F_NONBLOCK
+
+	sysSK_MEMINFO_VARS = C.SK_MEMINFO_VARS
+
+	sysIP_MTU_DISCOVER   = C.IP_MTU_DISCOVER
+	sysIP_MTU            = C.IP_MTU
+	sysIPV6_MTU_DISCOVER = C.IPV6_MTU_DISCOVER
+	sysIPV6_MTU          = C.IPV6_MTU
+	sysIP_RECVERR        = C.IP_RECVERR
+	sysIPV6_RECVERR      = C.IPV6_RECVERR
+
+	sysIPV6_FLOWINFO      = C.IPV6_FLOWINFO
+	sysIPV6_FLOWLABEL_MGR = C.IPV6_FLOWLABEL_MGR
+	sysIPV6_FLOWINFO_SEND = C.IPV6_FLOWINFO_SEND
+
+	sysIPV6_FL_A_GET    = C.IPV6_FL_A_GET
+	sysIPV6_FL_S_ANY    = C.IPV6_FL_S_ANY
+	sysIPV6_FL_F_CREATE = C.IPV6_FL_F_CREATE
+	sysIPV6_FL_F_REMOTE = C.IPV6_FL_F_REMOTE
+
+	sysSO_EE_ORIGIN_LOCAL = C.SO_EE_ORIGIN_LOCAL
+	sysSO_EE_ORIGIN_ICMP  = C.SO_EE_ORIGIN_ICMP
+	sysSO_EE_ORIGIN_ICMP6 = C.SO_EE_ORIGIN_ICMP6
+
+	sysIP_PMTUDISC_DONT  = C.IP_PMTUDISC_DONT
+	sysIP_PMTUDISC_WANT  = C.IP_PMTUDISC_WANT
+	sysIP_PMTUDISC_DO    = C.IP_PMTUDISC_DO
+	sysIP_PMTUDISC_PROBE = C.IP_PMTUDISC_PROBE
 
 	sysSO_ORIGINAL_DST      = C.SO_ORIGINAL_DST
 	sysIP6T_SO_ORIGINAL_DST = C.IP6T_SO_ORIGINAL_DST
+
+	sysTCP_INFO               = C.TCP_INFO
+	sysTCP_MD5SIG             = C.TCP_MD5SIG
+	sysTCP_MD5SIG_EXT         = C.TCP_MD5SIG_EXT
+	sysTCP_WINDOW_CLAMP       = C.TCP_WINDOW_CLAMP
+	sysTCP_LINGER2            = C.TCP_LINGER2
+	sysTCP_USER_TIMEOUT       = C.TCP_USER_TIMEOUT
+	sysTCP_FASTOPEN_CONNECT   = C.TCP_FASTOPEN_CONNECT
+	sysTCP_FASTOPEN_NO_COOKIE = C.TCP_FASTOPEN_NO_COOKIE
+	sysTCP_RTO_MIN_US         = C.TCP_RTO_MIN_US
+	sysTCP_ULP                = C.TCP_ULP
+	sysTCP_DEFER_ACCEPT       = C.TCP_DEFER_ACCEPT
+	sysTCP_SAVE_SYN           = C.TCP_SAVE_SYN
+	sysTCP_SAVED_SYN          = C.TCP_SAVED_SYN
+
+	sysTCPI_OPT_TIMESTAMPS = C.TCPI_OPT_TIMESTAMPS
+	sysTCPI_OPT_SACK       = C.TCPI_OPT_SACK
+	sysTCPI_OPT_WSCALE     = C.TCPI_OPT_WSCALE
+	sysTCPI_OPT_ECN        = C.TCPI_OPT_ECN
+	sysTCPI_OPT_ECN_SEEN   = C.TCPI_OPT_ECN_SEEN
+	sysTCPI_OPT_SYN_DATA   = C.TCPI_OPT_SYN_DATA
+
+	sysTCP_MD5SIG_FLAG_PREFIX  = C.TCP_MD5SIG_FLAG_PREFIX
+	sysTCP_MD5SIG_FLAG_IFINDEX = C.TCP_MD5SIG_FLAG_IFINDEX
+	sysTCP_MD5SIG_MAXKEYLEN    = C.TCP_MD5SIG_MAXKEYLEN
+
+	sysTCP_ESTABLISHED = C.TCP_ESTABLISHED
+	sysTCP_SYN_SENT    = C.TCP_SYN_SENT
+	sysTCP_SYN_RECV    = C.TCP_SYN_RECV
+	sysTCP_FIN_WAIT1   = C.TCP_FIN_WAIT1
+	sysTCP_FIN_WAIT2   = C.TCP_FIN_WAIT2
+	sysTCP_TIME_WAIT   = C.TCP_TIME_WAIT
+	sysTCP_CLOSE       = C.TCP_CLOSE
+	sysTCP_CLOSE_WAIT  = C.TCP_CLOSE_WAIT
+	sysTCP_LAST_ACK    = C.TCP_LAST_ACK
+	sysTCP_LISTEN      = C.TCP_LISTEN
+	sysTCP_CLOSING     = C.TCP_CLOSING
+
+	sysNETLINK_INET_DIAG   = C.NETLINK_INET_DIAG
+	sysSOCK_DIAG_BY_FAMILY = C.SOCK_DIAG_BY_FAMILY
+	sysSOCK_DESTROY        = C.SOCK_DESTROY
+
+	sysAF_SMC = C.AF_SMC
+
+	sysSMC_DIAG_MODE_SMCR         = C.SMC_DIAG_MODE_SMCR
+	sysSMC_DIAG_MODE_FALLBACK_TCP = C.SMC_DIAG_MODE_FALLBACK_TCP
+	sysSMC_DIAG_MODE_SMCD         = C.SMC_DIAG_MODE_SMCD
+
+	sysSKNLGRP_INET_TCP_DESTROY  = C.SKNLGRP_INET_TCP_DESTROY
+	sysSKNLGRP_INET6_TCP_DESTROY = C.SKNLGRP_INET6_TCP_DESTROY
+
+	sysINET_DIAG_REQ_BYTECODE = C.INET_DIAG_REQ_BYTECODE
+	sysINET_DIAG_INFO         = C.INET_DIAG_INFO
+
+	sysINET_DIAG_BC_S_GE   = C.INET_DIAG_BC_S_GE
+	sysINET_DIAG_BC_S_LE   = C.INET_DIAG_BC_S_LE
+	sysINET_DIAG_BC_D_GE   = C.INET_DIAG_BC_D_GE
+	sysINET_DIAG_BC_D_LE   = C.INET_DIAG_BC_D_LE
+	sysINET_DIAG_BC_S_COND = C.INET_DIAG_BC_S_COND
+	sysINET_DIAG_BC_D_COND = C.INET_DIAG_BC_D_COND
 )
 
 type sockaddrStorage C.struct_sockaddr_storage
@@ -38,9 +182,38 @@ type sockaddrInet C.struct_sockaddr_in
 
 type sockaddrInet6 C.struct_sockaddr_in6
 
+type tcpInfo C.struct_tcp_info
+
+type inetDiagSockID C.struct_inet_diag_sockid
+
+type inetDiagReqV2 C.struct_inet_diag_req_v2
+
+type inetDiagMsg C.struct_inet_diag_msg
+
+type inetDiagBcOp C.struct_inet_diag_bc_op
+
+type smcDiagReq C.struct_smc_diag_req
+
+type smcDiagMsg C.struct_smc_diag_msg
+
+type inetDiagHostcond C.struct_inet_diag_hostcond
+
+type sockExtendedErr C.struct_sock_extended_err
+
 const (
-	sizeofSockaddrStorage = C.sizeof_struct_sockaddr_storage
-	sizeofSockaddr        = C.sizeof_struct_sockaddr
-	sizeofSockaddrInet    = C.sizeof_struct_sockaddr_in
-	sizeofSockaddrInet6   = C.sizeof_struct_sockaddr_in6
+	sizeofSockaddrStorage  = C.sizeof_struct_sockaddr_storage
+	sizeofSockaddr         = C.sizeof_struct_sockaddr
+	sizeofSockaddrInet     = C.sizeof_struct_sockaddr_in
+	sizeofSockaddrInet6    = C.sizeof_struct_sockaddr_in6
+	sizeofIn6FlowlabelReq  = C.sizeof_struct_in6_flowlabel_req
+	sizeofTCPInfo          = C.sizeof_struct_tcp_info
+	sizeofInetDiagSockID   = C.sizeof_struct_inet_diag_sockid
+	sizeofInetDiagReqV2    = C.sizeof_struct_inet_diag_req_v2
+	sizeofInetDiagMsg      = C.sizeof_struct_inet_diag_msg
+	sizeofInetDiagBcOp     = C.sizeof_struct_inet_diag_bc_op
+	sizeofSMCDiagReq       = C.sizeof_struct_smc_diag_req
+	sizeofSMCDiagMsg       = C.sizeof_struct_smc_diag_msg
+	sizeofInetDiagHostcond = C.sizeof_struct_inet_diag_hostcond
+	sizeofTCPMD5Sig        = C.sizeof_struct_tcp_md5sig
+	sizeofSockExtendedErr  = C.sizeof_struct_sock_extended_err
 )
@@ -8,10 +8,19 @@ package tcp
 
 /*
 #include <sys/ioctl.h>
+#include <sys/socket.h>
 */
 import "C"
 
 const (
+	sysSOL_SOCKET = C.SOL_SOCKET
+
+	sysSO_REUSEADDR = C.SO_REUSEADDR
+	sysSO_REUSEPORT = C.SO_REUSEPORT
+	sysSO_SNDLOWAT  = C.SO_SNDLOWAT
+	sysSO_RCVLOWAT  = C.SO_RCVLOWAT
+	sysSO_OOBINLINE = C.SO_OOBINLINE
+
 	sysFIONREAD  = C.FIONREAD
 	sysFIONWRITE = C.FIONWRITE
 	sysFIONSPACE = C.FIONSPACE
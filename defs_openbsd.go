@@ -22,6 +22,14 @@ package tcp
 import "C"
 
 const (
+	sysSOL_SOCKET = C.SOL_SOCKET
+
+	sysSO_REUSEADDR = C.SO_REUSEADDR
+	sysSO_REUSEPORT = C.SO_REUSEPORT
+	sysSO_SNDLOWAT  = C.SO_SNDLOWAT
+	sysSO_RCVLOWAT  = C.SO_RCVLOWAT
+	sysSO_OOBINLINE = C.SO_OOBINLINE
+
 	sysFIONREAD = C.FIONREAD
 
 	sysAF_INET  = C.AF_INET
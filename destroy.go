@@ -0,0 +1,105 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+)
+
+// DestroyConn terminates the TCP connection on the host identified by
+// the local address laddr and the remote address raddr, as "ss -K"
+// does. The kernel resets the connection and the owner of the
+// connection gets ECONNABORTED on the next operation.
+//
+// Only Linux supports this feature. It requires the CAP_NET_ADMIN
+// capability and the kernel built with CONFIG_INET_DIAG_DESTROY.
+func DestroyConn(laddr, raddr *net.TCPAddr) error {
+	if laddr == nil || raddr == nil || (laddr.IP.To4() == nil) != (raddr.IP.To4() == nil) {
+		return &net.OpError{Op: "destroy", Net: "tcp", Source: laddr, Addr: raddr, Err: errors.New("invalid address pair")}
+	}
+	if err := sockDestroy(laddr, raddr); err != nil {
+		return &net.OpError{Op: "destroy", Net: "tcp", Source: laddr, Addr: raddr, Err: err}
+	}
+	return nil
+}
+
+// A DestroyWatcher notifies the destruction of tracked connections
+// by using the sock_diag destroy notifications.
+// The kernel sends a notification when it releases the socket of
+// connection, for example, when the connection is reset and no longer
+// referenced, or when the connection leaves the TIME-WAIT state.
+//
+// Only Linux supports this feature. It requires the CAP_NET_ADMIN
+// capability.
+type DestroyWatcher struct {
+	f *os.File // sock_diag destroy notification socket
+
+	mu      sync.Mutex
+	watches map[uint64]*destroyWatch
+}
+
+type destroyWatch struct {
+	c  *Conn
+	fn func(*Conn)
+}
+
+// NewDestroyWatcher returns a new watcher and starts receiving
+// destroy notifications.
+func NewDestroyWatcher() (*DestroyWatcher, error) {
+	f, err := listenDestroy()
+	if err != nil {
+		return nil, err
+	}
+	w := &DestroyWatcher{f: f, watches: make(map[uint64]*destroyWatch)}
+	go readDestroy(f, w.notify)
+	return w, nil
+}
+
+// Watch starts tracking the connection c.
+// The function fn is called once from a goroutine of the watcher
+// when the connection is destroyed.
+func (w *DestroyWatcher) Watch(c *Conn, fn func(c *Conn)) error {
+	cookie, err := c.Cookie()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.watches[cookie]; ok {
+		return c.opError("watch", errors.New("already watched"))
+	}
+	w.watches[cookie] = &destroyWatch{c: c, fn: fn}
+	return nil
+}
+
+// Unwatch stops tracking the connection c.
+func (w *DestroyWatcher) Unwatch(c *Conn) error {
+	cookie, err := c.Cookie()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	delete(w.watches, cookie)
+	w.mu.Unlock()
+	return nil
+}
+
+// Close stops receiving destroy notifications.
+func (w *DestroyWatcher) Close() error {
+	return w.f.Close()
+}
+
+func (w *DestroyWatcher) notify(cookie uint64) {
+	w.mu.Lock()
+	dw := w.watches[cookie]
+	delete(w.watches, cookie)
+	w.mu.Unlock()
+	if dw != nil {
+		dw.fn(dw.c)
+	}
+}
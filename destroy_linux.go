@@ -0,0 +1,68 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func sockDestroy(la, ra *net.TCPAddr) error { return inetDiagDestroy(la, ra) }
+
+func listenDestroy() (*os.File, error) {
+	s, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK, sysNETLINK_INET_DIAG)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	sa := syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: 1<<(sysSKNLGRP_INET_TCP_DESTROY-1) | 1<<(sysSKNLGRP_INET6_TCP_DESTROY-1),
+	}
+	if err := syscall.Bind(s, &sa); err != nil {
+		syscall.Close(s)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	return os.NewFile(uintptr(s), "sock_diag"), nil
+}
+
+// readDestroy calls fn with the socket cookie of each destroy
+// notification until f is closed.
+func readDestroy(f *os.File, fn func(uint64)) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return
+	}
+	b := make([]byte, 8*os.Getpagesize())
+	for {
+		var n int
+		var operr error
+		err := rc.Read(func(s uintptr) bool {
+			n, _, operr = syscall.Recvfrom(int(s), b, 0)
+			return operr != syscall.EAGAIN
+		})
+		if err != nil {
+			return
+		}
+		if operr != nil {
+			if operr == syscall.ENOBUFS || operr == syscall.EINTR {
+				continue // notifications overran the receive buffer
+			}
+			return
+		}
+		msgs, err := syscall.ParseNetlinkMessage(b[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			if m.Header.Type != sysSOCK_DIAG_BY_FAMILY || len(m.Data) < sizeofInetDiagMsg {
+				continue
+			}
+			dm := (*inetDiagMsg)(unsafe.Pointer(&m.Data[0]))
+			fn(uint64(dm.Id.Cookie[1])<<32 | uint64(dm.Id.Cookie[0]))
+		}
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import (
+	"net"
+	"os"
+)
+
+func sockDestroy(la, ra *net.TCPAddr) error { return ErrNotSupported }
+
+func listenDestroy() (*os.File, error) { return nil, ErrNotSupported }
+
+func readDestroy(f *os.File, fn func(uint64)) {}
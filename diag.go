@@ -0,0 +1,57 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A PortRange represents an inclusive range of port numbers.
+// A zero Min or Max means no bound.
+type PortRange struct {
+	Min int
+	Max int
+}
+
+// A SocketFilter represents a filter of TCP sockets.
+// The kernel evaluates the filter and reports only the matching
+// sockets. Nil fields match any socket.
+type SocketFilter struct {
+	States       []tcpinfo.State // connection states
+	LocalPorts   *PortRange      // range of local ports
+	RemotePorts  *PortRange      // range of remote ports
+	LocalPrefix  *net.IPNet      // prefix of local addresses
+	RemotePrefix *net.IPNet      // prefix of remote addresses
+}
+
+// A SocketInfo represents a TCP socket on the host.
+type SocketInfo struct {
+	Local        *net.TCPAddr  // local address
+	Remote       *net.TCPAddr  // remote address
+	State        tcpinfo.State // connection state
+	ReceiveQueue int           // # of bytes in receive queue, or accept queue length on listening socket
+	SendQueue    int           // # of bytes in send queue, or backlog on listening socket
+	UID          int           // user ID of socket owner
+	Inode        int           // inode number of socket
+	Cookie       uint64        // socket cookie
+	Info         *tcpinfo.Info // TCP information; nil when unavailable
+}
+
+// Sockets returns TCP sockets on the host that match the filter f.
+// A nil f matches all the sockets.
+//
+// Only Linux supports this feature.
+func Sockets(f *SocketFilter) ([]SocketInfo, error) {
+	if f == nil {
+		f = &SocketFilter{}
+	}
+	sis, err := sockets(f)
+	if err != nil {
+		return nil, &net.OpError{Op: "get", Net: "tcp", Err: err}
+	}
+	return sis, nil
+}
@@ -0,0 +1,353 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+// inetDiag dumps TCP sockets of the address family that are in one of
+// the states specified by the bit mask states, and calls fn with each
+// socket and its attributes. The dump stops when fn returns false.
+// The bit mask ext requests the extensions, such as INET_DIAG_INFO,
+// reported in the attributes.
+// When bc is not empty, the kernel runs it as the filter bytecode and
+// dumps only the sockets accepted by the filter.
+func inetDiag(family int, states uint32, ext uint8, bc []byte, fn func(*inetDiagMsg, []byte) bool) error {
+	s, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, sysNETLINK_INET_DIAG)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(s)
+	l := syscall.NLMSG_HDRLEN + sizeofInetDiagReqV2
+	if len(bc) > 0 {
+		l += syscall.SizeofRtAttr + len(bc)
+	}
+	b := make([]byte, l)
+	if len(bc) > 0 {
+		rta := (*syscall.RtAttr)(unsafe.Pointer(&b[syscall.NLMSG_HDRLEN+sizeofInetDiagReqV2]))
+		rta.Len = uint16(syscall.SizeofRtAttr + len(bc))
+		rta.Type = sysINET_DIAG_REQ_BYTECODE
+		copy(b[syscall.NLMSG_HDRLEN+sizeofInetDiagReqV2+syscall.SizeofRtAttr:], bc)
+	}
+	nlh := (*syscall.NlMsghdr)(unsafe.Pointer(&b[0]))
+	nlh.Len = uint32(len(b))
+	nlh.Type = sysSOCK_DIAG_BY_FAMILY
+	nlh.Flags = syscall.NLM_F_REQUEST | syscall.NLM_F_DUMP
+	nlh.Seq = 1
+	req := (*inetDiagReqV2)(unsafe.Pointer(&b[syscall.NLMSG_HDRLEN]))
+	req.Family = uint8(family)
+	req.Protocol = ianaProtocolTCP
+	req.Ext = ext
+	req.States = states
+	if err := syscall.Sendto(s, b, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+	b = make([]byte, 8*os.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(s, b, 0)
+		if err != nil {
+			return os.NewSyscallError("recvfrom", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(b[:n])
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return syscall.EINVAL
+				}
+				if errno := -int32(nativeEndian.Uint32(m.Data)); errno != 0 {
+					return os.NewSyscallError("netlink", syscall.Errno(errno))
+				}
+				return nil
+			}
+			if len(m.Data) < sizeofInetDiagMsg {
+				continue
+			}
+			if !fn((*inetDiagMsg)(unsafe.Pointer(&m.Data[0])), m.Data[sizeofInetDiagMsg:]) {
+				return nil
+			}
+		}
+	}
+}
+
+// linuxStates maps the TCP states of Linux kernel to the states of
+// tcpinfo package.
+var linuxStates = [...]tcpinfo.State{
+	sysTCP_ESTABLISHED: tcpinfo.Established,
+	sysTCP_SYN_SENT:    tcpinfo.SynSent,
+	sysTCP_SYN_RECV:    tcpinfo.SynReceived,
+	sysTCP_FIN_WAIT1:   tcpinfo.FinWait1,
+	sysTCP_FIN_WAIT2:   tcpinfo.FinWait2,
+	sysTCP_TIME_WAIT:   tcpinfo.TimeWait,
+	sysTCP_CLOSE:       tcpinfo.Closed,
+	sysTCP_CLOSE_WAIT:  tcpinfo.CloseWait,
+	sysTCP_LAST_ACK:    tcpinfo.LastAck,
+	sysTCP_LISTEN:      tcpinfo.Listen,
+	sysTCP_CLOSING:     tcpinfo.Closing,
+}
+
+func sockets(f *SocketFilter) ([]SocketInfo, error) {
+	states := uint32(1<<uint(len(linuxStates)) - 1)
+	if len(f.States) > 0 {
+		states = 0
+		for _, st := range f.States {
+			i := stateIndex(st)
+			if i < 0 {
+				return nil, fmt.Errorf("unknown state: %v", st)
+			}
+			states |= 1 << uint(i)
+		}
+	}
+	families := []int{syscall.AF_INET, syscall.AF_INET6}
+	for _, p := range []*net.IPNet{f.LocalPrefix, f.RemotePrefix} {
+		if p == nil {
+			continue
+		}
+		family := syscall.AF_INET6
+		if p.IP.To4() != nil {
+			family = syscall.AF_INET
+		}
+		if len(families) > 1 {
+			families = []int{family}
+		} else if families[0] != family {
+			return nil, errors.New("mixed address families")
+		}
+	}
+	var ti tcpinfo.Info
+	var sis []SocketInfo
+	for _, family := range families {
+		err := inetDiag(family, states, 1<<(sysINET_DIAG_INFO-1), diagBytecode(f), func(m *inetDiagMsg, attrs []byte) bool {
+			si := SocketInfo{
+				Local:        m.Id.srcAddr(family),
+				Remote:       m.Id.dstAddr(family),
+				ReceiveQueue: int(m.Rqueue),
+				SendQueue:    int(m.Wqueue),
+				UID:          int(m.Uid),
+				Inode:        int(m.Inode),
+				Cookie:       uint64(m.Id.Cookie[1])<<32 | uint64(m.Id.Cookie[0]),
+			}
+			if int(m.State) < len(linuxStates) {
+				si.State = linuxStates[m.State]
+			}
+			if b := diagAttr(attrs, sysINET_DIAG_INFO); b != nil {
+				if o, err := tcpopt.Parse(ti.Level(), ti.Name(), b); err == nil {
+					si.Info = o.(*tcpinfo.Info)
+				}
+			}
+			sis = append(sis, si)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sis, nil
+}
+
+// diagAttr returns the payload of the attribute typ in the attributes
+// b, or nil.
+func diagAttr(b []byte, typ int) []byte {
+	for len(b) >= syscall.SizeofRtAttr {
+		rta := (*syscall.RtAttr)(unsafe.Pointer(&b[0]))
+		l := int(rta.Len)
+		if l < syscall.SizeofRtAttr || l > len(b) {
+			return nil
+		}
+		if int(rta.Type) == typ {
+			return b[syscall.SizeofRtAttr:l]
+		}
+		l = (l + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+		if l > len(b) {
+			return nil
+		}
+		b = b[l:]
+	}
+	return nil
+}
+
+func stateIndex(st tcpinfo.State) int {
+	for i, s := range linuxStates {
+		if i > 0 && s == st {
+			return i
+		}
+	}
+	return -1
+}
+
+// diagBytecode returns the filter bytecode for f.
+// The bytecode is a conjunction of conditions; each operation either
+// proceeds to the next condition or jumps beyond the end of bytecode
+// to reject the socket.
+func diagBytecode(f *SocketFilter) []byte {
+	var conds [][]byte
+	for _, pc := range []struct {
+		r      *PortRange
+		ge, le int
+	}{
+		{f.LocalPorts, sysINET_DIAG_BC_S_GE, sysINET_DIAG_BC_S_LE},
+		{f.RemotePorts, sysINET_DIAG_BC_D_GE, sysINET_DIAG_BC_D_LE},
+	} {
+		if pc.r == nil {
+			continue
+		}
+		if pc.r.Min > 0 {
+			conds = append(conds, diagPortCond(pc.ge, pc.r.Min))
+		}
+		if pc.r.Max > 0 && pc.r.Max < 0xffff {
+			conds = append(conds, diagPortCond(pc.le, pc.r.Max))
+		}
+	}
+	if f.LocalPrefix != nil {
+		conds = append(conds, diagHostCond(sysINET_DIAG_BC_S_COND, f.LocalPrefix))
+	}
+	if f.RemotePrefix != nil {
+		conds = append(conds, diagHostCond(sysINET_DIAG_BC_D_COND, f.RemotePrefix))
+	}
+	var l int
+	for _, c := range conds {
+		l += len(c)
+	}
+	bc := make([]byte, 0, l)
+	for _, c := range conds {
+		op := (*inetDiagBcOp)(unsafe.Pointer(&c[0]))
+		op.No = uint16(l - len(bc) + sizeofInetDiagBcOp)
+		bc = append(bc, c...)
+	}
+	return bc
+}
+
+// diagPortCond returns the port comparison. The port number is
+// stored in the no field of following operation.
+func diagPortCond(code, port int) []byte {
+	b := make([]byte, 2*sizeofInetDiagBcOp)
+	op := (*inetDiagBcOp)(unsafe.Pointer(&b[0]))
+	op.Code = uint8(code)
+	op.Yes = uint8(len(b))
+	(*inetDiagBcOp)(unsafe.Pointer(&b[sizeofInetDiagBcOp])).No = uint16(port)
+	return b
+}
+
+func diagHostCond(code int, p *net.IPNet) []byte {
+	family, ip := syscall.AF_INET6, p.IP.To16()
+	if ip4 := p.IP.To4(); ip4 != nil {
+		family, ip = syscall.AF_INET, ip4
+	}
+	ones, _ := p.Mask.Size()
+	b := make([]byte, sizeofInetDiagBcOp+sizeofInetDiagHostcond+len(ip))
+	op := (*inetDiagBcOp)(unsafe.Pointer(&b[0]))
+	op.Code = uint8(code)
+	op.Yes = uint8(len(b))
+	hc := (*inetDiagHostcond)(unsafe.Pointer(&b[sizeofInetDiagBcOp]))
+	hc.Family = uint8(family)
+	hc.Prefix_len = uint8(ones)
+	hc.Port = -1
+	copy(b[sizeofInetDiagBcOp+sizeofInetDiagHostcond:], ip)
+	return b
+}
+
+// inetDiagDestroy terminates the TCP socket identified by the local
+// address la and the remote address ra.
+func inetDiagDestroy(la, ra *net.TCPAddr) error {
+	s, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, sysNETLINK_INET_DIAG)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(s)
+	family := addrFamily(la)
+	b := make([]byte, syscall.NLMSG_HDRLEN+sizeofInetDiagReqV2)
+	nlh := (*syscall.NlMsghdr)(unsafe.Pointer(&b[0]))
+	nlh.Len = uint32(len(b))
+	nlh.Type = sysSOCK_DESTROY
+	nlh.Flags = syscall.NLM_F_REQUEST | syscall.NLM_F_ACK
+	nlh.Seq = 1
+	req := (*inetDiagReqV2)(unsafe.Pointer(&b[syscall.NLMSG_HDRLEN]))
+	req.Family = uint8(family)
+	req.Protocol = ianaProtocolTCP
+	req.States = ^uint32(0)
+	req.Id.setAddr(family, &req.Id.Src, &req.Id.Sport, la)
+	req.Id.setAddr(family, &req.Id.Dst, &req.Id.Dport, ra)
+	req.Id.Cookie = [2]uint32{^uint32(0), ^uint32(0)} // INET_DIAG_NOCOOKIE
+	if err := syscall.Sendto(s, b, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+	b = make([]byte, os.Getpagesize())
+	n, _, err := syscall.Recvfrom(s, b, 0)
+	if err != nil {
+		return os.NewSyscallError("recvfrom", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(b[:n])
+	if err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		if m.Header.Type != syscall.NLMSG_ERROR {
+			continue
+		}
+		if len(m.Data) < 4 {
+			return syscall.EINVAL
+		}
+		if errno := -int32(nativeEndian.Uint32(m.Data)); errno != 0 {
+			return os.NewSyscallError("netlink", syscall.Errno(errno))
+		}
+	}
+	return nil
+}
+
+func (id *inetDiagSockID) srcAddr(family int) *net.TCPAddr {
+	return id.addr(family, &id.Src, id.Sport)
+}
+
+func (id *inetDiagSockID) dstAddr(family int) *net.TCPAddr {
+	return id.addr(family, &id.Dst, id.Dport)
+}
+
+func (id *inetDiagSockID) addr(family int, ip *[4]uint32, port uint16) *net.TCPAddr {
+	b := (*[16]byte)(unsafe.Pointer(ip))
+	a := &net.TCPAddr{Port: int(binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&port))[:]))}
+	if family == syscall.AF_INET {
+		a.IP = make(net.IP, net.IPv4len)
+		copy(a.IP, b[:net.IPv4len])
+	} else {
+		a.IP = make(net.IP, net.IPv6len)
+		copy(a.IP, b[:])
+		a.Zone = zoneCache.name(int(id.If))
+	}
+	return a
+}
+
+func (id *inetDiagSockID) setAddr(family int, ip *[4]uint32, port *uint16, a *net.TCPAddr) {
+	b := (*[16]byte)(unsafe.Pointer(ip))
+	binary.BigEndian.PutUint16((*[2]byte)(unsafe.Pointer(port))[:], uint16(a.Port))
+	if family == syscall.AF_INET {
+		copy(b[:], a.IP.To4())
+	} else {
+		copy(b[:], a.IP.To16())
+		if a.Zone != "" {
+			id.If = uint32(zoneCache.index(a.Zone))
+		}
+	}
+}
+
+func addrFamily(a *net.TCPAddr) int {
+	if a.IP.To4() != nil {
+		return syscall.AF_INET
+	}
+	return syscall.AF_INET6
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpinfo"
+)
+
+func TestSockets(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+
+	for _, tt := range []struct {
+		f    tcp.SocketFilter
+		want []net.Addr // local addresses
+	}{
+		{
+			tcp.SocketFilter{States: []tcpinfo.State{tcpinfo.Listen}, LocalPorts: &tcp.PortRange{Min: port, Max: port}},
+			[]net.Addr{ln.Addr()},
+		},
+		{
+			tcp.SocketFilter{States: []tcpinfo.State{tcpinfo.Established}, LocalPorts: &tcp.PortRange{Min: port, Max: port}, LocalPrefix: loopback},
+			[]net.Addr{p.LocalAddr()},
+		},
+		{
+			tcp.SocketFilter{States: []tcpinfo.State{tcpinfo.Established}, RemotePorts: &tcp.PortRange{Min: port, Max: port}, RemotePrefix: loopback},
+			[]net.Addr{c.LocalAddr()},
+		},
+	} {
+		sis, err := tcp.Sockets(&tt.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sis) != len(tt.want) {
+			t.Fatalf("got %v; want %v", sis, tt.want)
+		}
+		for i, si := range sis {
+			if si.Local.String() != tt.want[i].String() {
+				t.Errorf("got %v; want %v", si.Local, tt.want[i])
+			}
+			if si.Info == nil || si.Info.State != si.State {
+				t.Errorf("got %+v; want tcp information in %v state", si.Info, si.State)
+			}
+		}
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	sc := tcp.NewStatsCollector()
+	var conns []*tcp.Conn
+	for i := 0; i < 4; i++ {
+		c, s := tcptest.Pair(t)
+		sc.Add(c)
+		sc.Add(s)
+		conns = append(conns, c, s)
+	}
+	closed := conns[len(conns)-1]
+	sc.Remove(closed)
+	if sc.Len() != len(conns)-1 {
+		t.Fatalf("got %d; want %d", sc.Len(), len(conns)-1)
+	}
+
+	m, err := sc.Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != len(conns)-1 {
+		t.Fatalf("got %d; want %d", len(m), len(conns)-1)
+	}
+	for _, c := range conns[:len(conns)-1] {
+		info, ok := m[c]
+		if !ok {
+			t.Fatalf("no information for %v", c)
+		}
+		if info.State != tcpinfo.Established {
+			t.Fatalf("got %v; want %v", info.State, tcpinfo.Established)
+		}
+	}
+	if _, ok := m[closed]; ok {
+		t.Fatalf("got information for untracked %v", closed)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A Dialer contains options for connecting to an address.
+// Unlike net.Dialer, it sets socket options before connecting and
+// returns a Conn.
+//
+// The socket options are set from the ControlContext hook of
+// net.Dialer. The ControlContext hook of embedded net.Dialer, or the
+// Control hook when ControlContext is nil, is called before setting
+// the options.
+type Dialer struct {
+	net.Dialer
+
+	// Options are the socket options set before connecting.
+	Options []tcpopt.Option
+
+	// FastOpenConnect specifies the use of TCP Fast Open.
+	// When true, the data of the first write on the connection may
+	// be sent in the SYN segment. See FastOpenConnect option.
+	//
+	// On Darwin, the connection is initiated from the Control hook
+	// by connectx with CONNECT_RESUME_ON_READ_WRITE flag, and
+	// LocalAddr must be nil. The connection falls back to the
+	// regular handshake when the running kernel doesn't allow the
+	// use of connectx.
+	FastOpenConnect bool
+
+	// FastOpenNoCookie specifies the use of TCP Fast Open without
+	// Fast Open cookies for trusted networks. It takes effect with
+	// FastOpenConnect. See FastOpenNoCookie option.
+	FastOpenNoCookie bool
+
+	// ReuseAddr and ReusePort specify the use of SO_REUSEADDR and
+	// SO_REUSEPORT options. They are set before the socket is bound
+	// to LocalAddr, which allows multiple connections to share an
+	// explicit local address and port.
+	ReuseAddr bool
+	ReusePort bool
+
+	// RecvErr specifies the reception of extended errors. When
+	// true, ICMP errors such as destination unreachable fail the
+	// connection attempt immediately with the actual cause. See
+	// Conn.SetRecvErr.
+	RecvErr bool
+
+	// Cgroup is the path of a cgroup directory, such as
+	// "/sys/fs/cgroup/net_cls/tenant", in which the socket is
+	// created. A socket is associated with the cgroup of the
+	// creating thread; it carries the net_cls classid of the cgroup
+	// on cgroup v1, and is matched by the cgroup-aware classifiers
+	// on cgroup v2, which requires a threaded cgroup.
+	// When set, the socket is created on a dedicated thread moved to
+	// the cgroup, and the fast fallback of net.Dialer is disabled.
+	//
+	// Only Linux supports this feature.
+	Cgroup string
+
+	// Netns is the name of a network namespace, such as "blue"
+	// created by "ip netns add blue", or the path of a network
+	// namespace file, such as "/proc/1234/ns/net", in which the
+	// socket is created. See ListenNetns.
+	// When set, the socket is created on a dedicated thread moved to
+	// the namespace, and the fast fallback of net.Dialer is disabled.
+	// Host names in the address are not necessarily resolved in the
+	// namespace.
+	//
+	// It requires the CAP_SYS_ADMIN capability.
+	// Only Linux supports this feature.
+	Netns string
+
+	// VRF is the name of a VRF device, such as "vrf-blue", or any
+	// other network interface to which the socket is bound using
+	// SO_BINDTODEVICE option before binding to LocalAddr and
+	// connecting. The connection uses the routing table of the VRF.
+	// See ListenVRF.
+	//
+	// Older kernels than Linux 5.7 require the CAP_NET_RAW
+	// capability.
+	// Only Linux supports this feature.
+	VRF string
+
+	// MD5Key is the key of TCP MD5 signature option for the
+	// connection to the remote address, which is set before
+	// connecting. See MD5Signature.
+	//
+	// Only Linux supports this feature.
+	MD5Key []byte
+
+	// FlowLabel is the IPv6 flow label of outgoing packets on IPv6
+	// connections. Zero means the kernel default. The label is
+	// leased from the flow label manager, which shares a label
+	// among the connections to the same destination, so that ECMP
+	// routers and load balancers keep the connections on the same
+	// path.
+	// When set, the connection is initiated from the Control hook,
+	// and LocalAddr must be nil.
+	//
+	// Only Linux supports this feature.
+	FlowLabel uint32
+
+	// SMC specifies the use of Shared Memory Communications over
+	// RDMA (SMC-R) or over DMA within a system (SMC-D). When true,
+	// the socket is converted to an SMC socket by the "smc" upper
+	// layer protocol before connecting, and the kernel negotiates
+	// SMC with the peer in the TCP handshake. The connection falls
+	// back to TCP transparently when the peer or the path doesn't
+	// support SMC, or when the running kernel doesn't support the
+	// "smc" upper layer protocol. See Conn.Transport.
+	//
+	// Only Linux supports this feature.
+	SMC bool
+
+	// RedirectRecords are the WFP redirect records set before
+	// connecting. A local proxy of the connections redirected by a
+	// WFP callout sets the records of a redirected connection on
+	// its outbound connection to the original destination. See
+	// Conn.RedirectRecords.
+	//
+	// Only Windows supports this feature.
+	RedirectRecords []byte
+
+	// Lookup is the resolver of the host name in the address. A nil
+	// Lookup means the Resolver of net.Dialer, or
+	// net.DefaultResolver.
+	//
+	// When any of Lookup, SelectAddrs, AddrOptions and SourceAddrs
+	// is set, the dialer resolves the host name by itself and tries
+	// the selected addresses one by one in order, instead of the
+	// fast fallback of net.Dialer. The Timeout of net.Dialer applies
+	// to each attempt.
+	Lookup IPResolver
+
+	// SelectAddrs is the policy of selecting the addresses to try
+	// among the resolved addresses. A nil SelectAddrs tries the
+	// addresses of the address family of the network in the order
+	// returned from the resolver.
+	SelectAddrs AddrPolicy
+
+	// AddrOptions returns the socket options set on the connection
+	// to the address ip in addition to Options, which allows the
+	// use of different option profiles per address, such as an
+	// aggressive UserTimeout for anycast endpoints and larger
+	// buffers for distant endpoints.
+	AddrOptions func(ip net.IPAddr) []tcpopt.Option
+
+	// SourceAddrs is the policy of selecting the local address
+	// bound to the socket before connecting to each address, such
+	// as PreferInterface, PreferSubnet and RoundRobin, for the
+	// hosts with multiple uplinks. The selected address replaces the
+	// IP address of LocalAddr. A nil SourceAddrs, or no preference
+	// of the policy, means LocalAddr.
+	SourceAddrs SourcePolicy
+}
+
+// Dial connects to the address on the named network.
+func (d *Dialer) Dial(network, address string) (*Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using the
+// provided context.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (*Conn, error) {
+	if d.resolves() {
+		return d.dialAddrs(ctx, network, address)
+	}
+	nd := d.Dialer
+	var hc handshakeClock
+	nd.Control, nd.ControlContext = nil, hc.control(d.control)
+	var c net.Conn
+	var err error
+	if d.Cgroup != "" || d.Netns != "" {
+		nd.FallbackDelay = -1 // keeps the socket creation on the thread
+		if terr := onThread(d.Netns, d.Cgroup, func() { c, err = nd.DialContext(ctx, network, address) }); terr != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: terr}
+		}
+	} else {
+		c, err = nd.DialContext(ctx, network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now()
+	tc, err := NewConn(c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	hc.measure(tc, end)
+	return tc, nil
+}
+
+func (d *Dialer) options() []tcpopt.Option {
+	if !d.FastOpenConnect && !d.FastOpenNoCookie && !d.ReuseAddr && !d.ReusePort {
+		return d.Options
+	}
+	opts := d.Options[:len(d.Options):len(d.Options)]
+	if d.ReuseAddr {
+		opts = append(opts, ReuseAddress(true))
+	}
+	if d.ReusePort {
+		opts = append(opts, ReusePort(true))
+	}
+	if d.FastOpenConnect && options[soFastOpenConnect].name > 0 {
+		opts = append(opts, FastOpenConnect(true))
+	}
+	if d.FastOpenNoCookie {
+		opts = append(opts, FastOpenNoCookie(true))
+	}
+	return opts
+}
+
+func (d *Dialer) control(ctx context.Context, network, address string, c syscall.RawConn) error {
+	switch {
+	case d.ControlContext != nil:
+		if err := d.ControlContext(ctx, network, address, c); err != nil {
+			return err
+		}
+	case d.Control != nil:
+		if err := d.Control(network, address, c); err != nil {
+			return err
+		}
+	}
+	var operr error
+	if err := c.Control(func(s uintptr) {
+		if operr = setOptions(s, d.options()); operr != nil {
+			return
+		}
+		if d.VRF != "" {
+			if operr = bindToDevice(s, d.VRF); operr != nil {
+				return
+			}
+		}
+		if len(d.MD5Key) > 0 {
+			var ra *net.TCPAddr
+			if ra, operr = net.ResolveTCPAddr(network, address); operr != nil {
+				return
+			}
+			if operr = setMD5Signature(s, &MD5Signature{Addr: ra.IP, Key: d.MD5Key}); operr != nil {
+				return
+			}
+		}
+		level := ianaProtocolIP
+		if network == "tcp6" {
+			level = ianaProtocolIPv6
+		}
+		if d.RecvErr {
+			if operr = setRecvErr(s, level, true); operr != nil {
+				return
+			}
+		}
+		if len(d.RedirectRecords) > 0 {
+			if operr = setRedirectRecords(s, d.RedirectRecords); operr != nil {
+				return
+			}
+		}
+		if d.SMC {
+			if operr = setULP(s, "smc"); isNotSupported(operr) || errors.Is(operr, syscall.ENOENT) {
+				operr = nil // falls back to tcp
+			}
+			if operr != nil {
+				return
+			}
+		}
+		if d.FlowLabel != 0 && network == "tcp6" {
+			if d.LocalAddr != nil {
+				operr = errors.New("flow label with local address")
+				return
+			}
+			operr = connectWithFlowLabel(s, address, d.FlowLabel)
+			return
+		}
+		if d.FastOpenConnect && options[soFastOpenConnect].name < 1 {
+			operr = connectFastOpen(s, network, address, d.LocalAddr)
+		}
+	}); err != nil {
+		return err
+	}
+	return operr
+}
+
+// ControlFunc returns a function that sets the socket options opts
+// on a socket, suitable for the Control hook of net.Dialer and
+// net.ListenConfig.
+// It allows to apply the options declaratively without using Dialer
+// or Listen of this package.
+func ControlFunc(opts ...tcpopt.Option) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var operr error
+		if err := c.Control(func(s uintptr) {
+			operr = setOptions(s, opts)
+		}); err != nil {
+			return err
+		}
+		return operr
+	}
+}
+
+// setOptions sets the socket options opts on the socket s.
+func setOptions(s uintptr, opts []tcpopt.Option) error {
+	for _, o := range opts {
+		b, err := o.Marshal()
+		if err != nil {
+			return err
+		}
+		if err := setsockopt(s, o.Level(), o.Name(), b); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	return nil
+}
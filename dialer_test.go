@@ -0,0 +1,888 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestDialer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.Copy(c, c)
+	}()
+
+	var d tcp.Dialer
+	switch runtime.GOOS {
+	case "darwin":
+		d.FastOpenConnect = true
+	case "linux":
+		d.Options = []tcpopt.Option{tcp.UserTimeout(3 * time.Second)}
+		d.FastOpenConnect = true
+	case "windows":
+		d.Options = []tcpopt.Option{tcp.UserTimeout(3 * time.Second)}
+	}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if runtime.GOOS == "linux" || runtime.GOOS == "windows" {
+		var b [4]byte
+		o, err := c.Option(tcp.UserTimeout(0).Level(), tcp.UserTimeout(0).Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o != tcp.UserTimeout(3*time.Second) {
+			t.Fatalf("got %v; want %v", o, tcp.UserTimeout(3*time.Second))
+		}
+	}
+	m := []byte("HELLO-R-U-THERE")
+	if _, err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, len(m))
+	if _, err := io.ReadFull(c, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, m) {
+		t.Fatalf("got %q; want %q", b, m)
+	}
+}
+
+func TestDialerReuse(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	var lns [2]net.Listener
+	for i := range lns {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer c.Close()
+			}
+		}()
+		lns[i] = ln
+	}
+
+	d := tcp.Dialer{ReuseAddr: true, ReusePort: true}
+	d.LocalAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	c1, err := d.Dial(lns[0].Addr().Network(), lns[0].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	d.LocalAddr = c1.LocalAddr()
+	c2, err := d.Dial(lns[1].Addr().Network(), lns[1].Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if c1.LocalAddr().String() != c2.LocalAddr().String() {
+		t.Fatalf("got %v; want %v", c2.LocalAddr(), c1.LocalAddr())
+	}
+}
+
+func TestControlFunc(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	lc := net.ListenConfig{Control: tcp.ControlFunc(tcp.ReusePort(true))}
+	ln1, err := lc.Listen(context.Background(), "tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln1.Close()
+	ln2, err := lc.Listen(context.Background(), "tcp4", ln1.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln2.Close()
+
+	d := net.Dialer{Control: tcp.ControlFunc(tcpopt.NoDelay(true), tcpopt.KeepAlive(true))}
+	c, err := d.Dial(ln1.Addr().Network(), ln1.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestDialerControlContext(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type key struct{}
+	var called bool
+	d := tcp.Dialer{Options: []tcpopt.Option{tcp.UserTimeout(3 * time.Second)}}
+	d.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		called = ctx.Value(key{}) != nil
+		return nil
+	}
+	c, err := d.DialContext(context.WithValue(context.Background(), key{}, true), ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !called {
+		t.Fatal("ControlContext not called with the dial context")
+	}
+	var b [4]byte
+	o, err := c.Option(tcp.UserTimeout(0).Level(), tcp.UserTimeout(0).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcp.UserTimeout(3*time.Second) {
+		t.Fatalf("got %v; want %v", o, tcp.UserTimeout(3*time.Second))
+	}
+}
+
+func TestDialerInitialRTO(t *testing.T) {
+	o := tcp.InitialRTO{RTT: 500 * time.Millisecond, MaxSynRetransmissions: 2}
+	if runtime.GOOS != "windows" {
+		if _, err := o.Marshal(); err != tcp.ErrNotSupported {
+			t.Fatalf("got %v; want %v", err, tcp.ErrNotSupported)
+		}
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	d := tcp.Dialer{Options: []tcpopt.Option{o}}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if _, err := (tcp.InitialRTO{RTT: time.Minute + 6*time.Second}).Marshal(); err == nil {
+		t.Fatal("got nil; want out of range error")
+	}
+}
+
+func TestDialerFailConnectOnICMPError(t *testing.T) {
+	o := tcp.FailConnectOnICMPError(true)
+	if runtime.GOOS != "windows" {
+		if _, err := o.Marshal(); err != tcp.ErrNotSupported {
+			t.Fatalf("got %v; want %v", err, tcp.ErrNotSupported)
+		}
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	d := tcp.Dialer{Options: []tcpopt.Option{o}}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	e, err := c.ICMPError()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != nil {
+		t.Fatalf("got %v; want no icmp error", e)
+	}
+}
+
+func TestDialerTimestamps(t *testing.T) {
+	o := tcp.Timestamps(false)
+	if runtime.GOOS != "windows" {
+		if _, err := o.Marshal(); err != tcp.ErrNotSupported {
+			t.Fatalf("got %v; want %v", err, tcp.ErrNotSupported)
+		}
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	d := tcp.Dialer{Options: []tcpopt.Option{o}}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var b [4]byte
+	oo, err := c.Option(o.Level(), o.Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oo != o {
+		t.Fatalf("got %v; want %v", oo, o)
+	}
+}
+
+func TestDialerFastOpenNoCookie(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tln.SetOption(tcp.FastOpenNoCookie(true)); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.Copy(c, c)
+	}()
+
+	d := tcp.Dialer{FastOpenConnect: true, FastOpenNoCookie: true}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var b [4]byte
+	o, err := c.Option(tcp.FastOpenNoCookie(false).Level(), tcp.FastOpenNoCookie(false).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcp.FastOpenNoCookie(true) {
+		t.Fatalf("got %v; want %v", o, tcp.FastOpenNoCookie(true))
+	}
+	m := []byte("HELLO-R-U-THERE")
+	if _, err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDialerCgroup(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	d := tcp.Dialer{Cgroup: "/sys/fs/cgroup/non-existent"}
+	if c, err := d.Dial(ln.Addr().Network(), ln.Addr().String()); err == nil {
+		c.Close()
+		t.Fatal("got nil; want an error")
+	}
+
+	var dir string
+	for _, root := range []string{"/sys/fs/cgroup/net_cls", "/sys/fs/cgroup/unified", "/sys/fs/cgroup"} {
+		dir, err = os.MkdirTemp(root, "tcp-test-")
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Skip(err)
+	}
+	defer func() {
+		// The dialing thread leaves the cgroup on its exit, which
+		// takes place asynchronously.
+		for i := 0; i < 50 && os.Remove(dir) != nil; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	os.WriteFile(filepath.Join(dir, "cgroup.type"), []byte("threaded"), 0)
+	d.Cgroup = dir
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Skip(err)
+	}
+	c.Close()
+}
+
+func TestDialerFlowLabel(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	const label = 0x1234
+	ch := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			ch <- err
+			return
+		}
+		defer c.Close()
+		tc, err := tcp.NewConn(c)
+		if err != nil {
+			ch <- err
+			return
+		}
+		if _, err := tc.PeerFlowLabel(); err != nil {
+			ch <- err
+			return
+		}
+		var b [1]byte
+		if _, err := tc.Read(b[:]); err != nil {
+			ch <- err
+			return
+		}
+		l, err := tc.PeerFlowLabel()
+		if err == nil && l != label {
+			err = fmt.Errorf("got %#x; want %#x", l, label)
+		}
+		ch <- err
+	}()
+
+	d := tcp.Dialer{FlowLabel: label}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	l, err := c.FlowLabel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l != label {
+		t.Fatalf("got %#x; want %#x", l, label)
+	}
+	if _, err := c.Write([]byte{0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-ch; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDialerSMC(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.Copy(c, c)
+	}()
+
+	d := tcp.Dialer{SMC: true}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	m := []byte("HELLO-R-U-THERE")
+	if _, err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, m); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := c.Transport()
+	if err != nil {
+		t.Skip(err) // requires the smc_diag module
+	}
+	t.Logf("transport: %v", tr)
+}
+
+func TestDialerVRF(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	if _, err := tcp.L3mdevAccept(); err != nil {
+		t.Log(err)
+	}
+	// The loopback interface stands in for a VRF device.
+	ln, err := tcp.ListenVRF(context.Background(), "tcp", "127.0.0.1:0", "lo")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Close()
+	}()
+
+	d := tcp.Dialer{VRF: "lo"}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	vrf, err := c.VRF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vrf != "lo" {
+		t.Fatalf("got %q; want lo", vrf)
+	}
+
+	d = tcp.Dialer{VRF: "nonexistent0"}
+	if c, err := d.Dial(ln.Addr().Network(), ln.Addr().String()); err == nil {
+		c.Close()
+		t.Fatal("got nil; want unknown device error")
+	}
+}
+
+func TestDialerNetns(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	if _, err := tcp.ListenNetns(context.Background(), "tcp", "127.0.0.1:0", "tcp-test-non-existent"); err == nil {
+		t.Fatal("got nil; want an error")
+	}
+
+	// The network namespace of the process stands in for a named
+	// network namespace.
+	const ns = "/proc/self/ns/net"
+	ln, err := tcp.ListenNetns(context.Background(), "tcp", "127.0.0.1:0", ns)
+	if err != nil {
+		t.Skip(err) // requires CAP_SYS_ADMIN
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Close()
+	}()
+
+	d := tcp.Dialer{Netns: ns}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestDialerMD5Key(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := []byte("HELLO-R-U-THERE")
+	// A key entry for the prefix covers the whole loopback network.
+	if err := tln.SetMD5Signature(&tcp.MD5Signature{Addr: net.IPv4(127, 0, 0, 0), PrefixLen: 8, Key: key}); err != nil {
+		t.Skip(err)
+	}
+	if err := tln.SetMD5Signature(&tcp.MD5Signature{Addr: net.IPv4(127, 0, 0, 0), PrefixLen: 33, Key: key}); err == nil {
+		t.Fatal("got nil; want invalid prefix length error")
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			io.Copy(c, c)
+			c.Close()
+		}
+	}()
+
+	d := tcp.Dialer{MD5Key: key}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Write(key); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, len(key))
+	if _, err := io.ReadFull(c, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// The listener drops the SYN segments without signature.
+	d = tcp.Dialer{Dialer: net.Dialer{Timeout: 200 * time.Millisecond}}
+	if c, err := d.Dial(ln.Addr().Network(), ln.Addr().String()); err == nil {
+		c.Close()
+		t.Fatal("got nil; want timeout error")
+	}
+}
+
+func TestDialerHandshake(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var d tcp.Dialer
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	hs, ok := c.Handshake()
+	if !ok || hs.Duration <= 0 {
+		t.Fatalf("got %+v, %v; want positive duration", hs, ok)
+	}
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd":
+		if hs.RTT <= 0 {
+			t.Fatalf("got %v; want positive rtt", hs.RTT)
+		}
+	}
+	t.Logf("%+v", hs)
+
+	nc, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := tcp.NewConn(nc)
+	if err != nil {
+		nc.Close()
+		t.Fatal(err)
+	}
+	defer tc.Close()
+	if _, ok := tc.Handshake(); ok {
+		t.Fatal("got true; want false for connection not dialed by Dialer")
+	}
+}
+
+func TestReconnectingConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for i := 0; ; i++ {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if i == 0 {
+				c.Close() // fails the first connection
+				continue
+			}
+			go func() {
+				defer c.Close()
+				io.Copy(c, c)
+			}()
+		}
+	}()
+
+	events := make(chan *tcp.ReconnectEvent, 4)
+	var rc *tcp.ReconnectingConn
+	rc, err = tcp.DialReconnecting(context.Background(), &tcp.Dialer{}, ln.Addr().Network(), ln.Addr().String(), &tcp.ReconnectConfig{
+		MinBackoff: time.Millisecond,
+		OnEvent: func(ev *tcp.ReconnectEvent) {
+			if c := rc.Conn(); ev.Conn != nil && c != ev.Conn {
+				t.Errorf("got %v; want %v", c, ev.Conn)
+			}
+			events <- ev
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if err := rc.SetOption(tcpopt.NoDelay(false)); err != nil {
+		t.Fatal(err)
+	}
+	first := rc.Conn()
+
+	b := make([]byte, 5)
+	done := make(chan error)
+	go func() {
+		_, err := io.ReadFull(rc, b) // continues on the new connection
+		done <- err
+	}()
+	if ev := <-events; ev.Err != nil || ev.Conn == nil || ev.Attempt != 1 || ev.Cause == nil {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	if rc.Conn() == first {
+		t.Fatal("connection not replaced")
+	}
+	o, err := rc.Option(tcpopt.NoDelay(false).Level(), tcpopt.NoDelay(false).Name(), make([]byte, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.(tcpopt.NoDelay) {
+		t.Fatal("option not re-applied")
+	}
+	if _, err := rc.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil || string(b) != "hello" {
+		t.Fatalf("got %q, %v; want hello", b, err)
+	}
+	rc.Close()
+	if _, err := rc.Write(b); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("got %v; want %v", err, net.ErrClosed)
+	}
+}
+
+func TestReDialer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close() // refuses the first attempts
+
+	var attempts []int
+	rd := tcp.ReDialer{
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  4 * time.Millisecond,
+		MaxAttempts: 3,
+		Options: func(attempt int, err error) []tcpopt.Option {
+			if attempt > 1 && err == nil {
+				t.Errorf("attempt %d: got nil; want error of previous attempt", attempt)
+			}
+			attempts = append(attempts, attempt)
+			return []tcpopt.Option{tcpopt.NoDelay(attempt%2 == 1)}
+		},
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			if delay <= 0 || delay > 4*time.Millisecond {
+				t.Errorf("attempt %d: got delay %v", attempt, delay)
+			}
+		},
+	}
+	if c, err := rd.Dial("tcp", address); err == nil {
+		c.Close()
+		t.Skip("address reused")
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("got %v; want 3 attempts", attempts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rd.MaxAttempts = 0
+	if _, err := rd.DialContext(ctx, "tcp", address); err == nil || ctx.Err() == nil {
+		t.Fatalf("got %v; want error after cancellation", err)
+	}
+
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	attempts = nil
+	c, err := rd.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if len(attempts) != 1 {
+		t.Fatalf("got %v; want 1 attempt", attempts)
+	}
+}
+
+type staticResolver map[string][]net.IPAddr
+
+func (r staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func TestDialerAddrPolicy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	var tried []string
+	d := tcp.Dialer{
+		Lookup: staticResolver{
+			"db.example": {{IP: net.IPv6loopback}, {IP: net.IPv4(127, 0, 0, 1)}, {IP: net.IPv4(127, 0, 0, 2)}},
+		},
+		SelectAddrs: func(network string, addrs []net.IPAddr) []net.IPAddr {
+			if network != "tcp4" || len(addrs) != 2 {
+				t.Errorf("got %s, %v; want IPv4 addresses only", network, addrs)
+			}
+			return []net.IPAddr{addrs[1], addrs[0]} // refused first
+		},
+		AddrOptions: func(ip net.IPAddr) []tcpopt.Option {
+			tried = append(tried, ip.String())
+			if runtime.GOOS == "linux" && ip.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+				return []tcpopt.Option{tcp.UserTimeout(3 * time.Second)}
+			}
+			return nil
+		},
+	}
+	c, err := d.Dial("tcp4", net.JoinHostPort("db.example", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if len(tried) != 2 || tried[0] != "127.0.0.2" || tried[1] != "127.0.0.1" {
+		t.Fatalf("got %v; want [127.0.0.2 127.0.0.1]", tried)
+	}
+	if runtime.GOOS == "linux" {
+		var b [4]byte
+		o, err := c.Option(tcp.UserTimeout(0).Level(), tcp.UserTimeout(0).Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o != tcp.UserTimeout(3*time.Second) {
+			t.Fatalf("got %v; want %v", o, tcp.UserTimeout(3*time.Second))
+		}
+	}
+
+	if _, err := d.Dial("tcp4", net.JoinHostPort("nx.example", port)); err == nil {
+		t.Fatal("got nil; want lookup error")
+	}
+}
+
+func TestDialerSourcePolicy(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	for _, tt := range []struct {
+		name   string
+		policy tcp.SourcePolicy
+		want   []net.IP
+	}{
+		{"RoundRobin", tcp.RoundRobin(net.IPv6loopback, net.IPv4(127, 0, 0, 2), net.IPv4(127, 0, 0, 3)), []net.IP{net.IPv4(127, 0, 0, 2), net.IPv4(127, 0, 0, 3), net.IPv4(127, 0, 0, 2)}},
+		{"PreferInterface", tcp.PreferInterface("lo"), []net.IP{net.IPv4(127, 0, 0, 1)}},
+		{"PreferSubnet", tcp.PreferSubnet(loopback), []net.IP{net.IPv4(127, 0, 0, 1)}},
+		{"NoPreference", tcp.RoundRobin(net.IPv6loopback), []net.IP{net.IPv4(127, 0, 0, 1)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tcp.Dialer{SourceAddrs: tt.policy}
+			for _, want := range tt.want {
+				c, err := d.Dial("tcp4", ln.Addr().String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				la := c.LocalAddr().(*net.TCPAddr)
+				c.Close()
+				if !la.IP.Equal(want) {
+					t.Fatalf("got %v; want %v", la.IP, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// ECN reports whether the use of Explicit Congestion Notification
+// has been negotiated on the connection.
+// On Linux, ECN is controlled by the net.ipv4.tcp_ecn sysctl and the
+// per-route features. On Darwin, it's also controlled per connection
+// by EnableECN option.
+//
+// Only Darwin and Linux support this feature.
+func (c *Conn) ECN() (bool, error) {
+	var ok bool
+	err := c.control(func(s uintptr) (err error) {
+		ok, err = ecn(s)
+		return
+	})
+	if err != nil {
+		return false, c.opError("get", err)
+	}
+	return ok, nil
+}
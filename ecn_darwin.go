@@ -0,0 +1,24 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"os"
+)
+
+func ecn(s uintptr) (bool, error) {
+	// The tcpi_options field of tcp_connection_info follows the
+	// 4-byte state and window scale fields.
+	var b [256]byte
+	n, err := getsockopt(s, ianaProtocolTCP, sysTCP_CONNECTION_INFO, b[:])
+	if err != nil {
+		return false, os.NewSyscallError("getsockopt", err)
+	}
+	if n < 8 {
+		return false, errors.New("short connection information")
+	}
+	return nativeEndian.Uint32(b[4:8])&sysTCPCI_OPT_ECN != 0, nil
+}
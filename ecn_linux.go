@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+func ecn(s uintptr) (bool, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return false, err
+	}
+	return ti.Options&sysTCPI_OPT_ECN != 0, nil
+}
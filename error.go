@@ -0,0 +1,80 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"fmt"
+	"net"
+)
+
+var (
+	_ net.Error = &OptionError{}
+	_ net.Error = &ExtendedError{}
+)
+
+// An OptionError represents an error on getting or setting a socket
+// option.
+type OptionError struct {
+	Op    string   // "get" or "set"
+	Level int      // option level
+	Name  int      // option name
+	Addr  net.Addr // local address
+	Err   error    // underlying error, usually syscall.Errno
+}
+
+func (e *OptionError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	s := e.Op
+	if e.Addr != nil {
+		s += " " + e.Addr.Network() + " " + e.Addr.String()
+	}
+	s += fmt.Sprintf(": level=%#x name=%#x", e.Level, e.Name)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying error.
+func (e *OptionError) Unwrap() error { return e.Err }
+
+// Timeout reports whether the underlying error represents a timeout.
+func (e *OptionError) Timeout() bool { return isTimeoutError(e.Err) }
+
+// Temporary reports whether the underlying error represents a
+// temporary failure.
+func (e *OptionError) Temporary() bool { return isTemporaryError(e.Err) }
+
+// isTimeoutError reports whether err implements the Timeout method of
+// net.Error and reports a timeout, such as syscall.ETIMEDOUT on the
+// connection aborted by UserTimeout.
+func isTimeoutError(err error) bool {
+	t, ok := err.(interface{ Timeout() bool })
+	return ok && t.Timeout()
+}
+
+// isTemporaryError reports whether err implements the Temporary
+// method of net.Error and reports a temporary failure.
+func isTemporaryError(err error) bool {
+	t, ok := err.(interface{ Temporary() bool })
+	return ok && t.Temporary()
+}
+
+// A wouldBlockError represents ErrWouldBlock. Like syscall.EAGAIN, it
+// is a temporary failure and a timeout of zero duration.
+type wouldBlockError struct{}
+
+func (e *wouldBlockError) Error() string   { return "operation would block" }
+func (e *wouldBlockError) Timeout() bool   { return true }
+func (e *wouldBlockError) Temporary() bool { return true }
+
+// optionError returns the error for the socket option operation op.
+// It's called only on failure to keep the success path free of
+// allocations.
+func (c *Conn) optionError(op string, level, name int, err error) error {
+	return &OptionError{Op: op, Level: level, Name: name, Addr: c.LocalAddr(), Err: err}
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Callbacks represents a set of event callbacks for a connection.
+// A nil callback means no interest in the event.
+type Callbacks struct {
+	// OnReadable is called when the connection becomes readable.
+	OnReadable func(c *Conn)
+
+	// OnWritable is called when the connection becomes writable.
+	OnWritable func(c *Conn)
+
+	// OnError is called when an error occurs on the connection.
+	// The error is io.EOF when the peer closed the connection.
+	// It is called after OnReadable, so that OnReadable can consume
+	// the data remaining in the receive buffer.
+	OnError func(c *Conn, err error)
+
+	// EdgeTriggered specifies the use of edge-triggered
+	// notification. When true, callbacks are called only when the
+	// state of connection changes and the callbacks must consume
+	// all the available data or space, typically by using TryRead
+	// and TryWrite until ErrWouldBlock.
+	EdgeTriggered bool
+}
+
+// An EventLoop dispatches readiness events on registered connections
+// to their callbacks.
+// All the callbacks are called sequentially from the goroutine
+// running Run and must not block.
+type EventLoop struct {
+	p *Poller
+
+	stateMu sync.Mutex
+	running bool
+	closed  int32
+
+	mu  sync.RWMutex
+	cbs map[*Conn]*Callbacks
+}
+
+// eventLoopInterval is the maximum interval between checks of
+// EventLoop closure.
+const eventLoopInterval = 100 * time.Millisecond
+
+// NewEventLoop returns a new event loop.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and OpenBSD
+// support this feature.
+func NewEventLoop() (*EventLoop, error) {
+	p, err := NewPoller()
+	if err != nil {
+		return nil, err
+	}
+	return &EventLoop{p: p, cbs: make(map[*Conn]*Callbacks)}, nil
+}
+
+// Register registers the callbacks cb for the connection c.
+func (l *EventLoop) Register(c *Conn, cb *Callbacks) error {
+	var ev PollEvents
+	if cb.OnReadable != nil || cb.OnError != nil {
+		ev |= PollRead
+	}
+	if cb.OnWritable != nil {
+		ev |= PollWrite
+	}
+	if cb.EdgeTriggered {
+		ev |= PollEdgeTriggered
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.p.Add(c, ev); err != nil {
+		return err
+	}
+	l.cbs[c] = cb
+	return nil
+}
+
+// Unregister unregisters the callbacks for the connection c.
+func (l *EventLoop) Unregister(c *Conn) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cbs, c)
+	return l.p.Remove(c)
+}
+
+// Run dispatches events until the event loop is closed.
+// It returns nil when the event loop is closed.
+func (l *EventLoop) Run() error {
+	l.stateMu.Lock()
+	if l.running || atomic.LoadInt32(&l.closed) != 0 {
+		l.stateMu.Unlock()
+		return errors.New("event loop already running or closed")
+	}
+	l.running = true
+	l.stateMu.Unlock()
+	defer func() {
+		l.stateMu.Lock()
+		l.running = false
+		if atomic.LoadInt32(&l.closed) != 0 {
+			l.p.Close()
+		}
+		l.stateMu.Unlock()
+	}()
+	events := make([]PollEvent, 128)
+	for atomic.LoadInt32(&l.closed) == 0 {
+		n, err := l.p.Wait(events, eventLoopInterval)
+		if err != nil {
+			return err
+		}
+		for _, e := range events[:n] {
+			l.dispatch(&e)
+		}
+	}
+	return nil
+}
+
+func (l *EventLoop) dispatch(e *PollEvent) {
+	l.mu.RLock()
+	cb := l.cbs[e.Conn]
+	l.mu.RUnlock()
+	if cb == nil {
+		return
+	}
+	if e.Events&PollRead != 0 && cb.OnReadable != nil {
+		cb.OnReadable(e.Conn)
+	}
+	if e.Events&PollWrite != 0 && cb.OnWritable != nil {
+		cb.OnWritable(e.Conn)
+	}
+	if e.Events&(PollHangup|PollError) == 0 || cb.OnError == nil {
+		return
+	}
+	var err error = io.EOF
+	if e.Events&PollError != 0 {
+		var serr error
+		e.Conn.control(func(s uintptr) error { serr = sockError(s); return nil })
+		if serr != nil {
+			err = e.Conn.ioError("read", serr)
+		}
+	}
+	cb.OnError(e.Conn, err)
+}
+
+// Close closes the event loop.
+// When Run is in progress, Run returns shortly after.
+func (l *EventLoop) Close() error {
+	l.stateMu.Lock()
+	defer l.stateMu.Unlock()
+	if !atomic.CompareAndSwapInt32(&l.closed, 0, 1) {
+		return nil
+	}
+	if l.running {
+		return nil // Run closes the poller on return
+	}
+	return l.p.Close()
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestEventLoop(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := tcp.NewEventLoop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- l.Run() }()
+
+	m := []byte("HELLO-R-U-THERE")
+	var got []byte
+	eof := make(chan error, 1)
+	cb := tcp.Callbacks{
+		OnReadable: func(c *tcp.Conn) {
+			b := make([]byte, 64)
+			for {
+				n, err := c.TryRead(b)
+				got = append(got, b[:n]...)
+				if err != nil {
+					return
+				}
+			}
+		},
+		OnError: func(c *tcp.Conn, err error) {
+			select {
+			case eof <- err:
+			default:
+			}
+		},
+		EdgeTriggered: true,
+	}
+	if err := l.Register(tc, &cb); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	select {
+	case err := <-eof:
+		if err != io.EOF {
+			t.Fatalf("got %v; want %v", err, io.EOF)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout")
+	}
+	if err := l.Unregister(tc); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(m) {
+		t.Fatalf("got %q; want %q", got, m)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A FallbackStep represents one of the alternative mechanisms for a
+// setting, such as a modern socket option and an older substitute.
+type FallbackStep struct {
+	Name    string          // name of mechanism
+	Options []tcpopt.Option // socket options used by the mechanism
+}
+
+// SetOptionsWithFallback tries the steps in order and sets the
+// options of the first step that the platform and the running kernel
+// support. It returns the name of the step used.
+//
+// When an option of a step is not supported, the options of the step
+// set before it are left as is and the next step is tried. Other
+// errors stop the fallback.
+func (c *Conn) SetOptionsWithFallback(steps ...FallbackStep) (string, error) {
+	var lastErr error
+	for _, st := range steps {
+		err := c.setStep(st)
+		if err == nil {
+			return st.Name, nil
+		}
+		if !isNotSupported(err) {
+			return "", err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = c.opError("set", errors.New("no fallback step"))
+	}
+	return "", lastErr
+}
+
+func (c *Conn) setStep(st FallbackStep) error {
+	for _, o := range st.Options {
+		if err := Validate(o); err != nil {
+			return err
+		}
+	}
+	for _, o := range st.Options {
+		if err := c.SetOption(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KeepAliveFallback returns the fallback steps for the keepalive
+// parameters ka.
+// The steps degrade from the full set of parameters to the idle
+// interval only, and then to the use of keepalive with the system
+// defaults.
+func KeepAliveFallback(ka *KeepAlive) []FallbackStep {
+	if !ka.Enable {
+		return []FallbackStep{{Name: "keepalive", Options: []tcpopt.Option{tcpopt.KeepAlive(false)}}}
+	}
+	full := []tcpopt.Option{tcpopt.KeepAlive(true)}
+	if ka.IdleInterval > 0 {
+		full = append(full, tcpopt.KeepAliveIdleInterval(ka.IdleInterval))
+	}
+	idle := full
+	if ka.ProbeInterval > 0 {
+		full = append(full[:len(full):len(full)], tcpopt.KeepAliveProbeInterval(ka.ProbeInterval))
+	}
+	if ka.ProbeCount > 0 {
+		full = append(full[:len(full):len(full)], tcpopt.KeepAliveProbeCount(ka.ProbeCount))
+	}
+	return []FallbackStep{
+		{Name: "keepalive-tuned", Options: full},
+		{Name: "keepalive-idle", Options: idle},
+		{Name: "keepalive", Options: []tcpopt.Option{tcpopt.KeepAlive(true)}},
+	}
+}
+
+// UserTimeoutFallback returns the fallback steps for the user timeout
+// d.
+// The steps degrade from UserTimeout to keepalive probes that drop
+// the connection after about d of silence from the peer.
+func UserTimeoutFallback(d time.Duration) []FallbackStep {
+	const probes = 4
+	idle := d / 2
+	if idle < time.Second {
+		idle = time.Second
+	}
+	intvl := (d - idle) / probes
+	if intvl < time.Second {
+		intvl = time.Second
+	}
+	return []FallbackStep{
+		{Name: "user-timeout", Options: []tcpopt.Option{UserTimeout(d)}},
+		{Name: "keepalive", Options: []tcpopt.Option{
+			tcpopt.KeepAlive(true),
+			tcpopt.KeepAliveIdleInterval(idle),
+			tcpopt.KeepAliveProbeInterval(intvl),
+			tcpopt.KeepAliveProbeCount(probes),
+		}},
+	}
+}
+
+// FastOpenFallback returns the fallback steps for TCP Fast Open on
+// connect.
+// The steps degrade from FastOpenConnect to the regular three-way
+// handshake.
+func FastOpenFallback() []FallbackStep {
+	return []FallbackStep{
+		{Name: "fastopen-connect", Options: []tcpopt.Option{FastOpenConnect(true)}},
+		{Name: "handshake"},
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestSetOptionsWithFallback(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		steps []tcp.FallbackStep
+		name  string
+	}{
+		{tcp.UserTimeoutFallback(3 * time.Second), "user-timeout"},
+		{tcp.KeepAliveFallback(&tcp.KeepAlive{Enable: true, IdleInterval: 10 * time.Second, ProbeInterval: time.Second, ProbeCount: 3}), "keepalive-tuned"},
+		{
+			[]tcp.FallbackStep{
+				{Name: "connection-timeout", Options: []tcpopt.Option{tcp.ConnectionTimeout(time.Second)}}, // Darwin, DragonFly BSD and FreeBSD only
+				{Name: "user-timeout", Options: []tcpopt.Option{tcp.UserTimeout(time.Second)}},
+			},
+			"user-timeout",
+		},
+	} {
+		name, err := tc.SetOptionsWithFallback(tt.steps...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != tt.name {
+			t.Fatalf("got %s; want %s", name, tt.name)
+		}
+	}
+	if _, err := tc.SetOptionsWithFallback(tcp.FallbackStep{Name: "bad", Options: []tcpopt.Option{tcpopt.SendBuffer(-1)}}); err == nil {
+		t.Fatal("got nil; want an error")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// connectFastOpen initiates the connection to address using connectx
+// with CONNECT_RESUME_ON_READ_WRITE flag, which defers the handshake
+// until the first write and sends the data of the write in the SYN
+// segment.
+// It returns nil without connecting when the running kernel doesn't
+// allow the use of connectx, so that the caller connects in the
+// regular way.
+func connectFastOpen(s uintptr, network, address string, laddr net.Addr) error {
+	if laddr != nil {
+		return errors.New("fast open with local address")
+	}
+	ra, err := net.ResolveTCPAddr(network, address)
+	if err != nil {
+		return err
+	}
+	var sa []byte
+	if network == "tcp4" {
+		var b [sizeofSockaddrInet]byte
+		b[0], b[1] = sizeofSockaddrInet, sysAF_INET
+		binary.BigEndian.PutUint16(b[2:4], uint16(ra.Port))
+		copy(b[4:8], ra.IP.To4())
+		sa = b[:]
+	} else {
+		var b [sizeofSockaddrInet6]byte
+		b[0], b[1] = sizeofSockaddrInet6, sysAF_INET6
+		binary.BigEndian.PutUint16(b[2:4], uint16(ra.Port))
+		copy(b[8:24], ra.IP.To16())
+		nativeEndian.PutUint32(b[24:28], uint32(zoneCache.index(ra.Zone)))
+		sa = b[:]
+	}
+	ep := saEndpoints{
+		Dstaddr:    (*sockaddr)(unsafe.Pointer(&sa[0])),
+		Dstaddrlen: uint32(len(sa)),
+	}
+	_, _, errno := syscall.Syscall9(sysSYS_CONNECTX, s, uintptr(unsafe.Pointer(&ep)), sysSAE_ASSOCID_ANY, sysCONNECT_RESUME_ON_READ_WRITE|sysCONNECT_DATA_IDEMPOTENT, 0, 0, 0, 0, 0)
+	switch errno {
+	case 0, syscall.EINPROGRESS:
+		return nil
+	case syscall.ENOSYS, syscall.EOPNOTSUPP, syscall.ENOTSUP, syscall.EPERM, syscall.EACCES:
+		return nil // falls back to the regular handshake
+	default:
+		return os.NewSyscallError("connectx", errno)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin
+
+package tcp
+
+import "net"
+
+func connectFastOpen(s uintptr, network, address string, laddr net.Addr) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A Feature represents a capability of TCP implementation.
+type Feature int
+
+const (
+	FeatureFastOpen     Feature = iota // TCP Fast Open on connect
+	FeatureMD5Signature                // TCP MD5 signature option
+	FeatureZeroCopy                    // zero-copy transmission
+	FeaturePacing                      // pacing rate limit
+	FeatureOriginalDst                 // original destination address
+	featureMax
+)
+
+var featureNames = [featureMax]string{
+	FeatureFastOpen:     "fast-open",
+	FeatureMD5Signature: "md5-signature",
+	FeatureZeroCopy:     "zero-copy",
+	FeaturePacing:       "pacing",
+	FeatureOriginalDst:  "original-dst",
+}
+
+func (f Feature) String() string {
+	if f < 0 || f >= featureMax {
+		return "<nil>"
+	}
+	return featureNames[f]
+}
+
+var features [featureMax]struct {
+	once sync.Once
+	err  error // result of probe
+}
+
+// Supports reports whether the feature f is available on the running
+// kernel.
+// The result is determined by a probe on a temporary socket at the
+// first call and cached.
+//
+// Only Linux supports probing.
+func Supports(f Feature) bool {
+	return !isNotSupported(probeFeature(f))
+}
+
+func probeFeature(f Feature) error {
+	if f < 0 || f >= featureMax {
+		return ErrNotSupported
+	}
+	p := &features[f]
+	p.once.Do(func() { p.err = probe(f) })
+	return p.err
+}
+
+// A ProbeReport represents the availability of the features and the
+// socket options known to the package on the running system.
+type ProbeReport struct {
+	Features map[Feature]bool // availability of features
+	Options  map[string]bool  // availability of socket options by type name, such as "NoDelay"
+	Denied   []string         // features and socket options denied by security policy
+}
+
+// Restricted reports whether the security policy of the system, such
+// as SELinux on Android, denies any of the features or socket
+// options.
+func (r *ProbeReport) Restricted() bool { return len(r.Denied) > 0 }
+
+// Probe reports what is usable on the running system.
+// The features are probed as Supports does, and the socket options
+// are probed by getting them from a temporary socket.
+//
+// The feature or socket option denied by the security policy of the
+// system is reported as not available and listed in Denied. On
+// Android, the methods of Conn and Listener return the error that
+// matches ErrNotSupported for such socket options instead of the
+// permission error.
+//
+// Only Linux supports probing.
+func Probe() *ProbeReport {
+	r := &ProbeReport{Features: make(map[Feature]bool), Options: make(map[string]bool)}
+	for f := Feature(0); f < featureMax; f++ {
+		err := probeFeature(f)
+		r.Features[f] = !isNotSupported(err)
+		if isDenied(err) {
+			r.Denied = append(r.Denied, f.String())
+		}
+	}
+	for _, o := range knownOptions {
+		name := reflect.TypeOf(o).Name()
+		if o.Name() < 1 {
+			r.Options[name] = false
+			continue
+		}
+		var b [4]byte
+		err := probeOption(o.Level(), o.Name(), b[:])
+		r.Options[name] = !isNotSupported(err)
+		if isDenied(err) {
+			r.Denied = append(r.Denied, name)
+		}
+	}
+	return r
+}
+
+var supportedOptions sync.Map // map[int64]bool
+
+// Supports reports whether the socket option o is available on the
+// connection.
+// The result is determined by getting the option from the connection
+// at the first call for the level and name of o, and cached.
+func (c *Conn) Supports(o tcpopt.Option) bool {
+	if o.Name() < 1 {
+		return false
+	}
+	key := int64(o.Level())<<32 | int64(o.Name())
+	if ok, found := supportedOptions.Load(key); found {
+		return ok.(bool)
+	}
+	ok := true
+	b := make([]byte, 4)
+	if bb, err := o.Marshal(); err != nil {
+		ok = false
+	} else if len(bb) > len(b) {
+		b = make([]byte, len(bb))
+	}
+	if ok {
+		_, err := c.getsockopt(o.Level(), o.Name(), b)
+		ok = !isNotSupported(err)
+	}
+	supportedOptions.Store(key, ok)
+	return ok
+}
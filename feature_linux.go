@@ -0,0 +1,46 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "syscall"
+
+func probe(f Feature) error {
+	s, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_TCP)
+	if err != nil {
+		return ErrNotSupported
+	}
+	defer syscall.Close(s)
+	switch f {
+	case FeatureFastOpen:
+		err = syscall.SetsockoptInt(s, ianaProtocolTCP, sysTCP_FASTOPEN_CONNECT, 1)
+	case FeatureMD5Signature:
+		// An empty signature key with no address family is
+		// rejected with EINVAL when the kernel supports the
+		// option.
+		var b [sizeofTCPMD5Sig]byte
+		err = setsockopt(uintptr(s), ianaProtocolTCP, sysTCP_MD5SIG, b[:])
+	case FeatureZeroCopy:
+		err = syscall.SetsockoptInt(s, sysSOL_SOCKET, sysSO_ZEROCOPY, 1)
+	case FeaturePacing:
+		err = syscall.SetsockoptInt(s, sysSOL_SOCKET, sysSO_MAX_PACING_RATE, -1)
+	case FeatureOriginalDst:
+		// The kernel without connection tracking doesn't know
+		// the option. Otherwise it complains about the socket
+		// not being tracked.
+		var b [sizeofSockaddrInet]byte
+		_, err = getsockopt(uintptr(s), ianaProtocolIP, sysSO_ORIGINAL_DST, b[:])
+	}
+	return err
+}
+
+func probeOption(level, name int, b []byte) error {
+	s, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_TCP)
+	if err != nil {
+		return ErrNotSupported
+	}
+	defer syscall.Close(s)
+	_, err = getsockopt(uintptr(s), level, name, b)
+	return err
+}
@@ -0,0 +1,11 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func probe(f Feature) error { return ErrNotSupported }
+
+func probeOption(level, name int, b []byte) error { return ErrNotSupported }
@@ -0,0 +1,82 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestSupports(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	for _, f := range []tcp.Feature{tcp.FeatureFastOpen, tcp.FeatureMD5Signature, tcp.FeatureZeroCopy, tcp.FeaturePacing, tcp.FeatureOriginalDst} {
+		ok := tcp.Supports(f)
+		if tcp.Supports(f) != ok {
+			t.Errorf("%v: inconsistent result", f)
+		}
+		t.Logf("%v: %v", f, ok)
+	}
+	if !tcp.Supports(tcp.FeaturePacing) {
+		t.Error("got false for pacing; want true")
+	}
+	if tcp.Supports(tcp.Feature(-1)) {
+		t.Error("got true for unknown feature")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tc.Supports(tcpopt.NoDelay(true)) {
+		t.Error("got false for TCP_NODELAY; want true")
+	}
+	if tc.Supports(tcp.ConnectionTimeout(0)) {
+		t.Error("got true for TCP_CONNECTIONTIMEOUT; want false")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "android":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	r := tcp.Probe()
+	for f, ok := range r.Features {
+		if ok != tcp.Supports(f) {
+			t.Errorf("%v: got %v; want %v", f, ok, !ok)
+		}
+	}
+	if !r.Options["NoDelay"] {
+		t.Error("got false for NoDelay; want true")
+	}
+	if r.Options["ConnectionTimeout"] {
+		t.Error("got true for ConnectionTimeout; want false")
+	}
+	if runtime.GOOS != "android" && r.Restricted() {
+		t.Errorf("got %v; want no denied features", r.Denied)
+	}
+	t.Logf("%+v", r)
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// A RawInstruction represents a classic BPF instruction.
+// It has the same layout as RawInstruction of
+// golang.org/x/net/bpf package.
+type RawInstruction struct {
+	Op uint16
+	Jt uint8
+	Jf uint8
+	K  uint32
+}
+
+// AttachFilter attaches the classic BPF program prog to the
+// connection. It replaces the filter already attached.
+//
+// Only Linux supports this feature.
+func (c *Conn) AttachFilter(prog []RawInstruction) error {
+	if err := c.control(func(s uintptr) error { return attachFilter(s, prog, false) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// AttachBPF attaches the eBPF program of BPF_PROG_TYPE_SOCKET_FILTER
+// type referred to by the file descriptor fd to the connection.
+//
+// Only Linux supports this feature.
+func (c *Conn) AttachBPF(fd int) error {
+	if err := c.control(func(s uintptr) error { return attachBPF(s, fd, false) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// AttachPinnedBPF is like AttachBPF but takes the path of eBPF
+// program pinned on the BPF file system.
+//
+// Only Linux supports this feature.
+func (c *Conn) AttachPinnedBPF(path string) error {
+	if err := c.control(func(s uintptr) error { return attachPinnedBPF(s, path, false) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// DetachFilter detaches the classic BPF or eBPF program from the
+// connection.
+//
+// Only Linux supports this feature.
+func (c *Conn) DetachFilter() error {
+	if err := c.control(func(s uintptr) error { return detachFilter(s, false) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// AttachFilter attaches the classic BPF program prog to the listener.
+// Connections accepted from the listener inherit the filter.
+//
+// Only Linux supports this feature.
+func (ln *Listener) AttachFilter(prog []RawInstruction) error {
+	if err := ln.control(func(s uintptr) error { return attachFilter(s, prog, false) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// AttachBPF attaches the eBPF program of BPF_PROG_TYPE_SOCKET_FILTER
+// type referred to by the file descriptor fd to the listener.
+//
+// Only Linux supports this feature.
+func (ln *Listener) AttachBPF(fd int) error {
+	if err := ln.control(func(s uintptr) error { return attachBPF(s, fd, false) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// AttachPinnedBPF is like AttachBPF but takes the path of eBPF
+// program pinned on the BPF file system.
+//
+// Only Linux supports this feature.
+func (ln *Listener) AttachPinnedBPF(path string) error {
+	if err := ln.control(func(s uintptr) error { return attachPinnedBPF(s, path, false) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// DetachFilter detaches the classic BPF or eBPF program from the
+// listener.
+//
+// Only Linux supports this feature.
+func (ln *Listener) DetachFilter() error {
+	if err := ln.control(func(s uintptr) error { return detachFilter(s, false) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// AttachReusePortFilter attaches the classic BPF program prog that
+// selects a listener from the SO_REUSEPORT group that the listener
+// belongs to. The program returns an index of the group, the order
+// of listeners joined the group, for each new connection.
+//
+// Only Linux supports this feature.
+func (ln *Listener) AttachReusePortFilter(prog []RawInstruction) error {
+	if err := ln.control(func(s uintptr) error { return attachFilter(s, prog, true) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// AttachReusePortBPF attaches the eBPF program of
+// BPF_PROG_TYPE_SOCKET_FILTER or BPF_PROG_TYPE_SK_REUSEPORT type
+// referred to by the file descriptor fd that selects a listener from
+// the SO_REUSEPORT group that the listener belongs to.
+//
+// Only Linux supports this feature.
+func (ln *Listener) AttachReusePortBPF(fd int) error {
+	if err := ln.control(func(s uintptr) error { return attachBPF(s, fd, true) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// AttachPinnedReusePortBPF is like AttachReusePortBPF but takes the
+// path of eBPF program pinned on the BPF file system.
+//
+// Only Linux supports this feature.
+func (ln *Listener) AttachPinnedReusePortBPF(path string) error {
+	if err := ln.control(func(s uintptr) error { return attachPinnedBPF(s, path, true) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// DetachReusePortFilter detaches the program that selects a listener
+// from the SO_REUSEPORT group.
+//
+// Only Linux supports this feature.
+func (ln *Listener) DetachReusePortFilter() error {
+	if err := ln.control(func(s uintptr) error { return detachFilter(s, true) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
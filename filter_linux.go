@@ -0,0 +1,102 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// sysBPF is the system call number of bpf, which the syscall package
+// doesn't provide on some architectures.
+var sysBPF = map[string]uintptr{
+	"386":      357,
+	"amd64":    321,
+	"arm":      386,
+	"arm64":    280,
+	"loong64":  280,
+	"mips":     4355,
+	"mipsle":   4355,
+	"mips64":   5315,
+	"mips64le": 5315,
+	"ppc64":    361,
+	"ppc64le":  361,
+	"riscv64":  280,
+	"s390x":    351,
+}[runtime.GOARCH]
+
+func attachFilter(s uintptr, prog []RawInstruction, reuseport bool) error {
+	if len(prog) == 0 {
+		return errors.New("empty program")
+	}
+	p := syscall.SockFprog{Len: uint16(len(prog)), Filter: (*syscall.SockFilter)(unsafe.Pointer(&prog[0]))}
+	b := (*[unsafe.Sizeof(p)]byte)(unsafe.Pointer(&p))[:]
+	name := sysSO_ATTACH_FILTER
+	if reuseport {
+		name = sysSO_ATTACH_REUSEPORT_CBPF
+	}
+	return setsockoptError(setsockopt(s, sysSOL_SOCKET, name, b))
+}
+
+func attachBPF(s uintptr, fd int, reuseport bool) error {
+	name := sysSO_ATTACH_BPF
+	if reuseport {
+		name = sysSO_ATTACH_REUSEPORT_EBPF
+	}
+	v := int32(fd)
+	return setsockoptError(setsockopt(s, sysSOL_SOCKET, name, (*[4]byte)(unsafe.Pointer(&v))[:]))
+}
+
+func attachPinnedBPF(s uintptr, path string, reuseport bool) error {
+	fd, err := openPinnedBPF(path)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	return attachBPF(s, fd, reuseport)
+}
+
+func detachFilter(s uintptr, reuseport bool) error {
+	name := sysSO_DETACH_FILTER
+	if reuseport {
+		name = sysSO_DETACH_REUSEPORT_BPF
+	}
+	var b [4]byte
+	return setsockoptError(setsockopt(s, sysSOL_SOCKET, name, b[:]))
+}
+
+// openPinnedBPF returns the file descriptor of BPF object pinned at
+// path.
+func openPinnedBPF(path string) (int, error) {
+	if sysBPF == 0 {
+		return -1, ErrNotSupported
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	var attr struct {
+		pathname uint64
+		bpfFD    uint32
+		flags    uint32
+	}
+	attr.pathname = uint64(uintptr(unsafe.Pointer(p)))
+	fd, _, errno := syscall.Syscall(sysBPF, sysBPF_OBJ_GET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	runtime.KeepAlive(p)
+	if errno != 0 {
+		return -1, os.NewSyscallError("bpf", errno)
+	}
+	return int(fd), nil
+}
+
+func setsockoptError(err error) error {
+	if err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestAttachReusePortFilter(t *testing.T) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(s uintptr) {
+				serr = syscall.SetsockoptInt(int(s), syscall.SOL_SOCKET, 0xf /* SO_REUSEPORT */, 1)
+			}); err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+	lc.SetMultipathTCP(false) // MPTCP sockets don't support reuseport programs
+	ln0, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln0.Close()
+	ln1, err := lc.Listen(context.Background(), "tcp", ln0.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln1.Close()
+	tln0, err := tcp.NewListener(ln0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tln1, err := tcp.NewListener(ln1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ret #1; steers all the connections to the second listener
+	if err := tln0.AttachReusePortFilter([]tcp.RawInstruction{{Op: 0x06, K: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	const N = 4
+	for i := 0; i < N; i++ {
+		c, err := net.Dial(ln0.Addr().Network(), ln0.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+	}
+	time.Sleep(100 * time.Millisecond)
+	st0, err := tln0.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st1, err := tln1.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st0.AcceptQueueLen != 0 || st1.AcceptQueueLen != N {
+		t.Fatalf("got %d, %d queued connections; want 0, %d", st0.AcceptQueueLen, st1.AcceptQueueLen, N)
+	}
+	if err := tln0.DetachReusePortFilter(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,12 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func attachFilter(s uintptr, prog []RawInstruction, reuseport bool) error { return ErrNotSupported }
+func attachBPF(s uintptr, fd int, reuseport bool) error                   { return ErrNotSupported }
+func attachPinnedBPF(s uintptr, path string, reuseport bool) error        { return ErrNotSupported }
+func detachFilter(s uintptr, reuseport bool) error                        { return ErrNotSupported }
@@ -0,0 +1,57 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestAttachFilter(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ret #0; drops all the inbound segments
+	if err := tc.AttachFilter([]tcp.RawInstruction{{Op: 0x06, K: 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write([]byte("HELLO")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := tc.Buffered(); n != 0 {
+		t.Fatalf("got %d; want 0", n)
+	}
+	if err := tc.DetachFilter(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"io"
+	"time"
+)
+
+// A FirstByte represents the measurement of the time to first byte
+// on a connection.
+type FirstByte struct {
+	Start    time.Time // start of the measurement
+	Readable time.Time // time when the first byte became readable
+	Received time.Time // time when the kernel received the first byte; zero when not available
+}
+
+// Duration returns the time to first byte. It uses the kernel
+// receive time when available, which excludes the scheduling delay
+// of the process.
+func (fb *FirstByte) Duration() time.Duration {
+	if !fb.Received.IsZero() && !fb.Received.Before(fb.Start) {
+		return fb.Received.Sub(fb.Start)
+	}
+	return fb.Readable.Sub(fb.Start)
+}
+
+// TimeToFirstByte waits for the first byte readable on the connection
+// and returns the measurement of the interval from start, such as the
+// time of writing a request. A zero start means the creation of the
+// connection, which is the establishment of the connection on a
+// dialed or accepted connection.
+//
+// It waits for readiness without consuming the data, which is left
+// readable to the application, and obeys the read deadline of the
+// connection. It returns io.EOF when the connection is closed by the
+// peer before sending any data.
+//
+// The kernel receive time is the time when the kernel received the
+// segment carrying the first byte, and is available on the platforms
+// that support ReceiveTimestamp.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD, OpenBSD and
+// Solaris support this feature.
+func (c *Conn) TimeToFirstByte(start time.Time) (*FirstByte, error) {
+	if start.IsZero() {
+		start = c.created
+	}
+	rc, err := c.rawConn()
+	if err != nil {
+		return nil, c.ioError("read", err)
+	}
+	c.EnableReceiveTimestamps()
+	if err := waitReadable(rc); err != nil {
+		if err != io.EOF {
+			err = c.ioError("read", err)
+		}
+		return nil, err
+	}
+	fb := FirstByte{Start: start, Readable: time.Now()}
+	if t, err := c.ReceiveTimestamp(); err == nil {
+		fb.Received = t
+	}
+	return &fb, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "errors"
+
+// maxFlowLabel is the maximum value of IPv6 flow label.
+const maxFlowLabel = 1<<20 - 1
+
+var errNotIPv6 = errors.New("not an ipv6 connection")
+
+// FlowLabel returns the IPv6 flow label of outgoing packets on the
+// connection. It returns an error when the connection has no flow
+// label leased from the flow label manager; see Dialer.FlowLabel.
+//
+// Only Linux supports this feature.
+func (c *Conn) FlowLabel() (uint32, error) {
+	if c.family() != ianaProtocolIPv6 {
+		return 0, c.opError("get", errNotIPv6)
+	}
+	var l uint32
+	err := c.control(func(s uintptr) (err error) {
+		l, err = flowLabel(s, false)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return l, nil
+}
+
+// PeerFlowLabel returns the IPv6 flow label of the latest packet
+// received from the peer.
+// The kernel starts recording the label on the first call, so the
+// first call may return zero until the next packet arrives.
+//
+// Only Linux supports this feature.
+func (c *Conn) PeerFlowLabel() (uint32, error) {
+	if c.family() != ianaProtocolIPv6 {
+		return 0, c.opError("get", errNotIPv6)
+	}
+	var l uint32
+	err := c.control(func(s uintptr) (err error) {
+		l, err = flowLabel(s, true)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return l, nil
+}
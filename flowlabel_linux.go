@@ -0,0 +1,68 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// connectWithFlowLabel leases the flow label for the destination
+// address and initiates the connection on the socket s with the
+// label. The connection is completed by the caller; net.Dialer
+// treats the connection in progress as its own.
+func connectWithFlowLabel(s uintptr, address string, label uint32) error {
+	if label > maxFlowLabel {
+		return errors.New("invalid flow label")
+	}
+	ra, err := net.ResolveTCPAddr("tcp6", address)
+	if err != nil {
+		return err
+	}
+	var req [sizeofIn6FlowlabelReq]byte
+	copy(req[:16], ra.IP.To16())
+	binary.BigEndian.PutUint32(req[16:20], label)
+	req[20] = sysIPV6_FL_A_GET
+	req[21] = sysIPV6_FL_S_ANY
+	nativeEndian.PutUint16(req[22:24], sysIPV6_FL_F_CREATE)
+	if err := setsockopt(s, ianaProtocolIPv6, sysIPV6_FLOWLABEL_MGR, req[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	v := int32(1)
+	if err := setsockopt(s, ianaProtocolIPv6, sysIPV6_FLOWINFO_SEND, (*[4]byte)(unsafe.Pointer(&v))[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	var sa [sizeofSockaddrInet6]byte
+	nativeEndian.PutUint16(sa[0:2], syscall.AF_INET6)
+	binary.BigEndian.PutUint16(sa[2:4], uint16(ra.Port))
+	binary.BigEndian.PutUint32(sa[4:8], label)
+	copy(sa[8:24], ra.IP.To16())
+	nativeEndian.PutUint32(sa[24:28], uint32(zoneCache.index(ra.Zone)))
+	if err := connect(s, sa[:]); err != nil && err != syscall.EINPROGRESS {
+		return os.NewSyscallError("connect", err)
+	}
+	return nil
+}
+
+// flowLabel returns the flow label of outgoing packets, or the one of
+// received packets when remote is true.
+func flowLabel(s uintptr, remote bool) (uint32, error) {
+	var req [sizeofIn6FlowlabelReq]byte
+	if remote {
+		v := int32(1)
+		if err := setsockopt(s, ianaProtocolIPv6, sysIPV6_FLOWINFO, (*[4]byte)(unsafe.Pointer(&v))[:]); err != nil {
+			return 0, os.NewSyscallError("setsockopt", err)
+		}
+		nativeEndian.PutUint16(req[22:24], sysIPV6_FL_F_REMOTE)
+	}
+	if _, err := getsockopt(s, ianaProtocolIPv6, sysIPV6_FLOWLABEL_MGR, req[:]); err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+	return binary.BigEndian.Uint32(req[16:20]) & maxFlowLabel, nil
+}
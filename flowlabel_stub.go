@@ -0,0 +1,11 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func connectWithFlowLabel(s uintptr, address string, label uint32) error { return ErrNotSupported }
+
+func flowLabel(s uintptr, remote bool) (uint32, error) { return 0, ErrNotSupported }
@@ -0,0 +1,140 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A Forwarder accepts connections and relays them bidirectionally to
+// an upstream server.
+// On Linux, data is relayed by using the splice system call.
+type Forwarder struct {
+	stats ForwarderStats // must be the first field for 64-bit atomic access
+
+	// Upstream is the address of upstream server.
+	// When empty, the original destination of each accepted
+	// connection is used instead, which is useful for transparent
+	// proxies.
+	Upstream string
+
+	// Dialer is used to connect to the upstream server.
+	// When nil, the zero value of net.Dialer is used.
+	Dialer *net.Dialer
+
+	// Options are the socket options applied to both legs of each
+	// session.
+	Options []tcpopt.Option
+}
+
+// A ForwarderStats represents aggregated statistics of forwarding
+// sessions.
+type ForwarderStats struct {
+	Sessions       uint64 // # of accepted sessions
+	Failures       uint64 // # of sessions failed to be set up
+	ActiveSessions int64  // # of sessions in progress
+	Upstream       uint64 // # of bytes relayed to upstream
+	Downstream     uint64 // # of bytes relayed to downstream
+}
+
+// Stats returns the aggregated statistics of forwarding sessions.
+func (fw *Forwarder) Stats() ForwarderStats {
+	return ForwarderStats{
+		Sessions:       atomic.LoadUint64(&fw.stats.Sessions),
+		Failures:       atomic.LoadUint64(&fw.stats.Failures),
+		ActiveSessions: atomic.LoadInt64(&fw.stats.ActiveSessions),
+		Upstream:       atomic.LoadUint64(&fw.stats.Upstream),
+		Downstream:     atomic.LoadUint64(&fw.stats.Downstream),
+	}
+}
+
+// Serve accepts connections on the listener ln and forwards each of
+// them in a new goroutine.
+// It always returns a non-nil error returned from ln.Accept.
+func (fw *Forwarder) Serve(ln net.Listener) error {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		atomic.AddUint64(&fw.stats.Sessions, 1)
+		go fw.forward(c)
+	}
+}
+
+func (fw *Forwarder) forward(c net.Conn) {
+	down, up, err := fw.setup(c)
+	if err != nil {
+		atomic.AddUint64(&fw.stats.Failures, 1)
+		c.Close()
+		return
+	}
+	atomic.AddInt64(&fw.stats.ActiveSessions, 1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		n, _ := Relay(up, down)
+		atomic.AddUint64(&fw.stats.Upstream, uint64(n))
+		closeWrite(up)
+	}()
+	go func() {
+		defer wg.Done()
+		n, _ := Relay(down, up)
+		atomic.AddUint64(&fw.stats.Downstream, uint64(n))
+		closeWrite(down)
+	}()
+	wg.Wait()
+	down.Close()
+	up.Close()
+	atomic.AddInt64(&fw.stats.ActiveSessions, -1)
+}
+
+func (fw *Forwarder) setup(c net.Conn) (down, up *Conn, err error) {
+	if down, err = NewConn(c); err != nil {
+		return nil, nil, err
+	}
+	addr := fw.Upstream
+	if addr == "" {
+		od, err := down.OriginalDst()
+		if err != nil {
+			return nil, nil, err
+		}
+		addr = od.String()
+	}
+	d := fw.Dialer
+	if d == nil {
+		d = &net.Dialer{}
+	}
+	uc, err := d.Dial("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if up, err = NewConn(uc); err != nil {
+		uc.Close()
+		return nil, nil, err
+	}
+	for _, o := range fw.Options {
+		if err := down.SetOption(o); err != nil {
+			up.Close()
+			return nil, nil, err
+		}
+		if err := up.SetOption(o); err != nil {
+			up.Close()
+			return nil, nil, err
+		}
+	}
+	return down, up, nil
+}
+
+func closeWrite(c *Conn) {
+	if err := c.CloseWrite(); err != nil {
+		c.Close()
+	}
+}
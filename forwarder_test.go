@@ -0,0 +1,75 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestForwarder(t *testing.T) {
+	up, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer up.Close()
+	go func() {
+		for {
+			c, err := up.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				io.Copy(c, c)
+			}()
+		}
+	}()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	fw := &tcp.Forwarder{
+		Upstream: up.Addr().String(),
+		Options:  []tcpopt.Option{tcpopt.NoDelay(true)},
+	}
+	go fw.Serve(ln)
+
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	m := bytes.Repeat([]byte("HELLO-R-U-THERE"), 1<<10)
+	go func() {
+		c.Write(m)
+		c.(*net.TCPConn).CloseWrite()
+	}()
+	b, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, m) {
+		t.Fatalf("got %d bytes; want %d bytes", len(b), len(m))
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for fw.Stats().ActiveSessions > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	st := fw.Stats()
+	want := tcp.ForwarderStats{Sessions: 1, Upstream: uint64(len(m)), Downstream: uint64(len(m))}
+	if st != want {
+		t.Fatalf("got %+v; want %+v", st, want)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"fmt"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// NoDelay reports whether Nagle's algorithm is disabled.
+func (c *Conn) NoDelay() (bool, error) {
+	o, err := c.option4(tcpopt.NoDelay(false))
+	if err != nil {
+		return false, err
+	}
+	v, ok := o.(tcpopt.NoDelay)
+	if !ok {
+		return false, c.typeError(o)
+	}
+	return bool(v), nil
+}
+
+// SendBuffer returns the size of send buffer in bytes.
+func (c *Conn) SendBuffer() (int, error) {
+	o, err := c.option4(tcpopt.SendBuffer(0))
+	if err != nil {
+		return 0, err
+	}
+	v, ok := o.(tcpopt.SendBuffer)
+	if !ok {
+		return 0, c.typeError(o)
+	}
+	return int(v), nil
+}
+
+// ReceiveBuffer returns the size of receive buffer in bytes.
+func (c *Conn) ReceiveBuffer() (int, error) {
+	o, err := c.option4(tcpopt.ReceiveBuffer(0))
+	if err != nil {
+		return 0, err
+	}
+	v, ok := o.(tcpopt.ReceiveBuffer)
+	if !ok {
+		return 0, c.typeError(o)
+	}
+	return int(v), nil
+}
+
+// KeepAliveEnabled reports whether keepalive is enabled.
+func (c *Conn) KeepAliveEnabled() (bool, error) {
+	o, err := c.option4(tcpopt.KeepAlive(false))
+	if err != nil {
+		return false, err
+	}
+	v, ok := o.(tcpopt.KeepAlive)
+	if !ok {
+		return false, c.typeError(o)
+	}
+	return bool(v), nil
+}
+
+// Cork reports whether TCP_CORK or TCP_NOPUSH is enabled.
+//
+// NetBSD and Windows don't support this feature.
+func (c *Conn) Cork() (bool, error) {
+	o, err := c.option4(tcpopt.Cork(false))
+	if err != nil {
+		return false, err
+	}
+	v, ok := o.(tcpopt.Cork)
+	if !ok {
+		return false, c.typeError(o)
+	}
+	return bool(v), nil
+}
+
+// knownOptions are the socket options read by Options.
+// The options that change on read, such as tcpopt.Error, are
+// excluded.
+var knownOptions = []tcpopt.Option{
+	tcpopt.NoDelay(false),
+	tcpopt.Cork(false),
+	tcpopt.MSS(0),
+	tcpopt.SendBuffer(0),
+	tcpopt.ReceiveBuffer(0),
+	tcpopt.NotSentLowWMK(0),
+	tcpopt.KeepAlive(false),
+	tcpopt.KeepAliveIdleInterval(0),
+	tcpopt.KeepAliveProbeInterval(0),
+	tcpopt.KeepAliveProbeCount(0),
+	ConnectionTimeout(0),
+	RetransmitConnDropTime(0),
+	UserTimeout(0),
+	FastOpenConnect(false),
+	FastOpenNoCookie(false),
+	ReuseAddress(false),
+	ReusePort(false),
+	WindowClamp(0),
+	Priority(0),
+	MinRTO(0),
+	Linger2(0),
+	EnableECN(false),
+	NoOptions(false),
+	ReceiveLowWMK(0),
+	SendLowWMK(0),
+	OOBInline(false),
+	UserCookie(0),
+	Timestamps(false),
+}
+
+// Options returns the current values of all the socket options
+// known to the package, in the form of typed options such as
+// tcpopt.NoDelay.
+// The options that the platform or the running kernel doesn't
+// support are omitted.
+func (c *Conn) Options() ([]tcpopt.Option, error) {
+	var opts []tcpopt.Option
+	for _, o := range knownOptions {
+		if o.Name() < 1 {
+			continue
+		}
+		v, err := c.option4(o)
+		if err != nil {
+			if isNotSupported(err) {
+				continue
+			}
+			return nil, err
+		}
+		opts = append(opts, v)
+	}
+	return opts, nil
+}
+
+// option4 returns the current value of 4-byte option that has the
+// same level and name as o.
+func (c *Conn) option4(o tcpopt.Option) (tcpopt.Option, error) {
+	var b [4]byte
+	return c.pooledOption(o.Level(), o.Name(), b[:])
+}
+
+// typeError returns an error for the option o of which the type is
+// not the one a getter expects, such as an option returned by a
+// parser registered with RegisterParser.
+func (c *Conn) typeError(o tcpopt.Option) error {
+	return c.opError("get", fmt.Errorf("unexpected option type %T", o))
+}
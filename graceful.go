@@ -0,0 +1,184 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// GracefulEnv is the name of environment variable that carries the
+// inherited listeners from a parent process to a child process.
+// The value is a comma-separated list of network:address pairs in
+// the order of inherited file descriptors starting at 3.
+const GracefulEnv = "TCP_GRACEFUL_LISTENERS"
+
+// A Graceful coordinates zero-downtime restarts of a server process.
+//
+// The parent process passes its listeners to a re-executed child
+// process with Restart, and then stops accepting and waits for the
+// accepted connections to finish with Drain. The child process
+// re-imports the listeners with Listen.
+//
+// Windows doesn't support this feature.
+type Graceful struct {
+	mu        sync.Mutex
+	inherited map[string]*os.File // inherited listener files keyed by network:address
+	lns       []*gracefulListener
+	conns     sync.WaitGroup
+}
+
+type gracefulListener struct {
+	key string
+	ln  *Listener
+}
+
+// NewGraceful returns a new Graceful.
+// It takes over the listeners inherited from the parent process, if
+// any.
+func NewGraceful() *Graceful {
+	g := &Graceful{inherited: make(map[string]*os.File)}
+	v := os.Getenv(GracefulEnv)
+	if v == "" {
+		return g
+	}
+	for i, key := range strings.Split(v, ",") {
+		g.inherited[key] = os.NewFile(uintptr(3+i), key)
+	}
+	os.Unsetenv(GracefulEnv)
+	return g
+}
+
+// Listen announces on the local network address.
+// It returns the listener inherited from the parent process when
+// available.
+func (g *Graceful) Listen(network, address string) (*Listener, error) {
+	key := network + ":" + address
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var ln net.Listener
+	var err error
+	if f := g.inherited[key]; f != nil {
+		delete(g.inherited, key)
+		ln, err = net.FileListener(f)
+		f.Close()
+	} else {
+		ln, err = net.Listen(network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tl, err := NewListener(ln)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	g.lns = append(g.lns, &gracefulListener{key: key, ln: tl})
+	return tl, nil
+}
+
+// Accept waits for and returns the next connection to the listener
+// ln. Drain waits for the returned connection to be closed.
+func (g *Graceful) Accept(ln *Listener) (*Conn, error) {
+	c, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	cc, ok := c.(*Conn)
+	if !ok {
+		if cc, err = NewConn(c); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	tc, ok := cc.Conn.(*net.TCPConn)
+	if !ok {
+		cc.Close()
+		return nil, errors.New("unknown connection type")
+	}
+	g.conns.Add(1)
+	cc.Conn = &gracefulConn{TCPConn: tc, g: g}
+	return cc, nil
+}
+
+// Restart starts a new process of the same executable with the
+// arguments args, passing the listeners returned from Listen.
+func (g *Graceful) Restart(args ...string) (*os.Process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var keys []string
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, gl := range g.lns {
+		fl, ok := gl.ln.Listener.(interface {
+			File() (*os.File, error)
+		})
+		if !ok {
+			return nil, fmt.Errorf("unknown listener type: %T", gl.ln.Listener)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, gl.key)
+		files = append(files, f)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), GracefulEnv+"="+strings.Join(keys, ","))
+	cmd.ExtraFiles = files
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd.Process, nil
+}
+
+// Drain closes the listeners returned from Listen and waits for the
+// connections returned from Accept to be closed, or ctx to be done.
+func (g *Graceful) Drain(ctx context.Context) error {
+	g.mu.Lock()
+	for _, gl := range g.lns {
+		gl.ln.Close()
+	}
+	g.lns = nil
+	g.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		g.conns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type gracefulConn struct {
+	*net.TCPConn
+	g    *Graceful
+	once sync.Once
+}
+
+func (gc *gracefulConn) Close() error {
+	err := gc.TCPConn.Close()
+	gc.once.Do(gc.g.conns.Done)
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestGraceful(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "nacl", "plan9", "windows":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	g := tcp.NewGraceful()
+	ln, err := g.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	c, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ac, err := g.Accept(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := g.Restart("-test.run=^TestGracefulChild$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ac.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := g.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetReadDeadline(time.Now().Add(3 * time.Second))
+	b, err := ioutil.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "child" {
+		t.Fatalf("got %q; want %q", b, "child")
+	}
+	if _, err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGracefulChild is the child process of TestGraceful.
+func TestGracefulChild(t *testing.T) {
+	v := os.Getenv(tcp.GracefulEnv)
+	if v == "" {
+		t.Skip("not a child process")
+	}
+	g := tcp.NewGraceful()
+	ln, err := g.Listen(v[:len("tcp")], v[len("tcp:"):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := g.Accept(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write([]byte("child"))
+	c.Close()
+	ln.Close()
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A ListenerGroup represents a group of listeners that share the same
+// local address using SO_REUSEPORT option, or SO_REUSEPORT_LB option
+// on FreeBSD. The kernel distributes incoming connections among the
+// listeners, which allows to run an accept loop per listener in
+// parallel.
+type ListenerGroup struct {
+	Listeners []*Listener // listeners in the order of joining the group
+}
+
+// ListenGroup announces on the local address with n listeners joined
+// the same SO_REUSEPORT group.
+// On FreeBSD, where SO_REUSEPORT option delivers all the connections
+// to one of the listeners, it uses SO_REUSEPORT_LB option instead.
+func ListenGroup(ctx context.Context, network, address string, n int) (*ListenerGroup, error) {
+	if n < 1 {
+		return nil, errors.New("invalid number of listeners")
+	}
+	lc := net.ListenConfig{Control: ControlFunc(groupReusePort())}
+	lc.SetMultipathTCP(false) // MPTCP sockets don't support reuseport programs
+	var g ListenerGroup
+	for i := 0; i < n; i++ {
+		ln, err := lc.Listen(ctx, network, address)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		address = ln.Addr().String() // others join the first one
+		tln, err := NewListener(ln)
+		if err != nil {
+			ln.Close()
+			g.Close()
+			return nil, err
+		}
+		g.Listeners = append(g.Listeners, tln)
+	}
+	return &g, nil
+}
+
+// groupReusePort returns the option that joins a listener to the
+// load-balancing group of the local address.
+func groupReusePort() tcpopt.Option {
+	if options[soReusePortLB].name > 0 {
+		return ReusePortLB(true)
+	}
+	return ReusePort(true)
+}
+
+// Close closes all the listeners of the group.
+func (g *ListenerGroup) Close() error {
+	var err error
+	for _, ln := range g.Listeners {
+		if cerr := ln.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// SteerByCPU attaches the classic BPF program that steers each
+// incoming connection to the listener at the index of the CPU
+// receiving the connection request modulo the number of listeners.
+// Combined with LockToCPU in the accept loop of each listener, it
+// keeps a connection on the CPU that processes its packets, which
+// improves the cache locality of servers with high accept rates.
+//
+// Only Linux supports this feature.
+func (g *ListenerGroup) SteerByCPU() error {
+	if len(g.Listeners) == 0 {
+		return errors.New("no listener")
+	}
+	return g.Listeners[0].AttachReusePortFilter(cpuSteeringFilter(len(g.Listeners)))
+}
+
+// LockToCPU wires the calling goroutine to its current operating
+// system thread, and binds the thread to the CPUs of which
+// connections are steered to the listener at index i by SteerByCPU.
+// It is intended to be called at the beginning of the accept loop of
+// the listener; the goroutine should call runtime.UnlockOSThread
+// only when it no longer cares about the CPUs.
+//
+// Only Linux supports this feature.
+func (g *ListenerGroup) LockToCPU(i int) error {
+	if i < 0 || i >= len(g.Listeners) {
+		return errors.New("invalid listener index")
+	}
+	if err := lockToCPU(i, len(g.Listeners)); err != nil {
+		return g.Listeners[i].opError("set", err)
+	}
+	return nil
+}
+
+const (
+	bpfALUModK = 0x94
+	bpfRetA    = 0x16
+
+	bpfAncillaryCPU = 0xfffff000 + 36 // SKF_AD_OFF + SKF_AD_CPU
+)
+
+// cpuSteeringFilter returns the classic BPF program that returns the
+// index of the receiving CPU modulo n.
+func cpuSteeringFilter(n int) []RawInstruction {
+	return []RawInstruction{
+		{Op: bpfLdAbsW, K: bpfAncillaryCPU},
+		{Op: bpfALUModK, K: uint32(n)},
+		{Op: bpfRetA},
+	}
+}
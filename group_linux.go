@@ -0,0 +1,31 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// maxCPUs is the number of CPUs in the affinity mask, CPU_SETSIZE.
+// The kernel ignores the CPUs that don't exist.
+const maxCPUs = 1024
+
+// lockToCPU locks the calling goroutine to its thread and binds the
+// thread to the CPUs c of which c mod n is equal to i.
+func lockToCPU(i, n int) error {
+	var mask [maxCPUs / 64]uint64
+	for c := i; c < maxCPUs; c += n {
+		mask[c/64] |= 1 << uint(c%64)
+	}
+	runtime.LockOSThread()
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0]))); errno != 0 {
+		runtime.UnlockOSThread()
+		return os.NewSyscallError("sched_setaffinity", errno)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestListenGroup(t *testing.T) {
+	switch runtime.GOOS {
+	case "freebsd", "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	g, err := tcp.ListenGroup(context.Background(), "tcp", "127.0.0.1:0", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	const N = 32
+	accepted := make(chan int, N)
+	var wg sync.WaitGroup
+	for i, ln := range g.Listeners {
+		wg.Add(1)
+		go func(i int, ln *tcp.Listener) {
+			defer wg.Done()
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				c.Close()
+				accepted <- i
+			}
+		}(i, ln)
+	}
+
+	address := g.Listeners[0].Addr().String()
+	for i := 0; i < N; i++ {
+		c, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+	counts := make([]int, len(g.Listeners))
+	for i := 0; i < N; i++ {
+		counts[<-accepted]++
+	}
+	g.Close()
+	wg.Wait()
+	for i, n := range counts {
+		if n == 0 {
+			t.Errorf("listener %d accepted no connection of %d", i, N)
+		}
+	}
+}
+
+func TestListenerGroupSteerByCPU(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	g, err := tcp.ListenGroup(context.Background(), "tcp", "127.0.0.1:0", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if err := g.SteerByCPU(); err != nil {
+		t.Fatal(err)
+	}
+	const N = 8
+	accepted := make(chan int, N)
+	var wg sync.WaitGroup
+	for i, ln := range g.Listeners {
+		wg.Add(1)
+		go func(i int, ln *tcp.Listener) {
+			defer wg.Done()
+			if err := g.LockToCPU(i); err != nil {
+				t.Log(err) // fewer CPUs than listeners
+			} else {
+				defer runtime.UnlockOSThread()
+			}
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				c.Close()
+				accepted <- i
+			}
+		}(i, ln)
+	}
+
+	address := g.Listeners[0].Addr().String()
+	for i := 0; i < N; i++ {
+		c, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+	for i := 0; i < N; i++ {
+		<-accepted
+	}
+	g.Close()
+	wg.Wait()
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"net"
+)
+
+// ContextDialer returns a function compatible with
+// grpc.WithContextDialer. The returned function connects to the
+// address on TCP using d and its option profile.
+//
+// The remote address of the returned connection is a PeerAddr, which
+// is carried to the Addr field of gRPC peer information. Use
+// PeerConn to obtain the connection from the peer information.
+func (d *Dialer) ContextDialer() func(ctx context.Context, address string) (net.Conn, error) {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		c, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		return &peerConn{Conn: c}, nil
+	}
+}
+
+// A PeerAddr represents a remote address that refers to the
+// connection to the remote end point.
+type PeerAddr struct {
+	net.Addr
+	Conn *Conn
+}
+
+// PeerConn returns the connection referred by the address addr, or
+// nil when addr is not a PeerAddr.
+func PeerConn(addr net.Addr) *Conn {
+	if pa, ok := addr.(*PeerAddr); ok {
+		return pa.Conn
+	}
+	return nil
+}
+
+type peerConn struct {
+	*Conn
+}
+
+func (pc *peerConn) RemoteAddr() net.Addr {
+	return &PeerAddr{Addr: pc.Conn.RemoteAddr(), Conn: pc.Conn}
+}
+
+// Unwrap returns the underlying connection.
+func (pc *peerConn) Unwrap() net.Conn { return pc.Conn }
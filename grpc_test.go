@@ -0,0 +1,60 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestContextDialer(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Close()
+	}()
+
+	d := tcp.Dialer{Options: []tcpopt.Option{tcpopt.NoDelay(true)}}
+	dial := d.ContextDialer()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	c, err := dial(ctx, ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.RemoteAddr().String() != ln.Addr().String() {
+		t.Fatalf("got %v; want %v", c.RemoteAddr(), ln.Addr())
+	}
+	tc := tcp.PeerConn(c.RemoteAddr())
+	if tc == nil {
+		t.Fatalf("no connection from %T", c.RemoteAddr())
+	}
+	if tc.LocalAddr().String() != c.LocalAddr().String() {
+		t.Fatalf("got %v; want %v", tc.LocalAddr(), c.LocalAddr())
+	}
+	if tcp.PeerConn(ln.Addr()) != nil {
+		t.Fatalf("got connection from %T", ln.Addr())
+	}
+}
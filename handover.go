@@ -0,0 +1,84 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// maxHandoverLen is the maximum length of handover metadata.
+const maxHandoverLen = 1 << 16
+
+// SendConn passes the connection c with the option profile opts to
+// another process over the Unix domain socket uc by using the
+// SCM_RIGHTS control message.
+// The caller may close c after SendConn returns; the connection
+// stays alive in the receiving process.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD, OpenBSD and
+// Solaris support this feature.
+func SendConn(uc *net.UnixConn, c *Conn, opts []tcpopt.Option) error {
+	var e checkpointEncoder
+	e.uint32(uint32(len(opts)))
+	for _, o := range opts {
+		b, err := o.Marshal()
+		if err != nil {
+			return err
+		}
+		e.uint32(uint32(int32(o.Level())))
+		e.uint32(uint32(int32(o.Name())))
+		e.bytes(b)
+	}
+	if len(e.b) > maxHandoverLen {
+		return errors.New("option profile too long")
+	}
+	rc, err := c.rawConn()
+	if err != nil {
+		return c.opError("send", err)
+	}
+	if err := sendConn(uc, rc, e.b); err != nil {
+		return c.opError("send", err)
+	}
+	return nil
+}
+
+// ReceiveConn receives a connection and its option profile passed by
+// SendConn over the Unix domain socket uc.
+func ReceiveConn(uc *net.UnixConn) (*Conn, []tcpopt.Option, error) {
+	b := make([]byte, maxHandoverLen)
+	c, n, err := receiveConn(uc, b)
+	if err != nil {
+		return nil, nil, &net.OpError{Op: "receive", Net: uc.LocalAddr().Network(), Source: uc.LocalAddr(), Err: err}
+	}
+	tc, err := NewConn(c)
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	d := checkpointDecoder{b: b[:n]}
+	var opts []tcpopt.Option
+	for i, l := uint32(0), d.uint32(); i < l && d.err == nil; i++ {
+		level := int(int32(d.uint32()))
+		name := int(int32(d.uint32()))
+		v := d.bytes()
+		if d.err != nil {
+			break
+		}
+		o, err := parse(level, name, v)
+		if err != nil {
+			tc.Close()
+			return nil, nil, tc.optionError("receive", level, name, err)
+		}
+		opts = append(opts, o)
+	}
+	if d.err != nil {
+		tc.Close()
+		return nil, nil, tc.opError("receive", d.err)
+	}
+	return tc, opts, nil
+}
@@ -0,0 +1,18 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package tcp
+
+import (
+	"net"
+	"syscall"
+)
+
+func sendConn(uc *net.UnixConn, rc syscall.RawConn, b []byte) error { return ErrNotSupported }
+
+func receiveConn(uc *net.UnixConn, b []byte) (net.Conn, int, error) {
+	return nil, 0, ErrNotSupported
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestConnHandover(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	dir, err := ioutil.TempDir("", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ul, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(dir, "sock"), Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ul.Close()
+	var ucs [2]*net.UnixConn
+	if ucs[0], err = net.DialUnix("unix", nil, ul.Addr().(*net.UnixAddr)); err != nil {
+		t.Fatal(err)
+	}
+	defer ucs[0].Close()
+	if ucs[1], err = ul.AcceptUnix(); err != nil {
+		t.Fatal(err)
+	}
+	defer ucs[1].Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := tcp.NewConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []tcpopt.Option{tcpopt.NoDelay(true), tcpopt.SendBuffer(1 << 16)}
+	if err := tcp.SendConn(ucs[0], tc, opts); err != nil {
+		t.Fatal(err)
+	}
+	tc.Close()
+	rc, ropts, err := tcp.ReceiveConn(ucs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if len(ropts) != len(opts) || ropts[0] != opts[0] || ropts[1] != opts[1] {
+		t.Fatalf("got %v; want %v", ropts, opts)
+	}
+	if rc.LocalAddr().String() != p.LocalAddr().String() {
+		t.Fatalf("got %v; want %v", rc.LocalAddr(), p.LocalAddr())
+	}
+	m := []byte("HELLO-R-U-THERE")
+	if _, err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, len(m))
+	if _, err := io.ReadFull(rc, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, m) {
+		t.Fatalf("got %q; want %q", b, m)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+func sendConn(uc *net.UnixConn, rc syscall.RawConn, b []byte) error {
+	var operr error
+	if err := rc.Control(func(s uintptr) {
+		_, _, operr = uc.WriteMsgUnix(b, syscall.UnixRights(int(s)), nil)
+	}); err != nil {
+		return err
+	}
+	return operr
+}
+
+func receiveConn(uc *net.UnixConn, b []byte) (net.Conn, int, error) {
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := uc.ReadMsgUnix(b, oob)
+	if err != nil {
+		return nil, 0, err
+	}
+	cms, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, 0, os.NewSyscallError("parse socket control message", err)
+	}
+	var fds []int
+	for _, cm := range cms {
+		rights, err := syscall.ParseUnixRights(&cm)
+		if err != nil {
+			continue
+		}
+		fds = append(fds, rights...)
+	}
+	if len(fds) != 1 {
+		for _, fd := range fds {
+			syscall.Close(fd)
+		}
+		return nil, 0, errors.New("no connection received")
+	}
+	f := os.NewFile(uintptr(fds[0]), "tcp")
+	defer f.Close()
+	c, err := net.FileConn(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, ok := c.(*net.TCPConn); !ok {
+		c.Close()
+		return nil, 0, errors.New("not a TCP connection")
+	}
+	return c, n, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A Handshake represents the measurement of the opening handshake of
+// a connection dialed by Dialer or accepted by Listener.
+type Handshake struct {
+	// Duration is the time from sending the SYN segment to the
+	// establishment of the connection, which is measured from the
+	// Control hook of the socket, right before connecting.
+	// With TCP Fast Open, which defers the handshake to the first
+	// write, it covers the socket setup only.
+	// It is zero on the accepted connections.
+	Duration time.Duration
+
+	// RTT is the smoothed RTT estimated by the kernel right after
+	// the establishment, which is derived from the handshake; on
+	// the accepted connections, it is the time from sending the
+	// SYN-ACK segment to receiving the ACK segment.
+	// A zero value means the platform doesn't support it.
+	RTT time.Duration
+
+	// Accepted is the time when the connection is accepted by
+	// Listener. It is zero on the dialed connections.
+	Accepted time.Time
+}
+
+// Handshake returns the measurement of the opening handshake of the
+// connection, which allows clients to log the connect latency
+// separately from the request latency, and servers to log the
+// network quality of clients from the very first request.
+// It reports false when the connection is neither dialed by Dialer
+// nor accepted by Listener.
+func (c *Conn) Handshake() (Handshake, bool) {
+	if c.hs == nil {
+		return Handshake{}, false
+	}
+	return *c.hs, true
+}
+
+// A handshakeClock records the start of the handshake on each socket
+// attempted by a dial, which may attempt multiple addresses in
+// parallel.
+type handshakeClock struct {
+	mu     sync.Mutex
+	starts map[uintptr]time.Time
+}
+
+func (hc *handshakeClock) control(fn func(context.Context, string, string, syscall.RawConn) error) func(context.Context, string, string, syscall.RawConn) error {
+	return func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		now := time.Now()
+		c.Control(func(s uintptr) {
+			hc.mu.Lock()
+			if hc.starts == nil {
+				hc.starts = make(map[uintptr]time.Time)
+			}
+			hc.starts[s] = now
+			hc.mu.Unlock()
+		})
+		return fn(ctx, network, address, c)
+	}
+}
+
+// measure sets the measurement of the handshake to the connection c
+// established at end.
+func (hc *handshakeClock) measure(c *Conn, end time.Time) {
+	hc.mu.Lock()
+	start, ok := hc.starts[c.s]
+	hc.mu.Unlock()
+	if !ok {
+		return
+	}
+	hs := Handshake{Duration: end.Sub(start)}
+	if info, err := connInfo(c); err == nil {
+		hs.RTT = info.RTT
+	}
+	c.hs = &hs
+}
+
+// accepted sets the measurement of the handshake to the connection c
+// accepted at t, which is read from the TCP information of c before
+// any data transfer updates the RTT.
+func accepted(c *Conn, t time.Time) {
+	hs := Handshake{Accepted: t}
+	if info, err := connInfo(c); err == nil {
+		hs.RTT = info.RTT
+	}
+	c.hs = &hs
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A Heartbeat represents a connection watched by application-level
+// heartbeats. It is an alternative to keepalive where the kernel
+// doesn't provide enough control over keepalive probes.
+//
+// A Heartbeat writes the ping message when nothing has been written
+// for the interval, and considers the peer dead when nothing has
+// been read for the timeout. The peer is expected to respond to the
+// ping message or to send its own heartbeats; the application must
+// read the connection through the Heartbeat for the received data to
+// count.
+type Heartbeat struct {
+	*Conn
+	fn       DeadPeerFunc
+	ping     []byte
+	interval time.Duration
+	timeout  time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	fireOnce sync.Once
+
+	wmu sync.Mutex // serializes writes and pings
+
+	mu        sync.Mutex
+	lastRead  time.Time
+	lastWrite time.Time
+	err       error // cause of failure
+}
+
+// NewHeartbeat returns a new heartbeat on connection c that writes
+// ping every interval of write inactivity and calls fn at most once
+// when nothing is read for timeout.
+func NewHeartbeat(c *Conn, interval, timeout time.Duration, ping []byte, fn DeadPeerFunc) (*Heartbeat, error) {
+	if interval <= 0 || timeout <= 0 || len(ping) == 0 || fn == nil {
+		return nil, errors.New("invalid heartbeat parameters")
+	}
+	now := time.Now()
+	hb := &Heartbeat{
+		Conn:      c,
+		fn:        fn,
+		ping:      append([]byte(nil), ping...),
+		interval:  interval,
+		timeout:   timeout,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		lastRead:  now,
+		lastWrite: now,
+	}
+	go hb.run()
+	return hb, nil
+}
+
+// Read reads data from the connection.
+func (hb *Heartbeat) Read(b []byte) (int, error) {
+	n, err := hb.Conn.Read(b)
+	hb.mu.Lock()
+	if n > 0 {
+		hb.lastRead = time.Now()
+	}
+	if hb.err != nil && err != nil {
+		err = hb.err
+	}
+	hb.mu.Unlock()
+	return n, err
+}
+
+// Write writes data to the connection.
+func (hb *Heartbeat) Write(b []byte) (int, error) {
+	hb.wmu.Lock()
+	n, err := hb.Conn.Write(b)
+	hb.wmu.Unlock()
+	hb.mu.Lock()
+	if n > 0 {
+		hb.lastWrite = time.Now()
+	}
+	if hb.err != nil && err != nil {
+		err = hb.err
+	}
+	hb.mu.Unlock()
+	return n, err
+}
+
+// ReadFrom implements the ReadFrom method of io.ReaderFrom interface.
+// Unlike Conn, it always copies through Write to track the activity.
+func (hb *Heartbeat) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{hb}, r)
+}
+
+// WriteTo implements the WriteTo method of io.WriterTo interface.
+// Unlike Conn, it always copies through Read to track the activity.
+func (hb *Heartbeat) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, readerOnly{hb})
+}
+
+// Err returns the error that caused the failure, or nil when no
+// failure has been detected.
+func (hb *Heartbeat) Err() error {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	return hb.err
+}
+
+// Stop stops heartbeats.
+// It doesn't close the connection.
+func (hb *Heartbeat) Stop() {
+	hb.stopOnce.Do(func() { close(hb.stop) })
+	<-hb.done
+}
+
+func (hb *Heartbeat) run() {
+	defer close(hb.done)
+	tick := hb.interval
+	if hb.timeout < tick {
+		tick = hb.timeout
+	}
+	t := time.NewTicker(tick / 2)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			if !hb.beat(now) {
+				return
+			}
+		case <-hb.stop:
+			return
+		}
+	}
+}
+
+// beat performs a heartbeat and reports whether the connection is
+// still alive.
+func (hb *Heartbeat) beat(now time.Time) bool {
+	hb.mu.Lock()
+	lastRead, lastWrite := hb.lastRead, hb.lastWrite
+	hb.mu.Unlock()
+	if now.Sub(lastRead) >= hb.timeout {
+		hb.fire(hb.ioError("read", syscall.ETIMEDOUT))
+		return false
+	}
+	if now.Sub(lastWrite) < hb.interval {
+		return true
+	}
+	hb.wmu.Lock()
+	hb.Conn.SetWriteDeadline(now.Add(hb.timeout))
+	_, err := hb.Conn.Write(hb.ping)
+	hb.Conn.SetWriteDeadline(time.Time{})
+	hb.wmu.Unlock()
+	if err != nil {
+		hb.fire(err)
+		return false
+	}
+	hb.mu.Lock()
+	hb.lastWrite = now
+	hb.mu.Unlock()
+	return true
+}
+
+func (hb *Heartbeat) fire(err error) {
+	hb.fireOnce.Do(func() {
+		hb.mu.Lock()
+		hb.err = err
+		hb.mu.Unlock()
+		hb.Conn.SetReadDeadline(time.Now()) // unblock pending reads
+		info, _ := connInfo(hb.Conn)
+		hb.fn(hb.Conn, info)
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpinfo"
+)
+
+func TestHeartbeat(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fired := make(chan struct{})
+	hb, err := tcp.NewHeartbeat(tc, 20*time.Millisecond, 200*time.Millisecond, []byte("PING"), func(*tcp.Conn, *tcpinfo.Info) {
+		close(fired)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hb.Stop()
+
+	// The peer echoes pings for a while and then goes silent.
+	go func() {
+		p.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		io.Copy(p, p)
+		io.Copy(ioutil.Discard, p)
+	}()
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(hb, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "PING" {
+		t.Fatalf("got %q; want %q", b, "PING")
+	}
+	select {
+	case <-fired:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout")
+	}
+	for {
+		if _, err := hb.Read(b); err != nil {
+			if !errors.Is(err, syscall.ETIMEDOUT) {
+				t.Fatalf("got %v; want %v", err, syscall.ETIMEDOUT)
+			}
+			break
+		}
+	}
+	if !errors.Is(hb.Err(), syscall.ETIMEDOUT) {
+		t.Fatalf("got %v; want %v", hb.Err(), syscall.ETIMEDOUT)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"sync"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// An OptionHook is called on every SetOption call.
+// The old is the previous value of the option o, or nil when it is
+// not readable. The err is the result of SetOption.
+type OptionHook func(c *Conn, o, old tcpopt.Option, err error)
+
+var optionHook struct {
+	sync.RWMutex
+	fn OptionHook
+}
+
+// SetOptionHook installs the package-wide hook fn for auditing socket
+// option changes. A nil fn removes the installed hook.
+func SetOptionHook(fn OptionHook) {
+	optionHook.Lock()
+	optionHook.fn = fn
+	optionHook.Unlock()
+}
+
+func (c *Conn) setOptionWithHook(o tcpopt.Option) error {
+	optionHook.RLock()
+	fn := optionHook.fn
+	optionHook.RUnlock()
+	t := c.tracer()
+	if fn == nil && t == nil {
+		return c.setOption(o)
+	}
+	var old tcpopt.Option
+	if b, err := o.Marshal(); err == nil && len(b) > 0 {
+		old, _ = c.Option(o.Level(), o.Name(), make([]byte, len(b)))
+	}
+	err := c.setOption(o)
+	if fn != nil {
+		fn(c, o, old, err)
+	}
+	if t != nil {
+		t.option(o, old, err)
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestOptionHook(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	tcp.SetOptionHook(func(c *tcp.Conn, o, old tcpopt.Option, err error) {
+		calls++
+		if c != tc {
+			t.Errorf("got %p; want %p", c, tc)
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		if old != tcpopt.NoDelay(true) {
+			t.Errorf("got %#v; want %#v", old, tcpopt.NoDelay(true))
+		}
+	})
+	defer tcp.SetOptionHook(nil)
+	if err := tc.SetOption(tcpopt.NoDelay(false)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("got %d calls; want 1", calls)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// TransportDialContext returns a function for the DialContext field
+// of http.Transport. The returned function connects using d and
+// returns a Conn, which allows WithRequestConn to find the
+// connection used for a request.
+func (d *Dialer) TransportDialContext() func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		c, err := d.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+type serverConnKey struct{}
+
+// ServerConnContext is a function for the ConnContext field of
+// http.Server. It stashes the Conn for the accepted connection c in
+// the returned context, which allows handlers to call ServerConn
+// with the request context.
+//
+// The Conn shares the socket with c and is not tracked by the leak
+// detector, because the server closes c.
+func ServerConnContext(ctx context.Context, c net.Conn) context.Context {
+	tc := requestConn(c)
+	if tc == nil {
+		nc := c
+		for u := unwrap(nc); u != nil; u = unwrap(nc) {
+			nc = u
+		}
+		s, rc, wrapped, err := wrappedSocketOf(nc)
+		if err != nil {
+			return ctx
+		}
+		tc = newConn(nc, s, rc)
+		tc.wrapped = wrapped
+	}
+	return context.WithValue(ctx, serverConnKey{}, tc)
+}
+
+// ServerConn returns the Conn stashed by ServerConnContext in ctx, or
+// nil.
+func ServerConn(ctx context.Context) *Conn {
+	tc, _ := ctx.Value(serverConnKey{}).(*Conn)
+	return tc
+}
+
+// HijackedConn returns a Conn for the connection c and the buffered
+// data brw returned from the Hijack method of http.Hijacker.
+// It flushes the data pending in the writer of brw, and returns the
+// remainder of the reader of brw, which has already been read from
+// the connection and must be consumed before reading from the Conn.
+func HijackedConn(c net.Conn, brw *bufio.ReadWriter) (*Conn, []byte, error) {
+	var rest []byte
+	if brw != nil {
+		if err := brw.Flush(); err != nil {
+			return nil, nil, err
+		}
+		if n := brw.Reader.Buffered(); n > 0 {
+			b, err := brw.Reader.Peek(n)
+			if err != nil {
+				return nil, nil, err
+			}
+			rest = append(rest, b...)
+		}
+	}
+	if tc, ok := c.(*Conn); ok {
+		return tc, rest, nil
+	}
+	tc, err := NewConn(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tc, rest, nil
+}
+
+// A RequestConn represents the connection used for an HTTP request.
+type RequestConn struct {
+	mu     sync.Mutex
+	c      *Conn
+	reused bool
+	start  Sample // sample when the connection is obtained
+}
+
+// WithRequestConn returns a new context that records the connection
+// used for an HTTP request sent with the context.
+// It installs an httptrace.ClientTrace hook for GotConn, and the
+// connection must be created by TransportDialContext or NewConn.
+func WithRequestConn(ctx context.Context) (context.Context, *RequestConn) {
+	rc := &RequestConn{}
+	trace := &httptrace.ClientTrace{GotConn: rc.gotConn}
+	return httptrace.WithClientTrace(ctx, trace), rc
+}
+
+// Conn returns the connection, or nil when the request has not
+// obtained any connection yet.
+func (rc *RequestConn) Conn() *Conn {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.c
+}
+
+// Reused reports whether the connection has been used for a previous
+// request.
+func (rc *RequestConn) Reused() bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.reused
+}
+
+// Info returns the current connection information such as RTT.
+func (rc *RequestConn) Info() (*tcpinfo.Info, error) {
+	c := rc.Conn()
+	if c == nil {
+		return nil, errors.New("no connection")
+	}
+	return connInfo(c)
+}
+
+// Delta returns the differences of counters, such as retransmitted
+// segments, since the request obtained the connection.
+//
+// Only Linux supports the counters.
+func (rc *RequestConn) Delta() (*SampleDelta, error) {
+	i, err := rc.Info()
+	if err != nil {
+		return nil, err
+	}
+	now := Sample{Time: time.Now(), Info: i}
+	rc.mu.Lock()
+	start := rc.start
+	rc.mu.Unlock()
+	if start.Info == nil {
+		return nil, errors.New("no initial sample")
+	}
+	return delta(&start, &now), nil
+}
+
+func (rc *RequestConn) gotConn(info httptrace.GotConnInfo) {
+	c := requestConn(info.Conn)
+	if c == nil {
+		return
+	}
+	s := Sample{Time: time.Now()}
+	s.Info, _ = connInfo(c)
+	rc.mu.Lock()
+	rc.c, rc.reused, rc.start = c, info.Reused, s
+	rc.mu.Unlock()
+}
+
+// requestConn returns the Conn underlying c, unwrapping TLS
+// connections, or nil.
+func requestConn(c net.Conn) *Conn {
+	for c != nil {
+		if tc, ok := c.(*Conn); ok {
+			return tc
+		}
+		c = unwrap(c)
+	}
+	return nil
+}
+
+func connInfo(c *Conn) (*tcpinfo.Info, error) {
+	var o tcpinfo.Info
+	var b [256]byte
+	io, err := c.Option(o.Level(), o.Name(), b[:])
+	if err != nil {
+		return nil, err
+	}
+	return io.(*tcpinfo.Info), nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestRequestConn(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "HELLO-R-U-THERE")
+	}))
+	defer ts.Close()
+	var d tcp.Dialer
+	tr := &http.Transport{DialContext: d.TransportDialContext()}
+	defer tr.CloseIdleConnections()
+	client := &http.Client{Transport: tr}
+
+	for i, reused := range []bool{false, true} {
+		ctx, rc := tcp.WithRequestConn(t.Context())
+		req, err := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		if rc.Conn() == nil {
+			t.Fatalf("#%d: no connection", i)
+		}
+		if rc.Reused() != reused {
+			t.Fatalf("#%d: got %v; want %v", i, rc.Reused(), reused)
+		}
+		info, err := rc.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.RTT <= 0 && runtime.GOOS == "linux" {
+			t.Fatalf("#%d: got %v; want positive rtt", i, info.RTT)
+		}
+		delta, err := rc.Delta()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runtime.GOOS == "linux" && delta.BytesReceived == 0 {
+			t.Fatalf("#%d: got %+v; want non-zero received bytes", i, delta)
+		}
+	}
+}
+
+func TestServerConnContext(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	for _, tls := range []bool{false, true} {
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc := tcp.ServerConn(r.Context())
+			if tc == nil {
+				http.Error(w, "no connection", http.StatusInternalServerError)
+				return
+			}
+			io.WriteString(w, tc.RemoteAddr().String())
+		}))
+		ts.Config.ConnContext = tcp.ServerConnContext
+		if tls {
+			ts.StartTLS()
+		} else {
+			ts.Start()
+		}
+		client := ts.Client()
+		var laddr string
+		client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			c, err := d.DialContext(ctx, network, address)
+			if err == nil {
+				laddr = c.LocalAddr().String()
+			}
+			return c, err
+		}
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			ts.Close()
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		ts.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(b) != laddr {
+			t.Fatalf("tls=%v: got %d, %q; want %q", tls, resp.StatusCode, b, laddr)
+		}
+	}
+}
+
+func TestHijackedConn(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tc, rest, err := tcp.HijackedConn(c, brw)
+		if err != nil {
+			c.Close()
+			return
+		}
+		defer tc.Close()
+		if err := tc.SetOption(tcpopt.NoDelay(true)); err != nil {
+			return
+		}
+		io.WriteString(tc, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		tc.Write(rest)
+		io.Copy(tc, tc)
+	}))
+	defer ts.Close()
+
+	c, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := io.WriteString(c, "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\nHELLO-R-U-THERE"); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got %d; want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	b := make([]byte, len("HELLO-R-U-THERE"))
+	if _, err := io.ReadFull(br, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "HELLO-R-U-THERE" {
+		t.Fatalf("got %q; want %q", b, "HELLO-R-U-THERE")
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	r := tcp.NewConnRegistry()
+	c, s := tcptest.Pair(t)
+	r.Register(c, tcp.Labels{"tenant": "blue"})
+	r.Register(s, tcp.Labels{"tenant": "red"})
+	h := tcp.DebugHandler(r)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/tcp", nil))
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "2 connections\n") || !strings.Contains(body, c.LocalAddr().String()+"->"+c.RemoteAddr().String()) || !strings.Contains(body, "tenant=blue") {
+		t.Fatalf("got %q", body)
+	}
+	t.Log(body)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/tcp?format=json&tenant=red", nil))
+	var es []struct {
+		Local  string
+		Labels map[string]string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &es); err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].Local != s.LocalAddr().String() || es[0].Labels["tenant"] != "red" {
+		t.Fatalf("got %+v; want the server connection only", es)
+	}
+}
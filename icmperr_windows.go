@@ -0,0 +1,37 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// sizeofICMPErrorInfo is the size of ICMP_ERROR_INFO, which consists
+// of SOCKADDR_INET, IPPROTO and the type and code of ICMP message.
+// The address family of SOCKADDR_INET tells the version of ICMP.
+const sizeofICMPErrorInfo = 0x24
+
+func icmpError(s uintptr) (*ExtendedError, error) {
+	var b [sizeofICMPErrorInfo]byte
+	l := int32(len(b))
+	if err := syscall.Getsockopt(syscall.Handle(s), ianaProtocolTCP, sysTCP_ICMP_ERROR_INFO, &b[0], &l); err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	e := ExtendedError{Type: int(b[32]), Code: int(b[33])}
+	switch nativeEndian.Uint16(b[0:2]) {
+	case syscall.AF_INET:
+		e.Origin = ErrorOriginICMP
+		e.Offender = net.IPv4(b[4], b[5], b[6], b[7])
+	case syscall.AF_INET6:
+		e.Origin = ErrorOriginICMPv6
+		e.Offender = make(net.IP, net.IPv6len)
+		copy(e.Offender, b[8:24])
+	default:
+		return nil, nil // no ICMP error received
+	}
+	return &e, nil
+}
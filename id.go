@@ -0,0 +1,32 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "sync/atomic"
+
+// synthesizedID is the bit set in the connection IDs synthesized by
+// the package, which keeps them apart from the socket cookies
+// counted up from zero by the kernel.
+const synthesizedID = 1 << 63
+
+var lastSynthesizedID uint64
+
+// ID returns the identifier of the connection.
+//
+// The identifier is fixed when the connection is created and stays
+// the same after Close, which makes it usable as a correlation key of
+// metrics and logs related to the connection. It is the socket cookie
+// on the platforms that support Cookie; otherwise, it is a number
+// synthesized by the package with the most significant bit set,
+// which is unique within the process.
+func (c *Conn) ID() uint64 { return c.id }
+
+// newID returns the identifier of the connection c.
+func newID(c *Conn) uint64 {
+	if cookie, err := c.Cookie(); err == nil && cookie != 0 {
+		return cookie
+	}
+	return synthesizedID | atomic.AddUint64(&lastSynthesizedID, 1)
+}
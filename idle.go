@@ -0,0 +1,47 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// IdleTime returns the elapsed time since the last activity on the
+// wire, as tracked by the kernel.
+// Unlike deadlines on application-level reads, keepalive and
+// acknowledgment segments also count as activity.
+//
+// On Linux, the activity is the most recent of sending data,
+// receiving data and receiving an acknowledgment.
+// On FreeBSD, the activity is receiving data.
+//
+// Only FreeBSD and Linux support this feature.
+func (c *Conn) IdleTime() (time.Duration, error) {
+	switch runtime.GOOS {
+	case "freebsd", "linux":
+	default:
+		return 0, c.opError("get", ErrNotSupported)
+	}
+	var o tcpinfo.Info
+	var b [256]byte
+	io, err := c.Option(o.Level(), o.Name(), b[:])
+	if err != nil {
+		return 0, err
+	}
+	i := io.(*tcpinfo.Info)
+	d := i.LastDataReceived
+	if runtime.GOOS == "linux" {
+		if i.LastDataSent < d {
+			d = i.LastDataSent
+		}
+		if i.LastAckReceived < d {
+			d = i.LastAckReceived
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,22 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "github.com/mikioh/tcpinfo"
+
+// ReadInfo reads the TCP information of the connection into the
+// caller-owned info.
+//
+// On Linux, it reuses the slices and the structs that info refers
+// to, and doesn't allocate once info is filled by the first call,
+// which suits telemetry loops sampling many connections frequently.
+// On the other platforms, it allocates as reading tcpinfo.Info
+// option does.
+func (c *Conn) ReadInfo(info *tcpinfo.Info) error {
+	if err := readInfo(c, info); err != nil {
+		return c.opError("get", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+func readInfo(c *Conn, info *tcpinfo.Info) error {
+	var ti tcpInfo
+	if err := c.readTCPInfo(&ti); err != nil {
+		return err
+	}
+	info.State = tcpinfo.Unknown
+	if int(ti.State) < len(linuxStates) {
+		info.State = linuxStates[ti.State]
+	}
+	info.Options, info.PeerOptions = info.Options[:0], info.PeerOptions[:0]
+	if ti.Options&sysTCPI_OPT_WSCALE != 0 {
+		info.Options = append(info.Options, tcpinfo.WindowScale(ti.Pad_cgo_0[0]>>4))
+		info.PeerOptions = append(info.PeerOptions, tcpinfo.WindowScale(ti.Pad_cgo_0[0]&0x0f))
+	}
+	if ti.Options&sysTCPI_OPT_SACK != 0 {
+		info.Options = append(info.Options, tcpinfo.SACKPermitted(true))
+		info.PeerOptions = append(info.PeerOptions, tcpinfo.SACKPermitted(true))
+	}
+	if ti.Options&sysTCPI_OPT_TIMESTAMPS != 0 {
+		info.Options = append(info.Options, tcpinfo.Timestamps(true))
+		info.PeerOptions = append(info.PeerOptions, tcpinfo.Timestamps(true))
+	}
+	info.SenderMSS = tcpinfo.MaxSegSize(ti.Snd_mss)
+	info.ReceiverMSS = tcpinfo.MaxSegSize(ti.Rcv_mss)
+	info.RTT = time.Duration(ti.Rtt) * time.Microsecond
+	info.RTTVar = time.Duration(ti.Rttvar) * time.Microsecond
+	info.RTO = time.Duration(ti.Rto) * time.Microsecond
+	info.ATO = time.Duration(ti.Ato) * time.Microsecond
+	info.LastDataSent = time.Duration(ti.Last_data_sent) * time.Millisecond
+	info.LastDataReceived = time.Duration(ti.Last_data_recv) * time.Millisecond
+	info.LastAckReceived = time.Duration(ti.Last_ack_recv) * time.Millisecond
+	if info.FlowControl == nil {
+		info.FlowControl = new(tcpinfo.FlowControl)
+	}
+	*info.FlowControl = tcpinfo.FlowControl{
+		ReceiverWindow: uint(ti.Rcv_space),
+	}
+	if info.CongestionControl == nil {
+		info.CongestionControl = new(tcpinfo.CongestionControl)
+	}
+	*info.CongestionControl = tcpinfo.CongestionControl{
+		SenderSSThreshold:   uint(ti.Snd_ssthresh),
+		ReceiverSSThreshold: uint(ti.Rcv_ssthresh),
+		SenderWindowSegs:    uint(ti.Snd_cwnd),
+	}
+	if info.Sys == nil {
+		info.Sys = new(tcpinfo.SysInfo)
+	}
+	*info.Sys = tcpinfo.SysInfo{
+		PathMTU:                 uint(ti.Pmtu),
+		AdvertisedMSS:           tcpinfo.MaxSegSize(ti.Advmss),
+		CAState:                 tcpinfo.CAState(ti.Ca_state),
+		Retransmissions:         uint(ti.Retransmits),
+		Backoffs:                uint(ti.Backoff),
+		WindowOrKeepAliveProbes: uint(ti.Probes),
+		UnackedSegs:             uint(ti.Unacked),
+		SackedSegs:              uint(ti.Sacked),
+		LostSegs:                uint(ti.Lost),
+		RetransSegs:             uint(ti.Retrans),
+		ForwardAckSegs:          uint(ti.Fackets),
+		ReorderedSegs:           uint(ti.Reordering),
+		ReceiverRTT:             time.Duration(ti.Rcv_rtt) * time.Microsecond,
+		TotalRetransSegs:        uint(ti.Total_retrans),
+		PacingRate:              uint64(ti.Pacing_rate),
+		ThruBytesAcked:          uint64(ti.Bytes_acked),
+		ThruBytesReceived:       uint64(ti.Bytes_received),
+		SegsIn:                  uint(ti.Segs_in),
+		SegsOut:                 uint(ti.Segs_out),
+		NotSentBytes:            uint(ti.Notsent_bytes),
+		MinRTT:                  time.Duration(ti.Min_rtt) * time.Microsecond,
+		DataSegsIn:              uint(ti.Data_segs_in),
+		DataSegsOut:             uint(ti.Data_segs_out),
+	}
+	return nil
+}
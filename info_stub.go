@@ -0,0 +1,28 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import (
+	"os"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+func readInfo(c *Conn, info *tcpinfo.Info) error {
+	var o tcpinfo.Info
+	var b [256]byte
+	n, err := c.getsockopt(o.Level(), o.Name(), b[:])
+	if err != nil {
+		return os.NewSyscallError("getsockopt", err)
+	}
+	io, err := parse(o.Level(), o.Name(), b[:n])
+	if err != nil {
+		return err
+	}
+	*info = *io.(*tcpinfo.Info)
+	return nil
+}
@@ -9,16 +9,19 @@ package tcp_test
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"runtime"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
 	"github.com/mikioh/tcpinfo"
 )
 
@@ -113,3 +116,389 @@ func monitor(c *tcp.Conn, log chan<- string, sig <-chan struct{}) {
 		}
 	}
 }
+
+func TestIdleTime(t *testing.T) {
+	switch runtime.GOOS {
+	case "freebsd", "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Write([]byte("HELLO-R-U-THERE")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	d, err := tc.IdleTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d < 50*time.Millisecond || d > 5*time.Second {
+		t.Fatalf("got %v; want around 100ms", d)
+	}
+}
+
+func TestSACKStats(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := tc.SACKStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Reordering <= 0 {
+		t.Fatalf("got %+v; want a positive reordering metric", st)
+	}
+}
+
+func TestWindows(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cwnd, err := tc.CongestionWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cwnd <= 0 {
+		t.Fatalf("got %d; want a positive congestion window", cwnd)
+	}
+	ssthresh, err := tc.SlowStartThreshold()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ssthresh <= 0 {
+		t.Fatalf("got %d; want a positive slow start threshold", ssthresh)
+	}
+	if _, err := tc.SendWindow(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuality(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	go io.Copy(ioutil.Discard, p)
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.Write(make([]byte, 1<<16)); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := tc.Quality()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Score <= 0.5 || q.Score > 1 {
+		t.Fatalf("got %+v; want a high score on loopback", q)
+	}
+}
+
+func TestZeroWindowStats(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close() // never read
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 1<<16)
+	for {
+		tc.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+		if _, err := tc.Write(b); err != nil {
+			if !isTimeout(err) {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		st, err := tc.ZeroWindowStats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.PeerWindow == 0 && st.Probes > 0 && st.RwndLimited > 0 {
+			return
+		}
+	}
+	st, _ := tc.ZeroWindowStats()
+	t.Fatalf("got %+v; want zero window probes", st)
+}
+
+func TestReceiveWindowStats(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, s := tcptest.Pair(t)
+	const N = 1 << 20
+	written := make(chan error, 1)
+	go func() {
+		_, err := c.Write(make([]byte, N))
+		written <- err
+	}()
+	if _, err := io.ReadFull(s, make([]byte, N)); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.ReceiveWindowStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Window < 0 || st.SSThreshold <= 0 || st.Space <= 0 || st.Buffer <= 0 || st.MSS <= 0 {
+		t.Fatalf("got %+v; want an open receive window", st)
+	}
+	if st.Window == 0 {
+		t.Log("window not reported; requires Linux 6.2 or above")
+	}
+}
+
+func TestByteCounters(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, s := tcptest.Pair(t)
+	prev, err := c.ByteCounters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const N = 1 << 20
+	go func() {
+		c.Write(make([]byte, N))
+	}()
+	if _, err := io.ReadFull(s, make([]byte, N)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	cur, err := c.ByteCounters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cur.Acked-prev.Acked < N || cur.Received-prev.Received != 1 {
+		t.Fatalf("got acked=%d received=%d; want at least %d, 1", cur.Acked-prev.Acked, cur.Received-prev.Received, N)
+	}
+	send, receive := cur.Goodput(prev)
+	if send <= 0 || receive <= 0 {
+		t.Fatalf("got %v, %v; want positive rates", send, receive)
+	}
+	t.Logf("%+v: send=%.0fB/s receive=%.0fB/s", cur, send, receive)
+}
+
+func TestReceiveTimestamp(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, s := tcptest.Pair(t)
+	if err := c.EnableReceiveTimestamps(); err != nil {
+		t.Fatal(err)
+	}
+	// The kernel may turn on the timestamping after the first
+	// segments arrive; retry with new data until a segment is
+	// timestamped.
+	var b [5]byte
+	for i := 0; ; i++ {
+		before := time.Now()
+		if _, err := s.Write([]byte("HELLO")); err != nil {
+			t.Fatal(err)
+		}
+		for c.Buffered() < 5 {
+			time.Sleep(time.Millisecond)
+		}
+		ts, err := c.ReceiveTimestamp()
+		if errors.Is(err, tcp.ErrNoTimestamp) && i < 100 {
+			if _, err := io.ReadFull(c, b[:]); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts.Before(before.Add(-time.Second)) || ts.After(time.Now().Add(time.Second)) {
+			t.Fatalf("got %v; want around %v", ts, before)
+		}
+		break
+	}
+	if _, err := io.ReadFull(c, b[:]); err != nil || string(b[:]) != "HELLO" {
+		t.Fatalf("got %q, %v; want HELLO left readable", b[:], err)
+	}
+	if _, err := c.ReceiveTimestamp(); !errors.Is(err, syscall.ENOENT) {
+		t.Fatalf("got %v; want %v", err, syscall.ENOENT)
+	}
+}
+
+func TestReadInfo(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	var info tcpinfo.Info
+	if err := c.ReadInfo(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.State != tcpinfo.Established {
+		t.Fatalf("got %v; want %v", info.State, tcpinfo.Established)
+	}
+	if runtime.GOOS != "linux" {
+		return
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := c.ReadInfo(&info); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("got %v allocs; want 0", allocs)
+	}
+}
+
+func TestNegotiatedOptions(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	no, err := c.NegotiatedOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info tcpinfo.Info
+	if err := c.ReadInfo(&info); err != nil {
+		t.Fatal(err)
+	}
+	var want tcp.NegotiatedOptions
+	for _, o := range info.Options {
+		switch o := o.(type) {
+		case tcpinfo.SACKPermitted:
+			want.SACK = bool(o)
+		case tcpinfo.Timestamps:
+			want.Timestamps = bool(o)
+		case tcpinfo.WindowScale:
+			want.WindowScale, want.ReceiveScale = true, int(o)
+		}
+	}
+	for _, o := range info.PeerOptions {
+		if o, ok := o.(tcpinfo.WindowScale); ok {
+			want.SendScale = int(o)
+		}
+	}
+	if no.SACK != want.SACK || no.Timestamps != want.Timestamps || no.WindowScale != want.WindowScale || no.SendScale != want.SendScale || no.ReceiveScale != want.ReceiveScale {
+		t.Fatalf("got %+v; want %+v", no, want)
+	}
+	t.Logf("%+v", no)
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+// An IOEvent represents a read or write on a connection.
+type IOEvent struct {
+	Op       string        // "read" or "write"
+	N        int64         // # of bytes transferred
+	Duration time.Duration // time spent in the operation
+	Err      error         // result of the operation
+}
+
+// An IOHook is called after every read and write on the connection
+// on which the hook is installed.
+// It must not retain ev after returning.
+type IOHook func(c *Conn, ev *IOEvent)
+
+// AddIOHook installs the hook fn observing the reads and writes on
+// the connection, and returns the function that removes the hook.
+// The hook observes the Read, Write, ReadFrom, WriteTo, ReadContext,
+// WriteContext, ReadFull, TryRead, TryWrite, WriteMore, Sendfile and
+// ReadPooled calls; Sendfile is observed as a write. The hooks are
+// called in the order of installation, which allows the layers of
+// metrics and tracing to observe the connection without wrapping it.
+func (c *Conn) AddIOHook(fn IOHook) (remove func()) {
+	p := &fn
+	c.ioHookMu.Lock()
+	old := c.hooks()
+	c.ioHooks.Store(append(old[:len(old):len(old)], p))
+	c.ioHookMu.Unlock()
+	return func() {
+		c.ioHookMu.Lock()
+		defer c.ioHookMu.Unlock()
+		old := c.hooks()
+		fns := make([]*IOHook, 0, len(old))
+		for _, q := range old {
+			if q != p {
+				fns = append(fns, q)
+			}
+		}
+		c.ioHooks.Store(fns)
+	}
+}
+
+// hooks returns the installed I/O hooks. The list is replaced on
+// every change, which keeps reads and writes free of locking.
+func (c *Conn) hooks() []*IOHook {
+	fns, _ := c.ioHooks.Load().([]*IOHook)
+	return fns
+}
+
+// Read reads data from the connection.
+func (c *Conn) Read(b []byte) (int, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.Conn.Read(b)
+	}
+	start := time.Now()
+	n, err := c.Conn.Read(b)
+	c.observe(fns, "read", int64(n), start, err)
+	return n, err
+}
+
+// Write writes data to the connection.
+func (c *Conn) Write(b []byte) (int, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.Conn.Write(b)
+	}
+	start := time.Now()
+	n, err := c.Conn.Write(b)
+	c.observe(fns, "write", int64(n), start, err)
+	return n, err
+}
+
+func (c *Conn) observe(fns []*IOHook, op string, n int64, start time.Time, err error) {
+	ev := IOEvent{Op: op, N: n, Duration: time.Since(start), Err: err}
+	for _, fn := range fns {
+		(*fn)(c, &ev)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// An IOStats represents the counters of reads and writes on a
+// connection, counted by the package above the kernel.
+type IOStats struct {
+	BytesRead    int64     // # of bytes read by the application
+	BytesWritten int64     // # of bytes written by the application
+	Reads        int64     // # of read calls
+	Writes       int64     // # of write calls
+	LastRead     time.Time // time of the last read transferring data; zero when never
+	LastWrite    time.Time // time of the last write transferring data; zero when never
+}
+
+// ioCounters are the atomic counters of IOStats.
+type ioCounters struct {
+	bytesRead    int64
+	bytesWritten int64
+	reads        int64
+	writes       int64
+	lastRead     int64 // in Unix nanoseconds
+	lastWrite    int64 // in Unix nanoseconds
+}
+
+func (ctr *ioCounters) observe(_ *Conn, ev *IOEvent) {
+	if ev.Op == "read" {
+		atomic.AddInt64(&ctr.reads, 1)
+		if ev.N > 0 {
+			atomic.AddInt64(&ctr.bytesRead, ev.N)
+			atomic.StoreInt64(&ctr.lastRead, time.Now().UnixNano())
+		}
+		return
+	}
+	atomic.AddInt64(&ctr.writes, 1)
+	if ev.N > 0 {
+		atomic.AddInt64(&ctr.bytesWritten, ev.N)
+		atomic.StoreInt64(&ctr.lastWrite, time.Now().UnixNano())
+	}
+}
+
+// EnableStats starts counting the reads and writes on the
+// connection, which are returned from Stats.
+// The counters are maintained by an I/O hook; see AddIOHook. The
+// counting is opt-in because it costs a few atomic operations per
+// call. Calling EnableStats again is a no-op.
+func (c *Conn) EnableStats() {
+	c.ioHookMu.Lock()
+	if c.ioStats != nil {
+		c.ioHookMu.Unlock()
+		return
+	}
+	c.ioStats = new(ioCounters)
+	fn := IOHook(c.ioStats.observe)
+	old := c.hooks()
+	c.ioHooks.Store(append(old[:len(old):len(old)], &fn))
+	c.ioHookMu.Unlock()
+}
+
+// Stats returns the counters of reads and writes on the connection.
+// It reports false when EnableStats has not been called.
+//
+// The byte counters count the data passed between the application
+// and the kernel, which may differ from the kernel byte counters
+// such as ByteCounters by the data queued in the socket buffers.
+func (c *Conn) Stats() (IOStats, bool) {
+	c.ioHookMu.Lock()
+	ctr := c.ioStats
+	c.ioHookMu.Unlock()
+	if ctr == nil {
+		return IOStats{}, false
+	}
+	st := IOStats{
+		BytesRead:    atomic.LoadInt64(&ctr.bytesRead),
+		BytesWritten: atomic.LoadInt64(&ctr.bytesWritten),
+		Reads:        atomic.LoadInt64(&ctr.reads),
+		Writes:       atomic.LoadInt64(&ctr.writes),
+	}
+	if ns := atomic.LoadInt64(&ctr.lastRead); ns != 0 {
+		st.LastRead = time.Unix(0, ns)
+	}
+	if ns := atomic.LoadInt64(&ctr.lastWrite); ns != 0 {
+		st.LastWrite = time.Unix(0, ns)
+	}
+	return st, true
+}
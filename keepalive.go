@@ -0,0 +1,88 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A KeepAlive represents a set of keepalive parameters.
+//
+// On Darwin, IdleInterval maps to TCP_KEEPALIVE, ProbeInterval maps
+// to TCP_KEEPINTVL and ProbeCount maps to TCP_KEEPCNT.
+// OpenBSD supports only Enable. Windows doesn't support ProbeCount.
+type KeepAlive struct {
+	Enable        bool          // use of keepalive
+	IdleInterval  time.Duration // idle interval until the first probe is sent
+	ProbeInterval time.Duration // interval between keepalive probes
+	ProbeCount    int           // # of unanswered probes before dropping the connection
+}
+
+// SetKeepAlive sets the keepalive parameters.
+// Zero values of IdleInterval, ProbeInterval and ProbeCount leave
+// the current values unchanged.
+func (c *Conn) SetKeepAlive(ka *KeepAlive) error {
+	opts := []tcpopt.Option{tcpopt.KeepAlive(ka.Enable)}
+	if ka.Enable && runtime.GOOS != "openbsd" {
+		if ka.IdleInterval > 0 {
+			opts = append(opts, tcpopt.KeepAliveIdleInterval(ka.IdleInterval))
+		}
+		if ka.ProbeInterval > 0 {
+			opts = append(opts, tcpopt.KeepAliveProbeInterval(ka.ProbeInterval))
+		}
+		if ka.ProbeCount > 0 && runtime.GOOS != "windows" {
+			opts = append(opts, tcpopt.KeepAliveProbeCount(ka.ProbeCount))
+		}
+	}
+	for _, o := range opts {
+		if err := c.SetOption(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KeepAlive returns the current keepalive parameters.
+//
+// Windows doesn't support this feature.
+func (c *Conn) KeepAlive() (*KeepAlive, error) {
+	var ka KeepAlive
+	var err error
+	if ka.Enable, err = c.KeepAliveEnabled(); err != nil {
+		return nil, err
+	}
+	if runtime.GOOS == "openbsd" {
+		return &ka, nil
+	}
+	o, err := c.option4(tcpopt.KeepAliveIdleInterval(0))
+	if err != nil {
+		return nil, err
+	}
+	idle, ok := o.(tcpopt.KeepAliveIdleInterval)
+	if !ok {
+		return nil, c.typeError(o)
+	}
+	ka.IdleInterval = time.Duration(idle)
+	if o, err = c.option4(tcpopt.KeepAliveProbeInterval(0)); err != nil {
+		return nil, err
+	}
+	probe, ok := o.(tcpopt.KeepAliveProbeInterval)
+	if !ok {
+		return nil, c.typeError(o)
+	}
+	ka.ProbeInterval = time.Duration(probe)
+	if o, err = c.option4(tcpopt.KeepAliveProbeCount(0)); err != nil {
+		return nil, err
+	}
+	count, ok := o.(tcpopt.KeepAliveProbeCount)
+	if !ok {
+		return nil, c.typeError(o)
+	}
+	ka.ProbeCount = int(count)
+	return &ka, nil
+}
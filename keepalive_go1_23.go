@@ -0,0 +1,80 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.23
+
+package tcp
+
+import (
+	"net"
+	"time"
+)
+
+// Default keepalive parameters used by the net package when the
+// fields of net.KeepAliveConfig are zero.
+const (
+	defaultKeepAliveIdle     = 15 * time.Second
+	defaultKeepAliveInterval = 15 * time.Second
+	defaultKeepAliveCount    = 9
+)
+
+// KeepAliveFromConfig returns the keepalive parameters converted from
+// cfg.
+// As with the net package, zero values of cfg mean the default values
+// and negative values mean leaving the current values unchanged.
+func KeepAliveFromConfig(cfg net.KeepAliveConfig) *KeepAlive {
+	ka := KeepAlive{Enable: cfg.Enable}
+	switch {
+	case cfg.Idle == 0:
+		ka.IdleInterval = defaultKeepAliveIdle
+	case cfg.Idle > 0:
+		ka.IdleInterval = cfg.Idle
+	}
+	switch {
+	case cfg.Interval == 0:
+		ka.ProbeInterval = defaultKeepAliveInterval
+	case cfg.Interval > 0:
+		ka.ProbeInterval = cfg.Interval
+	}
+	switch {
+	case cfg.Count == 0:
+		ka.ProbeCount = defaultKeepAliveCount
+	case cfg.Count > 0:
+		ka.ProbeCount = cfg.Count
+	}
+	return &ka
+}
+
+// Config returns the keepalive parameters as net.KeepAliveConfig.
+// Zero values of ka are converted to -1, which means leaving the
+// current values unchanged.
+func (ka *KeepAlive) Config() net.KeepAliveConfig {
+	cfg := net.KeepAliveConfig{Enable: ka.Enable, Idle: -1, Interval: -1, Count: -1}
+	if ka.IdleInterval > 0 {
+		cfg.Idle = ka.IdleInterval
+	}
+	if ka.ProbeInterval > 0 {
+		cfg.Interval = ka.ProbeInterval
+	}
+	if ka.ProbeCount > 0 {
+		cfg.Count = ka.ProbeCount
+	}
+	return cfg
+}
+
+// SetKeepAliveConfig is like SetKeepAlive but takes
+// net.KeepAliveConfig.
+func (c *Conn) SetKeepAliveConfig(cfg net.KeepAliveConfig) error {
+	return c.SetKeepAlive(KeepAliveFromConfig(cfg))
+}
+
+// KeepAliveConfig is like KeepAlive but returns
+// net.KeepAliveConfig.
+func (c *Conn) KeepAliveConfig() (net.KeepAliveConfig, error) {
+	ka, err := c.KeepAlive()
+	if err != nil {
+		return net.KeepAliveConfig{}, err
+	}
+	return ka.Config(), nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.23
+
+package tcp_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestKeepAliveConfig(t *testing.T) {
+	for _, tt := range []struct {
+		in  net.KeepAliveConfig
+		out tcp.KeepAlive
+	}{
+		{
+			net.KeepAliveConfig{Enable: true},
+			tcp.KeepAlive{Enable: true, IdleInterval: 15 * time.Second, ProbeInterval: 15 * time.Second, ProbeCount: 9},
+		},
+		{
+			net.KeepAliveConfig{Enable: true, Idle: time.Minute, Interval: -1, Count: 3},
+			tcp.KeepAlive{Enable: true, IdleInterval: time.Minute, ProbeCount: 3},
+		},
+	} {
+		ka := tcp.KeepAliveFromConfig(tt.in)
+		if !reflect.DeepEqual(ka, &tt.out) {
+			t.Errorf("got %#v; want %#v", ka, &tt.out)
+		}
+		if tt.in.Interval < 0 {
+			if cfg := ka.Config(); !reflect.DeepEqual(cfg, tt.in) {
+				t.Errorf("got %#v; want %#v", cfg, tt.in)
+			}
+		}
+	}
+}
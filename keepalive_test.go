@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpinfo"
 	"github.com/mikioh/tcpopt"
 )
 
@@ -76,3 +78,153 @@ func TestKeepAliveOptions(t *testing.T) {
 		}
 	}
 }
+
+func TestKeepAlive(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ka := tcp.KeepAlive{
+		Enable:        true,
+		IdleInterval:  10 * time.Second,
+		ProbeInterval: 2 * time.Second,
+		ProbeCount:    3,
+	}
+	if err := tc.SetKeepAlive(&ka); err != nil {
+		t.Fatal(err)
+	}
+	cka, err := tc.KeepAlive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cka, &ka) {
+		t.Fatalf("got %#v; want %#v", cka, &ka)
+	}
+}
+
+func TestKeepAliveTuner(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	p := &tcp.KeepAlivePolicy{
+		Interval:         10 * time.Millisecond,
+		MinIdleInterval:  15 * time.Second,
+		MaxIdleInterval:  time.Minute,
+		MinProbeInterval: 2 * time.Second,
+		ProbeCount:       3,
+	}
+	if _, err := tcp.NewKeepAliveTuner(c, &tcp.KeepAlivePolicy{MinIdleInterval: time.Hour, MaxIdleInterval: time.Minute}); err == nil {
+		t.Fatal("got nil; want invalid policy error")
+	}
+	kt, err := tcp.NewKeepAliveTuner(c, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kt.Stop()
+	time.Sleep(50 * time.Millisecond)
+	if err := kt.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The loopback RTT is well below the lower bounds.
+	want := tcp.KeepAlive{Enable: true, IdleInterval: 20 * time.Second, ProbeInterval: 2 * time.Second, ProbeCount: 3}
+	if ka := kt.KeepAlive(); ka != want {
+		t.Fatalf("got %+v; want %+v", ka, want)
+	}
+	ka, err := c.KeepAlive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *ka != want {
+		t.Fatalf("got %+v; want %+v", ka, want)
+	}
+}
+
+func TestKeepAliveProber(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	unhealthy := make(chan struct{}, 1)
+	p, err := tcp.NewKeepAliveProber(&tcp.ProberConfig{
+		KeepAlive:   tcp.KeepAlive{Enable: true, IdleInterval: 100 * time.Second, ProbeInterval: 5 * time.Second, ProbeCount: 3},
+		Jitter:      0.2,
+		UserTimeout: 30 * time.Second,
+		Interval:    64 * time.Millisecond,
+		Health: func(c *tcp.Conn, info *tcpinfo.Info) bool {
+			select {
+			case <-unhealthy:
+				return false
+			default:
+				return true
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	var conns []*tcp.Conn
+	for i := 0; i < 4; i++ {
+		c, _ := tcptest.Pair(t)
+		p.Add(c)
+		conns = append(conns, c)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	idles := make(map[time.Duration]bool)
+	for _, c := range conns {
+		ka, err := c.KeepAlive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ka.Enable || ka.IdleInterval < 80*time.Second || ka.IdleInterval > 120*time.Second || ka.ProbeCount != 3 {
+			t.Fatalf("got %+v; want spread idle interval", ka)
+		}
+		idles[ka.IdleInterval] = true
+		var b [4]byte
+		o, err := c.Option(tcp.UserTimeout(0).Level(), tcp.UserTimeout(0).Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o != tcp.UserTimeout(30*time.Second) {
+			t.Fatalf("got %v; want %v", o, tcp.UserTimeout(30*time.Second))
+		}
+	}
+	if len(idles) < 2 {
+		t.Errorf("got %v; want idle intervals spread", idles)
+	}
+
+	conns[0].Close()        // removed as closed
+	unhealthy <- struct{}{} // closes another one as unhealthy
+	time.Sleep(150 * time.Millisecond)
+	if n := p.Len(); n != 2 {
+		t.Fatalf("got %d connections; want 2", n)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errNotTCP is returned from NewConn when the connection is not
+// backed by a TCP socket.
+var errNotTCP = errors.New("not a tcp socket")
+
+// A SocketKind represents the domain, type and protocol of a socket.
+type SocketKind struct {
+	Domain   int // address family, such as syscall.AF_INET
+	Type     int // socket type, such as syscall.SOCK_STREAM
+	Protocol int // protocol number, such as 6 for TCP
+}
+
+func (sk *SocketKind) String() string {
+	return fmt.Sprintf("domain=%d type=%d protocol=%d", sk.Domain, sk.Type, sk.Protocol)
+}
+
+// SocketKind returns the domain, type and protocol of the socket
+// underlying the connection.
+//
+// Only FreeBSD and Linux support this feature.
+// See SO_DOMAIN, SO_TYPE and SO_PROTOCOL for further information.
+func (c *Conn) SocketKind() (*SocketKind, error) {
+	var sk *SocketKind
+	err := c.control(func(s uintptr) (err error) {
+		sk, err = socketKind(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return sk, nil
+}
+
+// checkSocketKind returns an error when the socket s is not a TCP
+// socket on the platforms that allow socket introspection.
+func checkSocketKind(s uintptr) error {
+	sk, err := socketKind(s)
+	if err == ErrNotSupported {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !sk.isTCP() {
+		return fmt.Errorf("%v: %v", errNotTCP, sk)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build freebsd linux
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+)
+
+// ianaProtocolMPTCP is the protocol number of multipath TCP sockets
+// on Linux.
+const ianaProtocolMPTCP = 0x106
+
+// isTCP reports whether sk is a TCP or multipath TCP socket.
+func (sk *SocketKind) isTCP() bool {
+	if sk.Domain != syscall.AF_INET && sk.Domain != syscall.AF_INET6 || sk.Type != syscall.SOCK_STREAM {
+		return false
+	}
+	return sk.Protocol == ianaProtocolTCP || sk.Protocol == ianaProtocolMPTCP
+}
+
+func socketKind(s uintptr) (*SocketKind, error) {
+	var vs [3]int
+	for i, name := range []int{sysSO_DOMAIN, sysSO_TYPE, sysSO_PROTOCOL} {
+		var b [4]byte
+		if _, err := getsockopt(s, sysSOL_SOCKET, name, b[:]); err != nil {
+			return nil, os.NewSyscallError("getsockopt", err)
+		}
+		vs[i] = int(int32(nativeEndian.Uint32(b[:])))
+	}
+	return &SocketKind{Domain: vs[0], Type: vs[1], Protocol: vs[2]}, nil
+}
@@ -0,0 +1,330 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A Leak represents a connection suspected of leaking.
+type Leak struct {
+	Reason  string    `json:"reason"`  // "not closed" or "close-wait"
+	Local   string    `json:"local"`   // local address
+	Remote  string    `json:"remote"`  // remote address
+	Created time.Time `json:"created"` // creation time
+	Stack   string    `json:"stack"`   // creation stack
+}
+
+// A LeakDetector represents a debug facility that records the
+// creation of every connection returned from NewConn and reports
+// connections that are garbage collected without Close or that stay
+// in the CLOSE_WAIT state beyond a threshold.
+//
+// Connections are tracked by socket; the connections sharing a
+// socket are reported once when all of them are garbage collected.
+// A connection garbage collected after its socket is closed through
+// the underlying net.Conn is not reported. On the platforms that
+// support Conn.Cookie, a connection is not reported either when its
+// socket is kept open by another owner after the connection is
+// garbage collected.
+//
+// It implements expvar.Var; String returns the list of tracked
+// connections in JSON.
+type LeakDetector struct {
+	// CloseWait is the maximum amount of time a connection may stay
+	// in the CLOSE_WAIT state.
+	// A zero value disables the check.
+	//
+	// Only Darwin, FreeBSD, Linux and NetBSD support the check.
+	CloseWait time.Duration
+
+	// Interval is the interval between the checks of connection
+	// states. A zero value means half of CloseWait.
+	Interval time.Duration
+
+	// Report is called for each suspected leak.
+	Report func(*Leak)
+
+	mu    sync.Mutex
+	conns map[uint64]*leakRecord // keyed by connection ID
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// A leakRecord represents a tracked socket.
+type leakRecord struct {
+	id        uint64          // connection ID, the socket cookie when supported
+	s         uintptr         // socket descriptor
+	rc        syscall.RawConn // nil when the connection doesn't implement syscall.Conn
+	refs      int             // # of tracked connections sharing the socket
+	leak      Leak
+	closeWait time.Time // when the CLOSE_WAIT state is observed first
+	reported  bool
+}
+
+// open reports whether the socket of r is still open.
+// It accesses the socket through the raw connection, which never
+// touches the descriptor of a closed socket.
+func (r *leakRecord) open() bool {
+	return r.rc != nil && r.rc.Control(func(uintptr) {}) == nil
+}
+
+var leakDetector struct {
+	sync.RWMutex
+	ld *LeakDetector
+}
+
+// SetLeakDetector installs the package-wide leak detector ld.
+// A nil ld removes the installed detector.
+// Connections created before the installation are not tracked.
+func SetLeakDetector(ld *LeakDetector) {
+	leakDetector.Lock()
+	old := leakDetector.ld
+	leakDetector.ld = ld
+	leakDetector.Unlock()
+	if old != nil {
+		old.halt()
+	}
+	if ld != nil {
+		ld.start()
+	}
+}
+
+// String implements the String method of expvar.Var interface.
+func (ld *LeakDetector) String() string {
+	ld.mu.Lock()
+	ls := make([]Leak, 0, len(ld.conns))
+	for _, r := range ld.conns {
+		ls = append(ls, r.leak)
+	}
+	ld.mu.Unlock()
+	b, err := json.Marshal(ls)
+	if err != nil {
+		return "[]"
+	}
+	return string(b)
+}
+
+func (ld *LeakDetector) start() {
+	ld.mu.Lock()
+	ld.conns = make(map[uint64]*leakRecord)
+	ld.mu.Unlock()
+	if ld.CloseWait <= 0 {
+		return
+	}
+	d := ld.Interval
+	if d <= 0 {
+		d = ld.CloseWait / 2
+	}
+	ld.stop, ld.done = make(chan struct{}), make(chan struct{})
+	go ld.run(d)
+}
+
+func (ld *LeakDetector) halt() {
+	if ld.stop != nil {
+		close(ld.stop)
+		<-ld.done
+		ld.stop, ld.done = nil, nil
+	}
+}
+
+func (ld *LeakDetector) run(d time.Duration) {
+	defer close(ld.done)
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			ld.check()
+		case <-ld.stop:
+			return
+		}
+	}
+}
+
+func (ld *LeakDetector) check() {
+	var o tcpinfo.Info
+	var b [256]byte
+	now := time.Now()
+	var ls []Leak
+	ld.mu.Lock()
+	for _, r := range ld.conns {
+		if r.reported || r.rc == nil {
+			continue
+		}
+		var n int
+		var err error
+		if cerr := r.rc.Control(func(s uintptr) {
+			n, err = getsockopt(s, o.Level(), o.Name(), b[:])
+		}); cerr != nil || err != nil {
+			continue // closed
+		}
+		io, err := parse(o.Level(), o.Name(), b[:n])
+		if err != nil || io.(*tcpinfo.Info).State != tcpinfo.CloseWait {
+			r.closeWait = time.Time{}
+			continue
+		}
+		if r.closeWait.IsZero() {
+			r.closeWait = now
+		}
+		if now.Sub(r.closeWait) >= ld.CloseWait {
+			r.reported = true
+			l := r.leak
+			l.Reason = "close-wait"
+			ls = append(ls, l)
+		}
+	}
+	ld.mu.Unlock()
+	for i := range ls {
+		ld.report(&ls[i])
+	}
+}
+
+func (ld *LeakDetector) report(l *Leak) {
+	if ld.Report != nil {
+		ld.Report(l)
+	}
+}
+
+// track starts tracking the connection c when a leak detector is
+// installed.
+func (c *Conn) track() {
+	leakDetector.RLock()
+	ld := leakDetector.ld
+	leakDetector.RUnlock()
+	if ld == nil {
+		return
+	}
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	var stack []byte
+	for {
+		f, more := frames.Next()
+		stack = append(stack, f.Function...)
+		stack = append(stack, "\n\t"...)
+		stack = append(stack, f.File...)
+		stack = append(stack, ':')
+		stack = strconv.AppendInt(stack, int64(f.Line), 10)
+		stack = append(stack, '\n')
+		if !more {
+			break
+		}
+	}
+	ld.mu.Lock()
+	r := ld.conns[c.id]
+	if r == nil {
+		r = &leakRecord{
+			id: c.id,
+			s:  c.s,
+			rc: c.rc,
+			leak: Leak{
+				Local:   c.LocalAddr().String(),
+				Remote:  c.RemoteAddr().String(),
+				Created: time.Now(),
+				Stack:   string(stack),
+			},
+		}
+		ld.conns[c.id] = r
+	}
+	r.refs++
+	ld.mu.Unlock()
+	c.leak = &connLeak{ld: ld, r: r}
+	runtime.SetFinalizer(c.leak, (*connLeak).finalize)
+}
+
+// A connLeak is referred only by the tracked connection. It becomes
+// unreachable when the connection is garbage collected.
+type connLeak struct {
+	ld *LeakDetector
+	r  *leakRecord
+}
+
+// close stops tracking the socket closed by the connection.
+func (cl *connLeak) close() {
+	cl.ld.mu.Lock()
+	if cl.ld.conns[cl.r.id] == cl.r {
+		delete(cl.ld.conns, cl.r.id)
+	}
+	cl.ld.mu.Unlock()
+}
+
+// release drops the reference of the garbage collected connection.
+// It reports whether the connection is the last one tracked for the
+// socket.
+func (cl *connLeak) release() bool {
+	cl.ld.mu.Lock()
+	defer cl.ld.mu.Unlock()
+	if cl.ld.conns[cl.r.id] != cl.r {
+		return false
+	}
+	if cl.r.refs--; cl.r.refs > 0 {
+		return false
+	}
+	delete(cl.ld.conns, cl.r.id)
+	return true
+}
+
+func (cl *connLeak) finalize() {
+	if !cl.release() {
+		return
+	}
+	r := cl.r
+	if r.rc != nil && !r.open() {
+		return // closed through the underlying connection
+	}
+	if r.rc != nil && r.id&synthesizedID == 0 {
+		// The socket, no longer referred by the record, is closed
+		// by the runtime when nobody else owns it. Examine it after
+		// the following garbage collections.
+		r.rc = nil
+		runtime.SetFinalizer(&leakProbe{ld: cl.ld, r: r, cycles: 2}, (*leakProbe).finalize)
+		return
+	}
+	l := r.leak
+	l.Reason = "not closed"
+	cl.ld.report(&l)
+}
+
+// A leakProbe represents a deferred examination of the socket of a
+// garbage collected connection, which runs on the finalization of the
+// probe after the garbage collection cycles.
+type leakProbe struct {
+	ld     *LeakDetector
+	r      *leakRecord
+	cycles int
+}
+
+func (lp *leakProbe) finalize() {
+	if lp.cycles--; lp.cycles > 0 {
+		runtime.SetFinalizer(&leakProbe{ld: lp.ld, r: lp.r, cycles: lp.cycles}, (*leakProbe).finalize)
+		return
+	}
+	// The descriptor may be reused by another socket; the cookie
+	// tells whether it still refers to the same socket.
+	var b [8]byte
+	so := options[soCookie]
+	if n, err := getsockopt(lp.r.s, so.level, so.name, b[:]); err == nil && n == len(b) && nativeEndian.Uint64(b[:]) == lp.r.id {
+		return // owned by someone else
+	}
+	l := lp.r.leak
+	l.Reason = "not closed"
+	lp.ld.report(&l)
+}
+
+// untrack stops tracking the connection closed by Close.
+func (c *Conn) untrack() {
+	if c.leak == nil {
+		return
+	}
+	c.leak.close()
+	runtime.SetFinalizer(c.leak, nil)
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestLeakDetector(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	leaks := make(chan *tcp.Leak, 4)
+	ld := &tcp.LeakDetector{
+		CloseWait: 50 * time.Millisecond,
+		Interval:  10 * time.Millisecond,
+		Report:    func(l *tcp.Leak) { leaks <- l },
+	}
+	tcp.SetLeakDetector(ld)
+	defer tcp.SetLeakDetector(nil)
+
+	dial := func() (*tcp.Conn, net.Conn) {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := ln.Accept()
+		if err != nil {
+			c.Close()
+			t.Fatal(err)
+		}
+		tc, err := tcp.NewConn(c)
+		if err != nil {
+			c.Close()
+			p.Close()
+			t.Fatal(err)
+		}
+		return tc, p
+	}
+
+	t.Run("Closed", func(t *testing.T) {
+		tc, p := dial()
+		defer p.Close()
+		if !strings.Contains(ld.String(), tc.LocalAddr().String()) {
+			t.Fatalf("%s not tracked: %s", tc.LocalAddr(), ld.String())
+		}
+		if err := tc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(ld.String(), tc.LocalAddr().String()) {
+			t.Fatalf("%s still tracked: %s", tc.LocalAddr(), ld.String())
+		}
+	})
+	t.Run("CloseWait", func(t *testing.T) {
+		tc, p := dial()
+		defer tc.Close()
+		p.Close()
+		select {
+		case l := <-leaks:
+			if l.Reason != "close-wait" || l.Local != tc.LocalAddr().String() {
+				t.Fatalf("got %+v", l)
+			}
+			if !strings.Contains(l.Stack, "TestLeakDetector") {
+				t.Fatalf("got %q; want creation stack", l.Stack)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+	t.Run("Owned", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+		}
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		p, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		if _, err := tcp.NewConn(c); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			runtime.GC()
+			select {
+			case l := <-leaks:
+				t.Fatalf("got %+v; want no report for socket owned by %s", l, c.LocalAddr())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+	t.Run("NotClosed", func(t *testing.T) {
+		tc, p := dial()
+		defer p.Close()
+		laddr := tc.LocalAddr().String()
+		tc = nil
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			runtime.GC()
+			select {
+			case l := <-leaks:
+				if l.Reason != "not closed" || l.Local != laddr {
+					t.Fatalf("got %+v", l)
+				}
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		t.Fatal("timeout")
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// A Limiter enforces an aggregate bandwidth budget over a set of
+// connections. It uses a token bucket that is shared by all the
+// connections attached to it.
+//
+// Transfers are split into chunks of at most the burst size and each
+// chunk reserves tokens in arrival order, so that a connection
+// transferring a large amount of data cannot starve the others.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  int     // bucket size in bytes
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a new limiter that allows rate bytes per second
+// with bursts of at most burst bytes.
+// A non-positive rate means no limit. A non-positive burst means the
+// same value as rate.
+func NewLimiter(rate, burst int) *Limiter {
+	l := &Limiter{last: time.Now()}
+	l.SetRate(rate, burst)
+	return l
+}
+
+// SetRate changes the rate and burst of the limiter.
+func (l *Limiter) SetRate(rate, burst int) {
+	if burst <= 0 {
+		burst = rate
+	}
+	l.mu.Lock()
+	l.advance(time.Now())
+	l.rate = float64(rate)
+	l.burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+	l.mu.Unlock()
+}
+
+// Limit attaches the connection c to the limiter.
+// Reads and writes on the returned connection consume the budget of
+// the limiter.
+func (l *Limiter) Limit(c *Conn) *LimitedConn {
+	return &LimitedConn{Conn: c, l: l}
+}
+
+// chunk returns the largest chunk size for a transfer of n bytes.
+func (l *Limiter) chunk(n int) int {
+	l.mu.Lock()
+	burst := l.burst
+	l.mu.Unlock()
+	if burst > 0 && n > burst {
+		return burst
+	}
+	return n
+}
+
+// wait consumes n tokens and blocks until the bucket has no debt.
+func (l *Limiter) wait(n int) {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return
+	}
+	l.advance(time.Now())
+	l.tokens -= float64(n)
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (l *Limiter) advance(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	l.last = now
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+}
+
+// A LimitedConn represents a connection attached to a Limiter.
+type LimitedConn struct {
+	*Conn
+	l *Limiter
+}
+
+// Read reads data from the connection.
+// It blocks after reading until the limiter allows the amount of
+// data read.
+func (lc *LimitedConn) Read(b []byte) (int, error) {
+	n, err := lc.Conn.Read(b[:lc.l.chunk(len(b))])
+	if n > 0 {
+		lc.l.wait(n)
+	}
+	return n, err
+}
+
+// Write writes data to the connection.
+// It blocks before writing each chunk until the limiter allows it.
+func (lc *LimitedConn) Write(b []byte) (int, error) {
+	var n int
+	for n < len(b) {
+		m := lc.l.chunk(len(b) - n)
+		lc.l.wait(m)
+		nn, err := lc.Conn.Write(b[n : n+m])
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ReadFrom implements the ReadFrom method of io.ReaderFrom interface.
+// Unlike Conn, it always copies through Write to apply the limit.
+func (lc *LimitedConn) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{lc}, r)
+}
+
+// WriteTo implements the WriteTo method of io.WriterTo interface.
+// Unlike Conn, it always copies through Read to apply the limit.
+func (lc *LimitedConn) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, readerOnly{lc})
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestLimiter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				io.Copy(ioutil.Discard, c)
+			}()
+		}
+	}()
+
+	const rate, burst, size = 1 << 20, 1 << 16, 1 << 18
+	l := tcp.NewLimiter(rate, burst)
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	begin := time.Now()
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		tc, err := tcp.NewConn(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lc := l.Limit(tc)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := lc.Write(make([]byte, size)); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	want := time.Duration(float64(2*size-burst) / rate * float64(time.Second))
+	if elapsed := time.Since(begin); elapsed < want*9/10 {
+		t.Fatalf("got %v; want >= %v", elapsed, want)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+var _ net.Listener = &Listener{}
+
+// A Listener represents a TCP listener end point.
+type Listener struct {
+	net.Listener
+	rc syscall.RawConn // raw connection for configuring options
+
+	limitMu   sync.Mutex
+	limit     *connLimit    // non-nil when the number of connections is limited
+	rate      *acceptRate   // non-nil when the accept rate is limited
+	preAccept PreAcceptFunc // non-nil when the verdict is in effect
+	isClosed  bool
+
+	deadline     time.Time     // deadline set by SetDeadline
+	interrupts   int           // # of AcceptContext calls interrupting the accept
+	interruptGen uint64        // incremented on each interruption
+	resumed      chan struct{} // closed when interrupts drops to zero
+
+	tracking bool               // true when the accepted connections are tracked
+	conns    map[*Conn]struct{} // connections accepted and not closed yet
+	drained  chan struct{}      // closed when conns becomes empty in shutdown
+	shutdown bool
+}
+
+// A ListenerStats represents statistics of a listening socket.
+type ListenerStats struct {
+	AcceptQueueLen int // # of established connections waiting for accept
+	AcceptQueueMax int // maximum length of accept queue, the backlog
+	SYNQueueLen    int // # of half-open connections in SYN-RECEIVED state
+}
+
+// Stats returns statistics of the listening socket.
+// Comparing AcceptQueueLen with AcceptQueueMax helps detecting accept
+// queue overflows.
+//
+// Only Linux supports this feature.
+func (ln *Listener) Stats() (*ListenerStats, error) {
+	var st *ListenerStats
+	err := ln.control(func(s uintptr) (err error) {
+		st, err = listenerStats(s, ln.Addr().(*net.TCPAddr))
+		return
+	})
+	if err != nil {
+		return nil, ln.opError("get", err)
+	}
+	return st, nil
+}
+
+// SetOption sets a socket option on the listener.
+// Connections accepted from the listener inherit most of the socket
+// options, and some options such as FastOpenNoCookie change the
+// behavior of the listener itself.
+func (ln *Listener) SetOption(o tcpopt.Option) error {
+	b, err := o.Marshal()
+	if err != nil {
+		return ln.optionError("set", o.Level(), o.Name(), err)
+	}
+	if err := ln.control(func(s uintptr) error { return setsockopt(s, o.Level(), o.Name(), b) }); err != nil {
+		return ln.optionError("set", o.Level(), o.Name(), err)
+	}
+	return nil
+}
+
+func (ln *Listener) optionError(op string, level, name int, err error) error {
+	return &OptionError{Op: op, Level: level, Name: name, Addr: ln.Addr(), Err: err}
+}
+
+func (ln *Listener) opError(op string, err error) error {
+	la := ln.Addr()
+	return &net.OpError{Op: op, Net: la.Network(), Source: nil, Addr: la, Err: err}
+}
+
+// NewListener returns a new listener end point.
+func NewListener(ln net.Listener) (*Listener, error) {
+	rc, err := listenerRawConnOf(ln)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{Listener: ln, rc: rc}, nil
+}
+
+// control calls fn with the socket descriptor of the listener.
+func (ln *Listener) control(fn func(s uintptr) error) error {
+	var ferr error
+	if err := ln.rc.Control(func(s uintptr) { ferr = fn(s) }); err != nil {
+		return err
+	}
+	return ferr
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"os"
+
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+func listenerStats(s uintptr, la *net.TCPAddr) (*ListenerStats, error) {
+	var o tcpinfo.Info
+	b := make([]byte, 256)
+	n, err := getsockopt(s, o.Level(), o.Name(), b)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	io, err := tcpopt.Parse(o.Level(), o.Name(), b[:n])
+	if err != nil {
+		return nil, err
+	}
+	i := io.(*tcpinfo.Info)
+	if i.Sys == nil {
+		return nil, ErrNotSupported
+	}
+	// On a listening socket, the kernel reports the length of accept
+	// queue as tcpi_unacked and the backlog as tcpi_sacked.
+	st := ListenerStats{AcceptQueueLen: int(i.Sys.UnackedSegs), AcceptQueueMax: int(i.Sys.SackedSegs)}
+	family := addrFamily(la)
+	err = inetDiag(family, 1<<sysTCP_SYN_RECV, 0, nil, func(m *inetDiagMsg, _ []byte) bool {
+		sa := m.Id.srcAddr(family)
+		if sa.Port == la.Port && (la.IP.IsUnspecified() || sa.IP.Equal(la.IP)) {
+			st.SYNQueueLen++
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import "net"
+
+func listenerStats(s uintptr, la *net.TCPAddr) (*ListenerStats, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,577 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestListenerStats(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const N = 3
+	for i := 0; i < N; i++ {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+	}
+	time.Sleep(100 * time.Millisecond)
+	st, err := tln.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.AcceptQueueLen != N || st.AcceptQueueMax <= 0 {
+		t.Fatalf("got %+v; want %d queued connections", st, N)
+	}
+	t.Logf("%+v", st)
+}
+
+func TestListenerMaxConns(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tln, err := tcp.NewListener(ln)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tln.Close()
+		tln.SetMaxConns(1, false)
+
+		c1, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c1.Close()
+		p1, err := tln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p1.Close()
+		if _, ok := p1.(*tcp.Conn); !ok {
+			t.Fatalf("got %T; want *tcp.Conn", p1)
+		}
+		c2, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c2.Close()
+		go func() {
+			if p, err := tln.Accept(); err == nil {
+				p.Close()
+			}
+		}()
+		c2.SetReadDeadline(time.Now().Add(3 * time.Second))
+		var b [1]byte
+		if _, err := c2.Read(b[:]); err == nil || isTimeout(err) {
+			t.Fatalf("got %v; want connection closed", err)
+		}
+	})
+	t.Run("Wait", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tln, err := tcp.NewListener(ln)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tln.Close()
+		tln.SetMaxConns(1, true)
+
+		for i := 0; i < 2; i++ {
+			c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+		}
+		p1, err := tln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			if p, err := tln.Accept(); err == nil {
+				accepted <- p
+			}
+		}()
+		select {
+		case p := <-accepted:
+			p.Close()
+			t.Fatal("accepted beyond the limit")
+		case <-time.After(100 * time.Millisecond):
+		}
+		p1.Close()
+		select {
+		case p := <-accepted:
+			p.Close()
+		case <-time.After(3 * time.Second):
+			t.Fatal("not accepted after the release")
+		}
+
+		errc := make(chan error)
+		go func() {
+			_, err := tln.Accept()
+			errc <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+		tln.Close()
+		if err := <-errc; err == nil {
+			t.Fatal("got nil; want an error")
+		}
+	})
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+func TestListenerAcceptRateLimit(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tln.Close()
+	if err := tln.SetAcceptRateLimit(&tcp.AcceptRateLimit{Burst: 2}); err == nil {
+		t.Fatal("got nil; want an error for zero rate")
+	}
+	if err := tln.SetAcceptRateLimit(&tcp.AcceptRateLimit{Rate: 0.001, Burst: 2, Ban: time.Hour, IPv4PrefixLen: 8}); err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			c, err := tln.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- c
+		}
+	}()
+	var cs []net.Conn
+	for i := 0; i < 4; i++ {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		cs = append(cs, c)
+	}
+	for i := 0; i < 2; i++ {
+		c := <-accepted
+		defer c.Close()
+	}
+	for _, c := range cs[2:] {
+		c.SetReadDeadline(time.Now().Add(3 * time.Second))
+		var b [1]byte
+		if _, err := c.Read(b[:]); err == nil || isTimeout(err) {
+			t.Fatalf("got %v; want connection closed", err)
+		}
+	}
+	select {
+	case c := <-accepted:
+		c.Close()
+		t.Fatal("accepted beyond the limit")
+	default:
+	}
+}
+
+func TestListenerHandshake(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tln.SetTrackConns(true)
+
+	start := time.Now()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := tln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	hs, ok := p.(*tcp.Conn).Handshake()
+	if !ok || hs.Duration != 0 || hs.Accepted.Before(start) || hs.Accepted.After(time.Now()) {
+		t.Fatalf("got %+v, %v; want time of accept", hs, ok)
+	}
+	switch runtime.GOOS {
+	case "darwin", "freebsd", "linux", "netbsd":
+		if hs.RTT <= 0 {
+			t.Fatalf("got %v; want positive rtt", hs.RTT)
+		}
+	}
+	t.Logf("%+v", hs)
+}
+
+func TestListenerPreAccept(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tln.SetOption(tcp.DeferAccept(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	var syns []*tcp.SYN
+	if err := tln.SetPreAccept(func(c *tcp.Conn, syn *tcp.SYN, data []byte) bool {
+		syns = append(syns, syn)
+		return !bytes.HasPrefix(data, []byte("JUNK"))
+	}); err != nil {
+		t.Skip(err) // requires the kernel with TCP_SAVE_SYN option
+	}
+
+	junk, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer junk.Close()
+	junk.Write([]byte("JUNK"))
+	time.Sleep(50 * time.Millisecond)
+	good, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer good.Close()
+	good.Write([]byte("GOOD"))
+
+	c, err := tln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.RemoteAddr().String() != good.LocalAddr().String() {
+		t.Fatalf("got %v; want %v", c.RemoteAddr(), good.LocalAddr())
+	}
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(c, b); err != nil || string(b) != "GOOD" {
+		t.Fatalf("got %q, %v; want GOOD", b, err)
+	}
+	junk.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := junk.Read(b); err == nil || err == io.EOF {
+		t.Errorf("got %v; want connection reset", err)
+	}
+	if len(syns) != 2 {
+		t.Fatalf("got %d verdicts; want 2", len(syns))
+	}
+	for _, syn := range syns {
+		if syn == nil || syn.MSS == 0 || syn.TTL == 0 {
+			t.Fatalf("got %+v; want SYN segment", syn)
+		}
+	}
+}
+
+func TestListenerShutdown(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	listen := func(t *testing.T, track bool) (*tcp.Listener, []net.Conn) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tln, err := tcp.NewListener(ln)
+		if err != nil {
+			ln.Close()
+			t.Fatal(err)
+		}
+		tln.SetTrackConns(track)
+		var cs []net.Conn
+		for i := 0; i < 2; i++ {
+			c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { c.Close() })
+			p, err := tln.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cs = append(cs, p)
+		}
+		return tln, cs
+	}
+
+	t.Run("Drain", func(t *testing.T) {
+		tln, cs := listen(t, true)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			for _, c := range cs {
+				c.Close()
+			}
+		}()
+		remained, err := tln.Shutdown(context.Background())
+		if err != nil || len(remained) != 0 {
+			t.Fatalf("got %v, %v; want no remaining connections", remained, err)
+		}
+		if _, err := tln.Accept(); err == nil {
+			t.Fatal("got nil; want error after shutdown")
+		}
+	})
+	t.Run("ClosedUnderlying", func(t *testing.T) {
+		tln, cs := listen(t, true)
+		for _, c := range cs {
+			c.(*tcp.Conn).Conn.Close()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if remained, err := tln.Shutdown(ctx); err != nil || len(remained) != 0 {
+			t.Fatalf("got %v, %v; want no remaining connections", remained, err)
+		}
+	})
+	t.Run("Untracked", func(t *testing.T) {
+		tln, cs := listen(t, false)
+		for _, c := range cs {
+			defer c.Close()
+			if _, ok := c.(*net.TCPConn); !ok {
+				t.Fatalf("got %T; want *net.TCPConn", c)
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if remained, err := tln.Shutdown(ctx); err != nil || len(remained) != 0 {
+			t.Fatalf("got %v, %v; want no tracked connections", remained, err)
+		}
+	})
+	t.Run("Deadline", func(t *testing.T) {
+		tln, cs := listen(t, true)
+		cs[0].Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		remained, err := tln.Shutdown(ctx)
+		if err != context.DeadlineExceeded {
+			t.Fatalf("got %v; want %v", err, context.DeadlineExceeded)
+		}
+		if len(remained) != 1 || remained[0].ID != cs[1].(*tcp.Conn).ID() {
+			t.Fatalf("got %v; want %v", remained, cs[1])
+		}
+		if _, err := cs[1].Write([]byte("HELLO")); err == nil {
+			t.Fatal("got nil; want error on forcibly closed connection")
+		}
+	})
+}
+
+func TestListenerDeadline(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tln.SetDeadline(time.Now().Add(20 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tln.Accept(); !isTimeout(err) {
+		t.Fatalf("got %v; want timeout", err)
+	}
+
+	// The deadline bounds the wait for a connection slot too.
+	tln.SetMaxConns(1, true)
+	if err := tln.SetDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := tln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if err := tln.SetDeadline(time.Now().Add(20 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tln.Accept(); !isTimeout(err) {
+		t.Fatalf("got %v; want timeout", err)
+	}
+}
+
+func TestListenerAcceptContext(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	tln, err := tcp.NewListener(ln)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An accept loop in another goroutine keeps waiting across the
+	// interruption.
+	accepted := make(chan error, 1)
+	go func() {
+		c, err := tln.Accept()
+		if err == nil {
+			c.Close()
+		}
+		accepted <- err
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := tln.AcceptContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v; want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case err := <-accepted:
+		t.Fatalf("got %v; want accept in progress", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := <-accepted; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err == nil {
+			c.Close()
+		}
+	}()
+	c, err = tln.AcceptContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	// The accept loops starting during the interruptions wait for
+	// them to end.
+	const N = 4
+	stop := make(chan struct{})
+	dialed := make(chan struct{})
+	go func() {
+		defer close(dialed)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if c, err := net.Dial(ln.Addr().Network(), ln.Addr().String()); err == nil {
+				c.Close()
+			}
+		}
+	}()
+	errs := make(chan error, N)
+	for i := 0; i < N; i++ {
+		go func() {
+			for {
+				c, err := tln.Accept()
+				if err != nil {
+					errs <- err
+					return
+				}
+				c.Close()
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		c, err := tln.AcceptContext(ctx)
+		if err == nil {
+			c.Close()
+		} else if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v; want %v", err, context.DeadlineExceeded)
+		}
+		cancel()
+	}
+	close(stop)
+	<-dialed
+	tln.Close()
+	for i := 0; i < N; i++ {
+		if err := <-errs; !errors.Is(err, net.ErrClosed) {
+			t.Errorf("got %v; want %v", err, net.ErrClosed)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "net"
+
+// An MD5Signature represents a key of TCP MD5 signature option, as
+// described in RFC 2385, for a peer or a range of peers.
+type MD5Signature struct {
+	// Addr is the address of peer, or the address prefix of peers
+	// along with PrefixLen.
+	Addr net.IP
+
+	// PrefixLen is the length of address prefix in bits, which
+	// allows a key entry to cover all the peers within the
+	// prefix. Zero means the whole address.
+	PrefixLen int
+
+	// IfIndex is the index of network interface, such as a VRF
+	// device, to which the key entry is bound. Zero means any
+	// interface.
+	IfIndex int
+
+	// Key is the signature key of up to 80 bytes. The empty key
+	// removes the key entry.
+	Key []byte
+}
+
+// SetMD5Signature adds, replaces or removes the key entry sig on the
+// listener. The connections accepted from the listener inherit the
+// key entries.
+// The key entry with PrefixLen or IfIndex uses TCP_MD5SIG_EXT option,
+// which requires Linux 4.14 or above for PrefixLen and Linux 5.6 or
+// above for IfIndex.
+//
+// Only Linux supports this feature.
+func (ln *Listener) SetMD5Signature(sig *MD5Signature) error {
+	if err := ln.control(func(s uintptr) error { return setMD5Signature(s, sig) }); err != nil {
+		return ln.opError("set", err)
+	}
+	return nil
+}
+
+// SetMD5Signature adds, replaces or removes the key entry sig on the
+// connection. See Listener.SetMD5Signature for further information.
+//
+// Only Linux supports this feature.
+func (c *Conn) SetMD5Signature(sig *MD5Signature) error {
+	if err := c.control(func(s uintptr) error { return setMD5Signature(s, sig) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
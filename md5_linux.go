@@ -0,0 +1,68 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+func setMD5Signature(s uintptr, sig *MD5Signature) error {
+	if len(sig.Key) > sysTCP_MD5SIG_MAXKEYLEN {
+		return errors.New("md5 signature key too long")
+	}
+	var f [4]byte
+	if _, err := getsockopt(s, sysSOL_SOCKET, sysSO_DOMAIN, f[:]); err != nil {
+		return os.NewSyscallError("getsockopt", err)
+	}
+	family := int(nativeEndian.Uint32(f[:]))
+	var b [sizeofTCPMD5Sig]byte
+	nativeEndian.PutUint16(b[0:2], uint16(family))
+	maxPrefixLen := 8 * net.IPv6len
+	switch family {
+	case syscall.AF_INET:
+		ip := sig.Addr.To4()
+		if ip == nil {
+			return errors.New("non-ipv4 address")
+		}
+		copy(b[4:8], ip)
+		maxPrefixLen = 8 * net.IPv4len
+	case syscall.AF_INET6:
+		ip := sig.Addr.To16()
+		if ip == nil {
+			return errors.New("invalid address")
+		}
+		// An IPv4-mapped IPv6 address refers to an IPv4 peer,
+		// and its prefix length applies to the IPv4 address.
+		copy(b[8:24], ip)
+		if sig.Addr.To4() != nil {
+			maxPrefixLen = 8 * net.IPv4len
+		}
+	default:
+		return errors.New("unknown address family")
+	}
+	if sig.PrefixLen < 0 || sig.PrefixLen > maxPrefixLen {
+		return errors.New("invalid prefix length")
+	}
+	name := sysTCP_MD5SIG
+	if sig.PrefixLen > 0 {
+		name = sysTCP_MD5SIG_EXT
+		b[128] |= sysTCP_MD5SIG_FLAG_PREFIX
+		b[129] = byte(sig.PrefixLen)
+	}
+	if sig.IfIndex > 0 {
+		name = sysTCP_MD5SIG_EXT
+		b[128] |= sysTCP_MD5SIG_FLAG_IFINDEX
+		nativeEndian.PutUint32(b[132:136], uint32(sig.IfIndex))
+	}
+	nativeEndian.PutUint16(b[130:132], uint16(len(sig.Key)))
+	copy(b[136:], sig.Key)
+	if err := setsockopt(s, ianaProtocolTCP, name, b[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// A MemInfo represents memory usage of the socket in bytes.
+type MemInfo struct {
+	ReceiveAlloc  int // memory allocated for receive queue
+	ReceiveBuffer int // size of receive buffer
+	SendAlloc     int // memory allocated for send queue
+	SendBuffer    int // size of send buffer
+	ForwardAlloc  int // memory allocated in advance for future use
+	SendQueued    int // memory queued for transmission
+	OptionMem     int // memory used for socket options and ancillary data
+	Backlog       int // memory used for backlog queue
+	Drops         int // # of dropped packets
+}
+
+// MemInfo returns memory usage of the underlying socket.
+//
+// Only Linux supports this feature.
+// See SO_MEMINFO for further information.
+func (c *Conn) MemInfo() (*MemInfo, error) {
+	so := options[soMemInfo]
+	if so.name < 1 {
+		return nil, c.opError("get", ErrNotSupported)
+	}
+	var b [4 * 16]byte
+	n, err := c.getsockopt(so.level, so.name, b[:])
+	if err != nil {
+		return nil, c.optionError("get", so.level, so.name, err)
+	}
+	var vs [9]int
+	for i := range vs {
+		if 4*(i+1) > n {
+			break
+		}
+		vs[i] = int(nativeEndian.Uint32(b[4*i:]))
+	}
+	return &MemInfo{
+		ReceiveAlloc:  vs[0],
+		ReceiveBuffer: vs[1],
+		SendAlloc:     vs[2],
+		SendBuffer:    vs[3],
+		ForwardAlloc:  vs[4],
+		SendQueued:    vs[5],
+		OptionMem:     vs[6],
+		Backlog:       vs[7],
+		Drops:         vs[8],
+	}, nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// A NegotiatedOptions represents the TCP options and features
+// negotiated with the peer in the opening handshake.
+type NegotiatedOptions struct {
+	SACK         bool // whether selective acknowledgments are permitted by both end points
+	Timestamps   bool // whether the timestamps option is used
+	WindowScale  bool // whether the window scale option is used
+	SendScale    int  // shift count of the windows advertised by the peer; zero without WindowScale
+	ReceiveScale int  // shift count of the windows advertised to the peer; zero without WindowScale
+	ECN          bool // whether Explicit Congestion Notification is negotiated
+	ECNSeen      bool // whether at least one ECT or CE marked packet is received
+	SYNData      bool // whether the data in the SYN segment is acknowledged by the peer with TCP Fast Open
+}
+
+// NegotiatedOptions returns the TCP options and features negotiated
+// on the connection, which allows operators to verify what the peer
+// actually agreed to, regardless of the local configuration.
+//
+// Only Linux supports this feature.
+// See tcpi_options of TCP_INFO for further information.
+func (c *Conn) NegotiatedOptions() (*NegotiatedOptions, error) {
+	var no *NegotiatedOptions
+	err := c.control(func(s uintptr) (err error) {
+		no, err = negotiatedOptions(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return no, nil
+}
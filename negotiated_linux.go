@@ -0,0 +1,27 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+func negotiatedOptions(s uintptr) (*NegotiatedOptions, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	no := NegotiatedOptions{
+		SACK:        ti.Options&sysTCPI_OPT_SACK != 0,
+		Timestamps:  ti.Options&sysTCPI_OPT_TIMESTAMPS != 0,
+		WindowScale: ti.Options&sysTCPI_OPT_WSCALE != 0,
+		ECN:         ti.Options&sysTCPI_OPT_ECN != 0,
+		ECNSeen:     ti.Options&sysTCPI_OPT_ECN_SEEN != 0,
+		SYNData:     ti.Options&sysTCPI_OPT_SYN_DATA != 0,
+	}
+	if no.WindowScale {
+		// tcpi_snd_wscale and tcpi_rcv_wscale are the 4-bit fields
+		// following tcpi_options.
+		no.SendScale = int(ti.Pad_cgo_0[0] & 0x0f)
+		no.ReceiveScale = int(ti.Pad_cgo_0[0] >> 4)
+	}
+	return &no, nil
+}
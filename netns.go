@@ -0,0 +1,45 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ListenNetns announces on the local address in the network
+// namespace ns, which is the name of a network namespace such as
+// "blue" created by "ip netns add blue", or the path of a network
+// namespace file such as "/proc/1234/ns/net".
+// The listening socket is created on a dedicated thread moved to the
+// namespace, and the listener and the accepted connections keep
+// working in the namespace after returning; the calling goroutine
+// and the other threads stay in their own namespaces.
+//
+// It requires the CAP_SYS_ADMIN capability.
+// Only Linux supports this feature.
+func ListenNetns(ctx context.Context, network, address, ns string) (*Listener, error) {
+	if ns == "" {
+		return nil, errors.New("empty network namespace")
+	}
+	var ln net.Listener
+	var err error
+	if terr := onThread(ns, "", func() {
+		var lc net.ListenConfig
+		ln, err = lc.Listen(ctx, network, address)
+	}); terr != nil {
+		return nil, &net.OpError{Op: "listen", Net: network, Err: terr}
+	}
+	if err != nil {
+		return nil, err
+	}
+	tln, err := NewListener(ln)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return tln, nil
+}
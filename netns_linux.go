@@ -0,0 +1,38 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// netnsDir is the directory of the named network namespaces created
+// by ip-netns(8).
+const netnsDir = "/var/run/netns"
+
+// netnsPath returns the path of the network namespace file for ns,
+// which is either a name under netnsDir or a path.
+func netnsPath(ns string) string {
+	if strings.ContainsRune(ns, '/') {
+		return ns
+	}
+	return filepath.Join(netnsDir, ns)
+}
+
+// joinNetns moves the calling thread to the network namespace ns.
+func joinNetns(ns string) error {
+	f, err := os.Open(netnsPath(ns))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, _, errno := syscall.RawSyscall(sysSETNS, f.Fd(), syscall.CLONE_NEWNET, 0); errno != 0 {
+		return os.NewSyscallError("setns", errno)
+	}
+	return nil
+}
@@ -0,0 +1,1003 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+	"unsafe"
+
+	"github.com/mikioh/tcpopt"
+)
+
+var (
+	_ tcpopt.Option = ConnectionTimeout(0)
+	_ tcpopt.Option = RetransmitConnDropTime(0)
+	_ tcpopt.Option = UserTimeout(0)
+	_ tcpopt.Option = FastOpenConnect(false)
+	_ tcpopt.Option = FastOpenNoCookie(false)
+	_ tcpopt.Option = ReuseAddress(false)
+	_ tcpopt.Option = ReusePort(false)
+	_ tcpopt.Option = ReusePortLB(false)
+	_ tcpopt.Option = WindowClamp(0)
+	_ tcpopt.Option = SendBufferForce(0)
+	_ tcpopt.Option = ReceiveBufferForce(0)
+	_ tcpopt.Option = Priority(0)
+	_ tcpopt.Option = MinRTO(0)
+	_ tcpopt.Option = Linger2(0)
+	_ tcpopt.Option = InitialRTO{}
+	_ tcpopt.Option = FailConnectOnICMPError(false)
+	_ tcpopt.Option = EnableECN(false)
+	_ tcpopt.Option = NoOptions(false)
+	_ tcpopt.Option = ReceiveLowWMK(0)
+	_ tcpopt.Option = SendLowWMK(0)
+	_ tcpopt.Option = OOBInline(false)
+	_ tcpopt.Option = UserCookie(0)
+	_ tcpopt.Option = Timestamps(false)
+	_ tcpopt.Option = DeferAccept(0)
+	_ tcpopt.Option = SaveSYN(false)
+	_ tcpopt.Option = LoopbackFastPath(false)
+	_ tcpopt.Option = &RawOption{}
+)
+
+func init() {
+	for _, p := range []struct {
+		so int
+		fn func([]byte) (tcpopt.Option, error)
+	}{
+		{soConnectionTimeout, parseConnectionTimeout},
+		{soRetransmitConnDropTime, parseRetransmitConnDropTime},
+		{soUserTimeout, parseUserTimeout},
+		{soFastOpenConnect, parseFastOpenConnect},
+		{soFastOpenNoCookie, parseFastOpenNoCookie},
+		{soReuseAddr, parseReuseAddress},
+		{soReusePort, parseReusePort},
+		{soReusePortLB, parseReusePortLB},
+		{soWindowClamp, parseWindowClamp},
+		{soPriority, parsePriority},
+		{soMinRTO, parseMinRTO},
+		{soLinger2, parseLinger2},
+		{soEnableECN, parseEnableECN},
+		{soNoOptions, parseNoOptions},
+		{soReceiveLowWMK, parseReceiveLowWMK},
+		{soSendLowWMK, parseSendLowWMK},
+		{soOOBInline, parseOOBInline},
+		{soUserCookie, parseUserCookie},
+		{soTimestamps, parseTimestamps},
+		{soDeferAccept, parseDeferAccept},
+		{soSaveSYN, parseSaveSYN},
+	} {
+		if o := options[p.so]; o.name > 0 {
+			tcpopt.Register(o.level, o.name, p.fn)
+		}
+	}
+}
+
+// ConnectionTimeout specifies the timeout for connection
+// establishment.
+// It must be set before the connection is established to take
+// effect.
+//
+// Only Darwin, DragonFly BSD and FreeBSD support this option.
+// See TCP_CONNECTIONTIMEOUT on Darwin and TCP_KEEPINIT on DragonFly
+// BSD and FreeBSD for further information.
+type ConnectionTimeout time.Duration
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ct ConnectionTimeout) Level() int { return options[soConnectionTimeout].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ct ConnectionTimeout) Name() int { return options[soConnectionTimeout].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ct ConnectionTimeout) Marshal() ([]byte, error) {
+	return marshalDuration(soConnectionTimeout, time.Duration(ct), connectionTimeoutUnit)
+}
+
+// RetransmitConnDropTime specifies the amount of time to keep
+// retransmitting unacknowledged data before dropping the connection.
+//
+// Only Darwin supports this option.
+// See TCP_RXT_CONNDROPTIME for further information.
+type RetransmitConnDropTime time.Duration
+
+// Level implements the Level method of tcpopt.Option interface.
+func (rt RetransmitConnDropTime) Level() int { return options[soRetransmitConnDropTime].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (rt RetransmitConnDropTime) Name() int { return options[soRetransmitConnDropTime].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (rt RetransmitConnDropTime) Marshal() ([]byte, error) {
+	return marshalDuration(soRetransmitConnDropTime, time.Duration(rt), time.Second)
+}
+
+// UserTimeout specifies the maximum amount of time that transmitted
+// data may remain unacknowledged before the connection is forcibly
+// closed.
+//
+// On Windows, it bounds the total retransmission time of the
+// connection and is rounded up to seconds.
+//
+// Only Linux and Windows support this option.
+// See TCP_USER_TIMEOUT and TCP_MAXRT for further information.
+type UserTimeout time.Duration
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ut UserTimeout) Level() int { return options[soUserTimeout].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ut UserTimeout) Name() int { return options[soUserTimeout].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ut UserTimeout) Marshal() ([]byte, error) {
+	return marshalDuration(soUserTimeout, time.Duration(ut), userTimeoutUnit)
+}
+
+// FastOpenConnect specifies the use of TCP Fast Open on connect.
+// When enabled, connect returns immediately and the data of first
+// write is sent in the SYN segment when a Fast Open cookie for the
+// peer is available.
+// It must be set before the connection is established to take
+// effect.
+//
+// Only Linux supports this option.
+// See TCP_FASTOPEN_CONNECT for further information.
+type FastOpenConnect bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (fo FastOpenConnect) Level() int { return options[soFastOpenConnect].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (fo FastOpenConnect) Name() int { return options[soFastOpenConnect].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (fo FastOpenConnect) Marshal() ([]byte, error) {
+	if options[soFastOpenConnect].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(fo))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// FastOpenNoCookie specifies the use of TCP Fast Open without Fast
+// Open cookies. It is intended for trusted networks where the data
+// in SYN segments needs no protection against spoofing.
+// On the client side, it must be set before the connection is
+// established to take effect. On the server side, it is set on the
+// listener and requires Fast Open enabled on the listener.
+//
+// Only Linux supports this option.
+// See TCP_FASTOPEN_NO_COOKIE for further information.
+type FastOpenNoCookie bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (fo FastOpenNoCookie) Level() int { return options[soFastOpenNoCookie].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (fo FastOpenNoCookie) Name() int { return options[soFastOpenNoCookie].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (fo FastOpenNoCookie) Marshal() ([]byte, error) {
+	if options[soFastOpenNoCookie].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(fo))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// ReuseAddress specifies the use of SO_REUSEADDR option.
+// It must be set before the socket is bound to a local address to
+// take effect.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and OpenBSD
+// support this option.
+type ReuseAddress bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ra ReuseAddress) Level() int { return options[soReuseAddr].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ra ReuseAddress) Name() int { return options[soReuseAddr].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ra ReuseAddress) Marshal() ([]byte, error) {
+	if options[soReuseAddr].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(ra))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// ReusePort specifies the use of SO_REUSEPORT option.
+// It must be set before the socket is bound to a local address to
+// take effect.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and OpenBSD
+// support this option.
+type ReusePort bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (rp ReusePort) Level() int { return options[soReusePort].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (rp ReusePort) Name() int { return options[soReusePort].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (rp ReusePort) Marshal() ([]byte, error) {
+	if options[soReusePort].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(rp))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// ReusePortLB specifies the use of SO_REUSEPORT_LB option, the
+// load-balancing variant of SO_REUSEPORT option. The kernel
+// distributes incoming connections among the listeners bound to the
+// same local address, whereas SO_REUSEPORT option on FreeBSD
+// delivers all the connections to the last bound listener.
+// It must be set before the socket is bound to a local address to
+// take effect.
+//
+// Only FreeBSD supports this option.
+type ReusePortLB bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (rp ReusePortLB) Level() int { return options[soReusePortLB].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (rp ReusePortLB) Name() int { return options[soReusePortLB].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (rp ReusePortLB) Marshal() ([]byte, error) {
+	if options[soReusePortLB].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(rp))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// WindowClamp specifies the upper bound of the advertised receive
+// window in bytes.
+// Unlike ReceiveBuffer, it limits the window advertised to the peer
+// without changing the buffer size.
+//
+// Only Linux supports this option.
+// See TCP_WINDOW_CLAMP for further information.
+type WindowClamp int
+
+// Level implements the Level method of tcpopt.Option interface.
+func (wc WindowClamp) Level() int { return options[soWindowClamp].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (wc WindowClamp) Name() int { return options[soWindowClamp].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (wc WindowClamp) Marshal() ([]byte, error) {
+	if options[soWindowClamp].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := int32(wc)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// SendBufferForce specifies the size of send buffer.
+// Unlike tcpopt.SendBuffer, it may exceed the system-wide limit.
+// It requires the CAP_NET_ADMIN capability and can only be set; use
+// Conn.SendBuffer to get the resulting size.
+//
+// Only Linux supports this option.
+// See SO_SNDBUFFORCE for further information.
+type SendBufferForce int
+
+// Level implements the Level method of tcpopt.Option interface.
+func (sb SendBufferForce) Level() int { return options[soSendBufferForce].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (sb SendBufferForce) Name() int { return options[soSendBufferForce].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (sb SendBufferForce) Marshal() ([]byte, error) {
+	if options[soSendBufferForce].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := int32(sb)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// ReceiveBufferForce specifies the size of receive buffer.
+// Unlike tcpopt.ReceiveBuffer, it may exceed the system-wide limit.
+// It requires the CAP_NET_ADMIN capability and can only be set; use
+// Conn.ReceiveBuffer to get the resulting size.
+//
+// Only Linux supports this option.
+// See SO_RCVBUFFORCE for further information.
+type ReceiveBufferForce int
+
+// Level implements the Level method of tcpopt.Option interface.
+func (rb ReceiveBufferForce) Level() int { return options[soReceiveBufferForce].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (rb ReceiveBufferForce) Name() int { return options[soReceiveBufferForce].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (rb ReceiveBufferForce) Marshal() ([]byte, error) {
+	if options[soReceiveBufferForce].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := int32(rb)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// Priority specifies the priority of packets sent on the connection.
+// The priority is used by the queueing disciplines to select a band
+// or a hardware queue of the outgoing interface, and complements the
+// DSCP marking that affects the network.
+// Values greater than 6 require the CAP_NET_ADMIN capability.
+//
+// Only Linux supports this option.
+// See SO_PRIORITY for further information.
+type Priority int
+
+// Level implements the Level method of tcpopt.Option interface.
+func (p Priority) Level() int { return options[soPriority].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (p Priority) Name() int { return options[soPriority].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (p Priority) Marshal() ([]byte, error) {
+	if options[soPriority].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := int32(p)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// MinRTO specifies the lower bound of retransmission timeout on the
+// connection. A value lower than the system default of 200
+// milliseconds lets the connections in a datacenter recover from
+// losses quickly.
+//
+// Only Linux supports this option. It requires Linux 6.15 or above.
+// See TCP_RTO_MIN_US for further information.
+type MinRTO time.Duration
+
+// Level implements the Level method of tcpopt.Option interface.
+func (mr MinRTO) Level() int { return options[soMinRTO].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (mr MinRTO) Name() int { return options[soMinRTO].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (mr MinRTO) Marshal() ([]byte, error) {
+	return marshalDuration(soMinRTO, time.Duration(mr), time.Microsecond)
+}
+
+// Linger2 specifies the lifetime of orphaned connections in the
+// FIN-WAIT-2 state, which are the connections closed by the
+// application and waiting for the peer to close.
+// Zero means the system default, and a negative value makes the
+// kernel reset the connection instead of entering the FIN-WAIT-2
+// state. A short lifetime keeps the kernel resources bounded on a
+// server closing a large number of connections.
+//
+// Only Linux supports this option.
+// See TCP_LINGER2 for further information.
+type Linger2 time.Duration
+
+// Level implements the Level method of tcpopt.Option interface.
+func (l2 Linger2) Level() int { return options[soLinger2].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (l2 Linger2) Name() int { return options[soLinger2].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (l2 Linger2) Marshal() ([]byte, error) {
+	if l2 < 0 {
+		if options[soLinger2].name < 1 {
+			return nil, ErrNotSupported
+		}
+		v := int32(-1)
+		return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+	}
+	return marshalDuration(soLinger2, time.Duration(l2), time.Second)
+}
+
+// maxLinger2 is the maximum value of Linger2; see
+// TCP_FIN_TIMEOUT_MAX.
+const maxLinger2 = 120 * time.Second
+
+// maxMinRTO is the maximum value of MinRTO; see TCP_RTO_MIN.
+const maxMinRTO = 200 * time.Millisecond
+
+// maxUnprivilegedPriority is the maximum value of Priority settable
+// without the CAP_NET_ADMIN capability.
+const maxUnprivilegedPriority = 6
+
+// privilegeError returns the error for the failure err of setting the
+// privileged option o.
+func privilegeError(o tcpopt.Option, err error) error {
+	switch o := o.(type) {
+	case SendBufferForce, ReceiveBufferForce:
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: CAP_NET_ADMIN required", err)
+		}
+	case Priority:
+		if o > maxUnprivilegedPriority && errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: CAP_NET_ADMIN required", err)
+		}
+	}
+	return err
+}
+
+// marshalInt32 encodes the well-known 4-byte option o into b without
+// allocation. It reports whether o is encoded.
+// The option unsupported on the platform is left to its Marshal
+// method, which reports the error.
+func marshalInt32(o tcpopt.Option, b *[4]byte) bool {
+	if o.Name() < 1 {
+		return false
+	}
+	var v int32
+	switch o := o.(type) {
+	case tcpopt.NoDelay:
+		v = boolint32(bool(o))
+	case tcpopt.KeepAlive:
+		v = boolint32(bool(o))
+	case tcpopt.Cork:
+		v = boolint32(bool(o))
+	case tcpopt.MSS:
+		v = int32(o)
+	case tcpopt.SendBuffer:
+		v = int32(o)
+	case tcpopt.ReceiveBuffer:
+		v = int32(o)
+	case tcpopt.KeepAliveProbeCount:
+		v = int32(o)
+	case tcpopt.NotSentLowWMK:
+		v = int32(o)
+	default:
+		return false
+	}
+	*(*int32)(unsafe.Pointer(b)) = v
+	return true
+}
+
+func boolint32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func marshalDuration(so int, d, unit time.Duration) ([]byte, error) {
+	if options[so].name < 1 {
+		return nil, ErrNotSupported
+	}
+	if d < 0 || d > math.MaxInt32*unit {
+		return nil, errors.New("duration out of range")
+	}
+	d += unit - time.Nanosecond
+	v := int32(d / unit)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+func parseDuration(b []byte, unit time.Duration) (time.Duration, error) {
+	if len(b) < 4 {
+		return 0, errors.New("short buffer")
+	}
+	return time.Duration(nativeEndian.Uint32(b)) * unit, nil
+}
+
+func parseConnectionTimeout(b []byte) (tcpopt.Option, error) {
+	d, err := parseDuration(b, connectionTimeoutUnit)
+	if err != nil {
+		return nil, err
+	}
+	return ConnectionTimeout(d), nil
+}
+
+func parseRetransmitConnDropTime(b []byte) (tcpopt.Option, error) {
+	d, err := parseDuration(b, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return RetransmitConnDropTime(d), nil
+}
+
+func parseUserTimeout(b []byte) (tcpopt.Option, error) {
+	d, err := parseDuration(b, userTimeoutUnit)
+	if err != nil {
+		return nil, err
+	}
+	return UserTimeout(d), nil
+}
+
+func parseFastOpenConnect(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return FastOpenConnect(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parsePriority(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return Priority(int32(nativeEndian.Uint32(b))), nil
+}
+
+func parseMinRTO(b []byte) (tcpopt.Option, error) {
+	d, err := parseDuration(b, time.Microsecond)
+	if err != nil {
+		return nil, err
+	}
+	return MinRTO(d), nil
+}
+
+func parseLinger2(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	v := int32(nativeEndian.Uint32(b))
+	if v < 0 {
+		return Linger2(-1), nil
+	}
+	return Linger2(time.Duration(v) * time.Second), nil
+}
+
+func parseEnableECN(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return EnableECN(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseNoOptions(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return NoOptions(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseReceiveLowWMK(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return ReceiveLowWMK(int32(nativeEndian.Uint32(b))), nil
+}
+
+func parseSendLowWMK(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return SendLowWMK(int32(nativeEndian.Uint32(b))), nil
+}
+
+func parseOOBInline(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return OOBInline(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseUserCookie(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return UserCookie(nativeEndian.Uint32(b)), nil
+}
+
+func parseTimestamps(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return Timestamps(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseDeferAccept(b []byte) (tcpopt.Option, error) {
+	d, err := parseDuration(b, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return DeferAccept(d), nil
+}
+
+func parseSaveSYN(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return SaveSYN(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseFastOpenNoCookie(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return FastOpenNoCookie(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseReuseAddress(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return ReuseAddress(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseReusePort(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return ReusePort(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseReusePortLB(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return ReusePortLB(nativeEndian.Uint32(b) != 0), nil
+}
+
+func parseWindowClamp(b []byte) (tcpopt.Option, error) {
+	if len(b) < 4 {
+		return nil, errors.New("short buffer")
+	}
+	return WindowClamp(nativeEndian.Uint32(b)), nil
+}
+
+// InitialRTO specifies the initial retransmission timeout and the
+// maximum number of SYN retransmissions for connection
+// establishment. It must be set before connecting to take effect,
+// and allows to shorten the detection of connection failures as
+// TCP_SYNCNT does on Linux.
+//
+// Only Windows supports this option.
+// See SIO_TCP_INITIAL_RTO for further information.
+type InitialRTO struct {
+	// RTT is the initial round-trip time used to derive the
+	// retransmission timeout. Zero means the system default.
+	RTT time.Duration
+
+	// MaxSynRetransmissions is the maximum number of SYN
+	// retransmissions. Zero means the system default and a
+	// negative value means no retransmissions.
+	MaxSynRetransmissions int
+}
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ir InitialRTO) Level() int { return options[soInitialRTO].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ir InitialRTO) Name() int { return options[soInitialRTO].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ir InitialRTO) Marshal() ([]byte, error) {
+	if options[soInitialRTO].name < 1 {
+		return nil, ErrNotSupported
+	}
+	if ir.RTT < 0 || ir.RTT/time.Millisecond > maxInitialRTT || ir.MaxSynRetransmissions > maxSynRetransmissions {
+		return nil, errors.New("initial rto out of range")
+	}
+	// See TCP_INITIAL_RTO_PARAMETERS.
+	b := make([]byte, 4)
+	nativeEndian.PutUint16(b[:2], uint16(ir.RTT/time.Millisecond))
+	switch {
+	case ir.MaxSynRetransmissions < 0:
+		b[2] = 0xfe // TCP_INITIAL_RTO_NO_SYN_RETRANSMISSIONS
+	default:
+		b[2] = byte(ir.MaxSynRetransmissions)
+	}
+	return b, nil
+}
+
+// Limits of TCP_INITIAL_RTO_PARAMETERS; the largest values are
+// reserved for the special meanings.
+const (
+	maxInitialRTT         = 0xfffe
+	maxSynRetransmissions = 0xfd
+)
+
+// FailConnectOnICMPError specifies the failure of connection
+// establishment on the reception of ICMP destination unreachable
+// errors, instead of retransmitting SYN segments until the timeout.
+// It must be set before connecting to take effect. See
+// Conn.ICMPError for the source of ICMP error.
+//
+// Only Windows supports this option.
+// See TCP_FAIL_CONNECT_ON_ICMP_ERROR for further information.
+type FailConnectOnICMPError bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (fc FailConnectOnICMPError) Level() int { return options[soFailConnectOnICMPError].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (fc FailConnectOnICMPError) Name() int { return options[soFailConnectOnICMPError].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (fc FailConnectOnICMPError) Marshal() ([]byte, error) {
+	if options[soFailConnectOnICMPError].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(fc))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// EnableECN enables or disables the use of Explicit Congestion
+// Notification on the connection regardless of the system default.
+// It must be set before the connection is established to take
+// effect. See Conn.ECN for the result of negotiation.
+//
+// Only Darwin supports this option.
+// See TCP_ENABLE_ECN for further information.
+type EnableECN bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ee EnableECN) Level() int { return options[soEnableECN].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ee EnableECN) Name() int { return options[soEnableECN].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ee EnableECN) Marshal() ([]byte, error) {
+	if options[soEnableECN].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(ee))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// NoOptions disables the use of TCP options, such as window scaling
+// and timestamps, in the segments sent on the connection.
+// It must be set before the connection is established to take
+// effect.
+//
+// Only Darwin supports this option.
+// See TCP_NOOPT for further information.
+type NoOptions bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (no NoOptions) Level() int { return options[soNoOptions].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (no NoOptions) Name() int { return options[soNoOptions].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (no NoOptions) Marshal() ([]byte, error) {
+	if options[soNoOptions].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(no))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// ReceiveLowWMK specifies the minimum number of bytes in the socket
+// receive buffer for the socket to become readable. A read on the
+// connection doesn't return until the low-water mark is satisfied,
+// the connection is closed or an error occurs, which allows
+// protocols with a known minimum frame size to reduce the number of
+// wake-ups.
+//
+// Only Darwin, Linux and BSD variants support this option.
+// See SO_RCVLOWAT for further information.
+type ReceiveLowWMK int
+
+// Level implements the Level method of tcpopt.Option interface.
+func (rl ReceiveLowWMK) Level() int { return options[soReceiveLowWMK].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (rl ReceiveLowWMK) Name() int { return options[soReceiveLowWMK].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (rl ReceiveLowWMK) Marshal() ([]byte, error) {
+	if options[soReceiveLowWMK].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := int32(rl)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// SendLowWMK specifies the minimum number of unused bytes in the
+// socket send buffer for the socket to become writable.
+//
+// Only Darwin and BSD variants support this option; Linux doesn't
+// allow to change the send low-water mark.
+// See SO_SNDLOWAT for further information.
+type SendLowWMK int
+
+// Level implements the Level method of tcpopt.Option interface.
+func (sl SendLowWMK) Level() int { return options[soSendLowWMK].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (sl SendLowWMK) Name() int { return options[soSendLowWMK].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (sl SendLowWMK) Marshal() ([]byte, error) {
+	if options[soSendLowWMK].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := int32(sl)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// OOBInline specifies the delivery of urgent data inline. When true,
+// the urgent byte marked by the peer is placed in the normal data
+// stream and returned by Read in order, instead of being held out of
+// band.
+//
+// Only Darwin, Linux and BSD variants support this option.
+// See SO_OOBINLINE for further information.
+type OOBInline bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (oi OOBInline) Level() int { return options[soOOBInline].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (oi OOBInline) Name() int { return options[soOOBInline].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (oi OOBInline) Marshal() ([]byte, error) {
+	if options[soOOBInline].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(oi))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// UserCookie specifies the tag of the connection, which allows the
+// firewall rules of ipfw and dummynet to match the packets on the
+// connection. It is the BSD analogue of the socket mark on Linux.
+//
+// Only FreeBSD supports this option.
+// See SO_USER_COOKIE for further information.
+type UserCookie uint32
+
+// Level implements the Level method of tcpopt.Option interface.
+func (uc UserCookie) Level() int { return options[soUserCookie].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (uc UserCookie) Name() int { return options[soUserCookie].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (uc UserCookie) Marshal() ([]byte, error) {
+	if options[soUserCookie].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := uint32(uc)
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// Timestamps specifies the use of the TCP timestamps option defined
+// in RFC 7323 on the connection, overriding the system-wide setting.
+// It must be set before the connection is established to take
+// effect.
+//
+// Only Windows supports this option.
+// See TCP_TIMESTAMPS for further information.
+type Timestamps bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ts Timestamps) Level() int { return options[soTimestamps].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ts Timestamps) Name() int { return options[soTimestamps].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ts Timestamps) Marshal() ([]byte, error) {
+	if options[soTimestamps].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(ts))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// DeferAccept specifies the time that the listener waits for the
+// first data from the peer before handing a connection to the
+// application. The connections are kept in the kernel while
+// waiting, which saves the accepts and the reads of the connections
+// opened by junk clients sending nothing. It is rounded up to
+// seconds, and the kernel may wait a little longer because it counts
+// the retransmissions of SYN-ACK segments.
+// It is set on the listener.
+//
+// Only Linux supports this option.
+// See TCP_DEFER_ACCEPT for further information.
+type DeferAccept time.Duration
+
+// Level implements the Level method of tcpopt.Option interface.
+func (da DeferAccept) Level() int { return options[soDeferAccept].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (da DeferAccept) Name() int { return options[soDeferAccept].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (da DeferAccept) Marshal() ([]byte, error) {
+	return marshalDuration(soDeferAccept, time.Duration(da), time.Second)
+}
+
+// SaveSYN specifies the retention of the IP and TCP headers of the
+// SYN segments received by the listener. The connections accepted
+// from the listener return the headers from Conn.SavedSYN.
+// It is set on the listener.
+//
+// Only Linux supports this option.
+// See TCP_SAVE_SYN for further information.
+type SaveSYN bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ss SaveSYN) Level() int { return options[soSaveSYN].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ss SaveSYN) Name() int { return options[soSaveSYN].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ss SaveSYN) Marshal() ([]byte, error) {
+	if options[soSaveSYN].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(ss))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// LoopbackFastPath enables the loopback fast path, which bypasses
+// most of the TCP/IP stack for the connections over the loopback
+// interface and reduces the latency of local communication such as
+// sidecars and IPC over TCP. It must be set on both the listener and
+// the connecting socket before listening and connecting to take
+// effect, such as by ControlFunc for net.ListenConfig and by
+// Dialer.Options.
+//
+// Only Windows supports this option; it is deprecated on Windows
+// Server 2019 and later, which ignore it.
+// See SIO_LOOPBACK_FAST_PATH for further information.
+type LoopbackFastPath bool
+
+// Level implements the Level method of tcpopt.Option interface.
+func (lf LoopbackFastPath) Level() int { return options[soLoopbackFastPath].level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (lf LoopbackFastPath) Name() int { return options[soLoopbackFastPath].name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (lf LoopbackFastPath) Marshal() ([]byte, error) {
+	if options[soLoopbackFastPath].name < 1 {
+		return nil, ErrNotSupported
+	}
+	v := boolint32(bool(lf))
+	return (*[4]byte)(unsafe.Pointer(&v))[:], nil
+}
+
+// A RawOption represents a socket option in the raw form.
+// It is returned by Conn.Option for the options that have no parser,
+// and allows to set an arbitrary option through Conn.SetOption.
+type RawOption struct {
+	level int
+	name  int
+	Value []byte // option value
+}
+
+// NewRawOption returns a new socket option specified by level and
+// name with the value b.
+func NewRawOption(level, name int, b []byte) *RawOption {
+	return &RawOption{level: level, name: name, Value: append([]byte(nil), b...)}
+}
+
+// Level implements the Level method of tcpopt.Option interface.
+func (ro *RawOption) Level() int { return ro.level }
+
+// Name implements the Name method of tcpopt.Option interface.
+func (ro *RawOption) Name() int { return ro.name }
+
+// Marshal implements the Marshal method of tcpopt.Option interface.
+func (ro *RawOption) Marshal() ([]byte, error) {
+	if len(ro.Value) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return ro.Value, nil
+}
@@ -5,13 +5,21 @@
 package tcp_test
 
 import (
+	"context"
+	"errors"
+	"io"
+	"math"
 	"math/rand"
 	"net"
 	"os"
+	"reflect"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpopt"
 )
 
 func TestOptionWithVariousBufferLenghts(t *testing.T) {
@@ -33,7 +41,11 @@ func TestOptionWithVariousBufferLenghts(t *testing.T) {
 	for i := 0; i < 256; i++ {
 		level, name := rand.Int(), rand.Int()
 		b := make([]byte, i)
-		tc.Option(level, name, b)
+		if _, err := tc.Option(level, name, b); err != nil && i > 0 {
+			if _, ok := err.(*tcp.OptionError); !ok {
+				t.Fatalf("got %T; want *tcp.OptionError", err)
+			}
+		}
 	}
 }
 
@@ -64,8 +76,926 @@ func TestOriginalDst(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if _, err := tc.OriginalDst(); err != nil {
+		od, err := tc.OriginalDst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cod, err := tc.OriginalDst()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cod != od {
+			t.Fatalf("got %v; want cached %v", cod, od)
+		}
+	}
+}
+
+func TestRedirectRecords(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The connection is not redirected by any WFP callout.
+	if od, err := tc.OriginalDst(); err == nil {
+		t.Fatalf("got %v; want an error", od)
+	}
+	if b, err := tc.RedirectRecords(); err == nil && len(b) > 0 {
+		t.Fatalf("got %d bytes; want no redirect records", len(b))
+	}
+}
+
+func TestLoopbackFastPath(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	lc := net.ListenConfig{Control: tcp.ControlFunc(tcp.LoopbackFastPath(true))}
+	ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err) // the running kernel may not support the loopback fast path
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		io.Copy(c, c)
+	}()
+
+	d := tcp.Dialer{Options: []tcpopt.Option{tcp.LoopbackFastPath(true)}}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Write([]byte("HELLO-R-U-THERE")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 15)
+	if _, err := io.ReadFull(c, b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnectionTimeoutOptions(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []tcpopt.Option{tcp.ConnectionTimeout(5 * time.Second)}
+	if runtime.GOOS == "darwin" {
+		opts = append(opts, tcp.RetransmitConnDropTime(30*time.Second))
+	}
+	for _, o := range opts {
+		if err := tc.SetOption(o); err != nil {
+			t.Fatal(err)
+		}
+		var b [4]byte
+		oo, err := tc.Option(o.Level(), o.Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(oo, o) {
+			t.Fatalf("got %#v; want %#v", oo, o)
+		}
+	}
+}
+
+func TestRawOption(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := tcpopt.NoDelay(false)
+	b, err := o.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SetRawOption(o.Level(), o.Name(), b); err != nil {
+		t.Fatal(err)
+	}
+	var bb [4]byte
+	n, err := tc.RawOption(o.Level(), o.Name(), bb[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bb[:n], b) {
+		t.Fatalf("got %v; want %v", bb[:n], b)
+	}
+}
+
+func TestSetOptionAllocs(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var o tcpopt.Option = tcpopt.SendBuffer(1 << 16)
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := tc.SetOption(o); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Fatalf("got %v allocs; want 0", allocs)
+	}
+}
+
+func TestOptionAllocs(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var o tcpopt.NoDelay
+	var b [4]byte
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := tc.Option(o.Level(), o.Name(), b[:]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Fatalf("got %v allocs; want 0", allocs)
+	}
+	allocs = testing.AllocsPerRun(100, func() {
+		if _, err := tc.NoDelay(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Fatalf("got %v allocs; want 0", allocs)
+	}
+}
+
+func TestOptionValue(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := tcpopt.KeepAlive(true)
+	if err := tc.SetOption(o); err != nil {
+		t.Fatal(err)
+	}
+	oo, err := tc.OptionValue(o.Level(), o.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(oo, o) {
+		t.Fatalf("got %#v; want %#v", oo, o)
+	}
+}
+
+func TestTypedOptions(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []tcpopt.Option{tcpopt.NoDelay(false), tcpopt.KeepAlive(true), tcpopt.Cork(true)} {
+		if err := tc.SetOption(o); err != nil {
 			t.Fatal(err)
 		}
 	}
+	if on, err := tc.NoDelay(); err != nil || on {
+		t.Fatalf("got %v, %v; want false, <nil>", on, err)
+	}
+	if on, err := tc.KeepAliveEnabled(); err != nil || !on {
+		t.Fatalf("got %v, %v; want true, <nil>", on, err)
+	}
+	if on, err := tc.Cork(); err != nil || !on {
+		t.Fatalf("got %v, %v; want true, <nil>", on, err)
+	}
+	if n, err := tc.SendBuffer(); err != nil || n <= 0 {
+		t.Fatalf("got %v, %v; want >0, <nil>", n, err)
+	}
+	if n, err := tc.ReceiveBuffer(); err != nil || n <= 0 {
+		t.Fatalf("got %v, %v; want >0, <nil>", n, err)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie, err := tc.Cookie()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie == 0 {
+		t.Fatal("got zero cookie")
+	}
+}
+
+func TestMemInfo(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi, err := tc.MemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mi.ReceiveBuffer <= 0 || mi.SendBuffer <= 0 {
+		t.Fatalf("got %+v; want non-zero buffer sizes", mi)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	for _, tt := range []struct {
+		o  tcpopt.Option
+		ok bool
+	}{
+		{tcpopt.NoDelay(true), true},
+		{tcpopt.SendBuffer(1 << 16), true},
+		{tcpopt.SendBuffer(0), false},
+		{tcpopt.ReceiveBuffer(-1), false},
+		{tcpopt.KeepAliveIdleInterval(10 * time.Second), true},
+		{tcpopt.KeepAliveIdleInterval(time.Millisecond), false},
+		{tcpopt.KeepAliveProbeInterval(10 * time.Hour), false},
+		{tcpopt.KeepAliveProbeCount(9), true},
+		{tcpopt.KeepAliveProbeCount(128), false},
+		{tcp.UserTimeout(3 * time.Second), true},
+		{tcp.UserTimeout(-time.Second), false},
+		{tcp.UserTimeout(math.MaxInt32 * time.Millisecond), true},
+		{tcp.UserTimeout(math.MaxInt32*time.Millisecond + time.Millisecond), false},
+		{tcp.UserTimeout(math.MaxInt64), false},
+		{tcp.ConnectionTimeout(time.Second), false}, // Darwin, DragonFly BSD and FreeBSD only
+	} {
+		err := tcp.Validate(tt.o)
+		if (err == nil) != tt.ok {
+			t.Errorf("%T(%v): got %v", tt.o, tt.o, err)
+		}
+		if err != nil {
+			if _, ok := err.(*tcp.OptionError); !ok {
+				t.Errorf("%T(%v): got %T; want *tcp.OptionError", tt.o, tt.o, err)
+			}
+		}
+	}
+}
+
+func TestWindowClamp(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SetOption(tcp.WindowClamp(1 << 16)); err != nil {
+		t.Fatal(err)
+	}
+	var b [4]byte
+	o, err := tc.Option(tcp.WindowClamp(0).Level(), tcp.WindowClamp(0).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcp.WindowClamp(1<<16) {
+		t.Fatalf("got %v; want %v", o, tcp.WindowClamp(1<<16))
+	}
+}
+
+func TestBufferForce(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []tcpopt.Option{tcp.SendBufferForce(1 << 24), tcp.ReceiveBufferForce(1 << 24)} {
+		err := tc.SetOption(o)
+		if os.Getuid() != 0 {
+			if !errors.Is(err, os.ErrPermission) {
+				t.Fatalf("%T: got %v; want permission error", o, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if os.Getuid() != 0 {
+		return
+	}
+	if n, err := tc.SendBuffer(); err != nil || n < 1<<24 {
+		t.Fatalf("got %v, %v; want >=%d, <nil>", n, err, 1<<24)
+	}
+}
+
+func TestUnknownOption(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const level, name = 0x1, 0x3 // SOL_SOCKET, SO_TYPE
+	var b [8]byte
+	o, err := tc.Option(level, name, b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ro, ok := o.(*tcp.RawOption)
+	if !ok {
+		t.Fatalf("got %T; want *tcp.RawOption", o)
+	}
+	if ro.Level() != level || ro.Name() != name || len(ro.Value) != 4 || ro.Value[0]|ro.Value[3] != 1 {
+		t.Fatalf("got level=%#x name=%#x value=%v; want SOCK_STREAM", ro.Level(), ro.Name(), ro.Value)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SetOption(tcpopt.NoDelay(true)); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := tc.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[reflect.Type]bool)
+	for _, o := range opts {
+		typ := reflect.TypeOf(o)
+		if seen[typ] {
+			t.Fatalf("got %v twice", typ)
+		}
+		seen[typ] = true
+		if nd, ok := o.(tcpopt.NoDelay); ok && !bool(nd) {
+			t.Fatalf("got %v; want true", nd)
+		}
+	}
+	if !seen[reflect.TypeOf(tcpopt.NoDelay(false))] {
+		t.Fatalf("NoDelay not found in %v", opts)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SetOption(tcp.Priority(4)); err != nil {
+		t.Fatal(err)
+	}
+	var b [4]byte
+	o, err := tc.Option(tcp.Priority(0).Level(), tcp.Priority(0).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcp.Priority(4) {
+		t.Fatalf("got %v; want %v", o, tcp.Priority(4))
+	}
+	if err := tcp.Validate(tcp.Priority(-1)); err == nil {
+		t.Fatal("got nil; want out of range error")
+	}
+}
+
+func TestMinRTO(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, err := tc.RTO(); err != nil || d <= 0 {
+		t.Fatalf("got %v, %v; want a positive retransmission timeout", d, err)
+	}
+	var b [4]byte
+	if _, err := tc.Option(tcp.MinRTO(0).Level(), tcp.MinRTO(0).Name(), b[:]); err != nil {
+		t.Skip(err) // requires Linux 6.15 or above
+	}
+	if err := tc.SetOption(tcp.MinRTO(20 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	o, err := tc.Option(tcp.MinRTO(0).Level(), tcp.MinRTO(0).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcp.MinRTO(20*time.Millisecond) {
+		t.Fatalf("got %v; want %v", o, tcp.MinRTO(20*time.Millisecond))
+	}
+	if err := tcp.Validate(tcp.MinRTO(time.Second)); err == nil {
+		t.Fatal("got nil; want out of range error")
+	}
+}
+
+func TestLinger2(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l2 := range []tcp.Linger2{tcp.Linger2(5 * time.Second), tcp.Linger2(-1)} {
+		if err := tc.SetOption(l2); err != nil {
+			t.Fatal(err)
+		}
+		var b [4]byte
+		o, err := tc.Option(l2.Level(), l2.Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o != l2 {
+			t.Fatalf("got %v; want %v", o, l2)
+		}
+	}
+	if err := tcp.Validate(tcp.Linger2(time.Hour)); err == nil {
+		t.Fatal("got nil; want out of range error")
+	}
+}
+
+func TestECN(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Close()
+	}()
+
+	var d tcp.Dialer
+	if runtime.GOOS == "darwin" {
+		d.Options = []tcpopt.Option{tcp.EnableECN(true)}
+	}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if runtime.GOOS == "darwin" {
+		var b [4]byte
+		o, err := c.Option(tcp.EnableECN(false).Level(), tcp.EnableECN(false).Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o != tcp.EnableECN(true) {
+			t.Fatalf("got %v; want %v", o, tcp.EnableECN(true))
+		}
+	} else if err := c.SetOption(tcp.EnableECN(true)); err == nil {
+		t.Fatal("got nil; want not supported error")
+	}
+	ok, err := c.ECN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("ecn: %v", ok)
+}
+
+func TestNoOptions(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Close()
+	}()
+
+	d := tcp.Dialer{Options: []tcpopt.Option{tcp.NoOptions(true)}}
+	c, err := d.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var b [4]byte
+	o, err := c.Option(tcp.NoOptions(false).Level(), tcp.NoOptions(false).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcp.NoOptions(true) {
+		t.Fatalf("got %v; want %v", o, tcp.NoOptions(true))
+	}
+}
+
+func TestLowWMK(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	opts := []tcpopt.Option{tcp.ReceiveLowWMK(64)}
+	if runtime.GOOS != "linux" {
+		opts = append(opts, tcp.SendLowWMK(1024))
+	}
+	for _, o := range opts {
+		if err := c.SetOption(o); err != nil {
+			t.Fatal(err)
+		}
+		var b [4]byte
+		oo, err := c.Option(o.Level(), o.Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oo != o {
+			t.Fatalf("got %v; want %v", oo, o)
+		}
+	}
+	if runtime.GOOS == "linux" {
+		if err := c.SetOption(tcp.SendLowWMK(1024)); !errors.Is(err, tcp.ErrNotSupported) {
+			t.Fatalf("got %v; want %v", err, tcp.ErrNotSupported)
+		}
+	}
+}
+
+func TestOOBInline(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	for _, o := range []tcp.OOBInline{true, false} {
+		if err := c.SetOption(o); err != nil {
+			t.Fatal(err)
+		}
+		var b [4]byte
+		oo, err := c.Option(o.Level(), o.Name(), b[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oo != o {
+			t.Fatalf("got %v; want %v", oo, o)
+		}
+	}
+}
+
+func TestUserCookie(t *testing.T) {
+	switch runtime.GOOS {
+	case "freebsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, _ := tcptest.Pair(t)
+	if err := c.SetOption(tcp.UserCookie(0xdeadbeef)); err != nil {
+		t.Fatal(err)
+	}
+	var b [4]byte
+	o, err := c.Option(tcp.UserCookie(0).Level(), tcp.UserCookie(0).Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != tcp.UserCookie(0xdeadbeef) {
+		t.Fatalf("got %v; want %v", o, tcp.UserCookie(0xdeadbeef))
+	}
+}
+
+func TestOptionSpecs(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "windows":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	specs := tcp.OptionSpecs()
+	for i := 1; i < len(specs); i++ {
+		if specs[i-1].Name >= specs[i].Name {
+			t.Fatalf("not sorted: %s, %s", specs[i-1].Name, specs[i].Name)
+		}
+	}
+	spec, ok := tcp.LookupOptionSpec("TCP_NODELAY")
+	if !ok {
+		t.Fatal("TCP_NODELAY not found")
+	}
+	var nd tcpopt.NoDelay
+	if spec.Level != nd.Level() || spec.Number != nd.Name() {
+		t.Fatalf("got %+v; want level=%d number=%d", spec, nd.Level(), nd.Name())
+	}
+	if _, ok := tcp.LookupOptionSpec("TCP_NONEXISTENT"); ok {
+		t.Fatal("got unknown option")
+	}
+
+	c, _ := tcptest.Pair(t)
+	b := make([]byte, spec.Size)
+	n, err := c.RawOption(spec.Level, spec.Number, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != spec.Size {
+		t.Fatalf("got %d; want %d", n, spec.Size)
+	}
+}
+
+type timeoutError struct{ timeout, temporary bool }
+
+func (e *timeoutError) Error() string   { return "timeout error" }
+func (e *timeoutError) Timeout() bool   { return e.timeout }
+func (e *timeoutError) Temporary() bool { return e.temporary }
+
+func TestNetError(t *testing.T) {
+	for _, tt := range []struct {
+		err                error
+		timeout, temporary bool
+	}{
+		{&tcp.OptionError{Op: "get", Err: &timeoutError{true, true}}, true, true},
+		{&tcp.OptionError{Op: "set", Err: &timeoutError{false, true}}, false, true},
+		{&tcp.OptionError{Op: "set", Err: errors.New("permanent")}, false, false},
+		{&tcp.ExtendedError{Err: &timeoutError{true, false}}, true, false},
+		{&net.OpError{Op: "get", Net: "tcp", Err: &tcp.OptionError{Op: "get", Err: &timeoutError{true, true}}}, true, true},
+		{tcp.ErrWouldBlock, true, true},
+	} {
+		var ne net.Error
+		if !errors.As(tt.err, &ne) {
+			t.Errorf("%v: not a net.Error", tt.err)
+			continue
+		}
+		if ne.Timeout() != tt.timeout || ne.Temporary() != tt.temporary {
+			t.Errorf("%v: got timeout=%v temporary=%v; want %v, %v", tt.err, ne.Timeout(), ne.Temporary(), tt.timeout, tt.temporary)
+		}
+	}
 }
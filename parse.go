@@ -0,0 +1,67 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/mikioh/tcpopt"
+)
+
+var parsers = struct {
+	sync.RWMutex
+	m map[int64]func([]byte) (tcpopt.Option, error)
+}{
+	m: make(map[int64]func([]byte) (tcpopt.Option, error)),
+}
+
+// RegisterParser registers a socket option parser used by the Option
+// method of Conn.
+// A parser registered with RegisterParser takes precedence over the
+// parser registered with the tcpopt package for the same option.
+// The parser must not retain the option value passed to fn after
+// returning, as the buffer of the value may be reused.
+func RegisterParser(level, name int, fn func([]byte) (tcpopt.Option, error)) {
+	parsers.Lock()
+	parsers.m[int64(level)<<32|int64(name)] = fn
+	parsers.Unlock()
+}
+
+// UnregisterParser unregisters a socket option parser.
+func UnregisterParser(level, name int) {
+	parsers.Lock()
+	delete(parsers.m, int64(level)<<32|int64(name))
+	parsers.Unlock()
+}
+
+func parse(level, name int, b []byte) (tcpopt.Option, error) {
+	parsers.RLock()
+	fn, ok := parsers.m[int64(level)<<32|int64(name)]
+	parsers.RUnlock()
+	if ok {
+		return fn(b)
+	}
+	return tcpopt.Parse(level, name, b)
+}
+
+// parseOrRaw parses the option value b of which the first n bytes are
+// filled by the kernel. It returns a RawOption when no parser is
+// registered for the option.
+func parseOrRaw(level, name int, b []byte, n int) (tcpopt.Option, error) {
+	o, err := parse(level, name, b)
+	if err != nil && isParserNotFound(err) {
+		return NewRawOption(level, name, b[:n]), nil
+	}
+	return o, err
+}
+
+// isParserNotFound reports whether err is returned by tcpopt.Parse
+// for the lack of a parser. The tcpopt package provides no other way
+// to tell it.
+func isParserNotFound(err error) bool {
+	s := err.Error()
+	return strings.HasPrefix(s, "parser for ") && strings.HasSuffix(s, " not found")
+}
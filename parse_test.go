@@ -0,0 +1,119 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+type rawOption struct {
+	level, name int
+	b           []byte
+}
+
+func (ro *rawOption) Level() int               { return ro.level }
+func (ro *rawOption) Name() int                { return ro.name }
+func (ro *rawOption) Marshal() ([]byte, error) { return ro.b, nil }
+
+func TestRegisterParser(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := tcpopt.NoDelay(true)
+	tcp.RegisterParser(o.Level(), o.Name(), func(b []byte) (tcpopt.Option, error) {
+		return &rawOption{level: o.Level(), name: o.Name(), b: append([]byte(nil), b...)}, nil
+	})
+	defer tcp.UnregisterParser(o.Level(), o.Name())
+	if err := tc.SetOption(o); err != nil {
+		t.Fatal(err)
+	}
+	var b [4]byte
+	oo, err := tc.Option(o.Level(), o.Name(), b[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ro, ok := oo.(*rawOption)
+	if !ok {
+		t.Fatalf("got %T; want *rawOption", oo)
+	}
+	if bb, _ := o.Marshal(); !reflect.DeepEqual(ro.b, bb) {
+		t.Fatalf("got %v; want %v", ro.b, bb)
+	}
+}
+
+func TestRegisterParserGetters(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		o    tcpopt.Option
+		skip string // platform not supporting the getter
+		get  func() error
+	}{
+		{tcpopt.NoDelay(false), "", func() error { _, err := tc.NoDelay(); return err }},
+		{tcpopt.SendBuffer(0), "", func() error { _, err := tc.SendBuffer(); return err }},
+		{tcpopt.ReceiveBuffer(0), "", func() error { _, err := tc.ReceiveBuffer(); return err }},
+		{tcpopt.KeepAlive(false), "", func() error { _, err := tc.KeepAliveEnabled(); return err }},
+		{tcpopt.Cork(false), "netbsd", func() error { _, err := tc.Cork(); return err }},
+		{tcpopt.KeepAliveIdleInterval(0), "openbsd", func() error { _, err := tc.KeepAlive(); return err }},
+		{tcpopt.KeepAliveProbeInterval(0), "openbsd", func() error { _, err := tc.KeepAlive(); return err }},
+		{tcpopt.KeepAliveProbeCount(0), "openbsd", func() error { _, err := tc.KeepAlive(); return err }},
+	} {
+		if tt.skip == runtime.GOOS || tt.o.Name() < 1 {
+			continue
+		}
+		level, name := tt.o.Level(), tt.o.Name()
+		tcp.RegisterParser(level, name, func(b []byte) (tcpopt.Option, error) {
+			return tcp.NewRawOption(level, name, b), nil
+		})
+		err := tt.get()
+		tcp.UnregisterParser(level, name)
+		if err == nil {
+			t.Errorf("%T: got nil; want an error for unexpected option type", tt.o)
+		}
+	}
+}
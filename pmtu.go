@@ -0,0 +1,85 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "net"
+
+// A PMTUDiscovery represents a mode of path MTU discovery.
+type PMTUDiscovery int
+
+const (
+	PMTUDiscoveryDont  PMTUDiscovery = iota // never set DF; fragment locally
+	PMTUDiscoveryWant                       // use per-route hints
+	PMTUDiscoveryDo                         // always set DF
+	PMTUDiscoveryProbe                      // set DF and ignore the path MTU
+)
+
+var pmtuDiscoveryNames = [...]string{
+	PMTUDiscoveryDont:  "dont",
+	PMTUDiscoveryWant:  "want",
+	PMTUDiscoveryDo:    "do",
+	PMTUDiscoveryProbe: "probe",
+}
+
+func (m PMTUDiscovery) String() string {
+	if m < 0 || int(m) >= len(pmtuDiscoveryNames) {
+		return "<nil>"
+	}
+	return pmtuDiscoveryNames[m]
+}
+
+// SetPathMTUDiscovery sets the mode of path MTU discovery.
+// It uses IP_MTU_DISCOVER or IPV6_MTU_DISCOVER option depending on
+// the address family of the connection.
+//
+// Only Linux supports this feature.
+func (c *Conn) SetPathMTUDiscovery(m PMTUDiscovery) error {
+	family := c.family()
+	if err := c.control(func(s uintptr) error { return setPMTUDiscovery(s, family, m) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// PathMTUDiscovery returns the mode of path MTU discovery.
+//
+// Only Linux supports this feature.
+func (c *Conn) PathMTUDiscovery() (PMTUDiscovery, error) {
+	family := c.family()
+	var m PMTUDiscovery
+	err := c.control(func(s uintptr) (err error) {
+		m, err = pmtuDiscovery(s, family)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return m, nil
+}
+
+// PathMTU returns the current path MTU known to the kernel.
+//
+// Only Linux supports this feature.
+func (c *Conn) PathMTU() (int, error) {
+	family := c.family()
+	var n int
+	err := c.control(func(s uintptr) (err error) {
+		n, err = pathMTU(s, family)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return n, nil
+}
+
+// family returns the IP protocol number of the connection,
+// ianaProtocolIP or ianaProtocolIPv6.
+func (c *Conn) family() int {
+	if la, ok := c.LocalAddr().(*net.TCPAddr); ok && la.IP.To4() == nil {
+		return ianaProtocolIPv6
+	}
+	return ianaProtocolIP
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"os"
+	"unsafe"
+)
+
+var pmtuModes = [...]int32{
+	PMTUDiscoveryDont:  sysIP_PMTUDISC_DONT,
+	PMTUDiscoveryWant:  sysIP_PMTUDISC_WANT,
+	PMTUDiscoveryDo:    sysIP_PMTUDISC_DO,
+	PMTUDiscoveryProbe: sysIP_PMTUDISC_PROBE,
+}
+
+func pmtuOptions(level int) (discover, mtu int) {
+	if level == ianaProtocolIPv6 {
+		return sysIPV6_MTU_DISCOVER, sysIPV6_MTU
+	}
+	return sysIP_MTU_DISCOVER, sysIP_MTU
+}
+
+func setPMTUDiscovery(s uintptr, level int, m PMTUDiscovery) error {
+	if m < 0 || int(m) >= len(pmtuModes) {
+		return errors.New("invalid path mtu discovery mode")
+	}
+	name, _ := pmtuOptions(level)
+	v := pmtuModes[m]
+	if err := setsockopt(s, level, name, (*[4]byte)(unsafe.Pointer(&v))[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func pmtuDiscovery(s uintptr, level int) (PMTUDiscovery, error) {
+	name, _ := pmtuOptions(level)
+	var b [4]byte
+	if _, err := getsockopt(s, level, name, b[:]); err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+	v := int32(nativeEndian.Uint32(b[:]))
+	for m, mv := range pmtuModes {
+		if mv == v {
+			return PMTUDiscovery(m), nil
+		}
+	}
+	return 0, errors.New("unknown path mtu discovery mode")
+}
+
+func pathMTU(s uintptr, level int) (int, error) {
+	_, name := pmtuOptions(level)
+	var b [4]byte
+	if _, err := getsockopt(s, level, name, b[:]); err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+	return int(nativeEndian.Uint32(b[:])), nil
+}
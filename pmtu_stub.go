@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func setPMTUDiscovery(s uintptr, level int, m PMTUDiscovery) error { return ErrNotSupported }
+
+func pmtuDiscovery(s uintptr, level int) (PMTUDiscovery, error) { return 0, ErrNotSupported }
+
+func pathMTU(s uintptr, level int) (int, error) { return 0, ErrNotSupported }
@@ -0,0 +1,59 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestPathMTU(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	for _, address := range []string{"127.0.0.1:0", "[::1]:0"} {
+		ln, err := net.Listen("tcp", address)
+		if err != nil {
+			t.Log(err)
+			continue
+		}
+		defer ln.Close()
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		tc, err := tcp.NewConn(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, m := range []tcp.PMTUDiscovery{tcp.PMTUDiscoveryDont, tcp.PMTUDiscoveryProbe, tcp.PMTUDiscoveryDo} {
+			if err := tc.SetPathMTUDiscovery(m); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tc.PathMTUDiscovery()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != m {
+				t.Fatalf("%s: got %v; want %v", address, got, m)
+			}
+		}
+		mtu, err := tc.PathMTU()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mtu <= 0 {
+			t.Fatalf("%s: got %d; want a positive mtu", address, mtu)
+		}
+	}
+}
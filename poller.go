@@ -0,0 +1,139 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// A PollEvents represents a set of readiness events.
+type PollEvents uint32
+
+const (
+	PollRead          PollEvents = 1 << iota // connection is readable
+	PollWrite                                // connection is writable
+	PollHangup                               // peer closed the connection; reported only
+	PollError                                // error on the connection; reported only
+	PollEdgeTriggered                        // report only state changes; registration only
+)
+
+// A PollEvent represents a readiness event on a connection.
+type PollEvent struct {
+	Conn   *Conn
+	Events PollEvents
+}
+
+// A Poller represents a readiness manager for many connections.
+// It uses a single epoll instance on Linux and a single kqueue
+// instance on BSD variants.
+//
+// The connections registered to the poller must not be closed before
+// being removed from the poller.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and OpenBSD
+// support this feature.
+type Poller struct {
+	fd int // epoll or kqueue descriptor
+
+	mu    sync.RWMutex
+	conns map[int]*pollEntry
+
+	waitMu sync.Mutex
+	buf    []sysPollEvent // buffer for Wait
+}
+
+type pollEntry struct {
+	c  *Conn
+	ev PollEvents
+}
+
+// NewPoller returns a new poller.
+func NewPoller() (*Poller, error) {
+	fd, err := newPoller()
+	if err != nil {
+		return nil, err
+	}
+	return &Poller{fd: fd, conns: make(map[int]*pollEntry)}, nil
+}
+
+// Add registers the connection c for the readiness events ev.
+func (p *Poller) Add(c *Conn, ev PollEvents) error {
+	s := int(c.s)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.conns[s]; ok {
+		return c.opError("poll", errors.New("already registered"))
+	}
+	if err := c.control(func(uintptr) error { return p.ctl(s, 0, ev) }); err != nil {
+		return c.opError("poll", err)
+	}
+	p.conns[s] = &pollEntry{c: c, ev: ev}
+	return nil
+}
+
+// Modify changes the readiness events for the connection c.
+func (p *Poller) Modify(c *Conn, ev PollEvents) error {
+	s := int(c.s)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pe, ok := p.conns[s]
+	if !ok {
+		return c.opError("poll", errors.New("not registered"))
+	}
+	if err := c.control(func(uintptr) error { return p.ctl(s, pe.ev, ev) }); err != nil {
+		return c.opError("poll", err)
+	}
+	pe.ev = ev
+	return nil
+}
+
+// Remove unregisters the connection c.
+// Removing a connection already closed succeeds, as closing the
+// socket unregisters it from the poller.
+func (p *Poller) Remove(c *Conn) error {
+	s := int(c.s)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pe, ok := p.conns[s]
+	if !ok {
+		return c.opError("poll", errors.New("not registered"))
+	}
+	delete(p.conns, s)
+	if err := c.control(func(uintptr) error { return p.ctl(s, pe.ev, 0) }); err != nil && !errors.Is(err, net.ErrClosed) {
+		return c.opError("poll", err)
+	}
+	return nil
+}
+
+// Wait waits for readiness events and stores them in events.
+// It returns the number of events stored.
+// A negative timeout means no timeout.
+// Wait blocks the calling goroutine and its operating system thread.
+func (p *Poller) Wait(events []PollEvent, timeout time.Duration) (int, error) {
+	if len(events) == 0 {
+		return 0, errors.New("short buffer")
+	}
+	p.waitMu.Lock()
+	defer p.waitMu.Unlock()
+	if len(p.buf) < len(events) {
+		p.buf = make([]sysPollEvent, len(events))
+	}
+	return p.wait(events, p.buf[:len(events)], timeout)
+}
+
+// Close closes the poller.
+func (p *Poller) Close() error {
+	return closePoller(p.fd)
+}
+
+func (p *Poller) lookup(s int) *pollEntry {
+	p.mu.RLock()
+	pe := p.conns[s]
+	p.mu.RUnlock()
+	return pe
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+type sysPollEvent = syscall.Kevent_t
+
+func newPoller() (int, error) {
+	fd, err := syscall.Kqueue()
+	if err != nil {
+		return -1, os.NewSyscallError("kqueue", err)
+	}
+	syscall.CloseOnExec(fd)
+	return fd, nil
+}
+
+func closePoller(fd int) error { return syscall.Close(fd) }
+
+func (p *Poller) ctl(s int, old, ev PollEvents) error {
+	flags := syscall.EV_ADD | syscall.EV_ENABLE
+	if ev&PollEdgeTriggered != 0 {
+		flags |= syscall.EV_CLEAR
+	}
+	edge := (old^ev)&PollEdgeTriggered != 0
+	var chg [2]syscall.Kevent_t
+	var n int
+	for _, f := range [2]struct {
+		e      PollEvents
+		filter int
+	}{{PollRead, syscall.EVFILT_READ}, {PollWrite, syscall.EVFILT_WRITE}} {
+		switch {
+		case ev&f.e != 0 && (old&f.e == 0 || edge):
+			syscall.SetKevent(&chg[n], s, f.filter, flags)
+			n++
+		case old&f.e != 0 && ev&f.e == 0:
+			syscall.SetKevent(&chg[n], s, f.filter, syscall.EV_DELETE)
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	if _, err := syscall.Kevent(p.fd, chg[:n], nil, nil); err != nil {
+		return os.NewSyscallError("kevent", err)
+	}
+	return nil
+}
+
+func (p *Poller) wait(events []PollEvent, buf []sysPollEvent, timeout time.Duration) (int, error) {
+	var ts *syscall.Timespec
+	if timeout >= 0 {
+		t := syscall.NsecToTimespec(int64(timeout))
+		ts = &t
+	}
+	n, err := syscall.Kevent(p.fd, nil, buf, ts)
+	if err == syscall.EINTR {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, os.NewSyscallError("kevent", err)
+	}
+	var i int
+	for _, e := range buf[:n] {
+		pe := p.lookup(int(e.Ident))
+		if pe == nil {
+			continue
+		}
+		var ev PollEvents
+		switch e.Filter {
+		case syscall.EVFILT_READ:
+			ev |= PollRead
+		case syscall.EVFILT_WRITE:
+			ev |= PollWrite
+		}
+		if e.Flags&syscall.EV_EOF != 0 {
+			ev |= PollHangup
+		}
+		if e.Flags&syscall.EV_ERROR != 0 {
+			ev |= PollError
+		}
+		events[i] = PollEvent{Conn: pe.c, Events: ev}
+		i++
+	}
+	return i, nil
+}
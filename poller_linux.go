@@ -0,0 +1,86 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+type sysPollEvent = syscall.EpollEvent
+
+func newPoller() (int, error) {
+	fd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return -1, os.NewSyscallError("epoll_create1", err)
+	}
+	return fd, nil
+}
+
+func closePoller(fd int) error { return syscall.Close(fd) }
+
+func (p *Poller) ctl(s int, old, ev PollEvents) error {
+	op := syscall.EPOLL_CTL_MOD
+	switch {
+	case old == 0 && ev == 0:
+		return nil
+	case old == 0:
+		op = syscall.EPOLL_CTL_ADD
+	case ev == 0:
+		op = syscall.EPOLL_CTL_DEL
+	}
+	e := syscall.EpollEvent{Events: syscall.EPOLLRDHUP, Fd: int32(s)}
+	if ev&PollRead != 0 {
+		e.Events |= syscall.EPOLLIN
+	}
+	if ev&PollWrite != 0 {
+		e.Events |= syscall.EPOLLOUT
+	}
+	if ev&PollEdgeTriggered != 0 {
+		e.Events |= sysEPOLLET
+	}
+	if err := syscall.EpollCtl(p.fd, op, s, &e); err != nil {
+		return os.NewSyscallError("epoll_ctl", err)
+	}
+	return nil
+}
+
+func (p *Poller) wait(events []PollEvent, buf []sysPollEvent, timeout time.Duration) (int, error) {
+	msec := -1
+	if timeout >= 0 {
+		msec = int((timeout + time.Millisecond - 1) / time.Millisecond)
+	}
+	n, err := syscall.EpollWait(p.fd, buf, msec)
+	if err == syscall.EINTR {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, os.NewSyscallError("epoll_wait", err)
+	}
+	var i int
+	for _, e := range buf[:n] {
+		pe := p.lookup(int(e.Fd))
+		if pe == nil {
+			continue
+		}
+		var ev PollEvents
+		if e.Events&syscall.EPOLLIN != 0 {
+			ev |= PollRead
+		}
+		if e.Events&syscall.EPOLLOUT != 0 {
+			ev |= PollWrite
+		}
+		if e.Events&(syscall.EPOLLRDHUP|syscall.EPOLLHUP) != 0 {
+			ev |= PollHangup
+		}
+		if e.Events&syscall.EPOLLERR != 0 {
+			ev |= PollError
+		}
+		events[i] = PollEvent{Conn: pe.c, Events: ev}
+		i++
+	}
+	return i, nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package tcp
+
+import "time"
+
+type sysPollEvent struct{}
+
+func newPoller() (int, error) { return -1, ErrNotSupported }
+
+func closePoller(fd int) error { return ErrNotSupported }
+
+func (p *Poller) ctl(s int, old, ev PollEvents) error { return ErrNotSupported }
+
+func (p *Poller) wait(events []PollEvent, buf []sysPollEvent, timeout time.Duration) (int, error) {
+	return 0, ErrNotSupported
+}
+
+func sockError(s uintptr) error { return nil }
@@ -0,0 +1,89 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestPoller(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	p, err := tcp.NewPoller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var cs [2]net.Conn
+	var tcs [2]*tcp.Conn
+	for i := range cs {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if cs[i], err = ln.Accept(); err != nil {
+			t.Fatal(err)
+		}
+		defer cs[i].Close()
+		if tcs[i], err = tcp.NewConn(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Add(tcs[i], tcp.PollRead); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := make([]tcp.PollEvent, 4)
+	n, err := p.Wait(events, 10*time.Millisecond)
+	if err != nil || n != 0 {
+		t.Fatalf("got %d, %v; want 0, nil", n, err)
+	}
+	if _, err := cs[1].Write([]byte("HELLO-R-U-THERE")); err != nil {
+		t.Fatal(err)
+	}
+	n, err = p.Wait(events, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || events[0].Conn != tcs[1] || events[0].Events&tcp.PollRead == 0 {
+		t.Fatalf("got %v; want a read event on %v", events[:n], tcs[1])
+	}
+
+	if err := p.Modify(tcs[0], tcp.PollWrite); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Remove(tcs[1]); err != nil {
+		t.Fatal(err)
+	}
+	n, err = p.Wait(events, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || events[0].Conn != tcs[0] || events[0].Events&tcp.PollWrite == 0 {
+		t.Fatalf("got %v; want a write event on %v", events[:n], tcs[0])
+	}
+	if err := p.Remove(tcs[1]); err == nil {
+		t.Fatal("removed twice")
+	}
+	tcs[0].Close()
+	if err := p.Remove(tcs[0]); err != nil {
+		t.Fatalf("got %v; want nil for closed connection", err)
+	}
+}
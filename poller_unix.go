@@ -0,0 +1,24 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+)
+
+// sockError returns the pending error on the socket s.
+func sockError(s uintptr) error {
+	errno, err := syscall.GetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_ERROR)
+	if err != nil {
+		return os.NewSyscallError("getsockopt", err)
+	}
+	if errno != 0 {
+		return syscall.Errno(errno)
+	}
+	return nil
+}
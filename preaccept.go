@@ -0,0 +1,65 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "net"
+
+// A PreAcceptFunc represents a verdict on a connection before Accept
+// returns it to the application.
+// The syn is the SYN segment that opened the connection, or nil when
+// it's not available, and data is the data already received on the
+// connection, which is left readable to the application.
+// It reports whether the connection is accepted. The rejected
+// connection is aborted with a RST segment.
+type PreAcceptFunc func(c *Conn, syn *SYN, data []byte) bool
+
+// preAcceptPeekLen is the maximum length of data passed to
+// PreAcceptFunc.
+const preAcceptPeekLen = 2048
+
+// SetPreAccept sets the verdict fn on new connections, which allows
+// lightweight filtering of junk clients, such as the clients sending
+// unexpected TCP options or first payloads, before handing the
+// connections to the application. A nil fn removes the verdict.
+//
+// It enables the SaveSYN option on the listener. The DeferAccept
+// option on the listener makes the verdict see the first payload.
+//
+// Only Linux supports this feature.
+func (ln *Listener) SetPreAccept(fn PreAcceptFunc) error {
+	if fn != nil {
+		if err := ln.SetOption(SaveSYN(true)); err != nil {
+			return err
+		}
+	}
+	ln.limitMu.Lock()
+	ln.preAccept = fn
+	ln.limitMu.Unlock()
+	return nil
+}
+
+// preAccept returns the connection c accepted by the verdict fn, or
+// nil when rejected.
+func preAccept(c net.Conn, fn PreAcceptFunc) (*Conn, error) {
+	tc, err := NewConn(c)
+	if err != nil {
+		return nil, err
+	}
+	syn, err := tc.SavedSYN()
+	if err != nil {
+		syn = nil
+	}
+	b := make([]byte, preAcceptPeekLen)
+	var n int
+	tc.control(func(s uintptr) (err error) {
+		n, err = peek(s, b)
+		return
+	})
+	if !fn(tc, syn, b[:n:n]) {
+		tc.Abort()
+		return nil, nil
+	}
+	return tc, nil
+}
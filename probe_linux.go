@@ -0,0 +1,77 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// Parameters of peer verification. The acknowledgment of a keepalive
+// probe is distinguished from the previous one by more than
+// verifyAckResolution, which covers the resolution of the kernel
+// timestamps; the probe is sent only after a second of idleness.
+const (
+	verifyPollInterval  = 10 * time.Millisecond
+	verifyAckResolution = 20 * time.Millisecond
+)
+
+// probePeer sends a keepalive probe and waits for the acknowledgment
+// from the peer.
+func (c *Conn) probePeer(ctx context.Context) error {
+	info, err := connInfo(c)
+	if err != nil {
+		return err
+	}
+	if info.State != tcpinfo.Established {
+		return errors.New("connection not established")
+	}
+	last := time.Now().Add(-info.LastAckReceived)
+	ka, err := c.KeepAlive()
+	if err != nil {
+		return err
+	}
+	defer c.restoreKeepAlive(ka)
+	if err := c.SetKeepAlive(&KeepAlive{Enable: true, IdleInterval: time.Second, ProbeInterval: time.Second}); err != nil {
+		return err
+	}
+	t := time.NewTicker(verifyPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		info, err := connInfo(c)
+		if err != nil {
+			return err
+		}
+		if info.State != tcpinfo.Established {
+			var serr error
+			c.control(func(s uintptr) error { serr = sockError(s); return nil })
+			if serr != nil {
+				return serr // reset or timed out
+			}
+			return io.EOF
+		}
+		if time.Now().Add(-info.LastAckReceived).Sub(last) > verifyAckResolution {
+			return nil
+		}
+	}
+}
+
+// restoreKeepAlive restores the keepalive parameters ka changed by
+// probePeer.
+func (c *Conn) restoreKeepAlive(ka *KeepAlive) {
+	c.SetKeepAlive(&KeepAlive{Enable: true, IdleInterval: ka.IdleInterval, ProbeInterval: ka.ProbeInterval, ProbeCount: ka.ProbeCount})
+	if !ka.Enable {
+		c.SetKeepAlive(ka)
+	}
+}
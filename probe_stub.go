@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import "context"
+
+// probePeer does nothing because only Linux reports the time of the
+// last acknowledgment received.
+func (c *Conn) probePeer(ctx context.Context) error { return nil }
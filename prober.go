@@ -0,0 +1,227 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A ProberConfig represents the configuration of KeepAliveProber.
+type ProberConfig struct {
+	// KeepAlive is the keepalive parameters set on the
+	// connections. The idle interval is spread over the
+	// connections by Jitter, so that the kernel doesn't send the
+	// probes of the connections established at once in bursts.
+	// The keepalive parameters are left unchanged when Enable is
+	// false.
+	KeepAlive KeepAlive
+
+	// Jitter is the fraction of the idle interval spread over the
+	// connections. Zero means 0.1, and a negative value disables
+	// the spread.
+	Jitter float64
+
+	// UserTimeout is the UserTimeout option set on the
+	// connections. Zero leaves the option unchanged.
+	UserTimeout time.Duration
+
+	// Interval is the interval of health checks on each
+	// connection. The checks of the connections are staggered
+	// evenly in the interval. Zero means a minute.
+	Interval time.Duration
+
+	// Health reports whether the connection c is healthy, using
+	// the connection information info. The prober closes and
+	// removes the unhealthy connections. A nil Health checks no
+	// connections; the prober removes the closed connections only.
+	Health func(c *Conn, info *tcpinfo.Info) bool
+}
+
+// proberSlots is the number of slots staggering the connections
+// registered with KeepAliveProber.
+const proberSlots = 64
+
+// A KeepAliveProber represents a central prober that maintains the
+// keepalive and health of a large number of mostly idle connections,
+// such as the connections of a push notification server.
+//
+// Unlike KeepAliveTuner, it runs no goroutine or timer per
+// connection. The connections are distributed over the slots of a
+// wheel by their IDs, and the prober visits a slot per tick, which
+// configures the connections new to the prober and checks the
+// health of the others. The work on the connections is staggered
+// over the interval instead of being synchronized.
+type KeepAliveProber struct {
+	cfg  ProberConfig
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mu    sync.Mutex
+	slots [proberSlots]map[*Conn]bool // value reports whether configured
+	n     int
+	err   error
+}
+
+// NewKeepAliveProber returns a new prober with the configuration
+// cfg.
+func NewKeepAliveProber(cfg *ProberConfig) (*KeepAliveProber, error) {
+	p := &KeepAliveProber{cfg: *cfg, stop: make(chan struct{}), done: make(chan struct{})}
+	if p.cfg.Interval == 0 {
+		p.cfg.Interval = time.Minute
+	}
+	if p.cfg.Interval < proberSlots*time.Nanosecond {
+		return nil, errors.New("invalid interval")
+	}
+	if p.cfg.Jitter == 0 {
+		p.cfg.Jitter = 0.1
+	}
+	if p.cfg.Jitter >= 1 {
+		return nil, errors.New("invalid jitter")
+	}
+	for i := range p.slots {
+		p.slots[i] = make(map[*Conn]bool)
+	}
+	go p.run()
+	return p, nil
+}
+
+// Add registers the connection c with the prober.
+// The prober configures c when visiting the slot of c, which happens
+// within the interval.
+func (p *KeepAliveProber) Add(c *Conn) {
+	slot := p.slots[c.ID()%proberSlots]
+	p.mu.Lock()
+	if _, ok := slot[c]; !ok {
+		slot[c] = false
+		p.n++
+	}
+	p.mu.Unlock()
+}
+
+// Remove unregisters the connection c from the prober.
+// It doesn't close c or restore the options of c.
+func (p *KeepAliveProber) Remove(c *Conn) {
+	slot := p.slots[c.ID()%proberSlots]
+	p.mu.Lock()
+	if _, ok := slot[c]; ok {
+		delete(slot, c)
+		p.n--
+	}
+	p.mu.Unlock()
+}
+
+// Len returns the number of registered connections.
+func (p *KeepAliveProber) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.n
+}
+
+// Err returns the last error in configuring the connections.
+func (p *KeepAliveProber) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Stop stops the prober.
+// It doesn't close the registered connections.
+func (p *KeepAliveProber) Stop() {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+func (p *KeepAliveProber) run() {
+	defer close(p.done)
+	t := time.NewTicker(p.cfg.Interval / proberSlots)
+	defer t.Stop()
+	for i := 0; ; i = (i + 1) % proberSlots {
+		select {
+		case <-t.C:
+			p.probe(p.slots[i])
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *KeepAliveProber) probe(slot map[*Conn]bool) {
+	p.mu.Lock()
+	conns := make([]*Conn, 0, len(slot))
+	var fresh []*Conn
+	for c, configured := range slot {
+		if configured {
+			conns = append(conns, c)
+		} else {
+			fresh = append(fresh, c)
+		}
+	}
+	p.mu.Unlock()
+	for _, c := range fresh {
+		err := p.configure(c)
+		p.mu.Lock()
+		if _, ok := slot[c]; ok {
+			slot[c] = true
+		}
+		if err != nil {
+			p.err = err
+		}
+		p.mu.Unlock()
+	}
+	for _, c := range conns {
+		info, err := connInfo(c)
+		if errors.Is(err, net.ErrClosed) {
+			p.Remove(c)
+			continue
+		}
+		if err != nil || p.cfg.Health == nil {
+			continue
+		}
+		if !p.cfg.Health(c, info) {
+			p.Remove(c)
+			c.Close()
+		}
+	}
+}
+
+// configure sets the keepalive parameters and the user timeout on
+// the connection c.
+func (p *KeepAliveProber) configure(c *Conn) error {
+	if p.cfg.KeepAlive.Enable {
+		ka := p.cfg.KeepAlive
+		if ka.IdleInterval > 0 && p.cfg.Jitter > 0 {
+			ka.IdleInterval = spread(ka.IdleInterval, p.cfg.Jitter, c.ID())
+		}
+		if err := c.SetKeepAlive(&ka); err != nil {
+			return err
+		}
+	}
+	if p.cfg.UserTimeout > 0 {
+		if err := c.SetOption(UserTimeout(p.cfg.UserTimeout)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spread returns the duration d spread by the fraction jitter,
+// using the connection ID id as the source of deterministic
+// randomness. The result is rounded to seconds, the unit of
+// keepalive parameters.
+func spread(d time.Duration, jitter float64, id uint64) time.Duration {
+	id *= 0x9e3779b97f4a7c15 // scatters sequential IDs
+	f := float64(id>>11) / (1 << 53)
+	d = time.Duration(float64(d) * (1 - jitter + 2*jitter*f))
+	if d < time.Second {
+		return time.Second
+	}
+	return d.Round(time.Second)
+}
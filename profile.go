@@ -0,0 +1,176 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A Profile represents a named set of socket options loaded from a
+// configuration file, which allows to change the tuning of
+// connections without recompiling programs.
+type Profile struct {
+	Name    string          `json:"name"`
+	Options []ProfileOption `json:"options"`
+
+	dial   []tcpopt.Option
+	accept []tcpopt.Option
+}
+
+// A ProfileOption represents a socket option in a profile.
+//
+// The option is specified by the name in the system headers, such as
+// "TCP_NODELAY", and is looked up in the registry of the platform;
+// see OptionSpecs. The value is a boolean or an integer for the
+// fixed-length options, and a string for the variable-length options
+// such as "TCP_CONGESTION". It is passed to the kernel as is, in the
+// unit of the option on the platform.
+type ProfileOption struct {
+	Name     string          `json:"name"`
+	Value    json.RawMessage `json:"value"`
+	ApplyOn  string          `json:"apply_on,omitempty"` // "dial", "accept" or "both"; default "both"
+	Optional bool            `json:"optional,omitempty"` // ignored when unknown on the platform
+}
+
+// LoadProfiles reads the profiles in JSON from r, such as
+//
+//	{"profiles": [
+//		{"name": "bulk", "options": [
+//			{"name": "SO_SNDBUF", "value": 4194304},
+//			{"name": "TCP_CONGESTION", "value": "bbr", "optional": true},
+//			{"name": "TCP_NODELAY", "value": true, "apply_on": "accept"}
+//		]}
+//	]}
+//
+// and returns the profiles by name.
+// Configuration files in YAML need to be converted to JSON before
+// loading.
+//
+// It returns a ConfigError when any option fails validation against
+// the registry of the platform.
+func LoadProfiles(r io.Reader) (map[string]*Profile, error) {
+	var v struct {
+		Profiles []*Profile `json:"profiles"`
+	}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	ps := make(map[string]*Profile, len(v.Profiles))
+	var errs []error
+	for _, p := range v.Profiles {
+		if _, ok := ps[p.Name]; ok {
+			errs = append(errs, fmt.Errorf("profile %q: duplicate profile", p.Name))
+			continue
+		}
+		errs = append(errs, p.compile()...)
+		ps[p.Name] = p
+	}
+	if len(errs) > 0 {
+		return nil, ConfigError(errs)
+	}
+	return ps, nil
+}
+
+// DialOptions returns the socket options to be set on dialing, such
+// as the ones passed to Dialer.Options or ControlFunc.
+func (p *Profile) DialOptions() []tcpopt.Option {
+	return append([]tcpopt.Option(nil), p.dial...)
+}
+
+// AcceptOptions returns the socket options to be set on accepted
+// connections.
+func (p *Profile) AcceptOptions() []tcpopt.Option {
+	return append([]tcpopt.Option(nil), p.accept...)
+}
+
+func (p *Profile) compile() []error {
+	var errs []error
+	for _, po := range p.Options {
+		o, err := po.option()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("profile %q: option %s: %v", p.Name, po.Name, err))
+			continue
+		}
+		if o == nil {
+			continue
+		}
+		switch po.ApplyOn {
+		case "dial":
+			p.dial = append(p.dial, o)
+		case "accept":
+			p.accept = append(p.accept, o)
+		case "", "both":
+			p.dial = append(p.dial, o)
+			p.accept = append(p.accept, o)
+		default:
+			errs = append(errs, fmt.Errorf("profile %q: option %s: unknown apply_on %q", p.Name, po.Name, po.ApplyOn))
+		}
+	}
+	return errs
+}
+
+// option returns the raw option for po, or nil when po is optional
+// and unknown on the platform.
+func (po *ProfileOption) option() (tcpopt.Option, error) {
+	spec, ok := LookupOptionSpec(po.Name)
+	if !ok {
+		if po.Optional {
+			return nil, nil
+		}
+		return nil, ErrNotSupported
+	}
+	d := json.NewDecoder(bytes.NewReader(po.Value))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	var b []byte
+	switch v := v.(type) {
+	case bool:
+		if spec.Size != 4 {
+			return nil, fmt.Errorf("boolean value for %d-byte option", spec.Size)
+		}
+		b = make([]byte, 4)
+		nativeEndian.PutUint32(b, uint32(boolint32(v)))
+	case json.Number:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		switch spec.Size {
+		case 4:
+			if n < math.MinInt32 || n > math.MaxUint32 {
+				return nil, fmt.Errorf("value %d out of range", n)
+			}
+			b = make([]byte, 4)
+			nativeEndian.PutUint32(b, uint32(n))
+		case 8:
+			b = make([]byte, 8)
+			nativeEndian.PutUint64(b, uint64(n))
+		default:
+			return nil, fmt.Errorf("integer value for %d-byte option", spec.Size)
+		}
+	case string:
+		if spec.Size != 0 {
+			return nil, fmt.Errorf("string value for %d-byte option", spec.Size)
+		}
+		if v == "" {
+			return nil, errors.New("empty value")
+		}
+		b = []byte(v)
+	default:
+		return nil, fmt.Errorf("unsupported value %s", po.Value)
+	}
+	return NewRawOption(spec.Level, spec.Number, b), nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// A Quality represents the health of a connection condensed from the
+// kernel statistics.
+type Quality struct {
+	LossRate     float64 // ratio of retransmitted segments to sent segments
+	RTTVariation float64 // ratio of RTT variation to smoothed RTT
+	RetransRatio float64 // ratio of retransmitted bytes to sent bytes
+	PacingRatio  float64 // ratio of delivery rate to pacing rate; 0 when unknown
+
+	// Score is a comparable score between 0 and 1. A higher score
+	// means a healthier connection.
+	Score float64
+}
+
+// Penalty weights and the values that are considered the worst for
+// the score.
+const (
+	qualityLossWeight    = 0.35
+	qualityLossWorst     = 0.05
+	qualityRetransWeight = 0.25
+	qualityRetransWorst  = 0.05
+	qualityRTTVarWeight  = 0.2
+	qualityRTTVarWorst   = 1.0
+	qualityPacingWeight  = 0.2
+)
+
+// Quality returns the quality of the connection.
+//
+// Only Linux supports this feature.
+func (c *Conn) Quality() (*Quality, error) {
+	var q *Quality
+	err := c.control(func(s uintptr) (err error) {
+		q, err = quality(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	q.Score = q.score()
+	return q, nil
+}
+
+func (q *Quality) score() float64 {
+	p := qualityLossWeight*penalty(q.LossRate, qualityLossWorst) +
+		qualityRetransWeight*penalty(q.RetransRatio, qualityRetransWorst) +
+		qualityRTTVarWeight*penalty(q.RTTVariation, qualityRTTVarWorst)
+	if q.PacingRatio > 0 {
+		p += qualityPacingWeight * penalty(1-q.PacingRatio, 1)
+	}
+	return 1 - p
+}
+
+// penalty returns v normalized by the worst value, clamped to [0, 1].
+func penalty(v, worst float64) float64 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= worst:
+		return 1
+	}
+	return v / worst
+}
+
+func ratio(n, d uint64) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,25 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+func quality(s uintptr) (*Quality, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	q := &Quality{
+		LossRate:     ratio(uint64(ti.Total_retrans), uint64(ti.Segs_out)),
+		RTTVariation: ratio(uint64(ti.Rttvar), uint64(ti.Rtt)),
+		RetransRatio: ratio(ti.Bytes_retrans, ti.Bytes_sent),
+	}
+	// The pacing rate is ~0 when pacing is not in use.
+	if ti.Pacing_rate > 0 && ti.Pacing_rate != ^uint64(0) && ti.Delivery_rate > 0 {
+		q.PacingRatio = ratio(ti.Delivery_rate, ti.Pacing_rate)
+		if q.PacingRatio > 1 {
+			q.PacingRatio = 1
+		}
+	}
+	return q, nil
+}
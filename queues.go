@@ -0,0 +1,35 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// A Queues represents the depths of the socket queues of the
+// connection.
+type Queues struct {
+	Buffered int // # of bytes in the receive queue not read yet
+	Queued   int // # of bytes in the send queue, both unsent and unacknowledged, or -1
+	Unsent   int // # of bytes in the send queue not sent yet, or -1
+	Unacked  int // # of bytes in the send queue sent but not acknowledged yet, or -1
+}
+
+// Queues returns the depths of the receive and send queues of the
+// connection, which is handy for backpressure decisions.
+// The values are read by consecutive system calls, and the queues
+// may change between the calls; Unacked, which is derived from Queued
+// and Unsent, is never negative.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and OpenBSD
+// support this feature. Only Linux reports Unsent and Unacked, and
+// DragonFly BSD and OpenBSD report Buffered only.
+func (c *Conn) Queues() (Queues, error) {
+	var q Queues
+	err := c.control(func(s uintptr) (err error) {
+		q, err = queues(s)
+		return
+	})
+	if err != nil {
+		return Queues{}, c.opError("get", err)
+	}
+	return q, nil
+}
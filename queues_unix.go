@@ -0,0 +1,49 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package tcp
+
+import "os"
+
+func queues(s uintptr) (Queues, error) {
+	q := Queues{Queued: -1, Unsent: -1, Unacked: -1}
+	var err error
+	if q.Buffered, err = queueLen(s, options[soBuffered]); err != nil {
+		return q, err
+	}
+	if options[soSendQueued].name < 1 {
+		return q, nil
+	}
+	if q.Queued, err = queueLen(s, options[soSendQueued]); err != nil {
+		return q, err
+	}
+	if options[soUnsent].name < 1 {
+		return q, nil
+	}
+	if q.Unsent, err = queueLen(s, options[soUnsent]); err != nil {
+		return q, err
+	}
+	if q.Unacked = q.Queued - q.Unsent; q.Unacked < 0 {
+		q.Unacked = 0
+	}
+	return q, nil
+}
+
+// queueLen returns the length of the queue read by the ioctl of o,
+// or by the socket option of o when o has a level.
+func queueLen(s uintptr, o option) (int, error) {
+	var b [4]byte
+	if o.level == 0 {
+		if err := ioctl(s, o.name, b[:]); err != nil {
+			return 0, os.NewSyscallError("ioctl", err)
+		}
+	} else {
+		if _, err := getsockopt(s, o.level, o.name, b[:]); err != nil {
+			return 0, os.NewSyscallError("getsockopt", err)
+		}
+	}
+	return int(nativeEndian.Uint32(b[:])), nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// An AcceptRateLimit represents a limit on the rate of accepting
+// connections from each client, where a client is a remote address
+// or a subnet of remote addresses.
+type AcceptRateLimit struct {
+	// Rate is the number of connections per second allowed for a
+	// client. It must be positive.
+	Rate float64
+
+	// Burst is the maximum number of connections allowed for a
+	// client at once. Zero means the larger of 1 and Rate.
+	Burst int
+
+	// Ban is the duration of rejecting all the connections from a
+	// client after the client exceeds the limit. Zero means
+	// rejecting only the connections exceeding the limit.
+	Ban time.Duration
+
+	// IPv4PrefixLen and IPv6PrefixLen are the lengths of prefix
+	// that group remote addresses into a client. Zero means a
+	// single address, which is the same as 32 and 128.
+	IPv4PrefixLen int
+	IPv6PrefixLen int
+}
+
+// SetAcceptRateLimit limits the rate of accepting connections with
+// l. A nil l removes the limit.
+// Accept closes the connections exceeding the limit before returning
+// them to the application.
+// It returns an error when Rate of l is not positive.
+func (ln *Listener) SetAcceptRateLimit(l *AcceptRateLimit) error {
+	var ar *acceptRate
+	if l != nil {
+		if !(l.Rate > 0) {
+			return ln.opError("set", errors.New("invalid rate"))
+		}
+		ar = &acceptRate{l: *l, clients: make(map[string]*acceptBucket), pruned: time.Now()}
+		if ar.l.Burst <= 0 {
+			ar.l.Burst = int(ar.l.Rate)
+			if ar.l.Burst < 1 {
+				ar.l.Burst = 1
+			}
+		}
+	}
+	ln.limitMu.Lock()
+	ln.rate = ar
+	ln.limitMu.Unlock()
+	return nil
+}
+
+// pruneInterval is the interval of removing the state of clients
+// that are no longer limited.
+const pruneInterval = time.Minute
+
+// An acceptRate represents the state of AcceptRateLimit.
+type acceptRate struct {
+	l AcceptRateLimit
+
+	mu      sync.Mutex
+	clients map[string]*acceptBucket
+	pruned  time.Time
+}
+
+// An acceptBucket is the token bucket of a client.
+type acceptBucket struct {
+	tokens float64
+	last   time.Time
+	banned time.Time // end of ban
+}
+
+// allow reports whether a connection from addr is allowed at now.
+func (ar *acceptRate) allow(addr net.Addr, now time.Time) bool {
+	key := ar.key(addr)
+	if key == "" {
+		return true
+	}
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	if now.Sub(ar.pruned) >= pruneInterval {
+		ar.prune(now)
+	}
+	b := ar.clients[key]
+	if b == nil {
+		b = &acceptBucket{tokens: float64(ar.l.Burst), last: now}
+		ar.clients[key] = b
+	}
+	if now.Before(b.banned) {
+		return false
+	}
+	b.refill(now, ar.l.Rate, ar.l.Burst)
+	if b.tokens < 1 {
+		b.banned = now.Add(ar.l.Ban)
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *acceptBucket) refill(now time.Time, rate float64, burst int) {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.last = now
+}
+
+// prune removes the clients that have a full bucket and no ban.
+func (ar *acceptRate) prune(now time.Time) {
+	for key, b := range ar.clients {
+		if now.Before(b.banned) {
+			continue
+		}
+		b.refill(now, ar.l.Rate, ar.l.Burst)
+		if b.tokens >= float64(ar.l.Burst) {
+			delete(ar.clients, key)
+		}
+	}
+	ar.pruned = now
+}
+
+// key returns the client key of addr, or the empty string when addr
+// has no IP address.
+func (ar *acceptRate) key(addr net.Addr) string {
+	ta, ok := addr.(*net.TCPAddr)
+	if !ok || ta.IP == nil {
+		return ""
+	}
+	ip, bits, plen := ta.IP.To4(), 8*net.IPv4len, ar.l.IPv4PrefixLen
+	if ip == nil {
+		ip, bits, plen = ta.IP.To16(), 8*net.IPv6len, ar.l.IPv6PrefixLen
+	}
+	if plen <= 0 || plen > bits {
+		plen = bits
+	}
+	return ip.Mask(net.CIDRMask(plen, bits)).String()
+}
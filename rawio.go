@@ -0,0 +1,158 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// ReadFull reads exactly len(b) bytes from the connection.
+// It uses MSG_WAITALL to reduce the number of system calls and waits
+// on the runtime poller between partial reads when the platform
+// supports it; otherwise it behaves the same as io.ReadFull.
+// It returns io.EOF only if no bytes were read, and
+// io.ErrUnexpectedEOF if the connection is closed by the peer in the
+// middle of reading.
+func (c *Conn) ReadFull(b []byte) (int, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.readFull(b)
+	}
+	start := time.Now()
+	n, err := c.readFull(b)
+	c.observe(fns, "read", int64(n), start, err)
+	return n, err
+}
+
+func (c *Conn) readFull(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	rc, err := c.rawConn()
+	if err != nil || !supportsRawIO {
+		return io.ReadFull(c.Conn, b)
+	}
+	n, err := readFull(rc, b)
+	switch {
+	case err == io.EOF && n > 0:
+		err = io.ErrUnexpectedEOF
+	case err != nil && err != io.EOF:
+		err = c.ioError("read", err)
+	}
+	return n, err
+}
+
+// TryRead reads data from the connection without blocking.
+// It returns ErrWouldBlock when no data is available.
+// It returns io.EOF when the connection is closed by the peer.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD, OpenBSD and
+// Solaris support this feature.
+func (c *Conn) TryRead(b []byte) (int, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.tryRead(b)
+	}
+	start := time.Now()
+	n, err := c.tryRead(b)
+	c.observe(fns, "read", int64(n), start, err)
+	return n, err
+}
+
+func (c *Conn) tryRead(b []byte) (int, error) {
+	rc, err := c.rawConn()
+	if err != nil {
+		return 0, c.ioError("read", err)
+	}
+	n, err := tryRead(rc, b)
+	if err != nil && err != io.EOF && err != ErrWouldBlock {
+		err = c.ioError("read", err)
+	}
+	return n, err
+}
+
+// TryWrite writes data to the connection without blocking.
+// It returns the number of bytes written and ErrWouldBlock when the
+// send buffer is full.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD, OpenBSD and
+// Solaris support this feature.
+func (c *Conn) TryWrite(b []byte) (int, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.tryWrite(b)
+	}
+	start := time.Now()
+	n, err := c.tryWrite(b)
+	c.observe(fns, "write", int64(n), start, err)
+	return n, err
+}
+
+func (c *Conn) tryWrite(b []byte) (int, error) {
+	rc, err := c.rawConn()
+	if err != nil {
+		return 0, c.ioError("write", err)
+	}
+	n, err := tryWrite(rc, b)
+	if err != nil && err != ErrWouldBlock {
+		err = c.ioError("write", err)
+	}
+	return n, err
+}
+
+// WriteMore writes data to the connection with a hint that more
+// data will follow. It lets the kernel coalesce small writes into
+// full-sized segments without toggling the TCP_CORK option around
+// each write. A subsequent Write flushes the pending data.
+//
+// Only Linux supports the hint; on other platforms WriteMore is the
+// same as Write.
+func (c *Conn) WriteMore(b []byte) (int, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.writeMore(b)
+	}
+	start := time.Now()
+	n, err := c.writeMore(b)
+	c.observe(fns, "write", int64(n), start, err)
+	return n, err
+}
+
+func (c *Conn) writeMore(b []byte) (int, error) {
+	rc, err := c.rawConn()
+	if err != nil || msgMore == 0 {
+		return c.Conn.Write(b)
+	}
+	n, err := write(rc, b, msgMore)
+	if err != nil {
+		err = c.ioError("write", err)
+	}
+	return n, err
+}
+
+// rawConn returns the raw connection for reading and writing the
+// socket directly.
+func (c *Conn) rawConn() (syscall.RawConn, error) {
+	if c.rc == nil {
+		return nil, errors.New("unknown connection type")
+	}
+	if c.wrapped {
+		return nil, errors.New("raw I/O on wrapped connection")
+	}
+	return c.rc, nil
+}
+
+// msgMore is the MSG_MORE flag or 0 when the platform doesn't
+// support it.
+var msgMore int
+
+// ioError returns the error for the I/O operation op.
+func (c *Conn) ioError(op string, err error) error {
+	return &net.OpError{Op: op, Net: c.LocalAddr().Network(), Source: c.LocalAddr(), Addr: c.RemoteAddr(), Err: err}
+}
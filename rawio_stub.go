@@ -0,0 +1,21 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package tcp
+
+import "syscall"
+
+const supportsRawIO = false
+
+func readFull(rc syscall.RawConn, b []byte) (int, error) { return 0, ErrNotSupported }
+
+func tryRead(rc syscall.RawConn, b []byte) (int, error) { return 0, ErrNotSupported }
+
+func waitReadable(rc syscall.RawConn) error { return ErrNotSupported }
+
+func tryWrite(rc syscall.RawConn, b []byte) (int, error) { return 0, ErrNotSupported }
+
+func write(rc syscall.RawConn, b []byte, flags int) (int, error) { return 0, ErrNotSupported }
@@ -0,0 +1,190 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+)
+
+func TestReadFull(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := []byte("HELLO-R-U-THERE")
+	go func() {
+		for i := range m {
+			c.Write(m[i : i+1])
+			time.Sleep(time.Millisecond)
+		}
+		c.Close()
+	}()
+	b := make([]byte, len(m))
+	if _, err := tc.ReadFull(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, m) {
+		t.Fatalf("got %q; want %q", b, m)
+	}
+	if _, err := tc.ReadFull(b); err != io.EOF {
+		t.Fatalf("got %v; want %v", err, io.EOF)
+	}
+}
+
+func TestTryReadWrite(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 64)
+	if _, err := tc.TryRead(b); err != tcp.ErrWouldBlock {
+		t.Fatalf("got %v; want %v", err, tcp.ErrWouldBlock)
+	}
+	m := []byte("HELLO-R-U-THERE")
+	if n, err := tc.TryWrite(m); err != nil || n != len(m) {
+		t.Fatalf("got %d, %v; want %d, nil", n, err, len(m))
+	}
+	if _, err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		n, err := tc.TryRead(b)
+		if err == tcp.ErrWouldBlock && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b[:n], m) {
+			t.Fatalf("got %q; want %q", b[:n], m)
+		}
+		break
+	}
+}
+
+func TestWriteMore(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := []byte("HELLO-R-U-THERE")
+	if _, err := tc.WriteMore(m[:5]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.Write(m[5:]); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, len(m))
+	if _, err := io.ReadFull(p, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, m) {
+		t.Fatalf("got %q; want %q", b, m)
+	}
+}
+
+func TestTimeToFirstByte(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, s := tcptest.Pair(t)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.Write([]byte("HELLO"))
+	}()
+	start := time.Now()
+	fb, err := c.TimeToFirstByte(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := fb.Duration(); d < 20*time.Millisecond || d > time.Second || fb.Start != start {
+		t.Fatalf("got %v from %+v; want about 20ms", d, fb)
+	}
+	b := make([]byte, 5)
+	if _, err := io.ReadFull(c, b); err != nil || string(b) != "HELLO" {
+		t.Fatalf("got %q, %v; want HELLO left readable", b, err)
+	}
+	if runtime.GOOS == "linux" && (fb.Received.Before(start) || fb.Received.After(fb.Readable)) {
+		t.Fatalf("got %v; want kernel receive time between %v and %v", fb.Received, start, fb.Readable)
+	}
+	t.Logf("ttfb=%v readable=%v received=%v", fb.Duration(), fb.Readable.Sub(fb.Start), fb.Received)
+
+	c.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := c.TimeToFirstByte(time.Time{}); err == nil {
+		t.Fatal("got nil; want timeout error")
+	}
+	c.SetReadDeadline(time.Time{})
+	s.Close()
+	if _, err := c.TimeToFirstByte(time.Time{}); err != io.EOF {
+		t.Fatalf("got %v; want %v", err, io.EOF)
+	}
+}
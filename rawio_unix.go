@@ -0,0 +1,156 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package tcp
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+const supportsRawIO = true
+
+func readFull(rc syscall.RawConn, b []byte) (int, error) {
+	var n int
+	var operr error
+	err := rc.Read(func(s uintptr) bool {
+		for n < len(b) {
+			// MSG_DONTWAIT keeps MSG_WAITALL from blocking the
+			// thread when the descriptor is in blocking mode; the
+			// read waits on the runtime poller instead and
+			// continues from the partial count.
+			nn, _, err := syscall.Recvfrom(int(s), b[n:], syscall.MSG_WAITALL|syscall.MSG_DONTWAIT)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err == syscall.EAGAIN {
+				return false
+			}
+			if err != nil {
+				operr = os.NewSyscallError("recvfrom", err)
+				return true
+			}
+			if nn == 0 {
+				operr = io.EOF
+				return true
+			}
+			n += nn
+		}
+		return true
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, operr
+}
+
+func tryRead(rc syscall.RawConn, b []byte) (int, error) {
+	var n int
+	var operr error
+	err := rc.Read(func(s uintptr) bool {
+		for {
+			n, _, operr = syscall.Recvfrom(int(s), b, syscall.MSG_DONTWAIT)
+			if operr != syscall.EINTR {
+				return true
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case operr == syscall.EAGAIN:
+		return 0, ErrWouldBlock
+	case operr != nil:
+		return 0, os.NewSyscallError("recvfrom", operr)
+	case n == 0 && len(b) > 0:
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func waitReadable(rc syscall.RawConn) error {
+	var b [1]byte
+	var n int
+	var operr error
+	err := rc.Read(func(s uintptr) bool {
+		for {
+			n, _, operr = syscall.Recvfrom(int(s), b[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+			switch operr {
+			case syscall.EINTR:
+				continue
+			case syscall.EAGAIN:
+				return false
+			}
+			return true
+		}
+	})
+	switch {
+	case err != nil:
+		return err
+	case operr != nil:
+		return os.NewSyscallError("recvfrom", operr)
+	case n == 0:
+		return io.EOF
+	}
+	return nil
+}
+
+func tryWrite(rc syscall.RawConn, b []byte) (int, error) {
+	var n int
+	var operr error
+	err := rc.Write(func(s uintptr) bool {
+		for n < len(b) {
+			nn, err := syscall.SendmsgN(int(s), b[n:], nil, nil, syscall.MSG_DONTWAIT)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				operr = err
+				break
+			}
+			n += nn
+		}
+		return true
+	})
+	if err != nil {
+		return n, err
+	}
+	switch {
+	case operr == syscall.EAGAIN:
+		return n, ErrWouldBlock
+	case operr != nil:
+		return n, os.NewSyscallError("sendmsg", operr)
+	}
+	return n, nil
+}
+
+func write(rc syscall.RawConn, b []byte, flags int) (int, error) {
+	var n int
+	var operr error
+	err := rc.Write(func(s uintptr) bool {
+		for n < len(b) {
+			nn, err := syscall.SendmsgN(int(s), b[n:], nil, nil, flags)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err == syscall.EAGAIN {
+				return false
+			}
+			if err != nil {
+				operr = os.NewSyscallError("sendmsg", err)
+				break
+			}
+			n += nn
+		}
+		return true
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, operr
+}
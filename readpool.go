@@ -0,0 +1,85 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+// A ReadPool represents a pool of read buffers in the size classes of
+// powers of two, shared by many connections.
+type ReadPool struct {
+	min, max int // sizes of the smallest and largest classes
+	shift    int // log2 of min
+	pools    []sync.Pool
+}
+
+// NewReadPool returns a new pool of read buffers from min to max
+// bytes, both of which are rounded up to powers of two.
+func NewReadPool(min, max int) (*ReadPool, error) {
+	if min <= 0 || max < min {
+		return nil, errors.New("invalid buffer sizes")
+	}
+	p := &ReadPool{shift: bits.Len(uint(min - 1))}
+	p.min = 1 << p.shift
+	n := bits.Len(uint(max-1)) - p.shift + 1
+	p.max = p.min << (n - 1)
+	p.pools = make([]sync.Pool, n)
+	return p, nil
+}
+
+// class returns the index of the smallest class of n or more bytes.
+func (p *ReadPool) class(n int) int {
+	if n <= p.min {
+		return 0
+	}
+	if n >= p.max {
+		return len(p.pools) - 1
+	}
+	return bits.Len(uint(n-1)) - p.shift
+}
+
+// Get returns a buffer of the smallest class that holds n bytes, or
+// of the largest class when n exceeds it.
+func (p *ReadPool) Get(n int) []byte {
+	i := p.class(n)
+	if bp, ok := p.pools[i].Get().(*[]byte); ok {
+		return *bp
+	}
+	return make([]byte, p.min<<i)
+}
+
+// Put returns the buffer b taken from Get to the pool.
+// The buffers of the other capacities than the classes are dropped.
+func (p *ReadPool) Put(b []byte) {
+	i := p.class(cap(b))
+	if cap(b) != p.min<<i {
+		return
+	}
+	b = b[:cap(b)]
+	p.pools[i].Put(&b)
+}
+
+// ReadPooled reads data from the connection into a buffer taken from
+// the pool p, and returns the buffer holding the data read. The
+// caller returns the buffer to p using Put when done with it.
+//
+// The buffer is sized by the number of bytes in the socket read
+// buffer, which avoids both the over-allocation of buffers on many
+// idle connections and the short reads of large bursts. When nothing
+// is buffered, or when the platform doesn't support Buffered, the
+// buffer is of the smallest class and the read blocks until data
+// arrives.
+func (c *Conn) ReadPooled(p *ReadPool) ([]byte, error) {
+	b := p.Get(c.Buffered())
+	n, err := c.Read(b)
+	if n == 0 {
+		p.Put(b)
+		return nil, err
+	}
+	return b[:n], err
+}
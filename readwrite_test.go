@@ -7,10 +7,13 @@
 package tcp_test
 
 import (
+	"context"
+	"errors"
 	"net"
 	"runtime"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/mikioh/tcp"
 	"github.com/mikioh/tcpinfo"
@@ -100,3 +103,55 @@ func TestConcurrentReadWriteAndInfo(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestReadWriteContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	peer, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	var b [1]byte
+	if _, err := tc.ReadContext(ctx, b[:]); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v; want %v", err, context.Canceled)
+	}
+	if _, err := peer.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tc.ReadContext(context.Background(), b[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	wb := make([]byte, 1<<16)
+	for {
+		_, err := tc.WriteContext(ctx, wb)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v; want %v", err, context.DeadlineExceeded)
+		}
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			t.Fatalf("got %v; want timeout", err)
+		}
+		break
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// A ReapFunc is called by a Reaper for each idle connection with the
+// time elapsed since the last data received on the connection.
+// It reports whether the reaper should close the connection.
+type ReapFunc func(c *Conn, idle time.Duration) bool
+
+// A Reaper tracks connections and reaps the idle ones, using the
+// time of the last data received on each connection as tracked by
+// the kernel. It offloads the idle timeout of long-lived connections
+// from applications.
+//
+// Only FreeBSD and Linux support this feature.
+type Reaper struct {
+	timeout  time.Duration
+	interval time.Duration
+	fn       ReapFunc
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+
+	mu    sync.Mutex
+	conns map[*Conn]bool // value reports whether notified while idle
+}
+
+// NewReaper returns a new reaper that checks the connections every
+// interval and considers a connection idle when no data has been
+// received for timeout.
+// When fn is nil, the reaper closes idle connections. Otherwise fn
+// decides the policy; it is called once per idle period and a
+// connection receiving data again becomes a candidate again.
+func NewReaper(timeout, interval time.Duration, fn ReapFunc) (*Reaper, error) {
+	if timeout <= 0 || interval <= 0 {
+		return nil, errors.New("invalid timeout or interval")
+	}
+	if fn == nil {
+		fn = func(*Conn, time.Duration) bool { return true }
+	}
+	r := &Reaper{
+		timeout:  timeout,
+		interval: interval,
+		fn:       fn,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		conns:    make(map[*Conn]bool),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Add registers the connection c with the reaper.
+func (r *Reaper) Add(c *Conn) {
+	r.mu.Lock()
+	if _, ok := r.conns[c]; !ok {
+		r.conns[c] = false
+	}
+	r.mu.Unlock()
+}
+
+// Remove unregisters the connection c from the reaper.
+// It doesn't close c.
+func (r *Reaper) Remove(c *Conn) {
+	r.mu.Lock()
+	delete(r.conns, c)
+	r.mu.Unlock()
+}
+
+// Len returns the number of registered connections.
+func (r *Reaper) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.conns)
+}
+
+// Stop stops reaping.
+// It doesn't close the registered connections.
+func (r *Reaper) Stop() {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+func (r *Reaper) run() {
+	defer close(r.done)
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.reap()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *Reaper) reap() {
+	r.mu.Lock()
+	conns := make([]*Conn, 0, len(r.conns))
+	for c := range r.conns {
+		conns = append(conns, c)
+	}
+	r.mu.Unlock()
+	for _, c := range conns {
+		info, err := connInfo(c)
+		if err != nil {
+			r.Remove(c) // closed or not supported
+			continue
+		}
+		idle := info.LastDataReceived
+		r.mu.Lock()
+		notified, ok := r.conns[c]
+		if ok && idle < r.timeout {
+			r.conns[c] = false
+		}
+		r.mu.Unlock()
+		if !ok || notified || idle < r.timeout {
+			continue
+		}
+		if r.fn(c, idle) {
+			r.Remove(c)
+			c.Close()
+			continue
+		}
+		r.mu.Lock()
+		if _, ok := r.conns[c]; ok {
+			r.conns[c] = true
+		}
+		r.mu.Unlock()
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestReaper(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type pair struct {
+		c  net.Conn
+		tc *tcp.Conn
+	}
+	var ps [2]pair
+	for i := range ps {
+		c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		p, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		tc, err := tcp.NewConn(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps[i] = pair{c: c, tc: tc}
+	}
+
+	reaped := make(chan *tcp.Conn, 2)
+	r, err := tcp.NewReaper(200*time.Millisecond, 20*time.Millisecond, func(c *tcp.Conn, idle time.Duration) bool {
+		reaped <- c
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	for _, p := range ps {
+		r.Add(p.tc)
+	}
+
+	// Keeps the second connection active.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(50 * time.Millisecond):
+				ps[1].c.Write([]byte("PING"))
+			}
+		}
+	}()
+	select {
+	case c := <-reaped:
+		if c != ps[0].tc {
+			t.Fatal("reaped the active connection")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("idle connection not reaped")
+	}
+	var b [1]byte
+	ps[0].c.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := ps[0].c.Read(b[:]); err == nil || isTimeout(err) {
+		t.Fatalf("got %v; want connection closed", err)
+	}
+	if n := r.Len(); n != 1 {
+		t.Fatalf("got %d; want 1", n)
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+var (
+	_ net.Conn     = &ReconnectingConn{}
+	_ OptionSetter = &ReconnectingConn{}
+)
+
+// A ReconnectEvent represents an attempt of reconnection.
+type ReconnectEvent struct {
+	Time    time.Time
+	Attempt int   // # of attempts for the failure, starting at 1
+	Cause   error // failure of the previous connection
+	Err     error // error of the attempt; nil when reconnected
+	Conn    *Conn // new connection; nil when the attempt failed
+}
+
+// A ReconnectConfig represents the configuration of
+// ReconnectingConn.
+// Zero values are replaced with the defaults noted in comments.
+type ReconnectConfig struct {
+	MinBackoff  time.Duration // delay before the second attempt; default 100ms
+	MaxBackoff  time.Duration // maximum delay between attempts; default 30s
+	MaxAttempts int           // # of attempts for each failure; zero means unlimited
+
+	// Jitter is the fraction of each delay randomized as described
+	// in ReDialer.
+	Jitter float64
+
+	// OnEvent is called for each attempt of reconnection, which
+	// allows the application to log the events and to re-synchronize
+	// its protocol state on the new connection.
+	// The reads and writes wait for the completion of the
+	// reconnection, and must not be called from OnEvent.
+	OnEvent func(*ReconnectEvent)
+}
+
+// A ReconnectingConn represents a long-lived client connection that
+// re-dials the address transparently when the connection fails, and
+// re-applies the socket options and the deadlines set through it on
+// the new connections.
+//
+// A read or write failed by an error other than the deadline triggers
+// the reconnection, and continues on the new connection; the data in
+// flight on the failed connection is lost, and the application
+// re-synchronizes, if required, on the reconnection events.
+// A write failed after writing a part of the data returns the number
+// of bytes written and the error instead of continuing, and the
+// following read or write triggers the reconnection.
+type ReconnectingConn struct {
+	d       Dialer
+	network string
+	address string
+	cfg     ReconnectConfig
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu        sync.Mutex
+	c         *Conn
+	gen       uint64          // generation of c
+	redialing chan struct{}   // closed when the reconnection in progress completes
+	opts      []tcpopt.Option // options applied to every connection
+	rd, wd    time.Time       // deadlines
+	err       error           // permanent error
+}
+
+// DialReconnecting connects to the address on the named network using
+// the dialer d, and returns a new connection that re-dials the
+// address on failures.
+// The first connection is dialed without retries.
+func DialReconnecting(ctx context.Context, d *Dialer, network, address string, cfg *ReconnectConfig) (*ReconnectingConn, error) {
+	rc := &ReconnectingConn{d: *d, network: network, address: address}
+	if cfg != nil {
+		rc.cfg = *cfg
+	}
+	c, err := rc.d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	rc.c = c
+	rc.ctx, rc.cancel = context.WithCancel(context.Background())
+	return rc, nil
+}
+
+// Conn returns the current connection.
+func (rc *ReconnectingConn) Conn() *Conn {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.c
+}
+
+// Read reads data from the connection.
+func (rc *ReconnectingConn) Read(b []byte) (int, error) {
+	for {
+		c, gen, err := rc.current()
+		if err != nil {
+			return 0, err
+		}
+		n, err := c.Read(b)
+		if n > 0 || err == nil || isDeadlineExceeded(err) {
+			return n, err
+		}
+		if err := rc.reconnect(gen, err); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write writes data to the connection.
+func (rc *ReconnectingConn) Write(b []byte) (int, error) {
+	var n int
+	for {
+		c, gen, err := rc.current()
+		if err != nil {
+			return n, err
+		}
+		m, err := c.Write(b[n:])
+		n += m
+		if n > 0 || err == nil || isDeadlineExceeded(err) {
+			return n, err
+		}
+		if err := rc.reconnect(gen, err); err != nil {
+			return n, err
+		}
+	}
+}
+
+// Close closes the connection and stops the reconnection in
+// progress.
+func (rc *ReconnectingConn) Close() error {
+	rc.cancel()
+	rc.mu.Lock()
+	if rc.err != nil {
+		rc.mu.Unlock()
+		return nil
+	}
+	rc.err = net.ErrClosed
+	c := rc.c
+	rc.mu.Unlock()
+	return c.Close()
+}
+
+// LocalAddr returns the local network address of the current
+// connection.
+func (rc *ReconnectingConn) LocalAddr() net.Addr { return rc.Conn().LocalAddr() }
+
+// RemoteAddr returns the remote network address of the current
+// connection.
+func (rc *ReconnectingConn) RemoteAddr() net.Addr { return rc.Conn().RemoteAddr() }
+
+// SetDeadline sets the read and write deadlines of the connection.
+func (rc *ReconnectingConn) SetDeadline(t time.Time) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.rd, rc.wd = t, t
+	if rc.redialing != nil {
+		return nil // set on the new connection
+	}
+	return rc.c.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the connection.
+func (rc *ReconnectingConn) SetReadDeadline(t time.Time) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.rd = t
+	if rc.redialing != nil {
+		return nil // set on the new connection
+	}
+	return rc.c.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the connection.
+func (rc *ReconnectingConn) SetWriteDeadline(t time.Time) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.wd = t
+	if rc.redialing != nil {
+		return nil // set on the new connection
+	}
+	return rc.c.SetWriteDeadline(t)
+}
+
+// SetOption sets the socket option o on the current connection, and
+// records it to set on the new connections. It replaces the option
+// of the same level and name already recorded.
+func (rc *ReconnectingConn) SetOption(o tcpopt.Option) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.redialing == nil {
+		if err := rc.c.SetOption(o); err != nil {
+			return err
+		}
+	}
+	for i, oo := range rc.opts {
+		if oo.Level() == o.Level() && oo.Name() == o.Name() {
+			rc.opts[i] = o
+			return nil
+		}
+	}
+	rc.opts = append(rc.opts, o)
+	return nil
+}
+
+// Option returns a socket option of the current connection.
+func (rc *ReconnectingConn) Option(level, name int, b []byte) (tcpopt.Option, error) {
+	return rc.Conn().Option(level, name, b)
+}
+
+func (rc *ReconnectingConn) current() (*Conn, uint64, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.c, rc.gen, rc.err
+}
+
+// reconnect replaces the connection of the generation gen failed by
+// cause with a new connection. It does nothing when the connection
+// is already replaced, and waits for the completion when the
+// reconnection is in progress.
+func (rc *ReconnectingConn) reconnect(gen uint64, cause error) error {
+	rc.mu.Lock()
+	if rc.err != nil {
+		rc.mu.Unlock()
+		return rc.err
+	}
+	if rc.gen != gen {
+		rc.mu.Unlock()
+		return nil
+	}
+	if ch := rc.redialing; ch != nil {
+		rc.mu.Unlock()
+		<-ch
+		return nil
+	}
+	rc.redialing = make(chan struct{})
+	old := rc.c
+	rc.mu.Unlock()
+	old.Close()
+
+	b := newBackoff(rc.cfg.MinBackoff, rc.cfg.MaxBackoff, rc.cfg.Jitter)
+	for attempt := 1; ; attempt++ {
+		c, err := rc.d.DialContext(rc.ctx, rc.network, rc.address)
+		if err == nil {
+			err = rc.publish(c)
+		}
+		if err == net.ErrClosed {
+			return err
+		}
+		if err == nil {
+			rc.event(&ReconnectEvent{Time: time.Now(), Attempt: attempt, Cause: cause, Conn: c})
+			return nil
+		}
+		rc.event(&ReconnectEvent{Time: time.Now(), Attempt: attempt, Cause: cause, Err: err})
+		if rc.ctx.Err() != nil {
+			return rc.fail(net.ErrClosed)
+		}
+		if rc.cfg.MaxAttempts > 0 && attempt >= rc.cfg.MaxAttempts {
+			return rc.fail(err)
+		}
+		t := time.NewTimer(b.delay())
+		select {
+		case <-t.C:
+		case <-rc.ctx.Done():
+			t.Stop()
+		}
+	}
+}
+
+// publish applies the recorded options and deadlines to the new
+// connection c, and replaces the current connection with c.
+// It closes c and returns net.ErrClosed when the connection is
+// closed during the reconnection.
+func (rc *ReconnectingConn) publish(c *Conn) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.err != nil {
+		c.Close()
+		rc.complete()
+		return rc.err
+	}
+	for _, o := range rc.opts {
+		if err := c.SetOption(o); err != nil {
+			c.Close()
+			return err
+		}
+	}
+	if err := c.SetReadDeadline(rc.rd); err != nil {
+		c.Close()
+		return err
+	}
+	if err := c.SetWriteDeadline(rc.wd); err != nil {
+		c.Close()
+		return err
+	}
+	rc.c = c
+	rc.gen++
+	rc.complete()
+	return nil
+}
+
+// fail records the permanent error err and completes the
+// reconnection.
+func (rc *ReconnectingConn) fail(err error) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.err == nil {
+		rc.err = err
+	}
+	rc.complete()
+	return rc.err
+}
+
+// complete wakes up the waiters for the reconnection.
+// It must be called with rc.mu held.
+func (rc *ReconnectingConn) complete() {
+	close(rc.redialing)
+	rc.redialing = nil
+}
+
+func (rc *ReconnectingConn) event(ev *ReconnectEvent) {
+	if rc.cfg.OnEvent != nil {
+		rc.cfg.OnEvent(ev)
+	}
+}
+
+// isDeadlineExceeded reports whether err is caused by the deadline,
+// which doesn't fail the connection. Unlike isTimeoutError, it
+// excludes syscall.ETIMEDOUT on the connection aborted by the
+// kernel.
+func isDeadlineExceeded(err error) bool { return errors.Is(err, os.ErrDeadlineExceeded) }
@@ -0,0 +1,119 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// An ErrorOrigin represents the origin of an extended error.
+type ErrorOrigin int
+
+const (
+	ErrorOriginNone   ErrorOrigin = iota // unknown origin
+	ErrorOriginLocal                     // local network stack
+	ErrorOriginICMP                      // ICMP message
+	ErrorOriginICMPv6                    // ICMPv6 message
+)
+
+// An ExtendedError represents an error received from the socket
+// error queue.
+type ExtendedError struct {
+	Err      error       // underlying error, usually syscall.Errno
+	Origin   ErrorOrigin // origin of error
+	Type     int         // ICMP type
+	Code     int         // ICMP code
+	Info     uint32      // additional information such as next-hop MTU
+	Offender net.IP      // address of node that caused the error
+}
+
+func (e *ExtendedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	s := "extended error"
+	if e.Err != nil {
+		s = e.Err.Error()
+	}
+	switch e.Origin {
+	case ErrorOriginICMP, ErrorOriginICMPv6:
+		s += fmt.Sprintf(": icmp type=%d code=%d", e.Type, e.Code)
+	}
+	if e.Offender != nil {
+		s += " from " + e.Offender.String()
+	}
+	return s
+}
+
+// Unwrap returns the underlying error.
+func (e *ExtendedError) Unwrap() error { return e.Err }
+
+// Timeout reports whether the underlying error represents a timeout.
+func (e *ExtendedError) Timeout() bool { return isTimeoutError(e.Err) }
+
+// Temporary reports whether the underlying error represents a
+// temporary failure.
+func (e *ExtendedError) Temporary() bool { return isTemporaryError(e.Err) }
+
+// SetRecvErr enables or disables the reception of extended errors,
+// such as the ICMP destination unreachable errors, using IP_RECVERR
+// or IPV6_RECVERR option depending on the address family of the
+// connection.
+//
+// When enabled, the kernel reports ICMP errors on an established
+// connection as hard errors of the next operation instead of
+// retransmitting silently.
+//
+// Only Linux supports this feature.
+func (c *Conn) SetRecvErr(on bool) error {
+	if err := c.control(func(s uintptr) error { return setRecvErr(s, c.family(), on) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// ReadErrors drains the socket error queue and returns the queued
+// errors. It doesn't block and returns no error when the queue is
+// empty.
+//
+// For TCP, the queue mainly carries local errors; the kernel reports
+// ICMP errors directly as the errors of socket operations.
+//
+// Only Linux supports this feature.
+func (c *Conn) ReadErrors() ([]*ExtendedError, error) {
+	if c.rc == nil {
+		return nil, c.ioError("read", errors.New("unknown connection type"))
+	}
+	errs, err := readErrors(c.rc)
+	if err != nil {
+		return nil, c.ioError("read", err)
+	}
+	return errs, nil
+}
+
+// ICMPError returns the ICMP error that failed the connection
+// attempt on the socket when FailConnectOnICMPError option is
+// enabled. It returns nil when no ICMP error is received.
+//
+// Note that the net package closes the socket of a failed connection
+// attempt; a Dialer only reports the resulting error, such as
+// WSAEHOSTUNREACH, and ICMPError is useful for the sockets of which
+// connection attempts are made by other means.
+//
+// Only Windows supports this feature.
+// See TCP_ICMP_ERROR_INFO for further information.
+func (c *Conn) ICMPError() (*ExtendedError, error) {
+	var e *ExtendedError
+	err := c.control(func(s uintptr) (err error) {
+		e, err = icmpError(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return e, nil
+}
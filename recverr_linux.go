@@ -0,0 +1,97 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func setRecvErr(s uintptr, level int, on bool) error {
+	name := sysIP_RECVERR
+	if level == ianaProtocolIPv6 {
+		name = sysIPV6_RECVERR
+	}
+	v := boolint32(on)
+	if err := setsockopt(s, level, name, (*[4]byte)(unsafe.Pointer(&v))[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func readErrors(rc syscall.RawConn) ([]*ExtendedError, error) {
+	var errs []*ExtendedError
+	var operr error
+	oob := make([]byte, syscall.CmsgSpace(sizeofSockExtendedErr+sizeofSockaddrInet6))
+	if err := rc.Control(func(s uintptr) {
+		var b [1]byte
+		for {
+			_, oobn, _, _, err := syscall.Recvmsg(int(s), b[:], oob, syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err == syscall.EAGAIN {
+				return
+			}
+			if err != nil {
+				operr = os.NewSyscallError("recvmsg", err)
+				return
+			}
+			cms, err := syscall.ParseSocketControlMessage(oob[:oobn])
+			if err != nil {
+				operr = os.NewSyscallError("recvmsg", err)
+				return
+			}
+			for _, cm := range cms {
+				if e := parseExtendedError(&cm); e != nil {
+					errs = append(errs, e)
+				}
+			}
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return errs, operr
+}
+
+func parseExtendedError(cm *syscall.SocketControlMessage) *ExtendedError {
+	switch {
+	case cm.Header.Level == ianaProtocolIP && cm.Header.Type == sysIP_RECVERR:
+	case cm.Header.Level == ianaProtocolIPv6 && cm.Header.Type == sysIPV6_RECVERR:
+	default:
+		return nil
+	}
+	if len(cm.Data) < sizeofSockExtendedErr {
+		return nil
+	}
+	ee := (*sockExtendedErr)(unsafe.Pointer(&cm.Data[0]))
+	e := &ExtendedError{
+		Err:  syscall.Errno(ee.Errno),
+		Type: int(ee.Type),
+		Code: int(ee.Code),
+		Info: ee.Info,
+	}
+	switch ee.Origin {
+	case sysSO_EE_ORIGIN_LOCAL:
+		e.Origin = ErrorOriginLocal
+	case sysSO_EE_ORIGIN_ICMP:
+		e.Origin = ErrorOriginICMP
+	case sysSO_EE_ORIGIN_ICMP6:
+		e.Origin = ErrorOriginICMPv6
+	}
+	// The offender address follows the error; see SO_EE_OFFENDER.
+	b := cm.Data[sizeofSockExtendedErr:]
+	if len(b) >= sizeofSockaddrInet && (*sockaddr)(unsafe.Pointer(&b[0])).Family == syscall.AF_INET {
+		sa := (*sockaddrInet)(unsafe.Pointer(&b[0]))
+		e.Offender = append(net.IP(nil), sa.Addr[:]...)
+	}
+	if len(b) >= sizeofSockaddrInet6 && (*sockaddr)(unsafe.Pointer(&b[0])).Family == syscall.AF_INET6 {
+		sa := (*sockaddrInet6)(unsafe.Pointer(&b[0]))
+		e.Offender = append(net.IP(nil), sa.Addr[:]...)
+	}
+	return e
+}
@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import "syscall"
+
+func setRecvErr(s uintptr, level int, on bool) error { return ErrNotSupported }
+
+func readErrors(rc syscall.RawConn) ([]*ExtendedError, error) { return nil, ErrNotSupported }
@@ -0,0 +1,37 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+// A ReceiveWindowStats represents the state of the receive window and
+// its autotuning. A transfer limited by the receive window shows
+// Window staying well below the bandwidth-delay product of the path
+// while Buffer has not grown enough to cover it.
+type ReceiveWindowStats struct {
+	Window      int           // receive window currently advertised to peer
+	SSThreshold int           // current upper bound of the advertised window, rcv_ssthresh
+	Space       int           // # of bytes the application reads per RTT estimated by autotuning, rcv_space
+	Buffer      int           // size of the receive buffer, which autotuning grows
+	MSS         int           // segment size estimated for peer
+	RTT         time.Duration // round-trip time estimated by the receiver
+}
+
+// ReceiveWindowStats returns the state of the receive window and its
+// autotuning.
+//
+// Only Linux supports this feature. Window requires Linux 6.2 or
+// above.
+func (c *Conn) ReceiveWindowStats() (*ReceiveWindowStats, error) {
+	var st *ReceiveWindowStats
+	err := c.control(func(s uintptr) (err error) {
+		st, err = receiveWindowStats(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return st, nil
+}
@@ -0,0 +1,30 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func receiveWindowStats(s uintptr) (*ReceiveWindowStats, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	n, err := syscall.GetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	return &ReceiveWindowStats{
+		Window:      int(ti.Rcv_wnd),
+		SSThreshold: int(ti.Rcv_ssthresh),
+		Space:       int(ti.Rcv_space),
+		Buffer:      n,
+		MSS:         int(ti.Rcv_mss),
+		RTT:         time.Duration(ti.Rcv_rtt) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,9 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func receiveWindowStats(s uintptr) (*ReceiveWindowStats, error) { return nil, ErrNotSupported }
@@ -0,0 +1,112 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A ReDialer represents a dialer that retries dialing with the
+// jittered exponential backoff.
+// Zero values are replaced with the defaults noted in comments.
+type ReDialer struct {
+	Dialer Dialer // dialer of each attempt
+
+	MinBackoff  time.Duration // delay before the second attempt; default 100ms
+	MaxBackoff  time.Duration // maximum delay between attempts; default 30s
+	MaxAttempts int           // zero means unlimited
+
+	// Jitter is the fraction of each delay randomized to avoid the
+	// synchronized retries of many clients. The delay d is chosen
+	// uniformly from [d*(1-Jitter), d]. A zero value means 0.2, and
+	// a negative value disables the randomization.
+	Jitter float64
+
+	// Options, when not nil, returns the socket options of the
+	// attempt, which replace the options of Dialer, such as for
+	// disabling TCP Fast Open after a failure. The attempt starts
+	// at 1, and err is the error of the previous attempt.
+	Options func(attempt int, err error) []tcpopt.Option
+
+	// OnRetry, when not nil, is called with the error of the failed
+	// attempt before waiting for the delay.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Dial connects to the address on the named network.
+func (rd *ReDialer) Dial(network, address string) (*Conn, error) {
+	return rd.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using the
+// provided context.
+// It returns the error of the last attempt when all the attempts
+// fail, or when ctx is done while waiting for the delay.
+func (rd *ReDialer) DialContext(ctx context.Context, network, address string) (*Conn, error) {
+	b := newBackoff(rd.MinBackoff, rd.MaxBackoff, rd.Jitter)
+	var err error
+	for attempt := 1; ; attempt++ {
+		d := rd.Dialer
+		if rd.Options != nil {
+			d.Options = rd.Options(attempt, err)
+		}
+		var c *Conn
+		if c, err = d.DialContext(ctx, network, address); err == nil {
+			return c, nil
+		}
+		if ctx.Err() != nil || rd.MaxAttempts > 0 && attempt >= rd.MaxAttempts {
+			return nil, err
+		}
+		delay := b.delay()
+		if rd.OnRetry != nil {
+			rd.OnRetry(attempt, err, delay)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		}
+	}
+}
+
+// A backoff represents the jittered exponential backoff between
+// attempts.
+type backoff struct {
+	next, max time.Duration
+	jitter    float64
+}
+
+// newBackoff returns a new backoff with the parameters described in
+// ReDialer. Zero values are replaced with the defaults.
+func newBackoff(min, max time.Duration, jitter float64) *backoff {
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+	if jitter == 0 {
+		jitter = 0.2
+	}
+	return &backoff{next: min, max: max, jitter: jitter}
+}
+
+// delay returns the delay before the next attempt.
+func (b *backoff) delay() time.Duration {
+	d := b.next
+	if b.jitter > 0 {
+		d -= time.Duration(rand.Float64() * b.jitter * float64(d))
+	}
+	if b.next *= 2; b.next > b.max {
+		b.next = b.max
+	}
+	return d
+}
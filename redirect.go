@@ -0,0 +1,26 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// RedirectRecords returns the WFP redirect records of the connection
+// redirected by a WFP callout to a local proxy.
+// The proxy sets the records on its outbound connection to the
+// original destination by Dialer.RedirectRecords, which lets the
+// callout tell the outbound connection from the ones to redirect.
+//
+// Only Windows supports this feature.
+// See SIO_QUERY_WFP_CONNECTION_REDIRECT_RECORDS for further
+// information.
+func (c *Conn) RedirectRecords() ([]byte, error) {
+	var b []byte
+	err := c.control(func(s uintptr) (err error) {
+		b, err = redirectRecords(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return b, nil
+}
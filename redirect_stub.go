@@ -0,0 +1,11 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package tcp
+
+func redirectRecords(s uintptr) ([]byte, error) { return nil, ErrNotSupported }
+
+func setRedirectRecords(s uintptr, b []byte) error { return ErrNotSupported }
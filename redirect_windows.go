@@ -0,0 +1,34 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+)
+
+const sysWSAEFAULT = syscall.Errno(10014)
+
+func redirectRecords(s uintptr) ([]byte, error) {
+	for l := 1 << 10; ; l *= 2 {
+		b := make([]byte, l)
+		var n uint32
+		err := syscall.WSAIoctl(syscall.Handle(s), sysSIO_QUERY_WFP_CONNECTION_REDIRECT_RECORDS, nil, 0, &b[0], uint32(len(b)), &n, nil, 0)
+		if err == nil {
+			return b[:n], nil
+		}
+		if err != sysWSAEFAULT || l >= maxOptionLen {
+			return nil, os.NewSyscallError("wsaioctl", err)
+		}
+	}
+}
+
+func setRedirectRecords(s uintptr, b []byte) error {
+	var n uint32
+	if err := syscall.WSAIoctl(syscall.Handle(s), sysSIO_SET_WFP_CONNECTION_REDIRECT_RECORDS, &b[0], uint32(len(b)), nil, 0, &n, nil, 0); err != nil {
+		return os.NewSyscallError("wsaioctl", err)
+	}
+	return nil
+}
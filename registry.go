@@ -0,0 +1,62 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"sort"
+	"sync"
+)
+
+// An OptionSpec represents the specification of a socket option on
+// the platform, which helps to use the raw option API such as
+// Conn.RawOption and NewRawOption without looking up the system
+// headers.
+type OptionSpec struct {
+	Name   string // name in the system headers, such as "TCP_NODELAY"
+	Level  int    // option level
+	Number int    // option name
+	Size   int    // expected length of option value in bytes; zero means variable length
+}
+
+var registry struct {
+	once  sync.Once
+	specs []OptionSpec
+	names map[string]int // index of specs by name
+}
+
+// initRegistry builds the registry from optionSpecs of the platform,
+// omitting the options that the platform doesn't support.
+func initRegistry() {
+	for _, spec := range optionSpecs {
+		if spec.Number < 1 {
+			continue
+		}
+		registry.specs = append(registry.specs, spec)
+	}
+	sort.Slice(registry.specs, func(i, j int) bool { return registry.specs[i].Name < registry.specs[j].Name })
+	registry.names = make(map[string]int, len(registry.specs))
+	for i, spec := range registry.specs {
+		registry.names[spec.Name] = i
+	}
+}
+
+// OptionSpecs returns the specifications of the socket options known
+// to the package on the platform, sorted by name.
+func OptionSpecs() []OptionSpec {
+	registry.once.Do(initRegistry)
+	return append([]OptionSpec(nil), registry.specs...)
+}
+
+// LookupOptionSpec returns the specification of the socket option
+// named name, such as "TCP_NODELAY", on the platform.
+// It reports false when the option is unknown on the platform.
+func LookupOptionSpec(name string) (OptionSpec, bool) {
+	registry.once.Do(initRegistry)
+	i, ok := registry.names[name]
+	if !ok {
+		return OptionSpec{}, false
+	}
+	return registry.specs[i], true
+}
@@ -0,0 +1,36 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+var optionSpecs = []OptionSpec{
+	{"SO_SNDBUF", sysSOL_SOCKET, tcpopt.SendBuffer(0).Name(), 4},
+	{"SO_RCVBUF", sysSOL_SOCKET, tcpopt.ReceiveBuffer(0).Name(), 4},
+	{"SO_KEEPALIVE", sysSOL_SOCKET, tcpopt.KeepAlive(false).Name(), 4},
+	{"SO_REUSEADDR", sysSOL_SOCKET, sysSO_REUSEADDR, 4},
+	{"SO_REUSEPORT", sysSOL_SOCKET, sysSO_REUSEPORT, 4},
+	{"SO_SNDLOWAT", sysSOL_SOCKET, sysSO_SNDLOWAT, 4},
+	{"SO_RCVLOWAT", sysSOL_SOCKET, sysSO_RCVLOWAT, 4},
+	{"SO_OOBINLINE", sysSOL_SOCKET, sysSO_OOBINLINE, 4},
+	{"TCP_NODELAY", ianaProtocolTCP, tcpopt.NoDelay(false).Name(), 4},
+	{"TCP_MAXSEG", ianaProtocolTCP, tcpopt.MSS(0).Name(), 4},
+	{"TCP_NOPUSH", ianaProtocolTCP, tcpopt.Cork(false).Name(), 4},
+	{"SO_NREAD", sysSOL_SOCKET, sysSO_NREAD, 4},
+	{"SO_NWRITE", sysSOL_SOCKET, sysSO_NWRITE, 4},
+	{"SO_NUMRCVPKT", sysSOL_SOCKET, sysSO_NUMRCVPKT, 4},
+	{"TCP_NOTSENT_LOWAT", ianaProtocolTCP, tcpopt.NotSentLowWMK(0).Name(), 4},
+	{"TCP_KEEPALIVE", ianaProtocolTCP, tcpopt.KeepAliveIdleInterval(0).Name(), 4},
+	{"TCP_KEEPINTVL", ianaProtocolTCP, tcpopt.KeepAliveProbeInterval(0).Name(), 4},
+	{"TCP_KEEPCNT", ianaProtocolTCP, tcpopt.KeepAliveProbeCount(0).Name(), 4},
+	{"TCP_CONNECTION_INFO", ianaProtocolTCP, (&tcpinfo.Info{}).Name(), 0},
+	{"TCP_NOOPT", ianaProtocolTCP, sysTCP_NOOPT, 4},
+	{"TCP_CONNECTIONTIMEOUT", ianaProtocolTCP, sysTCP_CONNECTIONTIMEOUT, 4},
+	{"TCP_RXT_CONNDROPTIME", ianaProtocolTCP, sysTCP_RXT_CONNDROPTIME, 4},
+	{"TCP_ENABLE_ECN", ianaProtocolTCP, sysTCP_ENABLE_ECN, 4},
+}
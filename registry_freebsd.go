@@ -0,0 +1,37 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+var optionSpecs = []OptionSpec{
+	{"SO_SNDBUF", sysSOL_SOCKET, tcpopt.SendBuffer(0).Name(), 4},
+	{"SO_RCVBUF", sysSOL_SOCKET, tcpopt.ReceiveBuffer(0).Name(), 4},
+	{"SO_KEEPALIVE", sysSOL_SOCKET, tcpopt.KeepAlive(false).Name(), 4},
+	{"SO_REUSEADDR", sysSOL_SOCKET, sysSO_REUSEADDR, 4},
+	{"SO_REUSEPORT", sysSOL_SOCKET, sysSO_REUSEPORT, 4},
+	{"SO_REUSEPORT_LB", sysSOL_SOCKET, sysSO_REUSEPORT_LB, 4},
+	{"SO_SNDLOWAT", sysSOL_SOCKET, sysSO_SNDLOWAT, 4},
+	{"SO_RCVLOWAT", sysSOL_SOCKET, sysSO_RCVLOWAT, 4},
+	{"SO_OOBINLINE", sysSOL_SOCKET, sysSO_OOBINLINE, 4},
+	{"TCP_NODELAY", ianaProtocolTCP, tcpopt.NoDelay(false).Name(), 4},
+	{"TCP_MAXSEG", ianaProtocolTCP, tcpopt.MSS(0).Name(), 4},
+	{"TCP_NOPUSH", ianaProtocolTCP, tcpopt.Cork(false).Name(), 4},
+	{"TCP_KEEPIDLE", ianaProtocolTCP, tcpopt.KeepAliveIdleInterval(0).Name(), 4},
+	{"TCP_KEEPINTVL", ianaProtocolTCP, tcpopt.KeepAliveProbeInterval(0).Name(), 4},
+	{"TCP_KEEPCNT", ianaProtocolTCP, tcpopt.KeepAliveProbeCount(0).Name(), 4},
+	{"SO_TYPE", sysSOL_SOCKET, sysSO_TYPE, 4},
+	{"SO_PROTOCOL", sysSOL_SOCKET, sysSO_PROTOCOL, 4},
+	{"SO_DOMAIN", sysSOL_SOCKET, sysSO_DOMAIN, 4},
+	{"SO_USER_COOKIE", sysSOL_SOCKET, sysSO_USER_COOKIE, 4},
+	{"TCP_INFO", ianaProtocolTCP, (&tcpinfo.Info{}).Name(), 0},
+	{"TCP_CONGESTION", ianaProtocolTCP, tcpinfo.CCAlgorithm("").Name(), 0},
+	{"TCP_KEEPINIT", ianaProtocolTCP, sysTCP_KEEPINIT, 4},
+	{"TCP_FUNCTION_BLK", ianaProtocolTCP, sysTCP_FUNCTION_BLK, sizeofTCPFunctionSet},
+	{"TCP_LOG", ianaProtocolTCP, sysTCP_LOG, 4},
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+var optionSpecs = []OptionSpec{
+	{"SO_SNDBUF", sysSOL_SOCKET, tcpopt.SendBuffer(0).Name(), 4},
+	{"SO_RCVBUF", sysSOL_SOCKET, tcpopt.ReceiveBuffer(0).Name(), 4},
+	{"SO_KEEPALIVE", sysSOL_SOCKET, tcpopt.KeepAlive(false).Name(), 4},
+	{"SO_REUSEADDR", sysSOL_SOCKET, sysSO_REUSEADDR, 4},
+	{"SO_REUSEPORT", sysSOL_SOCKET, sysSO_REUSEPORT, 4},
+	{"SO_RCVLOWAT", sysSOL_SOCKET, sysSO_RCVLOWAT, 4},
+	{"SO_OOBINLINE", sysSOL_SOCKET, sysSO_OOBINLINE, 4},
+	{"SO_SNDBUFFORCE", sysSOL_SOCKET, sysSO_SNDBUFFORCE, 4},
+	{"SO_RCVBUFFORCE", sysSOL_SOCKET, sysSO_RCVBUFFORCE, 4},
+	{"SO_PRIORITY", sysSOL_SOCKET, sysSO_PRIORITY, 4},
+	{"SO_DOMAIN", sysSOL_SOCKET, sysSO_DOMAIN, 4},
+	{"SO_TYPE", sysSOL_SOCKET, sysSO_TYPE, 4},
+	{"SO_PROTOCOL", sysSOL_SOCKET, sysSO_PROTOCOL, 4},
+	{"SO_COOKIE", sysSOL_SOCKET, sysSO_COOKIE, 8},
+	{"SO_MEMINFO", sysSOL_SOCKET, sysSO_MEMINFO, 4 * sysSK_MEMINFO_VARS},
+	{"SO_BINDTODEVICE", sysSOL_SOCKET, sysSO_BINDTODEVICE, 0},
+	{"SO_MAX_PACING_RATE", sysSOL_SOCKET, sysSO_MAX_PACING_RATE, 8},
+	{"SO_ZEROCOPY", sysSOL_SOCKET, sysSO_ZEROCOPY, 4},
+	{"SO_ORIGINAL_DST", ianaProtocolIP, sysSO_ORIGINAL_DST, sizeofSockaddrInet},
+	{"IP_MTU_DISCOVER", ianaProtocolIP, sysIP_MTU_DISCOVER, 4},
+	{"IP_MTU", ianaProtocolIP, sysIP_MTU, 4},
+	{"IP_RECVERR", ianaProtocolIP, sysIP_RECVERR, 4},
+	{"IPV6_MTU_DISCOVER", ianaProtocolIPv6, sysIPV6_MTU_DISCOVER, 4},
+	{"IPV6_MTU", ianaProtocolIPv6, sysIPV6_MTU, 4},
+	{"IPV6_RECVERR", ianaProtocolIPv6, sysIPV6_RECVERR, 4},
+	{"IPV6_FLOWINFO_SEND", ianaProtocolIPv6, sysIPV6_FLOWINFO_SEND, 4},
+	{"IPV6_FLOWLABEL_MGR", ianaProtocolIPv6, sysIPV6_FLOWLABEL_MGR, sizeofIn6FlowlabelReq},
+	{"TCP_NODELAY", ianaProtocolTCP, tcpopt.NoDelay(false).Name(), 4},
+	{"TCP_MAXSEG", ianaProtocolTCP, tcpopt.MSS(0).Name(), 4},
+	{"TCP_CORK", ianaProtocolTCP, tcpopt.Cork(false).Name(), 4},
+	{"TCP_NOTSENT_LOWAT", ianaProtocolTCP, tcpopt.NotSentLowWMK(0).Name(), 4},
+	{"TCP_KEEPIDLE", ianaProtocolTCP, tcpopt.KeepAliveIdleInterval(0).Name(), 4},
+	{"TCP_KEEPINTVL", ianaProtocolTCP, tcpopt.KeepAliveProbeInterval(0).Name(), 4},
+	{"TCP_KEEPCNT", ianaProtocolTCP, tcpopt.KeepAliveProbeCount(0).Name(), 4},
+	{"TCP_INFO", ianaProtocolTCP, sysTCP_INFO, sizeofTCPInfo},
+	{"TCP_CONGESTION", ianaProtocolTCP, tcpinfo.CCAlgorithm("").Name(), 0},
+	{"TCP_CC_INFO", ianaProtocolTCP, (&tcpinfo.CCInfo{}).Name(), 0},
+	{"TCP_MD5SIG", ianaProtocolTCP, sysTCP_MD5SIG, sizeofTCPMD5Sig},
+	{"TCP_MD5SIG_EXT", ianaProtocolTCP, sysTCP_MD5SIG_EXT, sizeofTCPMD5Sig},
+	{"TCP_WINDOW_CLAMP", ianaProtocolTCP, sysTCP_WINDOW_CLAMP, 4},
+	{"TCP_LINGER2", ianaProtocolTCP, sysTCP_LINGER2, 4},
+	{"TCP_USER_TIMEOUT", ianaProtocolTCP, sysTCP_USER_TIMEOUT, 4},
+	{"TCP_FASTOPEN_CONNECT", ianaProtocolTCP, sysTCP_FASTOPEN_CONNECT, 4},
+	{"TCP_FASTOPEN_NO_COOKIE", ianaProtocolTCP, sysTCP_FASTOPEN_NO_COOKIE, 4},
+	{"TCP_RTO_MIN_US", ianaProtocolTCP, sysTCP_RTO_MIN_US, 4},
+	{"TCP_ULP", ianaProtocolTCP, sysTCP_ULP, 0},
+}
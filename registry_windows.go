@@ -0,0 +1,22 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"syscall"
+
+	"github.com/mikioh/tcpopt"
+)
+
+var optionSpecs = []OptionSpec{
+	{"SO_SNDBUF", syscall.SOL_SOCKET, tcpopt.SendBuffer(0).Name(), 4},
+	{"SO_RCVBUF", syscall.SOL_SOCKET, tcpopt.ReceiveBuffer(0).Name(), 4},
+	{"SO_KEEPALIVE", syscall.SOL_SOCKET, tcpopt.KeepAlive(false).Name(), 4},
+	{"TCP_NODELAY", ianaProtocolTCP, tcpopt.NoDelay(false).Name(), 4},
+	{"TCP_MAXRT", ianaProtocolTCP, sysTCP_MAXRT, 4},
+	{"TCP_TIMESTAMPS", ianaProtocolTCP, sysTCP_TIMESTAMPS, 4},
+	{"TCP_FAIL_CONNECT_ON_ICMP_ERROR", ianaProtocolTCP, sysTCP_FAIL_CONNECT_ON_ICMP_ERROR, 4},
+	{"TCP_ICMP_ERROR_INFO", ianaProtocolTCP, sysTCP_ICMP_ERROR_INFO, sizeofICMPErrorInfo},
+}
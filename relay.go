@@ -0,0 +1,32 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "io"
+
+// Relay copies data from src to dst until either EOF is reached on
+// src or an error occurs. It returns the number of bytes copied.
+//
+// On Linux, Relay moves data through a pipe by using the splice
+// system call and never copies data into user space. On other
+// platforms, Relay is the same as io.Copy.
+func Relay(dst, src *Conn) (int64, error) {
+	dc, err := dst.rawConn()
+	if err != nil {
+		return io.Copy(dst.Conn, src.Conn)
+	}
+	sc, err := src.rawConn()
+	if err != nil {
+		return io.Copy(dst.Conn, src.Conn)
+	}
+	n, err := relay(dc, sc)
+	if err == ErrNotSupported {
+		return io.Copy(dst.Conn, src.Conn)
+	}
+	if err != nil {
+		err = dst.ioError("splice", err)
+	}
+	return n, err
+}
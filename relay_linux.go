@@ -0,0 +1,74 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxSpliceSize is the largest chunk size passed to a single splice
+// system call.
+const maxSpliceSize = 1 << 20
+
+func relay(dst, src syscall.RawConn) (int64, error) {
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		return 0, os.NewSyscallError("pipe2", err)
+	}
+	defer syscall.Close(p[0])
+	defer syscall.Close(p[1])
+	var written int64
+	for {
+		n, err := splice(src.Read, func(s uintptr) (int, error) {
+			return spliceN(int(s), p[1], maxSpliceSize)
+		})
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, nil
+		}
+		for n > 0 {
+			nn, err := splice(dst.Write, func(s uintptr) (int, error) {
+				return spliceN(p[0], int(s), n)
+			})
+			written += int64(nn)
+			if err != nil {
+				return written, err
+			}
+			n -= nn
+		}
+	}
+}
+
+// splice calls fn within the poller-aware function wait, which is
+// either Read or Write of syscall.RawConn. It waits for the socket
+// to be ready while fn returns EAGAIN.
+func splice(wait func(func(uintptr) bool) error, fn func(uintptr) (int, error)) (int, error) {
+	var n int
+	var operr error
+	err := wait(func(s uintptr) bool {
+		for {
+			n, operr = fn(s)
+			if operr == syscall.EINTR {
+				continue
+			}
+			return operr != syscall.EAGAIN
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if operr != nil {
+		return 0, os.NewSyscallError("splice", operr)
+	}
+	return n, nil
+}
+
+func spliceN(rfd, wfd, l int) (int, error) {
+	n, err := syscall.Splice(rfd, nil, wfd, nil, l, sysSPLICE_F_MOVE|sysSPLICE_F_NONBLOCK)
+	return int(n), err
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestRelay(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var cs, ps [2]net.Conn
+	for i := range cs {
+		if cs[i], err = net.Dial(ln.Addr().Network(), ln.Addr().String()); err != nil {
+			t.Fatal(err)
+		}
+		defer cs[i].Close()
+		if ps[i], err = ln.Accept(); err != nil {
+			t.Fatal(err)
+		}
+		defer ps[i].Close()
+	}
+	src, err := tcp.NewConn(ps[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := tcp.NewConn(ps[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := bytes.Repeat([]byte("HELLO-R-U-THERE"), 1<<16)
+	go func() {
+		cs[0].Write(m)
+		cs[0].Close()
+	}()
+	done := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(cs[1])
+		done <- b
+	}()
+	n, err := tcp.Relay(dst, src)
+	if err != nil || n != int64(len(m)) {
+		t.Fatalf("got %d, %v; want %d, nil", n, err, len(m))
+	}
+	ps[1].Close()
+	if b := <-done; !bytes.Equal(b, m) {
+		t.Fatalf("got %d bytes; want %d bytes", len(b), len(m))
+	}
+}
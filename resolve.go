@@ -0,0 +1,131 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// An IPResolver represents a resolver of host names used by Dialer.
+// The net.Resolver type implements it.
+type IPResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// An AddrPolicy represents a policy of Dialer selecting the addresses
+// to try among the resolved addresses addrs on the named network.
+// It returns the addresses in the order of attempts, and may reorder
+// or drop the addresses.
+type AddrPolicy func(network string, addrs []net.IPAddr) []net.IPAddr
+
+// resolves reports whether the dialer d resolves host names by
+// itself.
+func (d *Dialer) resolves() bool {
+	return d.Lookup != nil || d.SelectAddrs != nil || d.AddrOptions != nil || d.SourceAddrs != nil
+}
+
+// dialAddrs resolves the host name in the address and tries the
+// selected addresses in order. It returns the first error when no
+// attempt succeeds.
+func (d *Dialer) dialAddrs(ctx context.Context, network, address string) (*Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	addrs, err := d.lookup(ctx, network, host)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	addrs = filterAddrs(network, addrs)
+	if d.SelectAddrs != nil {
+		addrs = d.SelectAddrs(network, addrs)
+	}
+	if len(addrs) == 0 {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: &net.AddrError{Err: "no suitable address found", Addr: host}}
+	}
+	dd := *d
+	dd.Lookup, dd.SelectAddrs, dd.AddrOptions, dd.SourceAddrs = nil, nil, nil, nil
+	var first error
+	for _, ip := range addrs {
+		if err := ctx.Err(); err != nil {
+			if first == nil {
+				first = &net.OpError{Op: "dial", Net: network, Err: err}
+			}
+			break
+		}
+		dd.Options = d.addrOptions(ip)
+		dd.LocalAddr = d.sourceAddr(network, ip)
+		c, err := dd.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return c, nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return nil, first
+}
+
+// addrOptions returns the socket options for the address ip.
+func (d *Dialer) addrOptions(ip net.IPAddr) []tcpopt.Option {
+	if d.AddrOptions == nil {
+		return d.Options
+	}
+	opts := d.AddrOptions(ip)
+	if len(opts) == 0 {
+		return d.Options
+	}
+	return append(d.Options[:len(d.Options):len(d.Options)], opts...)
+}
+
+// lookup returns the addresses of the host. IP address literals and
+// the empty host, which means the local system, are not resolved.
+func (d *Dialer) lookup(ctx context.Context, network, host string) ([]net.IPAddr, error) {
+	if host == "" {
+		if network == "tcp6" {
+			return []net.IPAddr{{IP: net.IPv6unspecified}}, nil
+		}
+		return []net.IPAddr{{IP: net.IPv4zero}}, nil
+	}
+	literal, zone := host, ""
+	if i := strings.LastIndexByte(host, '%'); i > 0 {
+		literal, zone = host[:i], host[i+1:]
+	}
+	if ip := net.ParseIP(literal); ip != nil {
+		return []net.IPAddr{{IP: ip, Zone: zone}}, nil
+	}
+	var r IPResolver = net.DefaultResolver
+	switch {
+	case d.Lookup != nil:
+		r = d.Lookup
+	case d.Resolver != nil:
+		r = d.Resolver
+	}
+	return r.LookupIPAddr(ctx, host)
+}
+
+// filterAddrs returns the addresses in addrs that belong to the
+// address family of the named network.
+func filterAddrs(network string, addrs []net.IPAddr) []net.IPAddr {
+	var filtered []net.IPAddr
+	for _, a := range addrs {
+		switch network {
+		case "tcp4":
+			if a.IP.To4() == nil {
+				continue
+			}
+		case "tcp6":
+			if a.IP.To4() != nil {
+				continue
+			}
+		}
+		filtered = append(filtered, a)
+	}
+	return filtered
+}
@@ -0,0 +1,24 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+// RTO returns the current retransmission timeout of the connection,
+// including the backoff of the retransmission timer.
+// Use MinRTO option to lower the bound of retransmission timeout.
+//
+// Only Linux supports this feature.
+func (c *Conn) RTO() (time.Duration, error) {
+	var d time.Duration
+	err := c.control(func(s uintptr) (err error) {
+		d, err = rto(s)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,15 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+func rto(s uintptr) (time.Duration, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ti.Rto) * time.Microsecond, nil
+}
@@ -0,0 +1,35 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// A SACKStats represents selective acknowledgment and reordering
+// statistics of the connection. Comparing the reordering metrics
+// with the loss metrics helps distinguishing path reordering from
+// packet loss.
+type SACKStats struct {
+	SackedSegs   int    // # of segments currently selectively acknowledged by peer
+	LostSegs     int    // # of segments currently considered lost
+	TotalRetrans uint64 // # of retransmitted segments
+	DSACKDups    uint64 // # of received duplicate segments reported by DSACK
+	Reordering   int    // estimated reordering distance in # of segments
+	ReorderSeen  uint64 // # of reordering events detected
+}
+
+// SACKStats returns the selective acknowledgment and reordering
+// statistics.
+//
+// Only Linux supports this feature. DSACKDups and ReorderSeen
+// require Linux 5.0 or above.
+func (c *Conn) SACKStats() (*SACKStats, error) {
+	var st *SACKStats
+	err := c.control(func(s uintptr) (err error) {
+		st, err = sackStats(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return st, nil
+}
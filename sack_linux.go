@@ -0,0 +1,57 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"unsafe"
+)
+
+func sackStats(s uintptr) (*SACKStats, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	return &SACKStats{
+		SackedSegs:   int(ti.Sacked),
+		LostSegs:     int(ti.Lost),
+		TotalRetrans: uint64(ti.Total_retrans),
+		DSACKDups:    uint64(ti.Dsack_dups),
+		Reordering:   int(ti.Reordering),
+		ReorderSeen:  uint64(ti.Reord_seen),
+	}, nil
+}
+
+// getTCPInfo returns the full TCP_INFO of the socket s. The fields
+// unknown to the running kernel are left zero.
+func getTCPInfo(s uintptr) (*tcpInfo, error) {
+	var ti tcpInfo
+	if err := readTCPInfo(s, &ti); err != nil {
+		return nil, err
+	}
+	return &ti, nil
+}
+
+// readTCPInfo reads the full TCP_INFO of the socket s into ti without
+// allocation.
+func readTCPInfo(s uintptr, ti *tcpInfo) error {
+	*ti = tcpInfo{}
+	b := (*[sizeofTCPInfo]byte)(unsafe.Pointer(ti))[:]
+	if _, err := getsockopt(s, ianaProtocolTCP, sysTCP_INFO, b); err != nil {
+		return os.NewSyscallError("getsockopt", err)
+	}
+	return nil
+}
+
+// readTCPInfo is like the package-level readTCPInfo but reads the
+// TCP_INFO of the connection c through c.getsockopt.
+func (c *Conn) readTCPInfo(ti *tcpInfo) error {
+	*ti = tcpInfo{}
+	b := (*[sizeofTCPInfo]byte)(unsafe.Pointer(ti))[:]
+	if _, err := c.getsockopt(ianaProtocolTCP, sysTCP_INFO, b); err != nil {
+		return os.NewSyscallError("getsockopt", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A Sample represents a snapshot of connection information.
+type Sample struct {
+	Time time.Time
+	Info *tcpinfo.Info
+}
+
+// A SampleDelta represents the differences of counters between two
+// samples.
+//
+// Only Linux supports the counters.
+type SampleDelta struct {
+	Interval      time.Duration // elapsed time between the samples
+	BytesAcked    uint64        // # of bytes acknowledged by peer
+	BytesReceived uint64        // # of bytes received
+	SegsOut       uint64        // # of segments sent
+	SegsIn        uint64        // # of segments received
+	Retransmits   uint64        // # of retransmitted segments
+}
+
+// SendRate returns the rate of acknowledged bytes in bytes per
+// second.
+func (d *SampleDelta) SendRate() float64 { return d.rate(d.BytesAcked) }
+
+// ReceiveRate returns the rate of received bytes in bytes per second.
+func (d *SampleDelta) ReceiveRate() float64 { return d.rate(d.BytesReceived) }
+
+func (d *SampleDelta) rate(n uint64) float64 {
+	if d.Interval <= 0 {
+		return 0
+	}
+	return float64(n) / d.Interval.Seconds()
+}
+
+// A Sampler records snapshots of connection information at a regular
+// interval into a fixed-size ring buffer.
+type Sampler struct {
+	c        *Conn
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+
+	mu   sync.Mutex
+	ring []Sample
+	next int // index of next sample in ring
+	n    int // # of samples in ring
+	err  error
+
+	sink   Sink
+	labels Labels
+}
+
+// NewSampler returns a new sampler that records the information of
+// connection c every interval into a ring buffer of size samples.
+// It takes the first sample immediately.
+func NewSampler(c *Conn, interval time.Duration, size int) (*Sampler, error) {
+	if interval <= 0 || size <= 0 {
+		return nil, errors.New("invalid interval or size")
+	}
+	s := &Sampler{
+		c:        c,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		ring:     make([]Sample, size),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Stop stops sampling.
+func (s *Sampler) Stop() {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// SetSink sets the sink k to which the sampler records every sample
+// with the labels and the delta from the previous sample.
+// The labels take precedence over the labels of the connection set
+// by Conn.SetLabels.
+// A nil k stops recording.
+func (s *Sampler) SetSink(k Sink, labels Labels) {
+	s.mu.Lock()
+	s.sink, s.labels = k, labels.clone()
+	s.mu.Unlock()
+}
+
+// Err returns the last error in sampling.
+func (s *Sampler) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Samples returns the recorded samples, oldest first.
+func (s *Sampler) Samples() []Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss := make([]Sample, s.n)
+	for i := range ss {
+		ss[i] = s.ring[(s.next-s.n+i+len(s.ring))%len(s.ring)]
+	}
+	return ss
+}
+
+// Delta returns the differences between the latest sample and the
+// oldest sample within the window preceding the latest sample.
+// It returns false when there are less than two samples within the
+// window.
+func (s *Sampler) Delta(window time.Duration) (*SampleDelta, bool) {
+	ss := s.Samples()
+	if len(ss) < 2 {
+		return nil, false
+	}
+	last := ss[len(ss)-1]
+	first := -1
+	for i := range ss[:len(ss)-1] {
+		if last.Time.Sub(ss[i].Time) <= window {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return nil, false
+	}
+	return delta(&ss[first], &last), true
+}
+
+// delta returns the differences of counters between the samples x
+// and y.
+func delta(x, y *Sample) *SampleDelta {
+	a, b := counters(x.Info), counters(y.Info)
+	return &SampleDelta{
+		Interval:      y.Time.Sub(x.Time),
+		BytesAcked:    b.BytesAcked - a.BytesAcked,
+		BytesReceived: b.BytesReceived - a.BytesReceived,
+		SegsOut:       b.SegsOut - a.SegsOut,
+		SegsIn:        b.SegsIn - a.SegsIn,
+		Retransmits:   b.Retransmits - a.Retransmits,
+	}
+}
+
+func (s *Sampler) run() {
+	defer close(s.done)
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		s.sample()
+		select {
+		case <-t.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Sampler) sample() {
+	var o tcpinfo.Info
+	var b [256]byte
+	io, err := s.c.Option(o.Level(), o.Name(), b[:])
+	s.mu.Lock()
+	if err != nil {
+		s.err = err
+		s.mu.Unlock()
+		return
+	}
+	st := Stats{Sample: Sample{Time: time.Now(), Info: io.(*tcpinfo.Info)}}
+	if s.n > 0 {
+		st.Delta = delta(&s.ring[(s.next-1+len(s.ring))%len(s.ring)], &st.Sample)
+	}
+	s.ring[s.next] = st.Sample
+	s.next = (s.next + 1) % len(s.ring)
+	if s.n < len(s.ring) {
+		s.n++
+	}
+	k, labels := s.sink, s.labels
+	s.mu.Unlock()
+	if k != nil {
+		k.Record(connID(s.c), s.c.labelsWith(labels), &st)
+	}
+}
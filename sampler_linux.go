@@ -0,0 +1,31 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "github.com/mikioh/tcpinfo"
+
+// counters returns the cumulative counters of i in the form of
+// SampleDelta.
+func counters(i *tcpinfo.Info) SampleDelta {
+	if i == nil || i.Sys == nil {
+		return SampleDelta{}
+	}
+	return SampleDelta{
+		BytesAcked:    i.Sys.ThruBytesAcked,
+		BytesReceived: i.Sys.ThruBytesReceived,
+		SegsOut:       uint64(i.Sys.SegsOut),
+		SegsIn:        uint64(i.Sys.SegsIn),
+		Retransmits:   uint64(i.Sys.TotalRetransSegs),
+	}
+}
+
+// consecutiveRTOs returns the number of consecutive retransmission
+// timeouts in i.
+func consecutiveRTOs(i *tcpinfo.Info) int {
+	if i == nil || i.Sys == nil {
+		return 0
+	}
+	return int(i.Sys.Retransmissions)
+}
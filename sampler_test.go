@@ -0,0 +1,168 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+	"github.com/mikioh/tcpinfo"
+)
+
+func TestSampler(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	go io.Copy(ioutil.Discard, p)
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := tcp.NewSampler(tc, 10*time.Millisecond, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1<<16)
+	for i := 0; i < 10; i++ {
+		if _, err := c.Write(b); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Stop()
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	ss := s.Samples()
+	if len(ss) != 4 {
+		t.Fatalf("got %d samples; want 4", len(ss))
+	}
+	for i := 1; i < len(ss); i++ {
+		if !ss[i].Time.After(ss[i-1].Time) {
+			t.Fatalf("samples out of order: %v", ss)
+		}
+	}
+	d, ok := s.Delta(time.Hour)
+	if !ok {
+		t.Fatal("no delta")
+	}
+	if d.Interval != ss[3].Time.Sub(ss[0].Time) || d.BytesAcked == 0 || d.SendRate() <= 0 {
+		t.Fatalf("unexpected delta: %+v", d)
+	}
+}
+
+func TestSamplerSink(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	c, s := tcptest.Pair(t)
+	type record struct {
+		id     string
+		labels tcp.Labels
+		delta  *tcp.SampleDelta
+	}
+	ch := make(chan record, 16)
+	k := tcp.SinkFunc(func(id string, labels tcp.Labels, st *tcp.Stats) {
+		if st.Info == nil {
+			t.Error("no information")
+		}
+		select {
+		case ch <- record{id, labels, st.Delta}:
+		default:
+		}
+	})
+
+	sp, err := tcp.NewSampler(c, 10*time.Millisecond, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.SetSink(k, tcp.Labels{"purpose": "test"})
+	r := <-ch
+	for r.delta == nil {
+		r = <-ch
+	}
+	sp.Stop()
+	if want := c.LocalAddr().String() + "->" + c.RemoteAddr().String(); r.id != want || r.labels["purpose"] != "test" {
+		t.Fatalf("got %s, %v; want %s, purpose=test", r.id, r.labels, want)
+	}
+	if r.delta.Interval <= 0 {
+		t.Fatalf("unexpected delta: %+v", r.delta)
+	}
+
+	reg := tcp.NewConnRegistry()
+	reg.Register(s, tcp.Labels{"peer": "client"})
+	for len(ch) > 0 {
+		<-ch
+	}
+	reg.Record(k)
+	if r := <-ch; r.labels["peer"] != "client" || r.delta != nil {
+		t.Fatalf("got %v, %+v; want peer=client without delta", r.labels, r.delta)
+	}
+}
+
+type packetWriter struct{ packets []string }
+
+func (w *packetWriter) Write(b []byte) (int, error) {
+	w.packets = append(w.packets, string(b))
+	return len(b), nil
+}
+
+func TestStatsDSink(t *testing.T) {
+	st := &tcp.Stats{
+		Sample: tcp.Sample{Time: time.Now(), Info: &tcpinfo.Info{RTT: 1500 * time.Microsecond}},
+		Delta:  &tcp.SampleDelta{Interval: time.Second, BytesAcked: 1000, Retransmits: 2},
+	}
+	labels := tcp.Labels{"tenant": "blue", "peer": "db.local"}
+
+	var w packetWriter
+	k := tcp.NewStatsDSink(&w, &tcp.StatsDConfig{GroupBy: []string{"tenant", "peer", "purpose"}})
+	k.Record("192.0.2.1:49152->192.0.2.2:80", labels, st)
+	if len(w.packets) != 1 {
+		t.Fatalf("got %d packets; want 1", len(w.packets))
+	}
+	lines := strings.Split(w.packets[0], "\n")
+	if lines[0] != "tcp.blue.db_local.none.rtt:1.5|ms" || lines[1] != "tcp.blue.db_local.none.bytes_acked:1000|c" || lines[5] != "tcp.blue.db_local.none.retransmits:2|c" {
+		t.Fatalf("got %q", lines)
+	}
+
+	w = packetWriter{}
+	k = tcp.NewStatsDSink(&w, &tcp.StatsDConfig{Prefix: "app.tcp", GroupBy: []string{"tenant"}, DogStatsD: true, MaxPacketSize: 64})
+	k.Record("192.0.2.1:49152->192.0.2.2:80", labels, st)
+	if len(w.packets) != 6 || w.packets[0] != "app.tcp.rtt:1.5|ms|#tenant:blue" {
+		t.Fatalf("got %q", w.packets)
+	}
+	if err := k.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
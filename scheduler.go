@@ -0,0 +1,381 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// A WriteSchedulerConfig represents the configuration of
+// WriteScheduler.
+// Zero values are replaced with the defaults noted in comments.
+type WriteSchedulerConfig struct {
+	Weights      []int // weights of the priority classes in descending order of priority; default a single class of weight 1
+	Quantum      int   // # of bytes written per weight on each turn of a connection; default 16KB
+	LowWatermark int   // NOTSENT_LOWAT set on the connections; default Quantum
+}
+
+func (cfg *WriteSchedulerConfig) withDefaults() WriteSchedulerConfig {
+	tcfg := *cfg
+	if len(tcfg.Weights) == 0 {
+		tcfg.Weights = []int{1}
+	}
+	if tcfg.Quantum <= 0 {
+		tcfg.Quantum = 16 << 10
+	}
+	if tcfg.LowWatermark <= 0 {
+		tcfg.LowWatermark = tcfg.Quantum
+	}
+	return tcfg
+}
+
+// A WriteScheduler multiplexes the writes to many connections through
+// weighted priority classes, which prevents bulk flows from starving
+// latency-sensitive flows, such as control-plane traffic, in the same
+// process.
+//
+// On each round, the scheduler visits the classes in descending order
+// of priority and writes up to the weight times the quantum bytes to
+// each connection of the class that has pending data. A connection
+// written on a turn waits for its next turn until it becomes writable
+// again. The scheduler sets TCP_NOTSENT_LOWAT option on the
+// connections, so that a connection becomes writable only after the
+// kernel has sent most of the written data, and keeps the unsent
+// data in the kernel small.
+//
+// The connections registered to the scheduler must not be written
+// other than through the scheduler, and must not be closed before
+// being removed from the scheduler.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and OpenBSD
+// support this feature. Only Darwin and Linux support
+// TCP_NOTSENT_LOWAT option; on other platforms a connection becomes
+// writable when its send buffer has space.
+type WriteScheduler struct {
+	cfg     WriteSchedulerConfig
+	p       *Poller
+	wake    chan struct{}
+	stop    chan struct{}
+	pollWG  sync.WaitGroup
+	schedWG sync.WaitGroup
+
+	mu      sync.Mutex
+	conns   map[*Conn]*schedConn
+	classes [][]*schedConn // connections ready to write with pending data
+	closed  bool
+}
+
+type schedConn struct {
+	c        *Conn
+	class    int
+	queue    []*writeRequest
+	inflight *writeRequest // request written by turn with s.mu released
+	ready    bool          // writable
+	active   bool          // in classes
+	removed  bool          // removed from the scheduler
+	err      error         // error of the last write
+}
+
+type writeRequest struct {
+	b    []byte
+	n    int
+	done chan error
+}
+
+// NewWriteScheduler returns a new scheduler with the configuration
+// cfg.
+func NewWriteScheduler(cfg *WriteSchedulerConfig) (*WriteScheduler, error) {
+	tcfg := cfg.withDefaults()
+	for _, w := range tcfg.Weights {
+		if w <= 0 {
+			return nil, errors.New("invalid weight")
+		}
+	}
+	p, err := NewPoller()
+	if err != nil {
+		return nil, err
+	}
+	s := &WriteScheduler{
+		cfg:     tcfg,
+		p:       p,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		conns:   make(map[*Conn]*schedConn),
+		classes: make([][]*schedConn, len(tcfg.Weights)),
+	}
+	s.pollWG.Add(1)
+	go s.poll()
+	s.schedWG.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Add registers the connection c with the scheduler in the priority
+// class of index class, which is the index of Weights.
+func (s *WriteScheduler) Add(c *Conn, class int) error {
+	if class < 0 || class >= len(s.classes) {
+		return c.opError("schedule", errors.New("invalid priority class"))
+	}
+	if err := c.SetOption(tcpopt.NotSentLowWMK(s.cfg.LowWatermark)); err != nil && !isNotSupported(err) {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return c.opError("schedule", net.ErrClosed)
+	}
+	if _, ok := s.conns[c]; ok {
+		return c.opError("schedule", errors.New("already registered"))
+	}
+	if err := s.p.Add(c, 0); err != nil {
+		return err
+	}
+	s.conns[c] = &schedConn{c: c, class: class, ready: true}
+	return nil
+}
+
+// Remove unregisters the connection c from the scheduler.
+// The pending writes to c fail.
+func (s *WriteScheduler) Remove(c *Conn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.conns[c]
+	if !ok {
+		return c.opError("schedule", errors.New("not registered"))
+	}
+	delete(s.conns, c)
+	sc.removed = true
+	s.fail(sc, c.opError("write", errors.New("removed from scheduler")))
+	return s.p.Remove(c)
+}
+
+// Write writes the data b to the connection c through the scheduler.
+// It blocks until all the data is written to the kernel, or an error
+// occurs. It returns the number of bytes written.
+// Once a write to c fails, the subsequent writes fail with the same
+// error.
+func (s *WriteScheduler) Write(c *Conn, b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	r := &writeRequest{b: b, done: make(chan error, 1)}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, c.opError("write", net.ErrClosed)
+	}
+	sc, ok := s.conns[c]
+	if !ok {
+		s.mu.Unlock()
+		return 0, c.opError("write", errors.New("not registered"))
+	}
+	if sc.err != nil {
+		err := sc.err
+		s.mu.Unlock()
+		return 0, err
+	}
+	sc.queue = append(sc.queue, r)
+	s.activate(sc)
+	s.mu.Unlock()
+	s.signal()
+	err := <-r.done
+	return r.n, err
+}
+
+// Len returns the number of registered connections.
+func (s *WriteScheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Close closes the scheduler.
+// The pending writes fail, and the connections are unregistered.
+func (s *WriteScheduler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+	s.schedWG.Wait()
+	s.pollWG.Wait()
+	s.mu.Lock()
+	for c, sc := range s.conns {
+		delete(s.conns, c)
+		sc.removed = true
+		s.fail(sc, c.opError("write", net.ErrClosed))
+	}
+	s.mu.Unlock()
+	return s.p.Close()
+}
+
+func (s *WriteScheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// activate puts sc on the list of its class when sc is ready to
+// write and has pending data.
+// It must be called with s.mu held.
+func (s *WriteScheduler) activate(sc *schedConn) {
+	if sc.active || !sc.ready || sc.removed || len(sc.queue) == 0 {
+		return
+	}
+	sc.active = true
+	s.classes[sc.class] = append(s.classes[sc.class], sc)
+}
+
+// fail completes the pending writes to sc with err, except the
+// request in flight, which turn completes.
+// It must be called with s.mu held.
+func (s *WriteScheduler) fail(sc *schedConn, err error) {
+	sc.err = err
+	for _, r := range sc.queue {
+		if r != sc.inflight {
+			r.done <- err
+		}
+	}
+	sc.queue = nil
+}
+
+// poll marks the connections that become writable as ready.
+func (s *WriteScheduler) poll() {
+	defer s.pollWG.Done()
+	events := make([]PollEvent, 128)
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		n, err := s.p.Wait(events, eventLoopInterval)
+		if err != nil {
+			return
+		}
+		woken := false
+		s.mu.Lock()
+		for _, e := range events[:n] {
+			// The peer closing its half of the connection is
+			// reported only once with edge-triggered
+			// notification; the connection stays armed until it
+			// becomes writable or fails.
+			if e.Events&(PollWrite|PollError) == 0 {
+				continue
+			}
+			sc := s.conns[e.Conn]
+			if sc == nil || sc.ready {
+				continue
+			}
+			if err := s.p.Modify(e.Conn, 0); err != nil {
+				s.fail(sc, err)
+				continue
+			}
+			sc.ready = true
+			s.activate(sc)
+			woken = true
+		}
+		s.mu.Unlock()
+		if woken {
+			s.signal()
+		}
+	}
+}
+
+// run writes the pending data on each wake-up until no connection is
+// ready to write.
+func (s *WriteScheduler) run() {
+	defer s.schedWG.Done()
+	for {
+		select {
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+		for s.round() {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// round visits the classes in descending order of priority and gives
+// a turn to each connection ready to write. It reports whether any
+// connection was ready.
+func (s *WriteScheduler) round() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	visited := false
+	for class := range s.classes {
+		scs := s.classes[class]
+		s.classes[class] = nil
+		for _, sc := range scs {
+			sc.active = false
+			if sc.removed || !sc.ready || len(sc.queue) == 0 {
+				continue
+			}
+			visited = true
+			s.turn(sc, s.cfg.Weights[class]*s.cfg.Quantum)
+		}
+	}
+	return visited
+}
+
+// turn writes up to max bytes of the pending data to sc, and arms sc
+// for the next turn, even when no data remains, so that the next
+// write waits for the kernel to send the unsent data.
+// It must be called with s.mu held; it releases s.mu during the
+// write.
+func (s *WriteScheduler) turn(sc *schedConn, max int) {
+	for max > 0 && len(sc.queue) > 0 {
+		r := sc.queue[0]
+		b := r.b[r.n:]
+		if len(b) > max {
+			b = b[:max]
+		}
+		sc.inflight = r
+		s.mu.Unlock()
+		n, err := sc.c.TryWrite(b)
+		s.mu.Lock()
+		sc.inflight = nil
+		r.n += n
+		max -= n
+		if sc.err != nil { // failed by Remove or poll during the write
+			if r.n == len(r.b) {
+				r.done <- nil
+			} else {
+				r.done <- sc.err
+			}
+			return
+		}
+		if err != nil && err != ErrWouldBlock {
+			s.fail(sc, err)
+			return
+		}
+		if r.n == len(r.b) {
+			sc.queue = sc.queue[1:]
+			r.done <- nil
+		}
+		if err == ErrWouldBlock {
+			break
+		}
+	}
+	if err := s.p.Modify(sc.c, PollWrite|PollEdgeTriggered); err != nil {
+		s.fail(sc, err)
+		return
+	}
+	sc.ready = false
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+)
+
+func TestWriteScheduler(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	s, err := tcp.NewWriteScheduler(&tcp.WriteSchedulerConfig{Weights: []int{4, 1}, Quantum: 4 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctl, ctlPeer := tcptest.Pair(t)
+	bulk, bulkPeer := tcptest.Pair(t)
+	if err := s.Add(ctl, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(bulk, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(bulk, 1); err == nil {
+		t.Fatal("got nil; want an error for duplicate registration")
+	}
+	if n := s.Len(); n != 2 {
+		t.Fatalf("got %d; want 2", n)
+	}
+
+	bulkData := bytes.Repeat([]byte("BULK"), 1<<18)
+	ctlData := []byte("HELLO-R-U-THERE")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if n, err := s.Write(bulk, bulkData); err != nil || n != len(bulkData) {
+			t.Errorf("got %d, %v; want %d, nil", n, err, len(bulkData))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 16; i++ {
+			if n, err := s.Write(ctl, ctlData); err != nil || n != len(ctlData) {
+				t.Errorf("got %d, %v; want %d, nil", n, err, len(ctlData))
+				return
+			}
+		}
+	}()
+	b := make([]byte, len(bulkData))
+	if _, err := io.ReadFull(bulkPeer, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, bulkData) {
+		t.Fatal("bulk data corrupted")
+	}
+	b = make([]byte, 16*len(ctlData))
+	if _, err := io.ReadFull(ctlPeer, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, bytes.Repeat(ctlData, 16)) {
+		t.Fatal("control data corrupted")
+	}
+	wg.Wait()
+
+	if err := s.Remove(bulk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write(bulk, ctlData); err == nil {
+		t.Fatal("got nil; want an error for unregistered connection")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write(ctl, ctlData); err == nil {
+		t.Fatal("got nil; want an error after close")
+	}
+}
+
+func TestWriteSchedulerRemove(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	s, err := tcp.NewWriteScheduler(&tcp.WriteSchedulerConfig{Quantum: 4 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, peer := tcptest.Pair(t)
+	if err := s.Add(c, 0); err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(ioutil.Discard, peer)
+
+	// The writes in progress complete with an error, or without
+	// an error when written entirely before the removal.
+	data := bytes.Repeat([]byte("DATA"), 1<<18)
+	const N = 4
+	errs := make(chan error, N)
+	for i := 0; i < N; i++ {
+		go func() {
+			n, err := s.Write(c, data)
+			if err == nil && n != len(data) {
+				t.Errorf("got %d, nil; want %d, nil", n, len(data))
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	if err := s.Remove(c); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		<-errs
+	}
+}
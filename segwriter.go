@@ -0,0 +1,181 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// defaultMSS is the maximum segment size assumed when the platform
+// doesn't report it, which fits in an Ethernet frame.
+const defaultMSS = 1460
+
+// A SegmentWriter represents a buffered writer of a connection that
+// coalesces small writes into full-sized segments.
+//
+// It learns the maximum segment size of the connection using
+// TCP_MAXSEG option, and writes the buffered data to the connection
+// in multiples of the segment size, or when a partial segment has
+// stayed in the buffer for the idle interval. It reduces the overhead
+// of small segments for chatty protocols on connections with NoDelay
+// enabled, without the delays of Nagle's algorithm waiting for
+// acknowledgments.
+type SegmentWriter struct {
+	c    *Conn
+	idle time.Duration
+
+	mu    sync.Mutex
+	mss   int
+	buf   []byte
+	timer *time.Timer // idle timer
+	armed bool
+	err   error // error of the last write
+}
+
+// NewSegmentWriter returns a new writer of connection c that flushes
+// the partial segment after the idle interval.
+func NewSegmentWriter(c *Conn, idle time.Duration) (*SegmentWriter, error) {
+	if idle <= 0 {
+		return nil, errors.New("invalid idle interval")
+	}
+	w := &SegmentWriter{c: c, idle: idle}
+	w.mss = w.learnMSS()
+	w.buf = make([]byte, 0, 2*w.mss)
+	return w, nil
+}
+
+// learnMSS returns the maximum segment size of the connection.
+func (w *SegmentWriter) learnMSS() int {
+	var b [4]byte
+	o, err := w.c.Option(tcpopt.MSS(0).Level(), tcpopt.MSS(0).Name(), b[:])
+	if err != nil {
+		return defaultMSS
+	}
+	if mss, ok := o.(tcpopt.MSS); ok && mss > 0 {
+		return int(mss)
+	}
+	return defaultMSS
+}
+
+// MSS returns the segment size used by the writer.
+func (w *SegmentWriter) MSS() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.mss
+}
+
+// Refresh re-learns the maximum segment size of the connection, which
+// may change with the path MTU.
+func (w *SegmentWriter) Refresh() {
+	mss := w.learnMSS()
+	w.mu.Lock()
+	w.mss = mss
+	w.mu.Unlock()
+}
+
+// Buffered returns the number of bytes buffered in the writer.
+func (w *SegmentWriter) Buffered() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.buf)
+}
+
+// Write writes data to the buffer, and writes the full segments in
+// the buffer to the connection.
+// It returns the error of a previous write in the background, if
+// any.
+func (w *SegmentWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	n := len(b)
+	if len(w.buf) == 0 && len(b) >= w.mss {
+		m := len(b) - len(b)%w.mss
+		if _, err := w.c.Write(b[:m]); err != nil {
+			w.err = err
+			return 0, err
+		}
+		b = b[m:]
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.mss {
+		m := len(w.buf) - len(w.buf)%w.mss
+		if err := w.write(m); err != nil {
+			return n, err
+		}
+	}
+	w.arm()
+	return n, nil
+}
+
+// Flush writes any buffered data to the connection.
+func (w *SegmentWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	err := w.write(len(w.buf))
+	w.arm()
+	return err
+}
+
+// Stop flushes the buffered data and stops the idle timer.
+// It doesn't close the connection.
+func (w *SegmentWriter) Stop() error {
+	err := w.Flush()
+	w.mu.Lock()
+	if w.armed {
+		w.timer.Stop()
+		w.armed = false
+	}
+	w.mu.Unlock()
+	return err
+}
+
+// write writes the leading n bytes of the buffer to the connection.
+func (w *SegmentWriter) write(n int) error {
+	if n == 0 {
+		return nil
+	}
+	if _, err := w.c.Write(w.buf[:n]); err != nil {
+		w.err = err
+		return err
+	}
+	w.buf = w.buf[:copy(w.buf, w.buf[n:])]
+	return nil
+}
+
+// arm arms the idle timer when a partial segment is buffered, and
+// disarms it when the buffer is empty.
+func (w *SegmentWriter) arm() {
+	switch {
+	case len(w.buf) == 0 && w.armed:
+		w.timer.Stop()
+		w.armed = false
+	case len(w.buf) > 0 && !w.armed:
+		if w.timer == nil {
+			w.timer = time.AfterFunc(w.idle, w.flushIdle)
+		} else {
+			w.timer.Reset(w.idle)
+		}
+		w.armed = true
+	}
+}
+
+func (w *SegmentWriter) flushIdle() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.armed = false
+	if w.err == nil {
+		w.write(len(w.buf))
+	}
+}
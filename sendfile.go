@@ -0,0 +1,50 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// Sendfile writes count bytes of the file f starting at offset to the
+// connection by using the sendfile system call.
+// It returns the number of bytes written even when it fails, so the
+// caller can resume the transfer from offset+n.
+// It returns io.EOF when f ends before count bytes are written.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux and Solaris support this
+// feature.
+func (c *Conn) Sendfile(f *os.File, offset, count int64) (int64, error) {
+	fns := c.hooks()
+	if len(fns) == 0 {
+		return c.sendfile(f, offset, count)
+	}
+	start := time.Now()
+	n, err := c.sendfile(f, offset, count)
+	c.observe(fns, "write", n, start, err)
+	return n, err
+}
+
+func (c *Conn) sendfile(f *os.File, offset, count int64) (int64, error) {
+	if offset < 0 || count < 0 {
+		return 0, c.ioError("sendfile", errors.New("invalid offset or count"))
+	}
+	rc, err := c.rawConn()
+	if err != nil {
+		return 0, c.ioError("sendfile", err)
+	}
+	fc, err := f.SyscallConn()
+	if err != nil {
+		return 0, c.ioError("sendfile", err)
+	}
+	n, err := sendfile(rc, fc, offset, count)
+	if err != nil && err != io.EOF {
+		err = c.ioError("sendfile", err)
+	}
+	return n, err
+}
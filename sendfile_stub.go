@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin,!dragonfly,!freebsd,!linux,!solaris
+
+package tcp
+
+import "syscall"
+
+func sendfile(rc, fc syscall.RawConn, offset, count int64) (int64, error) {
+	return 0, ErrNotSupported
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+)
+
+func TestSendfile(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	f, err := ioutil.TempFile("", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	m := bytes.Repeat([]byte("HELLO-R-U-THERE"), 1<<12)
+	if _, err := f.Write(m); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(p)
+		done <- b
+	}()
+	off, count := int64(5), int64(len(m)/2)
+	n, err := tc.Sendfile(f, off, count)
+	if err != nil || n != count {
+		t.Fatalf("got %d, %v; want %d, nil", n, err, count)
+	}
+	n, err = tc.Sendfile(f, int64(len(m))-10, 20)
+	if err != io.EOF || n != 10 {
+		t.Fatalf("got %d, %v; want 10, %v", n, err, io.EOF)
+	}
+	c.Close()
+	b := <-done
+	want := append(append([]byte{}, m[off:off+count]...), m[len(m)-10:]...)
+	if !bytes.Equal(b, want) {
+		t.Fatalf("got %d bytes; want %d bytes", len(b), len(want))
+	}
+}
+
+func TestReadFromWriteTo(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	f, err := ioutil.TempFile("", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	m := bytes.Repeat([]byte("HELLO-R-U-THERE"), 1<<12)
+	if _, err := f.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	p, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp, err := tcp.NewConn(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []byte)
+	go func() {
+		var buf bytes.Buffer
+		tp.WriteTo(&buf)
+		done <- buf.Bytes()
+	}()
+	n, err := io.Copy(tc, f)
+	if err != nil || n != int64(len(m)) {
+		t.Fatalf("got %d, %v; want %d, nil", n, err, len(m))
+	}
+	c.Close()
+	if b := <-done; !bytes.Equal(b, m) {
+		t.Fatalf("got %d bytes; want %d bytes", len(b), len(m))
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux solaris
+
+package tcp
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// maxSendfileSize is the largest chunk size passed to a single
+// sendfile system call.
+const maxSendfileSize = 4 << 20
+
+func sendfile(rc, fc syscall.RawConn, offset, count int64) (int64, error) {
+	var n int64
+	var operr error
+	err := fc.Control(func(fd uintptr) {
+		err := rc.Write(func(s uintptr) bool {
+			for n < count {
+				l := count - n
+				if l > maxSendfileSize {
+					l = maxSendfileSize
+				}
+				off := offset + n
+				nn, err := syscall.Sendfile(int(s), int(fd), &off, int(l))
+				if nn > 0 {
+					n += int64(nn)
+				}
+				if err == syscall.EINTR {
+					continue
+				}
+				if err == syscall.EAGAIN {
+					return false
+				}
+				if err != nil {
+					operr = os.NewSyscallError("sendfile", err)
+					return true
+				}
+				if nn == 0 {
+					operr = io.EOF
+					return true
+				}
+			}
+			return true
+		})
+		if err != nil {
+			operr = err
+		}
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, operr
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// Default backoff parameters of Server, the same as net/http.
+const (
+	defaultMinBackoff = 5 * time.Millisecond
+	defaultMaxBackoff = time.Second
+)
+
+// A Server runs an accept loop that survives the temporary failures
+// of accept, such as running out of file descriptors.
+type Server struct {
+	// Handler is called on a new goroutine for each accepted
+	// connection. It must close the connection.
+	Handler func(c *Conn)
+
+	// TemporaryError is called with the error and the delay before
+	// the next accept when accept fails temporarily. It's intended
+	// for logging and is called on the accept loop.
+	TemporaryError func(err error, delay time.Duration)
+
+	// MinBackoff and MaxBackoff are the minimum and maximum delays
+	// between the retries of accept. The delay doubles on each
+	// consecutive failure. The defaults are 5 milliseconds and 1
+	// second.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Serve accepts connections on the listener ln and calls the handler
+// for each connection.
+//
+// On the temporary failures of accept, such as EMFILE, ENFILE and
+// ECONNABORTED, it retries accept with an exponential backoff. It
+// returns nil when ln is closed, or the error of the first permanent
+// failure.
+func (srv *Server) Serve(ln net.Listener) error {
+	if srv.Handler == nil {
+		return errors.New("no handler")
+	}
+	var delay time.Duration
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			if !isTemporaryAccept(err) {
+				return err
+			}
+			delay = srv.backoff(delay)
+			if srv.TemporaryError != nil {
+				srv.TemporaryError(err, delay)
+			}
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		tc, err := NewConn(c)
+		if err != nil {
+			c.Close()
+			if srv.TemporaryError != nil {
+				srv.TemporaryError(err, 0)
+			}
+			continue
+		}
+		go srv.Handler(tc)
+	}
+}
+
+func (srv *Server) backoff(delay time.Duration) time.Duration {
+	min, max := srv.MinBackoff, srv.MaxBackoff
+	if min <= 0 {
+		min = defaultMinBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	if delay < min {
+		return min
+	}
+	if delay *= 2; delay > max {
+		return max
+	}
+	return delay
+}
+
+func isTemporaryAccept(err error) bool {
+	if isOverload(err) {
+		return true
+	}
+	var ne interface{ Temporary() bool }
+	return errors.As(err, &ne) && ne.Temporary()
+}
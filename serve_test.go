@@ -0,0 +1,78 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp"
+)
+
+// overloadedListener fails accept with EMFILE for the first n calls.
+type overloadedListener struct {
+	net.Listener
+	n int
+}
+
+func (ln *overloadedListener) Accept() (net.Conn, error) {
+	if ln.n > 0 {
+		ln.n--
+		return nil, &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	}
+	return ln.Listener.Accept()
+}
+
+func TestServerBackoff(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var delays []time.Duration
+	handled := make(chan struct{})
+	srv := tcp.Server{
+		Handler: func(c *tcp.Conn) {
+			c.Close()
+			close(handled)
+		},
+		TemporaryError: func(err error, delay time.Duration) { delays = append(delays, delay) },
+		MinBackoff:     time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+	}
+	done := make(chan error)
+	go func() { done <- srv.Serve(&overloadedListener{Listener: ln, n: 4}) }()
+
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	<-handled
+	ln.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	if len(delays) != len(want) {
+		t.Fatalf("got %v; want %v", delays, want)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("got %v; want %v", delays, want)
+		}
+	}
+}
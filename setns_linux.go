@@ -0,0 +1,11 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux,!386,!amd64
+
+package tcp
+
+import "syscall"
+
+const sysSETNS = syscall.SYS_SETNS
@@ -0,0 +1,138 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// shutdownPollInterval is the interval of checking the tracked
+// connections closed through the underlying connections during
+// Shutdown.
+const shutdownPollInterval = 100 * time.Millisecond
+
+// SetTrackConns enables or disables the tracking of the connections
+// accepted from the listener, which allows Shutdown to wait for the
+// connections. The connections accepted before the call are not
+// affected.
+func (ln *Listener) SetTrackConns(on bool) {
+	ln.limitMu.Lock()
+	ln.tracking = on
+	ln.limitMu.Unlock()
+}
+
+// track makes the connection c accepted from the listener hold the
+// slot of connection limit l, if any, until c is closed, and starts
+// tracking c when tracking is true.
+// It closes c when the listener is shutting down.
+func (ln *Listener) track(c *Conn, l *connLimit, tracking bool) error {
+	var once sync.Once
+	c.release = func() {
+		once.Do(func() {
+			if l != nil {
+				l.release()
+			}
+			ln.forget(c)
+		})
+	}
+	if !tracking {
+		return nil
+	}
+	ln.limitMu.Lock()
+	if ln.shutdown {
+		ln.limitMu.Unlock()
+		c.Close()
+		return ln.opError("accept", net.ErrClosed)
+	}
+	if ln.conns == nil {
+		ln.conns = make(map[*Conn]struct{})
+	}
+	ln.conns[c] = struct{}{}
+	ln.limitMu.Unlock()
+	return nil
+}
+
+func (ln *Listener) forget(c *Conn) {
+	ln.limitMu.Lock()
+	defer ln.limitMu.Unlock()
+	if _, ok := ln.conns[c]; !ok {
+		return
+	}
+	delete(ln.conns, c)
+	if len(ln.conns) == 0 && ln.drained != nil {
+		close(ln.drained)
+		ln.drained = nil
+	}
+}
+
+// forgetClosed stops tracking the connections closed through the
+// underlying connections, which bypass Conn.Close.
+func (ln *Listener) forgetClosed() {
+	ln.limitMu.Lock()
+	cs := make([]*Conn, 0, len(ln.conns))
+	for c := range ln.conns {
+		cs = append(cs, c)
+	}
+	ln.limitMu.Unlock()
+	for _, c := range cs {
+		if c.rc != nil && c.rc.Control(func(uintptr) {}) != nil {
+			c.release()
+		}
+	}
+}
+
+// Shutdown gracefully shuts down the listener.
+// It closes the listener and waits for the tracked connections, which
+// are accepted while SetTrackConns is in effect, to be closed. When
+// ctx is done before draining the connections, it closes the
+// remaining connections forcibly, and returns the snapshots of them
+// taken before closing with ctx.Err().
+//
+// Shutdown doesn't notify the connections of the shutdown; the
+// application is responsible for finishing the connections, for
+// example by closing idle connections, when the listener stops
+// accepting.
+func (ln *Listener) Shutdown(ctx context.Context) ([]*DebugInfo, error) {
+	ln.limitMu.Lock()
+	ln.shutdown = true
+	var drained chan struct{}
+	if len(ln.conns) > 0 {
+		drained = make(chan struct{})
+		ln.drained = drained
+	}
+	ln.limitMu.Unlock()
+	err := ln.Close()
+	if drained == nil {
+		return nil, err
+	}
+	t := time.NewTicker(shutdownPollInterval)
+	defer t.Stop()
+wait:
+	for {
+		select {
+		case <-drained:
+			return nil, err
+		case <-ctx.Done():
+			break wait
+		case <-t.C:
+			ln.forgetClosed()
+		}
+	}
+	ln.limitMu.Lock()
+	cs := make([]*Conn, 0, len(ln.conns))
+	for c := range ln.conns {
+		cs = append(cs, c)
+	}
+	ln.limitMu.Unlock()
+	dis := make([]*DebugInfo, 0, len(cs))
+	for _, c := range cs {
+		dis = append(dis, c.DebugInfo())
+		c.Close()
+	}
+	return dis, ctx.Err()
+}
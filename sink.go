@@ -0,0 +1,53 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+// A Stats represents the statistics of a connection recorded into a
+// Sink.
+type Stats struct {
+	Sample              // latest sample
+	Delta  *SampleDelta // differences from the previous sample; nil when not available
+}
+
+// A Sink represents a destination of connection statistics, such as
+// an exporter of metrics or a logger.
+//
+// The connection ID is the 4-tuple of the connection in the form of
+// "192.0.2.1:49152->192.0.2.2:80", which identifies a connection
+// among the live connections. The labels include the labels of the
+// connection set by Conn.SetLabels. The implementation must not
+// retain or modify labels and stats after returning.
+type Sink interface {
+	Record(connID string, labels Labels, stats *Stats)
+}
+
+// The SinkFunc type is an adapter to allow the use of ordinary
+// functions as sinks.
+type SinkFunc func(connID string, labels Labels, stats *Stats)
+
+// Record calls f(connID, labels, stats).
+func (f SinkFunc) Record(connID string, labels Labels, stats *Stats) { f(connID, labels, stats) }
+
+// connID returns the ID of the connection c passed to sinks.
+func connID(c *Conn) string {
+	return c.LocalAddr().String() + "->" + c.RemoteAddr().String()
+}
+
+// Record records the statistics of all the registered connections
+// into the sink k at once, which is suitable for the exporters that
+// collect the statistics on demand. The recorded statistics carry no
+// deltas.
+// The connections whose information is not available are skipped.
+func (r *ConnRegistry) Record(k Sink) {
+	for _, rc := range r.Conns(nil) {
+		info, err := connInfo(rc.Conn)
+		if err != nil {
+			continue
+		}
+		k.Record(connID(rc.Conn), rc.Labels, &Stats{Sample: Sample{Time: time.Now(), Info: info}})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+// A Transport represents a transport of the connection.
+type Transport int
+
+const (
+	TransportTCP  Transport = iota // plain tcp, including the fallback from smc
+	TransportSMCR                  // smc over rdma
+	TransportSMCD                  // smc over dma within a system
+)
+
+var transportNames = [...]string{
+	TransportTCP:  "tcp",
+	TransportSMCR: "smc-r",
+	TransportSMCD: "smc-d",
+}
+
+func (t Transport) String() string {
+	if t < 0 || int(t) >= len(transportNames) {
+		return "<nil>"
+	}
+	return transportNames[t]
+}
+
+// Transport returns the transport negotiated on the connection.
+// It returns TransportTCP for the connections without the use of
+// SMC and the ones fallen back to TCP from SMC. See Dialer.SMC.
+//
+// Only Linux supports this feature. It requires the smc_diag module
+// for the SMC connections.
+func (c *Conn) Transport() (Transport, error) {
+	var t Transport
+	err := c.control(func(s uintptr) (err error) {
+		t, err = transport(s)
+		return
+	})
+	if err != nil {
+		return 0, c.opError("get", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func transport(s uintptr) (Transport, error) {
+	var b [4]byte
+	if _, err := getsockopt(s, sysSOL_SOCKET, sysSO_DOMAIN, b[:]); err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+	if nativeEndian.Uint32(b[:]) != sysAF_SMC {
+		return TransportTCP, nil
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(s), &st); err != nil {
+		return 0, os.NewSyscallError("fstat", err)
+	}
+	m, err := smcDiag(uint64(st.Ino))
+	if err != nil {
+		return 0, err
+	}
+	switch m.Mode {
+	case sysSMC_DIAG_MODE_SMCR:
+		return TransportSMCR, nil
+	case sysSMC_DIAG_MODE_SMCD:
+		return TransportSMCD, nil
+	default:
+		return TransportTCP, nil
+	}
+}
+
+// smcDiag returns the diagnostic message of the SMC socket with the
+// inode number ino.
+func smcDiag(ino uint64) (*smcDiagMsg, error) {
+	s, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, sysNETLINK_INET_DIAG)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(s)
+	b := make([]byte, syscall.NLMSG_HDRLEN+sizeofSMCDiagReq)
+	nlh := (*syscall.NlMsghdr)(unsafe.Pointer(&b[0]))
+	nlh.Len = uint32(len(b))
+	nlh.Type = sysSOCK_DIAG_BY_FAMILY
+	nlh.Flags = syscall.NLM_F_REQUEST | syscall.NLM_F_DUMP
+	nlh.Seq = 1
+	req := (*smcDiagReq)(unsafe.Pointer(&b[syscall.NLMSG_HDRLEN]))
+	req.Family = sysAF_SMC
+	if err := syscall.Sendto(s, b, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+	b = make([]byte, 8*os.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(s, b, 0)
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(b[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil, errors.New("smc socket not found")
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return nil, syscall.EINVAL
+				}
+				return nil, os.NewSyscallError("netlink", syscall.Errno(-int32(nativeEndian.Uint32(m.Data))))
+			}
+			if len(m.Data) < sizeofSMCDiagMsg {
+				continue
+			}
+			if dm := (*smcDiagMsg)(unsafe.Pointer(&m.Data[0])); dm.Inode == ino {
+				msg := *dm
+				return &msg, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+// A Snapshot represents the readable socket options and the
+// connection information of a connection at a point in time.
+type Snapshot struct {
+	Time    time.Time
+	Options []tcpopt.Option // readable socket options
+	Info    *tcpinfo.Info   // connection information; nil when not available
+}
+
+// Snapshot captures the readable socket options and the connection
+// information of the connection. See Options for the captured
+// options.
+func (c *Conn) Snapshot() (*Snapshot, error) {
+	opts, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+	ss := Snapshot{Time: time.Now(), Options: opts}
+	ss.Info, _ = connInfo(c)
+	return &ss, nil
+}
+
+// A Change represents a difference between two snapshots.
+type Change struct {
+	Name string      // name of option or information field
+	Old  interface{} // value in the old snapshot; nil when not present
+	New  interface{} // value in the new snapshot; nil when not present
+}
+
+// Diff returns the differences from the snapshot a to b, sorted by
+// name.
+// The options are named after their types, such as "NoDelay", and
+// the information fields are named after their paths in
+// tcpinfo.Info, such as "Info.CongestionControl.SenderWindowSegs".
+func Diff(a, b *Snapshot) []Change {
+	x, y := a.values(), b.values()
+	var chs []Change
+	for name, old := range x {
+		if nw, ok := y[name]; !ok || nw != old {
+			chs = append(chs, Change{Name: name, Old: old, New: y[name]})
+		}
+	}
+	for name, nw := range y {
+		if _, ok := x[name]; !ok {
+			chs = append(chs, Change{Name: name, New: nw})
+		}
+	}
+	sort.Slice(chs, func(i, j int) bool { return chs[i].Name < chs[j].Name })
+	return chs
+}
+
+// values returns the comparable values of ss indexed by name.
+func (ss *Snapshot) values() map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, o := range ss.Options {
+		m[reflect.TypeOf(o).Name()] = o
+	}
+	if ss.Info != nil {
+		flatten(m, "Info", reflect.ValueOf(ss.Info).Elem())
+	}
+	return m
+}
+
+// flatten stores the comparable fields of the struct v into m.
+func flatten(m map[string]interface{}, prefix string, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+		f := v.Field(i)
+		name := prefix + "." + sf.Name
+		switch f.Kind() {
+		case reflect.Ptr:
+			if !f.IsNil() && f.Elem().Kind() == reflect.Struct {
+				flatten(m, name, f.Elem())
+			}
+		case reflect.Struct:
+			flatten(m, name, f)
+		case reflect.Slice, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		default:
+			m[name] = f.Interface()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcpopt"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "solaris":
+	default:
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tc, err := tcp.NewConn(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tc.SetOption(tcpopt.NoDelay(false)); err != nil {
+		t.Fatal(err)
+	}
+	a, err := tc.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.SetOption(tcpopt.NoDelay(true)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := tc.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chs := tcp.Diff(a, a); len(chs) != 0 {
+		t.Fatalf("got %v; want no changes", chs)
+	}
+	var found bool
+	for _, ch := range tcp.Diff(a, b) {
+		if ch.Name == "NoDelay" {
+			if ch.Old != tcpopt.NoDelay(false) || ch.New != tcpopt.NoDelay(true) {
+				t.Fatalf("got %v; want false to true", ch)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("no change of NoDelay found")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !js,!plan9
+
+package tcp
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"syscall"
+
+	"github.com/mikioh/netreflect"
+)
+
+func socketOf(c net.Conn) (uintptr, error) { return netreflect.SocketOf(c) }
+
+// maxUnwrapDepth is the maximum number of wrapper layers walked by
+// wrappedSocketOf.
+const maxUnwrapDepth = 32
+
+// wrappedSocketOf returns the socket descriptor and the raw
+// connection, if any, of the TCP socket underlying the connection c.
+// It walks the chain of wrappers of c until it finds the layer that
+// carries the socket, such as a *net.TCPConn or a wrapper implementing
+// syscall.Conn, and reports whether the layer is wrapped by c.
+// The end points of this package are not counted as wrappers.
+func wrappedSocketOf(c net.Conn) (uintptr, syscall.RawConn, bool, error) {
+	var first error
+	wrapped := false
+	for i, x := 0, c; x != nil && i < maxUnwrapDepth; i, x = i+1, unwrap(x) {
+		if tc, ok := x.(*Conn); ok {
+			return tc.s, tc.rc, wrapped || tc.wrapped, nil
+		}
+		s, err := socketOf(x)
+		if err == nil {
+			var rc syscall.RawConn
+			if sc, ok := x.(syscall.Conn); ok {
+				rc, _ = sc.SyscallConn()
+			}
+			return s, rc, wrapped, nil
+		}
+		if sc, ok := x.(syscall.Conn); ok {
+			var rc syscall.RawConn
+			if rc, err = sc.SyscallConn(); err == nil {
+				if err = rc.Control(func(fd uintptr) { s = fd }); err == nil {
+					return s, rc, wrapped, nil
+				}
+			}
+		}
+		if first == nil {
+			first = err
+		}
+		wrapped = true
+	}
+	return 0, nil, false, first
+}
+
+func listenerRawConnOf(ln net.Listener) (syscall.RawConn, error) {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return nil, errors.New("unknown listener type")
+	}
+	return sc.SyscallConn()
+}
+
+// isNotSupported reports whether err indicates that the kernel
+// doesn't know the requested feature.
+func isNotSupported(err error) bool {
+	return errors.Is(err, syscall.ENOPROTOOPT) || errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, ErrNotSupported)
+}
+
+// A deniedError represents a socket option call denied by the
+// security policy of the system, such as SELinux on Android.
+// It matches ErrNotSupported as well as the original error number.
+type deniedError struct {
+	errno syscall.Errno
+}
+
+func (e *deniedError) Error() string        { return e.errno.Error() + " by security policy" }
+func (e *deniedError) Is(target error) bool { return target == ErrNotSupported }
+func (e *deniedError) Unwrap() error        { return e.errno }
+
+// sockoptError returns the error for the failed socket option call
+// with errno.
+// On Android, where SELinux denies many socket options to
+// applications, EACCES and EPERM are reported as not supported.
+func sockoptError(errno syscall.Errno) error {
+	if runtime.GOOS == "android" && (errno == syscall.EACCES || errno == syscall.EPERM) {
+		return &deniedError{errno: errno}
+	}
+	return errno
+}
+
+// isDenied reports whether err indicates that the security policy of
+// the system denies the requested feature.
+func isDenied(err error) bool {
+	var e *deniedError
+	return errors.As(err, &e)
+}
+
+// isOverload reports whether err indicates a temporary failure of
+// accept due to the lack of resources or an aborted connection.
+func isOverload(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ECONNABORTED, syscall.ENOBUFS, syscall.ENOMEM} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js plan9
+
+package tcp
+
+import (
+	"net"
+	"syscall"
+)
+
+func socketOf(c net.Conn) (uintptr, error) { return 0, ErrNotSupported }
+
+func wrappedSocketOf(c net.Conn) (uintptr, syscall.RawConn, bool, error) {
+	return 0, nil, false, ErrNotSupported
+}
+
+func listenerRawConnOf(ln net.Listener) (syscall.RawConn, error) { return nil, ErrNotSupported }
+
+func isNotSupported(err error) bool { return true }
+
+func isOverload(err error) bool { return false }
+
+func isDenied(err error) bool { return false }
@@ -0,0 +1,127 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "errors"
+
+// A SockMapAttach represents a point of a socket map to which an
+// eBPF program is attached.
+type SockMapAttach int
+
+const (
+	// AttachMsgVerdict is for the BPF_PROG_TYPE_SK_MSG program that
+	// decides the verdict of each message sent on the sockets in
+	// the map; see BPF_SK_MSG_VERDICT.
+	AttachMsgVerdict SockMapAttach = iota
+
+	// AttachStreamParser is for the BPF_PROG_TYPE_SK_SKB program
+	// that determines the boundary of messages received on the
+	// sockets in the map; see BPF_SK_SKB_STREAM_PARSER.
+	AttachStreamParser
+
+	// AttachStreamVerdict is for the BPF_PROG_TYPE_SK_SKB program
+	// that decides the verdict of each message determined by the
+	// stream parser; see BPF_SK_SKB_STREAM_VERDICT.
+	AttachStreamVerdict
+
+	// AttachSKBVerdict is for the BPF_PROG_TYPE_SK_SKB program that
+	// decides the verdict of each received packet without the
+	// stream parser; see BPF_SK_SKB_VERDICT.
+	// It requires Linux 5.13 or above.
+	AttachSKBVerdict
+)
+
+var sockMapAttachNames = [...]string{
+	AttachMsgVerdict:    "msg-verdict",
+	AttachStreamParser:  "stream-parser",
+	AttachStreamVerdict: "stream-verdict",
+	AttachSKBVerdict:    "skb-verdict",
+}
+
+func (a SockMapAttach) String() string {
+	if a < 0 || int(a) >= len(sockMapAttachNames) {
+		return "<nil>"
+	}
+	return sockMapAttachNames[a]
+}
+
+// A SockMap represents an eBPF map of BPF_MAP_TYPE_SOCKMAP or
+// BPF_MAP_TYPE_SOCKHASH type, which holds the sockets that the
+// programs attached to the map redirect the data to.
+// Once the connections are inserted, the kernel forwards the data
+// between the sockets as the verdict programs decide, without
+// copying the data to the user space.
+//
+// Only Linux supports this feature.
+type SockMap struct {
+	fd        int
+	hash      bool // BPF_MAP_TYPE_SOCKHASH
+	keySize   int
+	valueSize int
+}
+
+// NewSockMap returns a new socket map referring to the eBPF map of
+// the file descriptor fd.
+// The file descriptor is duplicated; the caller is responsible for
+// closing fd.
+func NewSockMap(fd int) (*SockMap, error) { return newSockMap(fd) }
+
+// OpenPinnedSockMap is like NewSockMap but takes the path of the
+// eBPF map pinned on the BPF file system.
+func OpenPinnedSockMap(path string) (*SockMap, error) { return openPinnedSockMap(path) }
+
+// Close closes the file descriptor of the socket map. It leaves the
+// map and the sockets in the map as is in the kernel.
+func (m *SockMap) Close() error { return m.close() }
+
+// FD returns the file descriptor of the socket map.
+func (m *SockMap) FD() int { return m.fd }
+
+// IsHash reports whether the socket map is of
+// BPF_MAP_TYPE_SOCKHASH type.
+func (m *SockMap) IsHash() bool { return m.hash }
+
+// KeySize returns the size of keys of the socket map in bytes.
+// Keys of the BPF_MAP_TYPE_SOCKMAP type are the indices of the map
+// in native byte order, such as the ones encoded by
+// binary.NativeEndian.PutUint32; keys of the BPF_MAP_TYPE_SOCKHASH
+// type are defined by the programs using the map.
+func (m *SockMap) KeySize() int { return m.keySize }
+
+// Add inserts the connection c into the socket map with the key,
+// replacing the socket already in the map with the same key.
+//
+// The connection must be established. The socket leaves the map
+// when the connection is closed.
+func (m *SockMap) Add(key []byte, c *Conn) error {
+	if len(key) != m.keySize {
+		return c.opError("set", errors.New("invalid key size"))
+	}
+	if err := c.control(func(s uintptr) error { return m.update(key, s) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// Delete removes the socket of the key from the socket map.
+func (m *SockMap) Delete(key []byte) error {
+	if len(key) != m.keySize {
+		return errors.New("invalid key size")
+	}
+	return m.delete(key)
+}
+
+// AttachProgram attaches the eBPF program referred to by the file
+// descriptor fd to the socket map at the attachment point a.
+// It replaces the program already attached to a.
+func (m *SockMap) AttachProgram(fd int, a SockMapAttach) error {
+	return m.attach(fd, a)
+}
+
+// DetachProgram detaches the eBPF program referred to by the file
+// descriptor fd from the attachment point a of the socket map.
+func (m *SockMap) DetachProgram(fd int, a SockMapAttach) error {
+	return m.detach(fd, a)
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var sockMapAttachTypes = [...]uint32{
+	AttachMsgVerdict:    sysBPF_SK_MSG_VERDICT,
+	AttachStreamParser:  sysBPF_SK_SKB_STREAM_PARSER,
+	AttachStreamVerdict: sysBPF_SK_SKB_STREAM_VERDICT,
+	AttachSKBVerdict:    sysBPF_SK_SKB_VERDICT,
+}
+
+// bpfMapInfo is the leading part of struct bpf_map_info. The kernel
+// fills the leading part of the structure given by info_len.
+type bpfMapInfo struct {
+	typ        uint32
+	id         uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+}
+
+func bpf(cmd uintptr, attr unsafe.Pointer, size uintptr) (uintptr, error) {
+	if sysBPF == 0 {
+		return 0, ErrNotSupported
+	}
+	r, _, errno := syscall.Syscall(sysBPF, cmd, uintptr(attr), size)
+	if errno != 0 {
+		return 0, os.NewSyscallError("bpf", errno)
+	}
+	return r, nil
+}
+
+func newSockMap(fd int) (*SockMap, error) {
+	r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_DUPFD_CLOEXEC, 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("fcntl", errno)
+	}
+	m, err := sockMapOf(int(r))
+	if err != nil {
+		syscall.Close(int(r))
+		return nil, err
+	}
+	return m, nil
+}
+
+func openPinnedSockMap(path string) (*SockMap, error) {
+	fd, err := openPinnedBPF(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := sockMapOf(fd)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return m, nil
+}
+
+// sockMapOf returns the socket map of the file descriptor fd owned
+// by the returned map.
+func sockMapOf(fd int) (*SockMap, error) {
+	var info bpfMapInfo
+	attr := struct {
+		bpfFD   uint32
+		infoLen uint32
+		info    uint64
+	}{bpfFD: uint32(fd), infoLen: uint32(unsafe.Sizeof(info)), info: uint64(uintptr(unsafe.Pointer(&info)))}
+	if _, err := bpf(sysBPF_OBJ_GET_INFO_BY_FD, unsafe.Pointer(&attr), unsafe.Sizeof(attr)); err != nil {
+		return nil, err
+	}
+	runtime.KeepAlive(&info)
+	switch info.typ {
+	case sysBPF_MAP_TYPE_SOCKMAP, sysBPF_MAP_TYPE_SOCKHASH:
+	default:
+		return nil, errors.New("not a socket map")
+	}
+	return &SockMap{
+		fd:        fd,
+		hash:      info.typ == sysBPF_MAP_TYPE_SOCKHASH,
+		keySize:   int(info.keySize),
+		valueSize: int(info.valueSize),
+	}, nil
+}
+
+func (m *SockMap) close() error { return syscall.Close(m.fd) }
+
+func (m *SockMap) update(key []byte, s uintptr) error {
+	var v [8]byte
+	switch m.valueSize {
+	case 4:
+		nativeEndian.PutUint32(v[:], uint32(s))
+	case 8:
+		nativeEndian.PutUint64(v[:], uint64(s))
+	default:
+		return errors.New("invalid value size")
+	}
+	attr := struct {
+		mapFD uint32
+		_     uint32
+		key   uint64
+		value uint64
+		flags uint64
+	}{mapFD: uint32(m.fd), key: uint64(uintptr(unsafe.Pointer(&key[0]))), value: uint64(uintptr(unsafe.Pointer(&v[0])))}
+	_, err := bpf(sysBPF_MAP_UPDATE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(&v)
+	return err
+}
+
+func (m *SockMap) delete(key []byte) error {
+	attr := struct {
+		mapFD uint32
+		_     uint32
+		key   uint64
+	}{mapFD: uint32(m.fd), key: uint64(uintptr(unsafe.Pointer(&key[0])))}
+	_, err := bpf(sysBPF_MAP_DELETE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	return err
+}
+
+func (m *SockMap) attach(fd int, a SockMapAttach) error {
+	return m.progAttach(sysBPF_PROG_ATTACH, fd, a)
+}
+
+func (m *SockMap) detach(fd int, a SockMapAttach) error {
+	return m.progAttach(sysBPF_PROG_DETACH, fd, a)
+}
+
+func (m *SockMap) progAttach(cmd uintptr, fd int, a SockMapAttach) error {
+	if a < 0 || int(a) >= len(sockMapAttachTypes) {
+		return errors.New("invalid attachment point")
+	}
+	attr := struct {
+		targetFD     uint32
+		attachBPFFD  uint32
+		attachType   uint32
+		attachFlags  uint32
+		replaceBPFFD uint32
+	}{targetFD: uint32(m.fd), attachBPFFD: uint32(fd), attachType: sockMapAttachTypes[a]}
+	_, err := bpf(cmd, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
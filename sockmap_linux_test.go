@@ -0,0 +1,87 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"encoding/binary"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/mikioh/tcp"
+	"github.com/mikioh/tcp/tcptest"
+)
+
+// createSockMap creates an eBPF map of BPF_MAP_TYPE_SOCKMAP type.
+func createSockMap(t *testing.T, maxEntries uint32) int {
+	nr := map[string]uintptr{"amd64": 321, "arm64": 280}[runtime.GOARCH]
+	if nr == 0 {
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	attr := struct {
+		mapType    uint32
+		keySize    uint32
+		valueSize  uint32
+		maxEntries uint32
+		mapFlags   uint32
+	}{mapType: 0xf /* BPF_MAP_TYPE_SOCKMAP */, keySize: 4, valueSize: 4, maxEntries: maxEntries}
+	fd, _, errno := syscall.Syscall(nr, 0x0 /* BPF_MAP_CREATE */, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	if errno != 0 {
+		t.Skipf("creating sockmap: %v", errno) // requires CAP_BPF or CAP_SYS_ADMIN
+	}
+	return int(fd)
+}
+
+func TestSockMap(t *testing.T) {
+	fd := createSockMap(t, 2)
+	m, err := tcp.NewSockMap(fd)
+	syscall.Close(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if m.IsHash() || m.KeySize() != 4 {
+		t.Fatalf("got hash=%v, key size=%d; want false, 4", m.IsHash(), m.KeySize())
+	}
+
+	c, _ := tcptest.Pair(t)
+	var key [4]byte
+	for i := uint32(0); i < 2; i++ {
+		binary.NativeEndian.PutUint32(key[:], i)
+		if err := m.Add(key[:], c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(key[:2], c); err == nil {
+		t.Fatal("got nil; want invalid key size error")
+	}
+	if err := m.Delete(key[:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(key[:]); err == nil {
+		t.Fatal("got nil; want non-existent key error")
+	}
+	binary.NativeEndian.PutUint32(key[:], 2)
+	if err := m.Add(key[:], c); err == nil {
+		t.Fatal("got nil; want out of range error")
+	}
+	if err := m.AttachProgram(fd, tcp.AttachStreamVerdict); err == nil {
+		t.Fatal("got nil; want bad file descriptor error")
+	}
+}
+
+func TestNewSockMap(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if _, err := tcp.NewSockMap(int(r.Fd())); err == nil {
+		t.Fatal("got nil; want not a socket map error")
+	}
+}
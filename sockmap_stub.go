@@ -0,0 +1,16 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func newSockMap(fd int) (*SockMap, error)             { return nil, ErrNotSupported }
+func openPinnedSockMap(path string) (*SockMap, error) { return nil, ErrNotSupported }
+
+func (m *SockMap) close() error                         { return ErrNotSupported }
+func (m *SockMap) update(key []byte, s uintptr) error   { return ErrNotSupported }
+func (m *SockMap) delete(key []byte) error              { return ErrNotSupported }
+func (m *SockMap) attach(fd int, a SockMapAttach) error { return ErrNotSupported }
+func (m *SockMap) detach(fd int, a SockMapAttach) error { return ErrNotSupported }
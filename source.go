@@ -0,0 +1,113 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// A SourcePolicy represents a policy of Dialer selecting the local
+// address bound to the socket before connecting to the remote
+// address dst on the named network. It returns the zero value when
+// the policy has no preference, and the kernel selects the local
+// address.
+type SourcePolicy func(network string, dst net.IPAddr) net.IPAddr
+
+// PreferInterface returns the policy preferring the address assigned
+// to the network interface of name, which belongs to the same
+// address family and, for IPv6, the same scope as the remote
+// address.
+// The addresses of the interface are looked up on each selection.
+func PreferInterface(name string) SourcePolicy {
+	return func(network string, dst net.IPAddr) net.IPAddr {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			return net.IPAddr{}
+		}
+		ifat, err := ifi.Addrs()
+		if err != nil {
+			return net.IPAddr{}
+		}
+		for _, ifa := range ifat {
+			ipn, ok := ifa.(*net.IPNet)
+			if !ok || !sameFamily(ipn.IP, dst.IP) || ipn.IP.IsLinkLocalUnicast() != dst.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			src := net.IPAddr{IP: ipn.IP}
+			if src.IP.To4() == nil && src.IP.IsLinkLocalUnicast() {
+				src.Zone = ifi.Name
+			}
+			return src
+		}
+		return net.IPAddr{}
+	}
+}
+
+// PreferSubnet returns the policy preferring the local address that
+// belongs to the first subnet in subnets that contains any of the
+// addresses assigned to the network interfaces with the same address
+// family as the remote address.
+// The addresses of the interfaces are looked up on each selection.
+func PreferSubnet(subnets ...*net.IPNet) SourcePolicy {
+	return func(network string, dst net.IPAddr) net.IPAddr {
+		ifat, err := net.InterfaceAddrs()
+		if err != nil {
+			return net.IPAddr{}
+		}
+		for _, subnet := range subnets {
+			for _, ifa := range ifat {
+				ipn, ok := ifa.(*net.IPNet)
+				if ok && sameFamily(ipn.IP, dst.IP) && subnet.Contains(ipn.IP) {
+					return net.IPAddr{IP: ipn.IP}
+				}
+			}
+		}
+		return net.IPAddr{}
+	}
+}
+
+// RoundRobin returns the policy selecting the local address among
+// addrs with the same address family as the remote address in
+// rotation, which spreads the connections across multiple uplinks
+// deterministically.
+func RoundRobin(addrs ...net.IP) SourcePolicy {
+	var next uint64
+	return func(network string, dst net.IPAddr) net.IPAddr {
+		var candidates []net.IP
+		for _, ip := range addrs {
+			if sameFamily(ip, dst.IP) {
+				candidates = append(candidates, ip)
+			}
+		}
+		if len(candidates) == 0 {
+			return net.IPAddr{}
+		}
+		n := atomic.AddUint64(&next, 1) - 1
+		return net.IPAddr{IP: candidates[n%uint64(len(candidates))]}
+	}
+}
+
+// sourceAddr returns the local address bound before connecting to
+// the remote address dst, or nil when the kernel selects the local
+// address. The port of LocalAddr, if any, is kept.
+func (d *Dialer) sourceAddr(network string, dst net.IPAddr) net.Addr {
+	if d.SourceAddrs == nil {
+		return d.LocalAddr
+	}
+	src := d.SourceAddrs(network, dst)
+	if src.IP == nil {
+		return d.LocalAddr
+	}
+	la := &net.TCPAddr{IP: src.IP, Zone: src.Zone}
+	if tla, ok := d.LocalAddr.(*net.TCPAddr); ok {
+		la.Port = tla.Port
+	}
+	return la
+}
+
+func sameFamily(ip1, ip2 net.IP) bool {
+	return (ip1.To4() != nil) == (ip2.To4() != nil)
+}
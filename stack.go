@@ -0,0 +1,43 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "errors"
+
+// SetStack switches the TCP stack of the connection to the one with
+// the name, such as "freebsd", "rack" or "bbr". The stack must be
+// loaded in the kernel, and a stack may refuse to take over the
+// connection in some states; switching before connecting always
+// works.
+// It is analogous to the selection of congestion control algorithm
+// on Linux, but replaces the whole TCP implementation of the
+// connection.
+//
+// Only FreeBSD supports this feature.
+// See TCP_FUNCTION_BLK for further information.
+func (c *Conn) SetStack(name string) error {
+	if name == "" {
+		return c.opError("set", errors.New("empty stack name"))
+	}
+	if err := c.control(func(s uintptr) error { return setStack(s, name) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// Stack returns the name of the TCP stack of the connection.
+//
+// Only FreeBSD supports this feature.
+func (c *Conn) Stack() (string, error) {
+	var name string
+	err := c.control(func(s uintptr) (err error) {
+		name, err = stack(s)
+		return
+	})
+	if err != nil {
+		return "", c.opError("get", err)
+	}
+	return name, nil
+}
@@ -0,0 +1,35 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+)
+
+func setStack(s uintptr, name string) error {
+	if len(name) >= sysTCP_FUNCTION_NAME_LEN_MAX {
+		return errors.New("stack name too long")
+	}
+	var b [sizeofTCPFunctionSet]byte
+	copy(b[:sysTCP_FUNCTION_NAME_LEN_MAX], name)
+	if err := setsockopt(s, ianaProtocolTCP, sysTCP_FUNCTION_BLK, b[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func stack(s uintptr) (string, error) {
+	var b [sizeofTCPFunctionSet]byte
+	if _, err := getsockopt(s, ianaProtocolTCP, sysTCP_FUNCTION_BLK, b[:]); err != nil {
+		return "", os.NewSyscallError("getsockopt", err)
+	}
+	name := b[:sysTCP_FUNCTION_NAME_LEN_MAX]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return string(name), nil
+}
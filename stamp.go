@@ -0,0 +1,56 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoTimestamp is returned by ReceiveTimestamp when the kernel has
+// no receive time recorded for the unread data.
+var ErrNoTimestamp = errors.New("no receive timestamp")
+
+// EnableReceiveTimestamps enables the timestamping of received
+// segments on the connection, which ReceiveTimestamp requires.
+//
+// The segments received before the call are not timestamped. The
+// kernel turns on the timestamping asynchronously when no socket on
+// the host has used it, and the segments received shortly after the
+// call may not be timestamped either.
+//
+// Only Linux supports this feature.
+// See SO_TIMESTAMPNS for further information.
+func (c *Conn) EnableReceiveTimestamps() error {
+	if err := c.control(enableReceiveTimestamps); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// ReceiveTimestamp returns the time when the kernel received the
+// segment carrying the next byte to be read on the connection.
+// It allows latency measurements to use the kernel receive time
+// instead of the time when the data is read in user space.
+//
+// It peeks at the data without consuming it, and requires
+// EnableReceiveTimestamps to be called before the data arrives. It
+// returns an error wrapping syscall.ENOENT when no unread data is
+// available, and an error wrapping ErrNoTimestamp when the kernel has
+// no timestamp recorded for the data.
+//
+// Only Linux supports this feature.
+// See SO_TIMESTAMPNS for further information.
+func (c *Conn) ReceiveTimestamp() (time.Time, error) {
+	var t time.Time
+	err := c.control(func(s uintptr) (err error) {
+		t, err = receiveTimestamp(s)
+		return
+	})
+	if err != nil {
+		return time.Time{}, c.opError("get", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+func enableReceiveTimestamps(s uintptr) error {
+	v := boolint32(true)
+	if err := setsockopt(s, sysSOL_SOCKET, sysSO_TIMESTAMPNS, (*[4]byte)(unsafe.Pointer(&v))[:]); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func receiveTimestamp(s uintptr) (time.Time, error) {
+	// The kernel passes the receive time of TCP segments only as
+	// ancillary data on receiving; peeking a byte leaves the data
+	// readable.
+	var b [1]byte
+	var ts syscall.Timespec
+	oob := make([]byte, syscall.CmsgSpace(int(unsafe.Sizeof(ts))))
+	for {
+		n, oobn, _, _, err := syscall.Recvmsg(int(s), b[:], oob, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err == syscall.EAGAIN || err == nil && n == 0 {
+			return time.Time{}, os.NewSyscallError("recvmsg", syscall.ENOENT)
+		}
+		if err != nil {
+			return time.Time{}, os.NewSyscallError("recvmsg", err)
+		}
+		cms, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			return time.Time{}, os.NewSyscallError("recvmsg", err)
+		}
+		for _, cm := range cms {
+			if cm.Header.Level == sysSOL_SOCKET && cm.Header.Type == sysSO_TIMESTAMPNS && len(cm.Data) >= int(unsafe.Sizeof(ts)) {
+				ts = *(*syscall.Timespec)(unsafe.Pointer(&cm.Data[0]))
+				return time.Unix(ts.Unix()), nil
+			}
+		}
+		return time.Time{}, ErrNoTimestamp
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+import "time"
+
+func enableReceiveTimestamps(s uintptr) error { return ErrNotSupported }
+
+func receiveTimestamp(s uintptr) (time.Time, error) { return time.Time{}, ErrNotSupported }
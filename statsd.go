@@ -0,0 +1,161 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A StatsDConfig represents the configuration of StatsDSink.
+type StatsDConfig struct {
+	// Prefix is the prefix of metric names, such as "myapp.tcp".
+	// A zero value means "tcp".
+	Prefix string
+
+	// GroupBy is the list of label keys that identify a group of
+	// connections. The metrics of the connections in the same group
+	// share the same names, or tags, and are aggregated by the
+	// StatsD server; the connections without a label are grouped
+	// under the value "none" of the label.
+	GroupBy []string
+
+	// DogStatsD specifies the use of DogStatsD tags for the groups.
+	// When false, the values of GroupBy labels are embedded in the
+	// metric names, such as "tcp.blue.db.rtt".
+	DogStatsD bool
+
+	// MaxPacketSize is the maximum size of each write in bytes.
+	// A zero value means 1432, which fits in a UDP datagram on the
+	// Ethernet.
+	MaxPacketSize int
+}
+
+// A StatsDSink represents a sink that emits the statistics of
+// connection groups over StatsD.
+//
+// For each recorded statistics it emits the RTT as a timer, and the
+// counters of SampleDelta as counters, such as "tcp.rtt" and
+// "tcp.retransmits"; the counters are emitted only when the deltas
+// are available.
+type StatsDSink struct {
+	w   io.Writer
+	cfg StatsDConfig
+
+	mu  sync.Mutex
+	buf []byte
+	err error
+}
+
+// NewStatsDSink returns a new sink that writes the metrics to w,
+// which is usually a UDP connection to the StatsD server.
+func NewStatsDSink(w io.Writer, cfg *StatsDConfig) *StatsDSink {
+	k := &StatsDSink{w: w, cfg: *cfg}
+	if k.cfg.Prefix == "" {
+		k.cfg.Prefix = "tcp"
+	}
+	if k.cfg.MaxPacketSize <= 0 {
+		k.cfg.MaxPacketSize = 1432
+	}
+	return k
+}
+
+// Err returns the last error in writing the metrics.
+func (k *StatsDSink) Err() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.err
+}
+
+// Record implements the Record method of Sink interface.
+func (k *StatsDSink) Record(connID string, labels Labels, stats *Stats) {
+	name, tags := k.group(labels)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if stats.Info != nil {
+		k.emit(name, "rtt", strconv.FormatFloat(float64(stats.Info.RTT.Microseconds())/1e3, 'f', -1, 64), "ms", tags)
+	}
+	if d := stats.Delta; d != nil {
+		for _, m := range []struct {
+			name string
+			v    uint64
+		}{
+			{"bytes_acked", d.BytesAcked},
+			{"bytes_received", d.BytesReceived},
+			{"segs_out", d.SegsOut},
+			{"segs_in", d.SegsIn},
+			{"retransmits", d.Retransmits},
+		} {
+			k.emit(name, m.name, strconv.FormatUint(m.v, 10), "c", tags)
+		}
+	}
+	k.flush()
+}
+
+// group returns the prefix of metric names and the DogStatsD tags
+// for labels.
+func (k *StatsDSink) group(labels Labels) (string, string) {
+	name := k.cfg.Prefix
+	var tags []string
+	for _, key := range k.cfg.GroupBy {
+		v, ok := labels[key]
+		if !ok {
+			v = "none"
+		}
+		if k.cfg.DogStatsD {
+			tags = append(tags, statsDEscape(key)+":"+statsDEscape(v))
+		} else {
+			name += "." + statsDEscape(v)
+		}
+	}
+	if len(tags) == 0 {
+		return name, ""
+	}
+	return name, "|#" + strings.Join(tags, ",")
+}
+
+// emit appends a metric line to the buffer, flushing the buffer
+// first when the line doesn't fit in the packet.
+func (k *StatsDSink) emit(prefix, name, value, typ, tags string) {
+	n := len(prefix) + 1 + len(name) + 1 + len(value) + 1 + len(typ) + len(tags)
+	if len(k.buf) > 0 && len(k.buf)+1+n > k.cfg.MaxPacketSize {
+		k.flush()
+	}
+	if len(k.buf) > 0 {
+		k.buf = append(k.buf, '\n')
+	}
+	k.buf = append(k.buf, prefix...)
+	k.buf = append(k.buf, '.')
+	k.buf = append(k.buf, name...)
+	k.buf = append(k.buf, ':')
+	k.buf = append(k.buf, value...)
+	k.buf = append(k.buf, '|')
+	k.buf = append(k.buf, typ...)
+	k.buf = append(k.buf, tags...)
+}
+
+func (k *StatsDSink) flush() {
+	if len(k.buf) == 0 {
+		return
+	}
+	if _, err := k.w.Write(k.buf); err != nil {
+		k.err = err
+	}
+	k.buf = k.buf[:0]
+}
+
+// statsDEscape replaces the characters reserved by the StatsD and
+// DogStatsD protocols in s with underscores.
+func statsDEscape(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '.', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
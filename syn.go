@@ -0,0 +1,120 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// A SYN represents the SYN segment that opened a connection.
+type SYN struct {
+	Header        []byte // IP and TCP headers of the segment
+	TTL           int    // TTL or hop limit
+	Window        int    // unscaled receive window
+	MSS           int    // maximum segment size; zero when not present
+	WindowScale   int    // shift count of window scale; -1 when not present
+	SACKPermitted bool   // whether the SACK-permitted option is present
+	Timestamps    bool   // whether the timestamps option is present
+	FastOpen      bool   // whether the Fast Open option is present
+	ECN           bool   // whether the ECE and CWR flags are set for ECN setup
+}
+
+// SavedSYN returns the SYN segment that opened the connection.
+// It requires the SaveSYN option set on the listener before the
+// connection is accepted, and is available only once since the
+// kernel frees the saved headers on the first read.
+//
+// Only Linux supports this feature.
+// See TCP_SAVED_SYN for further information.
+func (c *Conn) SavedSYN() (*SYN, error) {
+	var b []byte
+	err := c.control(func(s uintptr) (err error) {
+		b, err = savedSYN(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	syn, err := parseSYN(b)
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return syn, nil
+}
+
+// IPv6 extension headers preceding the TCP header of a saved SYN
+// segment.
+const (
+	ipv6HopByHop = 0
+	ipv6Routing  = 43
+	ipv6DestOpts = 60
+)
+
+func parseSYN(b []byte) (*SYN, error) {
+	if len(b) == 0 {
+		return nil, errors.New("no saved SYN")
+	}
+	syn := SYN{Header: b, WindowScale: -1}
+	var off, proto int
+	switch b[0] >> 4 {
+	case 4:
+		if len(b) < 20 {
+			return nil, errors.New("short IPv4 header")
+		}
+		off, proto, syn.TTL = int(b[0]&0x0f)<<2, int(b[9]), int(b[8])
+	case 6:
+		if len(b) < 40 {
+			return nil, errors.New("short IPv6 header")
+		}
+		off, proto, syn.TTL = 40, int(b[6]), int(b[7])
+		for proto == ipv6HopByHop || proto == ipv6Routing || proto == ipv6DestOpts {
+			if len(b) < off+2 {
+				return nil, errors.New("short IPv6 extension header")
+			}
+			proto, off = int(b[off]), off+(int(b[off+1])+1)<<3
+		}
+	default:
+		return nil, errors.New("unknown IP version")
+	}
+	if proto != ianaProtocolTCP || len(b) < off+20 {
+		return nil, errors.New("no TCP header")
+	}
+	h := b[off:]
+	hlen := int(h[12]>>4) << 2
+	if hlen < 20 || len(h) < hlen {
+		return nil, errors.New("short TCP header")
+	}
+	syn.ECN = h[13]&0xc0 == 0xc0
+	syn.Window = int(binary.BigEndian.Uint16(h[14:16]))
+	for opts := h[20:hlen]; len(opts) > 0; {
+		kind := opts[0]
+		if kind == 0 { // end of option list
+			break
+		}
+		if kind == 1 { // no-operation
+			opts = opts[1:]
+			continue
+		}
+		if len(opts) < 2 || int(opts[1]) < 2 || len(opts) < int(opts[1]) {
+			return nil, errors.New("malformed TCP option")
+		}
+		v := opts[2:opts[1]]
+		switch {
+		case kind == 2 && len(v) == 2:
+			syn.MSS = int(binary.BigEndian.Uint16(v))
+		case kind == 3 && len(v) == 1:
+			syn.WindowScale = int(v[0])
+		case kind == 4:
+			syn.SACKPermitted = true
+		case kind == 8:
+			syn.Timestamps = true
+		case kind == 34, kind == 254 && len(v) >= 2 && binary.BigEndian.Uint16(v) == 0xf989:
+			syn.FastOpen = true
+		}
+		opts = opts[opts[1]:]
+	}
+	return &syn, nil
+}
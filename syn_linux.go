@@ -0,0 +1,34 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"os"
+	"syscall"
+)
+
+func savedSYN(s uintptr) ([]byte, error) {
+	b := make([]byte, 512)
+	n, err := getsockopt(s, ianaProtocolTCP, sysTCP_SAVED_SYN, b)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockopt", err)
+	}
+	return b[:n], nil
+}
+
+func peek(s uintptr, b []byte) (int, error) {
+	for {
+		n, _, err := syscall.Recvfrom(int(s), b, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch err {
+		case nil:
+			return n, nil
+		case syscall.EINTR:
+			continue
+		case syscall.EAGAIN:
+			return 0, nil
+		}
+		return 0, os.NewSyscallError("recvfrom", err)
+	}
+}
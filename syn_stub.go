@@ -0,0 +1,11 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func savedSYN(s uintptr) ([]byte, error) { return nil, ErrNotSupported }
+
+func peek(s uintptr, b []byte) (int, error) { return 0, ErrNotSupported }
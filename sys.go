@@ -30,9 +30,42 @@ const (
 const (
 	soBuffered = iota
 	soAvailable
+	soConnectionTimeout
+	soRetransmitConnDropTime
+	soUserTimeout
+	soCookie
+	soMemInfo
+	soFastOpenConnect
+	soReuseAddr
+	soReusePort
+	soWindowClamp
+	soSendBufferForce
+	soReceiveBufferForce
+	soInitialRTO
+	soFailConnectOnICMPError
+	soFastOpenNoCookie
+	soPriority
+	soMinRTO
+	soLinger2
+	soEnableECN
+	soNoOptions
+	soReceiveLowWMK
+	soSendLowWMK
+	soOOBInline
+	soUserCookie
+	soTimestamps
+	soDeferAccept
+	soSaveSYN
+	soLoopbackFastPath
+	soSendQueued
+	soUnsent
+	soReusePortLB
 	soMax
 )
 
+// maxOptionLen is the maximum length of socket option value.
+const maxOptionLen = 1 << 16
+
 type option struct {
 	level int // option level
 	name  int // option name, must be equal or greater than 1
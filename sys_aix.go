@@ -0,0 +1,30 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "syscall"
+
+var options [soMax]option
+
+func buffered(s uintptr) int  { return -1 }
+func available(s uintptr) int { return -1 }
+
+func setsockopt(s uintptr, level, name int, b []byte) error {
+	return syscall.SetsockoptString(int(s), level, name, string(b))
+}
+
+// getsockopt supports only 4-byte socket options because the syscall
+// package on AIX doesn't provide a generic interface.
+func getsockopt(s uintptr, level, name int, b []byte) (int, error) {
+	if len(b) < 4 {
+		return 0, ErrNotSupported
+	}
+	v, err := syscall.GetsockoptInt(int(s), level, name)
+	if err != nil {
+		return 0, err
+	}
+	nativeEndian.PutUint32(b, uint32(v))
+	return 4, nil
+}
@@ -6,14 +6,29 @@ package tcp
 
 import (
 	"encoding/binary"
+	"time"
 	"unsafe"
 )
 
 var options = [soMax]option{
-	soBuffered:  {0, sysFIONREAD},
-	soAvailable: {sysSOL_SOCKET, sysSO_NWRITE},
+	soBuffered:               {0, sysFIONREAD},
+	soAvailable:              {sysSOL_SOCKET, sysSO_NWRITE},
+	soSendQueued:             {sysSOL_SOCKET, sysSO_NWRITE},
+	soConnectionTimeout:      {ianaProtocolTCP, sysTCP_CONNECTIONTIMEOUT},
+	soRetransmitConnDropTime: {ianaProtocolTCP, sysTCP_RXT_CONNDROPTIME},
+	soReuseAddr:              {sysSOL_SOCKET, sysSO_REUSEADDR},
+	soReusePort:              {sysSOL_SOCKET, sysSO_REUSEPORT},
+	soReceiveLowWMK:          {sysSOL_SOCKET, sysSO_RCVLOWAT},
+	soSendLowWMK:             {sysSOL_SOCKET, sysSO_SNDLOWAT},
+	soOOBInline:              {sysSOL_SOCKET, sysSO_OOBINLINE},
+	soEnableECN:              {ianaProtocolTCP, sysTCP_ENABLE_ECN},
+	soNoOptions:              {ianaProtocolTCP, sysTCP_NOOPT},
 }
 
+// connectionTimeoutUnit is the unit of ConnectionTimeout value.
+// TCP_CONNECTIONTIMEOUT takes seconds.
+const connectionTimeoutUnit = time.Second
+
 func (nl *pfiocNatlook) rdPort() int {
 	return int(binary.BigEndian.Uint16(nl.Rdxport[:2]))
 }
@@ -6,13 +6,24 @@ package tcp
 
 import (
 	"encoding/binary"
+	"time"
 	"unsafe"
 )
 
 var options = [soMax]option{
-	soBuffered: {0, sysFIONREAD},
+	soBuffered:          {0, sysFIONREAD},
+	soConnectionTimeout: {ianaProtocolTCP, sysTCP_KEEPINIT},
+	soReuseAddr:         {sysSOL_SOCKET, sysSO_REUSEADDR},
+	soReusePort:         {sysSOL_SOCKET, sysSO_REUSEPORT},
+	soReceiveLowWMK:     {sysSOL_SOCKET, sysSO_RCVLOWAT},
+	soSendLowWMK:        {sysSOL_SOCKET, sysSO_SNDLOWAT},
+	soOOBInline:         {sysSOL_SOCKET, sysSO_OOBINLINE},
 }
 
+// connectionTimeoutUnit is the unit of ConnectionTimeout value.
+// TCP_KEEPINIT takes milliseconds on DragonFly BSD.
+const connectionTimeoutUnit = time.Millisecond
+
 func (nl *pfiocNatlook) rdPort() int {
 	return int(binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&nl.Rdport))[:]))
 }
@@ -6,14 +6,28 @@ package tcp
 
 import (
 	"encoding/binary"
+	"time"
 	"unsafe"
 )
 
 var options = [soMax]option{
-	soBuffered:  {0, sysFIONREAD},
-	soAvailable: {0, sysFIONSPACE},
+	soBuffered:          {0, sysFIONREAD},
+	soAvailable:         {0, sysFIONSPACE},
+	soSendQueued:        {0, sysFIONWRITE},
+	soConnectionTimeout: {ianaProtocolTCP, sysTCP_KEEPINIT},
+	soReuseAddr:         {sysSOL_SOCKET, sysSO_REUSEADDR},
+	soReusePort:         {sysSOL_SOCKET, sysSO_REUSEPORT},
+	soReusePortLB:       {sysSOL_SOCKET, sysSO_REUSEPORT_LB},
+	soReceiveLowWMK:     {sysSOL_SOCKET, sysSO_RCVLOWAT},
+	soSendLowWMK:        {sysSOL_SOCKET, sysSO_SNDLOWAT},
+	soOOBInline:         {sysSOL_SOCKET, sysSO_OOBINLINE},
+	soUserCookie:        {sysSOL_SOCKET, sysSO_USER_COOKIE},
 }
 
+// connectionTimeoutUnit is the unit of ConnectionTimeout value.
+// TCP_KEEPINIT takes seconds on FreeBSD.
+const connectionTimeoutUnit = time.Second
+
 func (nl *pfiocNatlook) rdPort() int {
 	return int(binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&nl.Rdport))[:]))
 }
@@ -0,0 +1,35 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package tcp
+
+import "syscall"
+
+// sendSpace returns the amount of space in the socket send buffer by
+// using the EVFILT_WRITE filter of kqueue.
+// It returns 0 when the space is less than the socket send low-water
+// mark.
+func sendSpace(s uintptr) int {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return -1
+	}
+	defer syscall.Close(kq)
+	var chg, ev [1]syscall.Kevent_t
+	syscall.SetKevent(&chg[0], int(s), syscall.EVFILT_WRITE, syscall.EV_ADD|syscall.EV_ONESHOT)
+	var ts syscall.Timespec
+	n, err := syscall.Kevent(kq, chg[:], ev[:], &ts)
+	if err != nil {
+		return -1
+	}
+	if n == 0 {
+		return 0
+	}
+	if ev[0].Flags&syscall.EV_ERROR != 0 {
+		return -1
+	}
+	return int(ev[0].Data)
+}
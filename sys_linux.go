@@ -4,7 +4,35 @@
 
 package tcp
 
+import "time"
+
 var options = [soMax]option{
-	soBuffered:  {0, sysSIOCINQ},
-	soAvailable: {0, sysSIOCOUTQ},
+	soBuffered:    {0, sysSIOCINQ},
+	soAvailable:   {0, sysSIOCOUTQ},
+	soSendQueued:  {0, sysSIOCOUTQ},
+	soUnsent:      {0, sysSIOCOUTQNSD},
+	soUserTimeout: {ianaProtocolTCP, sysTCP_USER_TIMEOUT},
+	soCookie:      {sysSOL_SOCKET, sysSO_COOKIE},
+	soMemInfo:     {sysSOL_SOCKET, sysSO_MEMINFO},
+
+	soFastOpenConnect:    {ianaProtocolTCP, sysTCP_FASTOPEN_CONNECT},
+	soFastOpenNoCookie:   {ianaProtocolTCP, sysTCP_FASTOPEN_NO_COOKIE},
+	soReuseAddr:          {sysSOL_SOCKET, sysSO_REUSEADDR},
+	soReusePort:          {sysSOL_SOCKET, sysSO_REUSEPORT},
+	soWindowClamp:        {ianaProtocolTCP, sysTCP_WINDOW_CLAMP},
+	soSendBufferForce:    {sysSOL_SOCKET, sysSO_SNDBUFFORCE},
+	soReceiveBufferForce: {sysSOL_SOCKET, sysSO_RCVBUFFORCE},
+	soPriority:           {sysSOL_SOCKET, sysSO_PRIORITY},
+	soMinRTO:             {ianaProtocolTCP, sysTCP_RTO_MIN_US},
+	soLinger2:            {ianaProtocolTCP, sysTCP_LINGER2},
+	soReceiveLowWMK:      {sysSOL_SOCKET, sysSO_RCVLOWAT},
+	soOOBInline:          {sysSOL_SOCKET, sysSO_OOBINLINE},
+	soDeferAccept:        {ianaProtocolTCP, sysTCP_DEFER_ACCEPT},
+	soSaveSYN:            {ianaProtocolTCP, sysTCP_SAVE_SYN},
 }
+
+// userTimeoutUnit is the unit of UserTimeout value.
+// TCP_USER_TIMEOUT takes milliseconds.
+const userTimeoutUnit = time.Millisecond
+
+func sendSpace(s uintptr) int { return -1 }
@@ -17,6 +17,7 @@ func ioctl(s uintptr, ioc int, b []byte) error {
 }
 
 const (
+	sysCONNECT    = 0x3
 	sysSETSOCKOPT = 0xe
 	sysGETSOCKOPT = 0xf
 )
@@ -26,15 +27,22 @@ func socketcall(call int, a0, a1, a2, a3, a4, a5 uintptr) (int, syscall.Errno)
 func setsockopt(s uintptr, level, name int, b []byte) error {
 	l := uint32(len(b))
 	if _, errno := socketcall(sysSETSOCKOPT, s, uintptr(level), uintptr(name), uintptr(unsafe.Pointer(&b[0])), uintptr(l), 0); errno != 0 {
+		return sockoptError(errno)
+	}
+	return nil
+}
+
+func connect(s uintptr, sa []byte) error {
+	if _, errno := socketcall(sysCONNECT, s, uintptr(unsafe.Pointer(&sa[0])), uintptr(len(sa)), 0, 0, 0); errno != 0 {
 		return error(errno)
 	}
 	return nil
 }
 
-func getsockopt(s uintptr, level, name int, b []byte) error {
+func getsockopt(s uintptr, level, name int, b []byte) (int, error) {
 	l := uint32(len(b))
 	if _, errno := socketcall(sysGETSOCKOPT, s, uintptr(level), uintptr(name), uintptr(unsafe.Pointer(&b[0])), uintptr(unsafe.Pointer(&l)), 0); errno != 0 {
-		return error(errno)
+		return 0, sockoptError(errno)
 	}
-	return nil
+	return int(l), nil
 }
@@ -5,6 +5,12 @@
 package tcp
 
 var options = [soMax]option{
-	soBuffered:  {0, sysFIONREAD},
-	soAvailable: {0, sysFIONSPACE},
+	soBuffered:      {0, sysFIONREAD},
+	soAvailable:     {0, sysFIONSPACE},
+	soSendQueued:    {0, sysFIONWRITE},
+	soReuseAddr:     {sysSOL_SOCKET, sysSO_REUSEADDR},
+	soReusePort:     {sysSOL_SOCKET, sysSO_REUSEPORT},
+	soReceiveLowWMK: {sysSOL_SOCKET, sysSO_RCVLOWAT},
+	soSendLowWMK:    {sysSOL_SOCKET, sysSO_SNDLOWAT},
+	soOOBInline:     {sysSOL_SOCKET, sysSO_OOBINLINE},
 }
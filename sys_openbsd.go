@@ -10,7 +10,12 @@ import (
 )
 
 var options = [soMax]option{
-	soBuffered: {0, sysFIONREAD},
+	soBuffered:      {0, sysFIONREAD},
+	soReuseAddr:     {sysSOL_SOCKET, sysSO_REUSEADDR},
+	soReusePort:     {sysSOL_SOCKET, sysSO_REUSEPORT},
+	soReceiveLowWMK: {sysSOL_SOCKET, sysSO_RCVLOWAT},
+	soSendLowWMK:    {sysSOL_SOCKET, sysSO_SNDLOWAT},
+	soOOBInline:     {sysSOL_SOCKET, sysSO_OOBINLINE},
 }
 
 func (nl *pfiocNatlook) rdPort() int {
@@ -43,10 +43,10 @@ func setsockopt(s uintptr, level, name int, b []byte) error {
 	return nil
 }
 
-func getsockopt(s uintptr, level, name int, b []byte) error {
+func getsockopt(s uintptr, level, name int, b []byte) (int, error) {
 	l := uint32(len(b))
 	if _, _, errno := rtsysvicall6(uintptr(unsafe.Pointer(libcGetsockopt)), 5, s, uintptr(level), uintptr(name), uintptr(unsafe.Pointer(&b[0])), uintptr(unsafe.Pointer(&l)), 0); errno != 0 {
-		return error(errno)
+		return 0, error(errno)
 	}
-	return nil
+	return int(l), nil
 }
@@ -23,9 +23,12 @@ func buffered(s uintptr) int {
 }
 
 func available(s uintptr) int {
+	if options[soAvailable].name < 1 {
+		return sendSpace(s)
+	}
 	var b [4]byte
 	if runtime.GOOS == "darwin" {
-		if err := getsockopt(s, options[soAvailable].level, options[soAvailable].name, b[:]); err != nil {
+		if _, err := getsockopt(s, options[soAvailable].level, options[soAvailable].name, b[:]); err != nil {
 			return -1
 		}
 	} else {
@@ -36,7 +39,7 @@ func available(s uintptr) int {
 	n := int(nativeEndian.Uint32(b[:]))
 	if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
 		var o tcpopt.SendBuffer
-		if err := getsockopt(s, o.Level(), o.Name(), b[:]); err != nil {
+		if _, err := getsockopt(s, o.Level(), o.Name(), b[:]); err != nil {
 			return -1
 		}
 		return int(nativeEndian.Uint32(b[:])) - n
@@ -54,15 +57,22 @@ func ioctl(s uintptr, ioc int, b []byte) error {
 func setsockopt(s uintptr, level, name int, b []byte) error {
 	l := uint32(len(b))
 	if _, _, errno := syscall.Syscall6(syscall.SYS_SETSOCKOPT, s, uintptr(level), uintptr(name), uintptr(unsafe.Pointer(&b[0])), uintptr(l), 0); errno != 0 {
+		return sockoptError(errno)
+	}
+	return nil
+}
+
+func connect(s uintptr, sa []byte) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_CONNECT, s, uintptr(unsafe.Pointer(&sa[0])), uintptr(len(sa))); errno != 0 {
 		return error(errno)
 	}
 	return nil
 }
 
-func getsockopt(s uintptr, level, name int, b []byte) error {
+func getsockopt(s uintptr, level, name int, b []byte) (int, error) {
 	l := uint32(len(b))
 	if _, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, s, uintptr(level), uintptr(name), uintptr(unsafe.Pointer(&b[0])), uintptr(unsafe.Pointer(&l)), 0); errno != 0 {
-		return error(errno)
+		return 0, sockoptError(errno)
 	}
-	return nil
+	return int(l), nil
 }
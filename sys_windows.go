@@ -5,7 +5,6 @@
 package tcp
 
 import (
-	"errors"
 	"os"
 	"sync"
 	"syscall"
@@ -15,7 +14,40 @@ import (
 	"github.com/mikioh/tcpopt"
 )
 
-var options [soMax]option
+const (
+	sysTCP_MAXRT                      = 0x5
+	sysTCP_TIMESTAMPS                 = 0xa
+	sysTCP_FAIL_CONNECT_ON_ICMP_ERROR = 0x12
+	sysTCP_ICMP_ERROR_INFO            = 0x13
+
+	sysSIO_LOOPBACK_FAST_PATH = 0x98000010
+	sysSIO_TCP_INITIAL_RTO    = 0x98000011
+
+	sysSIO_QUERY_WFP_CONNECTION_REDIRECT_RECORDS = 0xd80000dc
+	sysSIO_QUERY_WFP_CONNECTION_REDIRECT_CONTEXT = 0xd80000dd
+	sysSIO_SET_WFP_CONNECTION_REDIRECT_RECORDS   = 0x980000de
+
+	// sysTCP_INITIAL_RTO is the pseudo option name of InitialRTO,
+	// which is set by SIO_TCP_INITIAL_RTO instead of setsockopt.
+	sysTCP_INITIAL_RTO = 0x10011
+
+	// sysTCP_LOOPBACK_FAST_PATH is the pseudo option name of
+	// LoopbackFastPath, which is set by SIO_LOOPBACK_FAST_PATH
+	// instead of setsockopt.
+	sysTCP_LOOPBACK_FAST_PATH = 0x10010
+)
+
+var options = [soMax]option{
+	soUserTimeout:            {ianaProtocolTCP, sysTCP_MAXRT},
+	soInitialRTO:             {ianaProtocolTCP, sysTCP_INITIAL_RTO},
+	soFailConnectOnICMPError: {ianaProtocolTCP, sysTCP_FAIL_CONNECT_ON_ICMP_ERROR},
+	soTimestamps:             {ianaProtocolTCP, sysTCP_TIMESTAMPS},
+	soLoopbackFastPath:       {ianaProtocolTCP, sysTCP_LOOPBACK_FAST_PATH},
+}
+
+// userTimeoutUnit is the unit of UserTimeout value.
+// TCP_MAXRT takes seconds.
+const userTimeoutUnit = time.Second
 
 func buffered(s uintptr) int  { return -1 }
 func available(s uintptr) int { return -1 }
@@ -60,13 +92,24 @@ func setsockopt(s uintptr, level, name int, b []byte) error {
 		}
 		return nil
 	}
+	if level == ianaProtocolTCP && (name == sysTCP_INITIAL_RTO || name == sysTCP_LOOPBACK_FAST_PATH) {
+		code := uint32(sysSIO_TCP_INITIAL_RTO)
+		if name == sysTCP_LOOPBACK_FAST_PATH {
+			code = sysSIO_LOOPBACK_FAST_PATH
+		}
+		rv := uint32(0)
+		if err := syscall.WSAIoctl(syscall.Handle(s), code, &b[0], uint32(len(b)), nil, 0, &rv, nil, 0); err != nil {
+			return os.NewSyscallError("wsaioctl", err)
+		}
+		return nil
+	}
 	if len(b) == 4 {
 		v := int(nativeEndian.Uint32(b))
 		return syscall.SetsockoptInt(syscall.Handle(s), level, name, v)
 	}
-	return errors.New("operation not supported")
+	return ErrNotSupported
 }
 
-func getsockopt(s uintptr, level, name int, b []byte) error {
-	return errors.New("operation not supported")
+func getsockopt(s uintptr, level, name int, b []byte) (int, error) {
+	return 0, ErrNotSupported
 }
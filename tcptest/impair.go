@@ -0,0 +1,141 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcptest
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrReset is the underlying error of the operations on the
+// connection reset by the impairment.
+var ErrReset = errors.New("connection reset by impairment")
+
+// An Impairment represents the impairments of network injected into
+// the connection.
+// The impairments are applied in the order of the operations on the
+// connection and don't depend on randomness.
+type Impairment struct {
+	Latency    time.Duration // delay of each write chunk
+	Bandwidth  int           // bytes per second of writes; zero means unlimited
+	WriteChunk int           // maximum bytes passed to each write of the underlying connection; zero means unlimited
+	ReadChunk  int           // maximum bytes returned from each read; zero means unlimited
+	ResetAfter int64         // bytes written before resetting the connection; zero means never
+}
+
+// An ImpairedConn represents a connection with the injected
+// impairments.
+type ImpairedConn struct {
+	net.Conn
+	imp Impairment
+
+	wmu     sync.Mutex // serializes writes
+	written int64      // bytes written
+
+	mu    sync.Mutex
+	reset bool
+}
+
+// Impair returns the connection c with the impairments imp.
+// The connection c may be a real connection such as tcp.Conn, or a
+// fake connection such as the one returned from net.Pipe.
+//
+// When resetting, the connection is aborted with a RST segment if c
+// supports it either by the Abort method of tcp.Conn or by the
+// SetLinger method of net.TCPConn; otherwise it is closed.
+func Impair(c net.Conn, imp Impairment) *ImpairedConn {
+	return &ImpairedConn{Conn: c, imp: imp}
+}
+
+// Unwrap returns the underlying connection.
+func (c *ImpairedConn) Unwrap() net.Conn { return c.Conn }
+
+// Read reads data from the connection.
+func (c *ImpairedConn) Read(b []byte) (int, error) {
+	if c.isReset() {
+		return 0, c.resetError("read")
+	}
+	if m := c.imp.ReadChunk; m > 0 && len(b) > m {
+		b = b[:m]
+	}
+	n, err := c.Conn.Read(b)
+	if err != nil && c.isReset() {
+		err = c.resetError("read")
+	}
+	return n, err
+}
+
+// Write writes data to the connection.
+func (c *ImpairedConn) Write(b []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	var n int
+	for n < len(b) {
+		if c.isReset() {
+			return n, c.resetError("write")
+		}
+		chunk := b[n:]
+		if m := c.imp.WriteChunk; m > 0 && len(chunk) > m {
+			chunk = chunk[:m]
+		}
+		if c.imp.ResetAfter > 0 {
+			if rem := c.imp.ResetAfter - c.written; int64(len(chunk)) > rem {
+				chunk = chunk[:rem]
+			}
+		}
+		c.delay(len(chunk))
+		m, err := c.Conn.Write(chunk)
+		n += m
+		c.written += int64(m)
+		if err != nil {
+			return n, err
+		}
+		if c.imp.ResetAfter > 0 && c.written >= c.imp.ResetAfter {
+			c.Reset()
+		}
+	}
+	return n, nil
+}
+
+// Reset resets the connection immediately.
+func (c *ImpairedConn) Reset() error {
+	c.mu.Lock()
+	if c.reset {
+		c.mu.Unlock()
+		return nil
+	}
+	c.reset = true
+	c.mu.Unlock()
+	switch cc := c.Conn.(type) {
+	case interface{ Abort() error }:
+		return cc.Abort()
+	case interface{ SetLinger(int) error }:
+		cc.SetLinger(0)
+	}
+	return c.Conn.Close()
+}
+
+func (c *ImpairedConn) isReset() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reset
+}
+
+func (c *ImpairedConn) resetError(op string) error {
+	return &net.OpError{Op: op, Net: c.LocalAddr().Network(), Source: c.LocalAddr(), Addr: c.RemoteAddr(), Err: ErrReset}
+}
+
+// delay sleeps for the latency and the transmission time of n bytes.
+func (c *ImpairedConn) delay(n int) {
+	d := c.imp.Latency
+	if c.imp.Bandwidth > 0 {
+		d += time.Duration(n) * time.Second / time.Duration(c.imp.Bandwidth)
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
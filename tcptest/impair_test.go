@@ -0,0 +1,85 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcptest_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mikioh/tcp/tcptest"
+)
+
+func TestImpair(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "plan9":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	t.Run("Chunk", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+		ic1 := tcptest.Impair(c1, tcptest.Impairment{WriteChunk: 3})
+		ic2 := tcptest.Impair(c2, tcptest.Impairment{ReadChunk: 2})
+		go ic1.Write([]byte("abcdefg"))
+		b := make([]byte, 8)
+		for _, want := range []string{"ab", "c", "de", "f", "g"} {
+			n, err := ic2.Read(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b[:n]) != want {
+				t.Fatalf("got %q; want %q", b[:n], want)
+			}
+		}
+	})
+	t.Run("Latency", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+		go io.Copy(io.Discard, c2)
+		ic := tcptest.Impair(c1, tcptest.Impairment{Latency: 10 * time.Millisecond, Bandwidth: 400, WriteChunk: 4})
+		start := time.Now()
+		if _, err := ic.Write(make([]byte, 8)); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d < 40*time.Millisecond {
+			t.Fatalf("got %v; want at least 40ms", d)
+		}
+	})
+	t.Run("Reset", func(t *testing.T) {
+		c, s := tcptest.Pair(t)
+		ic := tcptest.Impair(c, tcptest.Impairment{ResetAfter: 10})
+		if _, err := ic.Write(make([]byte, 8)); err != nil {
+			t.Fatal(err)
+		}
+		n, err := ic.Write(make([]byte, 8))
+		if n != 2 || !errors.Is(err, tcptest.ErrReset) {
+			t.Fatalf("got %d, %v; want 2, %v", n, err, tcptest.ErrReset)
+		}
+		if _, err := ic.Read(make([]byte, 1)); !errors.Is(err, tcptest.ErrReset) {
+			t.Fatalf("got %v; want %v", err, tcptest.ErrReset)
+		}
+		b := make([]byte, 16)
+		var total int
+		for {
+			n, err := s.Read(b)
+			total += n
+			if err != nil {
+				if err == io.EOF {
+					t.Fatal("got EOF; want connection reset")
+				}
+				break
+			}
+		}
+		if total > 10 {
+			t.Fatalf("got %d bytes; want at most 10", total)
+		}
+	})
+}
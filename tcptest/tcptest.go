@@ -0,0 +1,78 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tcptest provides utilities for testing the code that uses
+// package tcp.
+package tcptest
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mikioh/tcp"
+)
+
+// Pair returns a connected pair of TCP connections on the IPv4
+// loopback address.
+// It's equivalent to PairNetwork(t, "tcp4").
+func Pair(t testing.TB) (client, server *tcp.Conn) {
+	t.Helper()
+	return PairNetwork(t, "tcp4")
+}
+
+// PairNetwork returns a connected pair of TCP connections on the
+// loopback address of network, which must be "tcp", "tcp4" or
+// "tcp6". The client is the dialed end point and the server is the
+// accepted end point.
+//
+// The connections are closed when the test and its subtests
+// complete. PairNetwork skips the test when the network is not
+// available on the loopback interface, and fails the test on other
+// errors.
+func PairNetwork(t testing.TB, network string) (client, server *tcp.Conn) {
+	t.Helper()
+	var address string
+	switch network {
+	case "tcp", "tcp4":
+		address = "127.0.0.1:0"
+	case "tcp6":
+		address = "[::1]:0"
+	default:
+		t.Fatalf("unknown network: %s", network)
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		if network == "tcp6" {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ch := make(chan net.Conn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			close(ch)
+			return
+		}
+		ch <- c
+	}()
+	c, err := net.Dial(ln.Addr().Network(), ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	s, ok := <-ch
+	if !ok {
+		t.Fatal("failed to accept connection")
+	}
+	t.Cleanup(func() { s.Close() })
+	if client, err = tcp.NewConn(c); err != nil {
+		t.Fatal(err)
+	}
+	if server, err = tcp.NewConn(s); err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
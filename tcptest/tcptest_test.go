@@ -0,0 +1,35 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcptest_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/mikioh/tcp/tcptest"
+)
+
+func TestPairNetwork(t *testing.T) {
+	switch runtime.GOOS {
+	case "js", "plan9":
+		t.Skipf("not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+
+	for _, network := range []string{"tcp4", "tcp6"} {
+		t.Run(network, func(t *testing.T) {
+			c, s := tcptest.PairNetwork(t, network)
+			if c.LocalAddr().String() != s.RemoteAddr().String() || c.RemoteAddr().String() != s.LocalAddr().String() {
+				t.Fatalf("got %v->%v and %v->%v; want mirrored end points", c.LocalAddr(), c.RemoteAddr(), s.LocalAddr(), s.RemoteAddr())
+			}
+			if _, err := c.Write([]byte("a")); err != nil {
+				t.Fatal(err)
+			}
+			var b [1]byte
+			if _, err := s.Read(b[:]); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
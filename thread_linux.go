@@ -0,0 +1,33 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "runtime"
+
+// onThread calls fn on a dedicated thread moved to the network
+// namespace netns and the cgroup at path cgroup, either of which may
+// be empty. The thread is terminated after the call and never returns
+// to the Go scheduler.
+func onThread(netns, cgroup string, fn func()) error {
+	ch := make(chan error)
+	go func() {
+		runtime.LockOSThread() // never unlocked; the thread exits with the goroutine
+		if netns != "" {
+			if err := joinNetns(netns); err != nil {
+				ch <- err
+				return
+			}
+		}
+		if cgroup != "" {
+			if err := joinCgroup(cgroup); err != nil {
+				ch <- err
+				return
+			}
+		}
+		fn()
+		ch <- nil
+	}()
+	return <-ch
+}
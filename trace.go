@@ -0,0 +1,156 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+	"github.com/mikioh/tcpopt"
+)
+
+// defaultTraceInterval is the interval of checking state transitions
+// when no snapshot interval is specified.
+const defaultTraceInterval = time.Second
+
+// SetTrace turns on the trace mode of the connection. In the trace
+// mode, every option change and state transition of the connection
+// is logged to l as well as a snapshot of connection information
+// every interval. A zero interval disables snapshots.
+// The records carry the local and remote addresses of the connection
+// in the "local" and "remote" attributes.
+//
+// Calling SetTrace again replaces the trace mode. A nil l turns off
+// the trace mode.
+//
+// State transitions and snapshots require the connection information
+// that is not available on some platforms.
+func (c *Conn) SetTrace(l *slog.Logger, interval time.Duration) {
+	var t *tracer
+	if l != nil {
+		t = &tracer{
+			c:        c,
+			l:        l.With("local", c.LocalAddr().String(), "remote", c.RemoteAddr().String()),
+			interval: interval,
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+	}
+	c.traceMu.Lock()
+	old := c.trace
+	c.trace = t
+	c.traceMu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	if t != nil {
+		go t.run()
+	}
+}
+
+// SetTraceWriter turns on the trace mode of the connection with the
+// records written to w in the text format of log/slog. See SetTrace
+// for further information.
+func (c *Conn) SetTraceWriter(w io.Writer, interval time.Duration) {
+	c.SetTrace(slog.New(slog.NewTextHandler(w, nil)), interval)
+}
+
+func (c *Conn) tracer() *tracer {
+	c.traceMu.Lock()
+	defer c.traceMu.Unlock()
+	return c.trace
+}
+
+// A tracer logs the events of a connection in the trace mode.
+type tracer struct {
+	c        *Conn
+	l        *slog.Logger
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func (t *tracer) close() {
+	close(t.stop)
+	<-t.done
+}
+
+func (t *tracer) option(o, old tcpopt.Option, err error) {
+	attrs := []slog.Attr{
+		slog.Int("level", o.Level()),
+		slog.Int("name", o.Name()),
+		slog.Any("value", o),
+	}
+	if old != nil {
+		attrs = append(attrs, slog.Any("old", old))
+	}
+	if err != nil {
+		t.l.LogAttrs(context.Background(), slog.LevelWarn, "set option", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	t.l.LogAttrs(context.Background(), slog.LevelInfo, "set option", attrs...)
+}
+
+func (t *tracer) run() {
+	defer close(t.done)
+	tick := t.interval
+	if tick <= 0 {
+		tick = defaultTraceInterval
+	}
+	tk := time.NewTicker(tick)
+	defer tk.Stop()
+	var state tcpinfo.State
+	known := false
+	for {
+		info, err := connInfo(t.c)
+		if err != nil {
+			t.l.Info("trace stopped", "error", err)
+			return
+		}
+		if !known || info.State != state {
+			args := []any{"to", info.State.String()}
+			if known {
+				args = append(args, "from", state.String())
+			}
+			t.l.Info("state transition", args...)
+			state, known = info.State, true
+		}
+		if t.interval > 0 {
+			t.snapshot(info)
+		}
+		select {
+		case <-tk.C:
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *tracer) snapshot(info *tcpinfo.Info) {
+	attrs := []slog.Attr{
+		slog.String("state", info.State.String()),
+		slog.Duration("rtt", info.RTT),
+		slog.Duration("rttvar", info.RTTVar),
+		slog.Duration("rto", info.RTO),
+		slog.Int("snd_mss", int(info.SenderMSS)),
+	}
+	if cc := info.CongestionControl; cc != nil {
+		attrs = append(attrs, slog.Uint64("snd_cwnd", uint64(cc.SenderWindowSegs)), slog.Uint64("snd_ssthresh", uint64(cc.SenderSSThreshold)))
+	}
+	if info.Sys != nil {
+		d := counters(info)
+		attrs = append(attrs,
+			slog.Uint64("bytes_acked", d.BytesAcked),
+			slog.Uint64("bytes_received", d.BytesReceived),
+			slog.Uint64("segs_out", d.SegsOut),
+			slog.Uint64("segs_in", d.SegsIn),
+			slog.Uint64("retransmits", d.Retransmits),
+		)
+	}
+	t.l.LogAttrs(context.Background(), slog.LevelInfo, "stats", attrs...)
+}
@@ -0,0 +1,192 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mikioh/tcpinfo"
+)
+
+// A KeepAlivePolicy represents the bounds of keepalive parameters
+// adjusted by KeepAliveTuner.
+// Zero values are replaced with the defaults noted in comments.
+type KeepAlivePolicy struct {
+	Interval         time.Duration // interval of adjustments; default 30s
+	MinIdleInterval  time.Duration // default 10s
+	MaxIdleInterval  time.Duration // default 2h
+	MinProbeInterval time.Duration // default 1s
+	MaxProbeInterval time.Duration // default 75s
+	ProbeCount       int           // # of unanswered probes; default 4
+}
+
+func (p *KeepAlivePolicy) withDefaults() KeepAlivePolicy {
+	pp := *p
+	for _, v := range []struct {
+		d   *time.Duration
+		def time.Duration
+	}{
+		{&pp.Interval, 30 * time.Second},
+		{&pp.MinIdleInterval, 10 * time.Second},
+		{&pp.MaxIdleInterval, 2 * time.Hour},
+		{&pp.MinProbeInterval, time.Second},
+		{&pp.MaxProbeInterval, 75 * time.Second},
+	} {
+		if *v.d <= 0 {
+			*v.d = v.def
+		}
+	}
+	if pp.ProbeCount <= 0 {
+		pp.ProbeCount = 4
+	}
+	return pp
+}
+
+// Factors of adaptive keepalive. The probe interval is the
+// retransmission timeout estimated from the RTT multiplied by
+// tunerProbeFactor and doubled for each level of backoff, and the
+// idle interval is the probe interval multiplied by tunerIdleFactor.
+const (
+	tunerProbeFactor = 8
+	tunerIdleFactor  = 10
+	tunerMaxBackoff  = 4
+)
+
+// A KeepAliveTuner represents a controller that adjusts the
+// keepalive parameters of a connection.
+//
+// The tuner derives the parameters from the measured RTT, which
+// keeps dead-peer detection tight on fast paths and conservative on
+// slow paths. When retransmissions are observed since the previous
+// adjustment, the tuner backs off and doubles the intervals up to 16
+// times; each clean adjustment interval halves them back.
+//
+// Only Linux supports the retransmission counters. On the other
+// platforms the tuner follows the RTT only.
+type KeepAliveTuner struct {
+	c      *Conn
+	policy KeepAlivePolicy
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	mu          sync.Mutex
+	ka          KeepAlive // current parameters
+	backoff     uint      // level of backoff
+	retransmits uint64    // cumulative # of retransmitted segments
+	err         error
+}
+
+// NewKeepAliveTuner enables keepalive on connection c and returns a
+// new tuner that adjusts the keepalive parameters every interval of
+// policy p.
+// It makes the first adjustment before returning.
+func NewKeepAliveTuner(c *Conn, p *KeepAlivePolicy) (*KeepAliveTuner, error) {
+	pp := p.withDefaults()
+	if pp.MinIdleInterval > pp.MaxIdleInterval || pp.MinProbeInterval > pp.MaxProbeInterval {
+		return nil, errors.New("invalid keepalive policy")
+	}
+	t := &KeepAliveTuner{
+		c:      c,
+		policy: pp,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := t.tune(); err != nil {
+		return nil, err
+	}
+	go t.run()
+	return t, nil
+}
+
+// KeepAlive returns the keepalive parameters currently set by the
+// tuner.
+func (t *KeepAliveTuner) KeepAlive() KeepAlive {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ka
+}
+
+// Err returns the last error in adjustments.
+func (t *KeepAliveTuner) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Stop stops adjustments.
+// It leaves the current keepalive parameters as is.
+func (t *KeepAliveTuner) Stop() {
+	t.once.Do(func() { close(t.stop) })
+	<-t.done
+}
+
+func (t *KeepAliveTuner) run() {
+	defer close(t.done)
+	tick := time.NewTicker(t.policy.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			t.tune()
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *KeepAliveTuner) tune() error {
+	info, err := connInfo(t.c)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.err = err
+		return err
+	}
+	retransmits := counters(info).Retransmits
+	switch {
+	case retransmits > t.retransmits && t.backoff < tunerMaxBackoff:
+		t.backoff++
+	case retransmits == t.retransmits && t.backoff > 0:
+		t.backoff--
+	}
+	t.retransmits = retransmits
+	ka := t.policy.keepAlive(info, t.backoff)
+	if ka == t.ka {
+		return nil
+	}
+	if err := t.c.SetKeepAlive(&ka); err != nil {
+		t.err = err
+		return err
+	}
+	t.ka = ka
+	return nil
+}
+
+// keepAlive returns the keepalive parameters for the connection
+// information i and the level of backoff.
+func (p *KeepAlivePolicy) keepAlive(i *tcpinfo.Info, backoff uint) KeepAlive {
+	rto := i.RTT + 4*i.RTTVar
+	probe := clampDuration(tunerProbeFactor*rto<<backoff, p.MinProbeInterval, p.MaxProbeInterval)
+	idle := clampDuration(tunerIdleFactor*probe, p.MinIdleInterval, p.MaxIdleInterval)
+	return KeepAlive{
+		Enable:        true,
+		IdleInterval:  idle.Round(time.Second),
+		ProbeInterval: probe.Round(time.Second),
+		ProbeCount:    p.ProbeCount,
+	}
+}
+
+func clampDuration(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,41 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "errors"
+
+// SetULP attaches the upper layer protocol with the name, such as
+// "tls", "smc" or "espintcp", to the connection.
+// The kernel may load the module of the protocol on demand, and each
+// protocol has its own requirements on the state of the connection;
+// for example, "tls" requires an established connection.
+//
+// Only Linux supports this feature.
+func (c *Conn) SetULP(name string) error {
+	if name == "" {
+		return c.opError("set", errors.New("empty upper layer protocol name"))
+	}
+	if err := c.control(func(s uintptr) error { return setULP(s, name) }); err != nil {
+		return c.opError("set", err)
+	}
+	return nil
+}
+
+// ULP returns the name of the upper layer protocol attached to the
+// connection. It returns the empty string when no protocol is
+// attached.
+//
+// Only Linux supports this feature.
+func (c *Conn) ULP() (string, error) {
+	var name string
+	err := c.control(func(s uintptr) (err error) {
+		name, err = ulp(s)
+		return
+	})
+	if err != nil {
+		return "", c.opError("get", err)
+	}
+	return name, nil
+}
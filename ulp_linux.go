@@ -0,0 +1,37 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+)
+
+// maxULPNameLen is the maximum length of upper layer protocol name;
+// see TCP_ULP_NAME_MAX.
+const maxULPNameLen = 16
+
+func setULP(s uintptr, name string) error {
+	if len(name) >= maxULPNameLen {
+		return errors.New("upper layer protocol name too long")
+	}
+	if err := setsockopt(s, ianaProtocolTCP, sysTCP_ULP, []byte(name)); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func ulp(s uintptr) (string, error) {
+	var b [maxULPNameLen]byte
+	n, err := getsockopt(s, ianaProtocolTCP, sysTCP_ULP, b[:])
+	if err != nil {
+		return "", os.NewSyscallError("getsockopt", err)
+	}
+	if i := bytes.IndexByte(b[:n], 0); i >= 0 {
+		n = i
+	}
+	return string(b[:n]), nil
+}
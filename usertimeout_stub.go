@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux,!windows
+
+package tcp
+
+import "time"
+
+// userTimeoutUnit is the unit of UserTimeout value on the platforms
+// not supporting the option.
+const userTimeoutUnit = time.Millisecond
@@ -0,0 +1,135 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"errors"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/mikioh/tcpopt"
+)
+
+// Validate checks whether the socket option o can be set on the
+// current platform without touching any socket.
+// It checks the platform support, the range of value and the binary
+// encoding of o, and returns an OptionError with "validate" operation
+// on failure.
+//
+// A successful validation doesn't guarantee that the kernel accepts
+// o because the kernel may have further restrictions.
+func Validate(o tcpopt.Option) error {
+	if o.Name() < 1 {
+		return validateError(o, ErrNotSupported)
+	}
+	if err := validateRange(o); err != nil {
+		return validateError(o, err)
+	}
+	if _, err := o.Marshal(); err != nil {
+		return validateError(o, err)
+	}
+	return nil
+}
+
+// Validate checks whether the socket option o can be set on the
+// connection without touching the socket. See the package-level
+// Validate function for further information.
+func (c *Conn) Validate(o tcpopt.Option) error {
+	if err := Validate(o); err != nil {
+		err.(*OptionError).Addr = c.LocalAddr()
+		return err
+	}
+	return nil
+}
+
+func validateError(o tcpopt.Option, err error) error {
+	return &OptionError{Op: "validate", Level: o.Level(), Name: o.Name(), Err: err}
+}
+
+// Linux limits of keepalive parameters; see MAX_TCP_KEEPIDLE,
+// MAX_TCP_KEEPINTVL and MAX_TCP_KEEPCNT.
+const (
+	maxLinuxKeepAliveInterval = 32767 * time.Second
+	maxLinuxKeepAliveCount    = 127
+)
+
+func validateRange(o tcpopt.Option) error {
+	switch o := o.(type) {
+	case tcpopt.MSS:
+		return validateInt(int(o), 0)
+	case tcpopt.SendBuffer:
+		return validateInt(int(o), 1)
+	case tcpopt.ReceiveBuffer:
+		return validateInt(int(o), 1)
+	case tcpopt.NotSentLowWMK:
+		return validateInt(int(o), 0)
+	case tcpopt.KeepAliveProbeCount:
+		if err := validateInt(int(o), 1); err != nil {
+			return err
+		}
+		if runtime.GOOS == "linux" && o > maxLinuxKeepAliveCount {
+			return errors.New("keepalive probe count out of range")
+		}
+	case tcpopt.KeepAliveIdleInterval:
+		return validateKeepAliveInterval(time.Duration(o))
+	case tcpopt.KeepAliveProbeInterval:
+		return validateKeepAliveInterval(time.Duration(o))
+	case SendBufferForce:
+		return validateInt(int(o), 1)
+	case ReceiveBufferForce:
+		return validateInt(int(o), 1)
+	case WindowClamp:
+		return validateInt(int(o), 0)
+	case Priority:
+		return validateInt(int(o), 0)
+	case ReceiveLowWMK:
+		return validateInt(int(o), 1)
+	case SendLowWMK:
+		return validateInt(int(o), 1)
+	case ConnectionTimeout:
+		return validateDuration(time.Duration(o), connectionTimeoutUnit)
+	case RetransmitConnDropTime:
+		return validateDuration(time.Duration(o), time.Second)
+	case UserTimeout:
+		return validateDuration(time.Duration(o), userTimeoutUnit)
+	case Linger2:
+		if time.Duration(o) > maxLinger2 {
+			return errors.New("fin-wait-2 lifetime out of range")
+		}
+	case MinRTO:
+		if o <= 0 || time.Duration(o) > maxMinRTO {
+			return errors.New("minimum retransmission timeout out of range")
+		}
+	}
+	return nil
+}
+
+func validateInt(v, min int) error {
+	if v < min || v > math.MaxInt32 {
+		return errors.New("value out of range")
+	}
+	return nil
+}
+
+func validateDuration(d, unit time.Duration) error {
+	if d < 0 || d/unit > math.MaxInt32 {
+		return errors.New("duration out of range")
+	}
+	return nil
+}
+
+func validateKeepAliveInterval(d time.Duration) error {
+	if runtime.GOOS == "windows" {
+		return validateDuration(d, time.Millisecond)
+	}
+	if d < time.Second || d/time.Second > math.MaxInt32 {
+		return errors.New("keepalive interval out of range")
+	}
+	if runtime.GOOS == "linux" && d > maxLinuxKeepAliveInterval {
+		return errors.New("keepalive interval out of range")
+	}
+	return nil
+}
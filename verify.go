@@ -0,0 +1,43 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"io"
+)
+
+// VerifyPeer determines whether the peer of the connection is still
+// alive without disturbing application data, which allows connection
+// pools to validate idle connections before reuse.
+//
+// It first inspects the local state of the connection. A pending
+// socket error or a reset is returned as an error, and the FIN
+// segment from the peer is returned as io.EOF. Unread data from the
+// peer proves the peer alive.
+// On Linux, it then elicits an acknowledgment from the peer by a
+// keepalive probe and waits for the acknowledgment until ctx is
+// done. The probe is sent once the connection has been idle for a
+// second, and the keepalive parameters are restored before
+// returning. It must not be called concurrently with SetKeepAlive.
+//
+// It returns nil when the peer is alive.
+//
+// Only Darwin, DragonFly BSD, FreeBSD, Linux, NetBSD and OpenBSD
+// support this feature, and only Linux probes the peer.
+func (c *Conn) VerifyPeer(ctx context.Context) error {
+	var pending bool
+	err := c.control(func(s uintptr) (err error) {
+		pending, err = peerState(s)
+		return
+	})
+	if err == nil && !pending {
+		err = c.probePeer(ctx)
+	}
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return c.opError("verify", err)
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package tcp
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// peerState returns the local state of the connection on the socket
+// s. It reports whether unread data is pending, and returns io.EOF
+// when the peer has closed its side.
+func peerState(s uintptr) (pending bool, err error) {
+	if err := sockError(s); err != nil {
+		return false, err
+	}
+	var b [1]byte
+	for {
+		n, _, err := syscall.Recvfrom(int(s), b[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch err {
+		case nil:
+			if n == 0 {
+				return false, io.EOF
+			}
+			return true, nil
+		case syscall.EINTR:
+			continue
+		case syscall.EAGAIN:
+			return false, nil
+		}
+		return false, os.NewSyscallError("recvfrom", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// ListenVRF announces on the local address in the VRF of the device
+// vrf, which is the name of a VRF device such as "vrf-blue", or any
+// other network interface, using SO_BINDTODEVICE option.
+// The socket is bound to the device before binding to the address,
+// which allows the listeners in different VRFs to share the same
+// address and port.
+//
+// When the net.ipv4.tcp_l3mdev_accept sysctl is enabled, a listener
+// not bound to any VRF accepts connections in all the VRFs as well;
+// see L3mdevAccept and Conn.VRF.
+//
+// Older kernels than Linux 5.7 require the CAP_NET_RAW capability.
+// Only Linux supports this feature.
+func ListenVRF(ctx context.Context, network, address, vrf string) (*Listener, error) {
+	if vrf == "" {
+		return nil, errors.New("empty vrf device name")
+	}
+	lc := net.ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+		var operr error
+		if err := c.Control(func(s uintptr) {
+			operr = bindToDevice(s, vrf)
+		}); err != nil {
+			return err
+		}
+		return operr
+	}}
+	ln, err := lc.Listen(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	tln, err := NewListener(ln)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return tln, nil
+}
+
+// VRF returns the name of the device, such as a VRF device, to which
+// the connection is bound. It returns the empty string when the
+// connection is not bound to any device.
+//
+// The connection accepted by a listener not bound to any VRF is
+// bound to the VRF of the incoming interface when the
+// net.ipv4.tcp_l3mdev_accept sysctl is enabled.
+//
+// Only Linux supports this feature.
+func (c *Conn) VRF() (string, error) {
+	var name string
+	err := c.control(func(s uintptr) (err error) {
+		name, err = boundDevice(s)
+		return
+	})
+	if err != nil {
+		return "", c.opError("get", err)
+	}
+	return name, nil
+}
+
+// L3mdevAccept reports whether the net.ipv4.tcp_l3mdev_accept sysctl
+// of the network namespace of the calling thread is enabled, which
+// applies to both IPv4 and IPv6 listeners.
+// When enabled, the listeners not bound to any VRF accept the
+// connections in all the VRFs; otherwise they accept only the
+// connections in the default VRF, and the services in the other VRFs
+// require the listeners created by ListenVRF.
+//
+// Only Linux supports this feature.
+func L3mdevAccept() (bool, error) { return l3mdevAccept() }
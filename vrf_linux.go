@@ -0,0 +1,49 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// ifNameSize is the size of network interface name including the
+// terminating NUL; see IFNAMSIZ.
+const ifNameSize = 16
+
+func bindToDevice(s uintptr, name string) error {
+	if len(name) >= ifNameSize {
+		return errors.New("device name too long")
+	}
+	if err := setsockopt(s, sysSOL_SOCKET, sysSO_BINDTODEVICE, []byte(name)); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
+
+func boundDevice(s uintptr) (string, error) {
+	var b [ifNameSize]byte
+	n, err := getsockopt(s, sysSOL_SOCKET, sysSO_BINDTODEVICE, b[:])
+	if err != nil {
+		return "", os.NewSyscallError("getsockopt", err)
+	}
+	if i := bytes.IndexByte(b[:n], 0); i >= 0 {
+		n = i
+	}
+	return string(b[:n]), nil
+}
+
+func l3mdevAccept() (bool, error) {
+	b, err := ioutil.ReadFile("/proc/sys/net/ipv4/tcp_l3mdev_accept")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, ErrNotSupported // kernel without VRF support
+		}
+		return false, err
+	}
+	return len(b) > 0 && b[0] != '0', nil
+}
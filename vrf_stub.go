@@ -0,0 +1,13 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package tcp
+
+func bindToDevice(s uintptr, name string) error { return ErrNotSupported }
+
+func boundDevice(s uintptr) (string, error) { return "", ErrNotSupported }
+
+func l3mdevAccept() (bool, error) { return false, ErrNotSupported }
@@ -0,0 +1,39 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+// A ZeroWindowStats represents the statistics of the flow control by
+// the peer's receive window. A peer that stops reading closes its
+// receive window, which makes the kernel hold the data and probe the
+// window with the persist timer.
+type ZeroWindowStats struct {
+	PeerWindow  int           // receive window currently advertised by peer
+	Probes      int           // # of unanswered zero window or keepalive probes
+	Backoffs    int           // # of backoffs of persist or retransmission timer
+	RwndLimited time.Duration // total time sending was limited by peer's receive window
+	BusyTime    time.Duration // total time sending data was in progress
+}
+
+// ZeroWindowStats returns the statistics of the flow control by the
+// peer's receive window.
+// The application may abandon the peer when the probes keep going
+// unanswered with zero PeerWindow.
+//
+// Only Linux supports this feature. RwndLimited and BusyTime require
+// Linux 4.10 or above, and PeerWindow requires Linux
+// 5.4 or above.
+func (c *Conn) ZeroWindowStats() (*ZeroWindowStats, error) {
+	var st *ZeroWindowStats
+	err := c.control(func(s uintptr) (err error) {
+		st, err = zeroWindowStats(s)
+		return
+	})
+	if err != nil {
+		return nil, c.opError("get", err)
+	}
+	return st, nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2017 Mikio Hara. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+func zeroWindowStats(s uintptr) (*ZeroWindowStats, error) {
+	ti, err := getTCPInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	return &ZeroWindowStats{
+		PeerWindow:  int(ti.Snd_wnd),
+		Probes:      int(ti.Probes),
+		Backoffs:    int(ti.Backoff),
+		RwndLimited: time.Duration(ti.Rwnd_limited) * time.Microsecond,
+		BusyTime:    time.Duration(ti.Busy_time) * time.Microsecond,
+	}, nil
+}
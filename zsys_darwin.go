@@ -6,15 +6,36 @@ package tcp
 const (
 	sysSOL_SOCKET = 0xffff
 
+	sysSO_REUSEADDR = 0x4
+	sysSO_REUSEPORT = 0x200
+	sysSO_SNDLOWAT  = 0x1003
+	sysSO_RCVLOWAT  = 0x1004
+	sysSO_OOBINLINE = 0x100
+
 	sysFIONREAD = 0x4004667f
 
 	sysSO_NREAD     = 0x1020
 	sysSO_NWRITE    = 0x1024
 	sysSO_NUMRCVPKT = 0x1112
 
+	sysTCP_NOOPT             = 0x8
+	sysTCP_CONNECTIONTIMEOUT = 0x20
+	sysTCP_RXT_CONNDROPTIME  = 0x80
+	sysTCP_ENABLE_ECN        = 0x104
+	sysTCP_CONNECTION_INFO   = 0x106
+
+	sysTCPCI_OPT_ECN = 0x8
+
 	sysAF_INET  = 0x2
 	sysAF_INET6 = 0x1e
 
+	sysSYS_CONNECTX = 0x1bf
+
+	sysSAE_ASSOCID_ANY = 0x0
+
+	sysCONNECT_RESUME_ON_READ_WRITE = 0x1
+	sysCONNECT_DATA_IDEMPOTENT      = 0x2
+
 	sysPF_INOUT = 0
 	sysPF_IN    = 1
 	sysPF_OUT   = 2
@@ -68,10 +89,22 @@ type pfiocNatlook struct {
 	Direction uint8
 }
 
+type saEndpoints struct {
+	Srcif      uint32
+	Pad_cgo_0  [4]byte
+	Srcaddr    *sockaddr
+	Srcaddrlen uint32
+	Pad_cgo_1  [4]byte
+	Dstaddr    *sockaddr
+	Dstaddrlen uint32
+	Pad_cgo_2  [4]byte
+}
+
 const (
 	sizeofSockaddrStorage = 0x80
 	sizeofSockaddr        = 0x10
 	sizeofSockaddrInet    = 0x10
 	sizeofSockaddrInet6   = 0x1c
 	sizeofPfiocNatlook    = 0x54
+	sizeofSaEndpoints     = 0x28
 )
@@ -4,6 +4,16 @@
 package tcp
 
 const (
+	sysSOL_SOCKET = 0xffff
+
+	sysSO_REUSEADDR = 0x4
+	sysSO_REUSEPORT = 0x200
+	sysSO_SNDLOWAT  = 0x1003
+	sysSO_RCVLOWAT  = 0x1004
+	sysSO_OOBINLINE = 0x100
+
+	sysTCP_KEEPINIT = 0x20
+
 	sysFIONREAD = 0x4004667f
 
 	sysAF_INET  = 0x2
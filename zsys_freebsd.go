@@ -4,6 +4,31 @@
 package tcp
 
 const (
+	sysSOL_SOCKET = 0xffff
+
+	sysSO_REUSEADDR    = 0x4
+	sysSO_REUSEPORT    = 0x200
+	sysSO_REUSEPORT_LB = 0x10000
+	sysSO_SNDLOWAT     = 0x1003
+	sysSO_RCVLOWAT     = 0x1004
+	sysSO_OOBINLINE    = 0x100
+	sysSO_TYPE         = 0x1008
+	sysSO_PROTOCOL     = 0x1016
+	sysSO_DOMAIN       = 0x1019
+	sysSO_USER_COOKIE  = 0x1015
+
+	sysTCP_KEEPINIT = 0x80
+
+	sysTCP_FUNCTION_BLK          = 0x2000
+	sysTCP_FUNCTION_NAME_LEN_MAX = 0x20
+
+	sysTCP_LOG            = 0x22
+	sysTCP_LOGBUF         = 0x23
+	sysTCP_LOGID          = 0x24
+	sysTCP_LOGDUMP        = 0x25
+	sysTCP_LOG_ID_LEN     = 0x40
+	sysTCP_LOG_REASON_LEN = 0x20
+
 	sysFIONREAD  = 0x4004667f
 	sysFIONWRITE = 0x40046677
 	sysFIONSPACE = 0x40046676
@@ -71,4 +96,5 @@ const (
 	sizeofSockaddrInet    = 0x10
 	sizeofSockaddrInet6   = 0x1c
 	sizeofPfiocNatlook    = 0x4c
+	sizeofTCPFunctionSet  = 0x24
 )
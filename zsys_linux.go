@@ -4,11 +4,146 @@
 package tcp
 
 const (
-	sysSIOCINQ  = 0x541b
-	sysSIOCOUTQ = 0x5411
+	sysSIOCINQ     = 0x541b
+	sysSIOCOUTQ    = 0x5411
+	sysSIOCOUTQNSD = 0x894b
+
+	sysSOL_SOCKET = 0x1
+
+	sysSO_ATTACH_FILTER = 0x1a
+	sysSO_DETACH_FILTER = 0x1b
+	sysSO_ATTACH_BPF    = 0x32
+	sysSO_COOKIE        = 0x39
+	sysSO_MEMINFO       = 0x37
+	sysSO_REUSEADDR     = 0x2
+	sysSO_REUSEPORT     = 0xf
+	sysSO_RCVLOWAT      = 0x12
+	sysSO_OOBINLINE     = 0xa
+	sysSO_TIMESTAMPNS   = 0x23
+	sysSO_ZEROCOPY      = 0x3c
+	sysSO_SNDBUFFORCE   = 0x20
+	sysSO_RCVBUFFORCE   = 0x21
+	sysSO_PRIORITY      = 0xc
+	sysSO_DOMAIN        = 0x27
+	sysSO_TYPE          = 0x3
+	sysSO_PROTOCOL      = 0x26
+	sysSO_BINDTODEVICE  = 0x19
+
+	sysSO_MAX_PACING_RATE = 0x2f
+
+	sysSO_ATTACH_REUSEPORT_CBPF = 0x33
+	sysSO_ATTACH_REUSEPORT_EBPF = 0x34
+	sysSO_DETACH_REUSEPORT_BPF  = 0x44
+
+	sysBPF_OBJ_GET            = 0x7
+	sysBPF_MAP_UPDATE_ELEM    = 0x2
+	sysBPF_MAP_DELETE_ELEM    = 0x3
+	sysBPF_PROG_ATTACH        = 0x8
+	sysBPF_PROG_DETACH        = 0x9
+	sysBPF_OBJ_GET_INFO_BY_FD = 0xf
+
+	sysBPF_MAP_TYPE_SOCKMAP  = 0xf
+	sysBPF_MAP_TYPE_SOCKHASH = 0x12
+
+	sysBPF_SK_SKB_STREAM_PARSER  = 0x4
+	sysBPF_SK_SKB_STREAM_VERDICT = 0x5
+	sysBPF_SK_MSG_VERDICT        = 0x7
+	sysBPF_SK_SKB_VERDICT        = 0x26
+
+	sysEPOLLET = 0x80000000
+
+	sysSPLICE_F_MOVE     = 0x1
+	sysSPLICE_F_NONBLOCK = 0x2
+
+	sysSK_MEMINFO_VARS = 0x9
+
+	sysIP_MTU_DISCOVER   = 0xa
+	sysIP_MTU            = 0xe
+	sysIPV6_MTU_DISCOVER = 0x17
+	sysIPV6_MTU          = 0x18
+	sysIP_RECVERR        = 0xb
+	sysIPV6_RECVERR      = 0x19
+
+	sysIPV6_FLOWINFO      = 0xb
+	sysIPV6_FLOWLABEL_MGR = 0x20
+	sysIPV6_FLOWINFO_SEND = 0x21
+
+	sysIPV6_FL_A_GET    = 0x0
+	sysIPV6_FL_S_ANY    = 0xff
+	sysIPV6_FL_F_CREATE = 0x1
+	sysIPV6_FL_F_REMOTE = 0x8
+
+	sysSO_EE_ORIGIN_LOCAL = 0x1
+	sysSO_EE_ORIGIN_ICMP  = 0x2
+	sysSO_EE_ORIGIN_ICMP6 = 0x3
+
+	sysIP_PMTUDISC_DONT  = 0x0
+	sysIP_PMTUDISC_WANT  = 0x1
+	sysIP_PMTUDISC_DO    = 0x2
+	sysIP_PMTUDISC_PROBE = 0x3
 
 	sysSO_ORIGINAL_DST      = 0x50
 	sysIP6T_SO_ORIGINAL_DST = 0x50
+
+	sysTCP_INFO               = 0xb
+	sysTCP_MD5SIG             = 0xe
+	sysTCP_MD5SIG_EXT         = 0x20
+	sysTCP_WINDOW_CLAMP       = 0xa
+	sysTCP_LINGER2            = 0x8
+	sysTCP_USER_TIMEOUT       = 0x12
+	sysTCP_FASTOPEN_CONNECT   = 0x1e
+	sysTCP_FASTOPEN_NO_COOKIE = 0x22
+	sysTCP_RTO_MIN_US         = 0x2d
+	sysTCP_ULP                = 0x1f
+	sysTCP_DEFER_ACCEPT       = 0x9
+	sysTCP_SAVE_SYN           = 0x1b
+	sysTCP_SAVED_SYN          = 0x1c
+
+	sysTCPI_OPT_TIMESTAMPS = 0x1
+	sysTCPI_OPT_SACK       = 0x2
+	sysTCPI_OPT_WSCALE     = 0x4
+	sysTCPI_OPT_ECN        = 0x8
+	sysTCPI_OPT_ECN_SEEN   = 0x10
+	sysTCPI_OPT_SYN_DATA   = 0x20
+
+	sysTCP_MD5SIG_FLAG_PREFIX  = 0x1
+	sysTCP_MD5SIG_FLAG_IFINDEX = 0x2
+	sysTCP_MD5SIG_MAXKEYLEN    = 0x50
+
+	sysTCP_ESTABLISHED = 0x1
+	sysTCP_SYN_SENT    = 0x2
+	sysTCP_SYN_RECV    = 0x3
+	sysTCP_FIN_WAIT1   = 0x4
+	sysTCP_FIN_WAIT2   = 0x5
+	sysTCP_TIME_WAIT   = 0x6
+	sysTCP_CLOSE       = 0x7
+	sysTCP_CLOSE_WAIT  = 0x8
+	sysTCP_LAST_ACK    = 0x9
+	sysTCP_LISTEN      = 0xa
+	sysTCP_CLOSING     = 0xb
+
+	sysNETLINK_INET_DIAG   = 0x4
+	sysSOCK_DIAG_BY_FAMILY = 0x14
+	sysSOCK_DESTROY        = 0x15
+
+	sysAF_SMC = 0x2b
+
+	sysSMC_DIAG_MODE_SMCR         = 0x0
+	sysSMC_DIAG_MODE_FALLBACK_TCP = 0x1
+	sysSMC_DIAG_MODE_SMCD         = 0x2
+
+	sysSKNLGRP_INET_TCP_DESTROY  = 0x1
+	sysSKNLGRP_INET6_TCP_DESTROY = 0x3
+
+	sysINET_DIAG_REQ_BYTECODE = 0x1
+	sysINET_DIAG_INFO         = 0x2
+
+	sysINET_DIAG_BC_S_GE   = 0x2
+	sysINET_DIAG_BC_S_LE   = 0x3
+	sysINET_DIAG_BC_D_GE   = 0x4
+	sysINET_DIAG_BC_D_LE   = 0x5
+	sysINET_DIAG_BC_S_COND = 0x7
+	sysINET_DIAG_BC_D_COND = 0x8
 )
 
 type sockaddrStorage struct {
@@ -38,9 +173,150 @@ type sockaddrInet6 struct {
 	Scope_id uint32
 }
 
+type tcpInfo struct {
+	State           uint8
+	Ca_state        uint8
+	Retransmits     uint8
+	Probes          uint8
+	Backoff         uint8
+	Options         uint8
+	Pad_cgo_0       [1]byte
+	Pad_cgo_1       [1]byte
+	Rto             uint32
+	Ato             uint32
+	Snd_mss         uint32
+	Rcv_mss         uint32
+	Unacked         uint32
+	Sacked          uint32
+	Lost            uint32
+	Retrans         uint32
+	Fackets         uint32
+	Last_data_sent  uint32
+	Last_ack_sent   uint32
+	Last_data_recv  uint32
+	Last_ack_recv   uint32
+	Pmtu            uint32
+	Rcv_ssthresh    uint32
+	Rtt             uint32
+	Rttvar          uint32
+	Snd_ssthresh    uint32
+	Snd_cwnd        uint32
+	Advmss          uint32
+	Reordering      uint32
+	Rcv_rtt         uint32
+	Rcv_space       uint32
+	Total_retrans   uint32
+	Pacing_rate     uint64
+	Max_pacing_rate uint64
+	Bytes_acked     uint64
+	Bytes_received  uint64
+	Segs_out        uint32
+	Segs_in         uint32
+	Notsent_bytes   uint32
+	Min_rtt         uint32
+	Data_segs_in    uint32
+	Data_segs_out   uint32
+	Delivery_rate   uint64
+	Busy_time       uint64
+	Rwnd_limited    uint64
+	Sndbuf_limited  uint64
+	Delivered       uint32
+	Delivered_ce    uint32
+	Bytes_sent      uint64
+	Bytes_retrans   uint64
+	Dsack_dups      uint32
+	Reord_seen      uint32
+	Rcv_ooopack     uint32
+	Snd_wnd         uint32
+	Rcv_wnd         uint32
+	Rehash          uint32
+}
+
+type inetDiagSockID struct {
+	Sport  uint16
+	Dport  uint16
+	Src    [4]uint32
+	Dst    [4]uint32
+	If     uint32
+	Cookie [2]uint32
+}
+
+type inetDiagReqV2 struct {
+	Family   uint8
+	Protocol uint8
+	Ext      uint8
+	Pad      uint8
+	States   uint32
+	Id       inetDiagSockID
+}
+
+type inetDiagMsg struct {
+	Family  uint8
+	State   uint8
+	Timer   uint8
+	Retrans uint8
+	Id      inetDiagSockID
+	Expires uint32
+	Rqueue  uint32
+	Wqueue  uint32
+	Uid     uint32
+	Inode   uint32
+}
+
+type smcDiagReq struct {
+	Family uint8
+	Pad    [2]uint8
+	Ext    uint8
+	Id     inetDiagSockID
+}
+
+type smcDiagMsg struct {
+	Family   uint8
+	State    uint8
+	Mode     uint8
+	Shutdown uint8
+	Id       inetDiagSockID
+	Uid      uint32
+	Inode    uint64
+}
+
+type inetDiagBcOp struct {
+	Code uint8
+	Yes  uint8
+	No   uint16
+}
+
+type inetDiagHostcond struct {
+	Family     uint8
+	Prefix_len uint8
+	Pad_cgo_0  [2]byte
+	Port       int32
+}
+
+type sockExtendedErr struct {
+	Errno  uint32
+	Origin uint8
+	Type   uint8
+	Code   uint8
+	Pad    uint8
+	Info   uint32
+	Data   uint32
+}
+
 const (
-	sizeofSockaddrStorage = 0x80
-	sizeofSockaddr        = 0x10
-	sizeofSockaddrInet    = 0x10
-	sizeofSockaddrInet6   = 0x1c
+	sizeofSockaddrStorage  = 0x80
+	sizeofSockaddr         = 0x10
+	sizeofSockaddrInet     = 0x10
+	sizeofSockaddrInet6    = 0x1c
+	sizeofIn6FlowlabelReq  = 0x20
+	sizeofTCPInfo          = 0xf0
+	sizeofInetDiagSockID   = 0x30
+	sizeofInetDiagReqV2    = 0x38
+	sizeofInetDiagMsg      = 0x48
+	sizeofInetDiagBcOp     = 0x4
+	sizeofSMCDiagReq       = 0x34
+	sizeofSMCDiagMsg       = 0x40
+	sizeofInetDiagHostcond = 0x8
+	sizeofTCPMD5Sig        = 0xd8
+	sizeofSockExtendedErr  = 0x10
 )